@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spf13/cobra"
+)
+
+var ApplyCmd = &cobra.Command{
+	Use:   "apply <plan.json>",
+	Short: "Download the assets of a plan written by get --plan-out",
+	Long: `Runs only the download phase of the get command, from a plan written by get --plan-out.
+The pages are not fetched again and the configuration file is not read, the plan contains
+the destinations, the info files and the network options of every asset.
+Destinations are relative to the location of the plan, use --location to download somewhere else.`,
+	Example: `  grab get urls.ini --plan-out plan.json
+  grab apply plan.json
+  grab apply plan.json --location ~/Downloads/grab`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return utils.Getwd()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.Logger = log.Output(instance.DefaultLogger(cmd.OutOrStderr()))
+
+		g := instance.New(cmd)
+		g.ParseFlags()
+
+		humanOut, err := setupOutput(cmd, g)
+		if err != nil {
+			return err
+		}
+
+		log.Logger = log.Output(instance.DefaultLogger(humanOut))
+
+		report := instance.NewReport()
+		g.OnEvent(report.Handle)
+
+		path := utils.Abs(args[0])
+
+		fc, err := utils.Io.ReadFile(utils.Fs, path)
+		if err != nil {
+			log.Err(err).Str("path", path).Msg("could not read the plan")
+			return errConfig
+		}
+
+		plan, err := instance.LoadPlan(fc)
+		if err != nil {
+			log.Err(err).Str("path", path).Msg("invalid plan")
+			return errConfig
+		}
+
+		location, _ := cmd.Flags().GetString("location")
+		if location != "" {
+			location = utils.Abs(location)
+		}
+
+		g.ApplyPlan(plan, location)
+
+		journal, err := openJournal(g)
+		if err != nil {
+			log.Err(err).Msg("could not read the journal of the failed items")
+			return utils.ErrSilent
+		}
+
+		log.Info().Str("path", path).Msgf("applying plan, %d %s to download", plan.Count(), utils.Plural(plan.Count(), "asset", "assets"))
+
+		err = g.Download()
+
+		return finishRun(cmd, g, report, journal, humanOut, err)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(ApplyCmd)
+
+	ApplyCmd.Flags().BoolP("force", "f", false, "overwrite existing files")
+	ApplyCmd.Flags().String("on-exists", "", "what happens to the existing files (skip, overwrite, rename, if_newer or if_changed), replaces the on_exists attributes")
+	ApplyCmd.Flags().StringP("location", "l", "", "download to this directory instead of the location of the plan")
+
+	ApplyCmd.Flags().BoolP("strict", "s", false, "fail on errors")
+	ApplyCmd.Flags().BoolP("dry-run", "n", false, "do not write on disk")
+
+	ApplyCmd.Flags().BoolP("quiet", "q", false, "do not emit any output")
+	ApplyCmd.Flags().CountP("verbose", "v", "verbosity level")
+
+	ApplyCmd.Flags().StringP("output", "o", "text", "the output format (text or json), json emits one event per line on stdout")
+	ApplyCmd.Flags().String("report", "", "write the summary of the run to a JSON file")
+	ApplyCmd.Flags().String("print", "", "a Go template printed on stdout for every downloaded file, e.g. '{{.Destination}}'")
+}
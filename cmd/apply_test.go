@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestApplyCmd(t *testing.T) {
+	root := tu.GetOSRoot()
+	globalLocation := filepath.Join(root, "global")
+	otherLocation := filepath.Join(root, "other")
+	planPath := filepath.Join(root, "plan.json")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	config.LatestReleaseURL = ts.URL + "/bad_releases"
+
+	configContent := `
+global {
+	location = "` + tu.EscapeHCLString(globalLocation) + `"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/[^\"]+)"
+		capture = 1
+		find_all = true
+	}
+}
+`
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+	utils.Fs.MkdirAll(globalLocation, os.ModePerm)
+	utils.Io.WriteFile(utils.Fs, filepath.Join(root, "grab.hcl"), []byte(configContent), os.ModePerm)
+
+	GetCmd.Flags().Set("output", "text")
+	GetCmd.Flags().Set("print", "")
+	defer GetCmd.Flags().Set("plan-out", "")
+
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "get", ts.URL+"/gallery/123/test", "--plan-out", "plan.json"); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	// nothing is downloaded when writing the plan
+	if exists, _ := utils.Io.Exists(utils.Fs, filepath.Join(globalLocation, "example", "a.jpg")); exists {
+		t.Fatalf("got: a.jpg, want: no downloads")
+	}
+
+	if exists, _ := utils.Io.Exists(utils.Fs, planPath); !exists {
+		t.Fatalf("got: no plan, want: %s", planPath)
+	}
+
+	tests := []struct {
+		Name    string
+		Args    []string
+		Want    map[string]string
+		WantErr bool
+	}{
+		{
+			Name: "location of the plan",
+			Args: []string{planPath},
+			Want: map[string]string{
+				filepath.Join(globalLocation, "example", "a.jpg"): "imagea",
+				filepath.Join(globalLocation, "example", "b.jpg"): "imageb",
+				filepath.Join(globalLocation, "example", "c.jpg"): "imagec",
+			},
+		},
+		{
+			Name: "other location",
+			Args: []string{"plan.json", "--location", "other"},
+			Want: map[string]string{
+				filepath.Join(otherLocation, "example", "a.jpg"): "imagea",
+			},
+		},
+		{
+			Name:    "missing plan",
+			Args:    []string{"missing.json"},
+			WantErr: true,
+		},
+		{
+			Name:    "no arguments",
+			Args:    []string{},
+			WantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			ApplyCmd.Flags().Set("location", "")
+
+			c, _, _, err := tu.ExecuteCommandErr(RootCmd, append([]string{"apply"}, tt.Args...)...)
+
+			if c.Name() != ApplyCmd.Name() {
+				tc.Fatalf("got: %s, want: %s", c.Name(), ApplyCmd.Name())
+			}
+
+			if (err != nil) != tt.WantErr {
+				tc.Fatalf("got: %v, want errors: %v", err, tt.WantErr)
+			}
+
+			for f, v := range tt.Want {
+				if got, _ := utils.Io.ReadFile(utils.Fs, f); string(got) != v {
+					tc.Errorf("got: %q, want: %q", string(got), v)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyCmdExitCode(t *testing.T) {
+	root := tu.GetOSRoot()
+	globalLocation := filepath.Join(root, "global")
+	planPath := filepath.Join(root, "plan.json")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	config.LatestReleaseURL = ts.URL + "/bad_releases"
+
+	// a plan fetches no pages, its downloads alone decide the exit code
+	plan := func(sources ...string) string {
+		downloads := make([]string, 0, len(sources))
+		for i, src := range sources {
+			downloads = append(downloads, fmt.Sprintf(`{"source": "%s", "destination": "example/%d.jpg"}`, ts.URL+src, i))
+		}
+
+		return `{"version": 1, "location": "` + tu.EscapeHCLString(globalLocation) + `", "sites": [
+	{"name": "example", "assets": [{"name": "image", "network": {}, "downloads": [` + strings.Join(downloads, ",") + `]}], "infos": []}
+]}`
+	}
+
+	tests := []struct {
+		Name     string
+		Plan     string
+		WantCode int
+	}{
+		{
+			Name:     "all downloaded",
+			Plan:     plan("/img/a.jpg", "/img/b.jpg"),
+			WantCode: utils.ExitOK,
+		},
+		{
+			Name:     "some downloads failed",
+			Plan:     plan("/img/a.jpg", "/img/b.jpg", "/givesNotFound"),
+			WantCode: utils.ExitPartial,
+		},
+		{
+			Name:     "all downloads failed",
+			Plan:     plan("/givesNotFound"),
+			WantCode: utils.ExitFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			utils.Fs.MkdirAll(globalLocation, os.ModePerm)
+			utils.Io.WriteFile(utils.Fs, planPath, []byte(tt.Plan), os.ModePerm)
+
+			ApplyCmd.Flags().Set("location", "")
+			GetCmd.Flags().Set("output", "text")
+			GetCmd.Flags().Set("print", "")
+
+			if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "apply", planPath); utils.ExitCode(err) != tt.WantCode {
+				tc.Errorf("got: %v, want: exit code %d", err, tt.WantCode)
+			}
+		})
+	}
+}
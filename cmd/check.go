@@ -24,7 +24,7 @@ var CheckCmd = &cobra.Command{
 		if configPath == "" {
 			configPath = utils.Wd
 
-			resolved, err := config.Resolve("grab.hcl", configPath)
+			resolved, err := config.ResolveConfig(configPath)
 			if err != nil {
 
 				utils.PrintDiag(cmd.ErrOrStderr(), &hcl.Diagnostic{
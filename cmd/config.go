@@ -10,11 +10,26 @@ var ConfigCmd = &cobra.Command{
 	Example: `  Check for errors in the configuration file:
     grab config check -c ../grab.hcl
 
+  Format the configuration file, failing if it was not formatted:
+    grab config fmt --check
+
   Generate the default configuration:
     grab config generate
 
   Print the path of the closest config file:
-    grab config find`,
+    grab config find
+
+  Run the fixtures defined in the configuration:
+    grab config test
+
+  Print the resolved configuration as json:
+    grab config show -o json
+
+  Explain which site handles a url:
+    grab config which https://example.com/gallery/1337
+
+  Save the JSON Schema of the json and yaml formats:
+    grab config schema > grab.schema.json`,
 }
 
 func init() {
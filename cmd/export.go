@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/cobra"
+)
+
+var ExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Collect the info of the scraped pages into a single dataset",
+	Long: `Reads the info records stored by the index of the configuration: the _info.json
+files under the global location, or the file of the ndjson, csv and sqlite formats.
+The records of all the sites are written on stdout as a single dataset.
+The csv format has a column for the site, the directory and each info value.`,
+	Example: `  grab export > info.csv
+  grab export --format ndjson --site example > example.ndjson`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return utils.Getwd()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		sites, _ := cmd.Flags().GetStringSlice("site")
+
+		log.Logger = log.Output(instance.DefaultLogger(cmd.ErrOrStderr()))
+
+		if format != "csv" && format != "json" && format != "ndjson" {
+			utils.PrintDiag(cmd.ErrOrStderr(), &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid format",
+				Detail:   fmt.Sprintf("The format '%s' is not one of \"csv\", \"json\" or \"ndjson\".", format),
+			})
+			return utils.ErrSilent
+		}
+
+		g := instance.New(cmd)
+		g.ParseFlags()
+
+		if diags := g.ParseConfig(); diags.HasErrors() {
+			for _, diag := range *diags {
+				utils.PrintDiag(cmd.ErrOrStderr(), diag)
+			}
+			return utils.ErrSilent
+		}
+
+		index, err := instance.NewIndex(g.Config.Global)
+		if err != nil {
+			log.Err(err).Msg("invalid index")
+			return utils.ErrSilent
+		}
+
+		records, err := index.Read()
+		if err != nil {
+			log.Err(err).Msg("could not read the index")
+			return utils.ErrSilent
+		}
+
+		if len(sites) > 0 {
+			records = utils.Filter(records, func(r instance.InfoRecord) bool {
+				return utils.Contains(sites, r.Site)
+			})
+		}
+
+		log.Debug().Msgf("exporting %d %s", len(records), utils.Plural(len(records), "record", "records"))
+
+		// the output is meant to be piped, so do not use cmd.Print (which writes to stderr)
+		if err := instance.EncodeRecords(cmd.OutOrStdout(), format, records); err != nil {
+			log.Err(err).Msg("could not write the records")
+			return utils.ErrSilent
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(ExportCmd)
+
+	ExportCmd.Flags().String("format", "csv", "the format of the dataset (csv, json or ndjson)")
+	ExportCmd.Flags().StringSlice("site", nil, "export only the records of these sites")
+	ExportCmd.Flags().StringP("config", "c", "", "the path of the config file to use")
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+	"github.com/spf13/pflag"
+)
+
+func TestExportCmd(t *testing.T) {
+	root := tu.GetOSRoot()
+	configPath := filepath.Join(root, "grab.hcl")
+
+	config := `
+global {
+	location = "global"
+}
+
+site "example" {
+	test = "example\\.com"
+
+	info "title" {
+		pattern = "<title>([^<]+)"
+		capture = 1
+	}
+}
+`
+
+	infos := map[string]string{
+		filepath.Join(root, "global", "example", "1", "_info.json"): `{"url": "https://example.com/1", "timestamp": "2022-08-17T12:00:00Z", "title": "One, and a comma"}`,
+		filepath.Join(root, "global", "other", "_info.json"):        `{"url": "https://other.com/2", "timestamp": "2022-08-18T12:00:00Z", "author": "someone"}`,
+	}
+
+	tests := []struct {
+		Name    string
+		Args    []string
+		Want    string
+		WantErr bool
+	}{
+		{
+			Name: "csv",
+			Args: []string{},
+			Want: `site,directory,url,timestamp,author,title
+example,example/1,https://example.com/1,2022-08-17T12:00:00Z,,"One, and a comma"
+other,other,https://other.com/2,2022-08-18T12:00:00Z,someone,
+`,
+		},
+		{
+			Name: "ndjson of a site",
+			Args: []string{"--format", "ndjson", "--site", "other"},
+			Want: `{"site":"other","directory":"other","info":{"author":"someone","timestamp":"2022-08-18T12:00:00Z","url":"https://other.com/2"}}
+`,
+		},
+		{
+			Name:    "invalid format",
+			Args:    []string{"--format", "sqlite"},
+			WantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			utils.Io.WriteFile(utils.Fs, configPath, []byte(config), os.ModePerm)
+
+			for path, contents := range infos {
+				utils.Fs.MkdirAll(filepath.Dir(path), os.ModePerm)
+				utils.Io.WriteFile(utils.Fs, path, []byte(contents), os.ModePerm)
+			}
+
+			// reset the flags, cobra keeps their values between executions
+			ExportCmd.Flags().Set("format", "csv")
+			ExportCmd.Flags().Lookup("site").Value.(pflag.SliceValue).Replace(nil)
+
+			c, got, err := tu.ExecuteCommand(RootCmd, append([]string{"export", "-c", configPath}, tt.Args...)...)
+
+			if c.Name() != ExportCmd.Name() {
+				tc.Fatalf("got: %s, want: %s", c.Name(), ExportCmd.Name())
+			}
+
+			if (err != nil) != tt.WantErr {
+				tc.Errorf("got: %v, want errors: %v", err, tt.WantErr)
+			}
+
+			if !tt.WantErr && got != tt.Want {
+				tc.Errorf("got: %q, want: %q", got, tt.Want)
+			}
+
+			if tt.WantErr && !strings.Contains(got, "Invalid format") {
+				tc.Errorf("got: %q, want the invalid format error", got)
+			}
+		})
+	}
+}
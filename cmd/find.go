@@ -34,7 +34,7 @@ To specify a path use the --path flag.`,
 			}
 		}
 
-		resolved, err := config.Resolve("grab.hcl", startPath)
+		resolved, err := config.ResolveConfig(startPath)
 		if err != nil {
 			cmd.PrintErrln(err)
 			return utils.ErrSilent
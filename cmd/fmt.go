@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/cobra"
+)
+
+var FmtCmd = &cobra.Command{
+	Use:   "fmt [file...]",
+	Short: "Rewrite configuration files in the canonical format",
+	Long: `Rewrites the configuration files in the canonical format: attributes are aligned,
+the global block comes first and regular expressions use the canonical escape sequences.
+If no file is given, the closest configuration file is formatted.
+The names of the files that were changed are printed.
+
+With --check, the files are not written and the command fails if any file is not formatted.
+With --diff, the differences are printed as a unified diff.`,
+	Example: `  Fail if the configuration is not formatted, printing what would change:
+    grab config fmt --check --diff`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return utils.Getwd()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		check, _ := cmd.Flags().GetBool("check")
+		diff, _ := cmd.Flags().GetBool("diff")
+
+		paths := make([]string, 0, len(args))
+		for _, arg := range args {
+			paths = append(paths, utils.Abs(arg))
+		}
+
+		if len(paths) == 0 {
+			resolved, err := config.ResolveConfig(utils.Wd)
+			if err != nil {
+				utils.PrintDiag(cmd.ErrOrStderr(), &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "could not resolve config file",
+					Detail:   err.Error(),
+				})
+				return utils.ErrSilent
+			}
+
+			paths = append(paths, resolved)
+		}
+
+		failed := false
+		unformatted := false
+
+		for _, path := range paths {
+			if ext := filepath.Ext(path); ext != ".hcl" {
+				utils.PrintDiag(cmd.ErrOrStderr(), &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unsupported file",
+					Detail:   fmt.Sprintf("Only files written in the HCL native syntax (.hcl) can be formatted, got '%s'.", path),
+				})
+				failed = true
+				continue
+			}
+
+			src, err := utils.Io.ReadFile(utils.Fs, path)
+			if err != nil {
+				utils.PrintDiag(cmd.ErrOrStderr(), &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "could not read config file",
+					Detail:   err.Error(),
+				})
+				failed = true
+				continue
+			}
+
+			formatted, diags := config.Format(src, path)
+			if diags.HasErrors() {
+				for _, diag := range diags {
+					utils.PrintDiag(cmd.ErrOrStderr(), diag)
+				}
+				failed = true
+				continue
+			}
+
+			if bytes.Equal(src, formatted) {
+				continue
+			}
+
+			unformatted = true
+
+			// the output is meant to be piped, so do not use cmd.Print (which writes to stderr)
+			fmt.Fprintln(cmd.OutOrStdout(), path)
+
+			if diff {
+				fmt.Fprint(cmd.OutOrStdout(), unifiedDiff(path, src, formatted))
+			}
+
+			if check {
+				continue
+			}
+
+			if err := utils.Io.WriteFile(utils.Fs, path, formatted, os.ModePerm); err != nil {
+				utils.PrintDiag(cmd.ErrOrStderr(), &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "could not write config file",
+					Detail:   err.Error(),
+				})
+				failed = true
+			}
+		}
+
+		if failed || (check && unformatted) {
+			return utils.ErrSilent
+		}
+
+		return nil
+	},
+}
+
+func unifiedDiff(path string, a, b []byte) string {
+	str, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(a)),
+		B:        difflib.SplitLines(string(b)),
+		FromFile: path,
+		ToFile:   path + " (formatted)",
+		Context:  3,
+	})
+
+	if !strings.HasSuffix(str, "\n") {
+		str += "\n"
+	}
+
+	return str
+}
+
+func init() {
+	ConfigCmd.AddCommand(FmtCmd)
+
+	FmtCmd.Flags().Bool("check", false, "do not write the files, fail if any file is not formatted")
+	FmtCmd.Flags().Bool("diff", false, "print the differences with the formatted files")
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestFmtCmd(t *testing.T) {
+	root := tu.GetOSRoot()
+	configPath := filepath.Join(root, "grab.hcl")
+
+	unformatted := `site "a" {
+test = "a"
+}
+
+global {
+location = "x"
+}
+`
+
+	formatted := `global {
+  location = "x"
+}
+
+site "a" {
+  test = "a"
+}
+`
+
+	tests := []struct {
+		Name         string
+		Args         []string
+		Input        string
+		Want         string
+		WantContains []string
+		WantErr      bool
+	}{
+		{
+			Name:         "writes the file",
+			Args:         []string{},
+			Input:        unformatted,
+			Want:         formatted,
+			WantContains: []string{configPath + "\n"},
+		},
+		{
+			Name:  "already formatted",
+			Args:  []string{},
+			Input: formatted,
+			Want:  formatted,
+		},
+		{
+			Name:         "check",
+			Args:         []string{"--check"},
+			Input:        unformatted,
+			Want:         unformatted,
+			WantContains: []string{configPath + "\n"},
+			WantErr:      true,
+		},
+		{
+			Name:    "check formatted",
+			Args:    []string{"--check", configPath},
+			Input:   formatted,
+			Want:    formatted,
+			WantErr: false,
+		},
+		{
+			Name:  "check and diff",
+			Args:  []string{"--check", "--diff"},
+			Input: unformatted,
+			Want:  unformatted,
+			WantContains: []string{
+				"--- " + configPath + "\n",
+				"+++ " + configPath + " (formatted)\n",
+				"-site \"a\" {\n",
+				"+  location = \"x\"\n",
+			},
+			WantErr: true,
+		},
+		{
+			Name:    "syntax error",
+			Args:    []string{},
+			Input:   `site "a {`,
+			Want:    `site "a {`,
+			WantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			utils.Io.WriteFile(utils.Fs, configPath, []byte(tt.Input), os.ModePerm)
+
+			// flags keep their values between executions
+			FmtCmd.Flags().Set("check", "false")
+			FmtCmd.Flags().Set("diff", "false")
+
+			c, got, _, err := tu.ExecuteCommandErr(RootCmd, append([]string{"config", "fmt"}, tt.Args...)...)
+
+			if c.Name() != FmtCmd.Name() {
+				tc.Fatalf("got: %s, want: %s", c.Name(), FmtCmd.Name())
+			}
+
+			if (err != nil) != tt.WantErr {
+				tc.Errorf("got: %v, want errors: %v", err, tt.WantErr)
+			}
+
+			for _, want := range tt.WantContains {
+				if !strings.Contains(got, want) {
+					tc.Errorf("got: %s, does not contain: %s", got, want)
+				}
+			}
+
+			written, _ := utils.Io.ReadFile(utils.Fs, configPath)
+			if string(written) != tt.Want {
+				tc.Errorf("got:\n%s\nwant:\n%s", written, tt.Want)
+			}
+		})
+	}
+}
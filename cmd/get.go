@@ -1,8 +1,15 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+
 	"github.com/everdrone/grab/internal/config"
 	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/net"
 	"github.com/everdrone/grab/internal/update"
 	"github.com/everdrone/grab/internal/utils"
 	"github.com/fatih/color"
@@ -14,29 +21,78 @@ import (
 var GetCmd = &cobra.Command{
 	Use:   "get",
 	Short: "Scrape and download assets from a URL, a file or a both",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Scrapes the pages passed as arguments and downloads the assets that match the configuration.
+An argument can be a URL, the path of a list of URLs, or "-" to read a list from the standard input.
+Lists can have one URL per line, or be JSON (an array of URLs or of objects with a "url" key),
+CSV (see --column), sitemaps (including sitemap indexes), RSS or Atom feeds.
+Use --input to read a list from a URL, e.g. a remote sitemap.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		// the urls can come from the journal of the failed items or from the inputs only
+		retry, _ := cmd.Flags().GetBool("retry-failed")
+		inputs, _ := cmd.Flags().GetStringArray("input")
+		if retry || len(inputs) > 0 {
+			return nil
+		}
+
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		log.Logger = log.Output(instance.DefaultLogger(cmd.OutOrStderr()))
 
 		g := instance.New(cmd)
 		g.ParseFlags()
 
+		humanOut, err := setupOutput(cmd, g)
+		if err != nil {
+			return err
+		}
+
+		log.Logger = log.Output(instance.DefaultLogger(humanOut))
+
 		if diags := g.ParseConfig(); diags.HasErrors() {
 			for _, diag := range diags.Errs() {
 				log.Err(diag).Msg("config error")
 			}
-			return utils.ErrSilent
+			return errConfig
+		}
+
+		closeArchive, err := setupArchive(cmd)
+		if err != nil {
+			return err
 		}
 
+		defer closeArchive()
+
 		if diags := g.ParseURLs(args); diags.HasErrors() {
 			for _, diag := range diags.Errs() {
 				log.Err(diag).Msg("argument error")
 			}
+			return errConfig
+		}
+
+		journal, err := openJournal(g)
+		if err != nil {
+			log.Err(err).Msg("could not read the journal of the failed items")
 			return utils.ErrSilent
 		}
 
+		retry, _ := cmd.Flags().GetBool("retry-failed")
+		if retry {
+			// only the pages that failed are scraped again
+			g.URLs = utils.Unique(append(g.URLs, journal.Pages()...))
+		}
+
+		report := instance.NewReport()
+		g.OnEvent(report.Handle)
+
 		updateMessageChan := make(chan string)
 		go func() {
+			// the check would end up in the WARC file, or fail when replaying
+			if archived(cmd) {
+				updateMessageChan <- ""
+				return
+			}
+
 			newVersion, err := update.CheckForUpdates(config.Version, config.LatestReleaseURL)
 			if err != nil {
 				updateMessageChan <- ""
@@ -50,35 +106,218 @@ var GetCmd = &cobra.Command{
 			for _, diag := range diags.Errs() {
 				log.Err(diag).Msg("runtime error")
 			}
-			return utils.ErrSilent
+			return finishRun(cmd, g, report, journal, humanOut, diags)
 		}
 
-		if err := g.Download(); err != nil {
-			return utils.ErrSilent
+		if retry {
+			g.RetryFailed(journal)
+		}
+
+		if planOut, _ := cmd.Flags().GetString("plan-out"); planOut != "" {
+			if err := writePlan(g, utils.Abs(planOut)); err != nil {
+				log.Err(err).Str("path", planOut).Msg("could not write the plan")
+				return utils.ErrSilent
+			}
+
+			log.Info().Str("path", planOut).Msgf("plan written, %d %s to download", g.TotalAssets, utils.Plural(int(g.TotalAssets), "asset", "assets"))
+		} else if err := g.Download(); err != nil {
+			return finishRun(cmd, g, report, journal, humanOut, err)
 		}
 
+		result := finishRun(cmd, g, report, journal, humanOut, nil)
+
 		latest := <-updateMessageChan
 		if latest != "" {
 			// TODO: take in account possible package managers
 			// if for example we installed with homebrew, we should display a different message
-			cmd.Printf("\n\n%s %s → %s\n",
+			fmt.Fprintf(humanOut, "\n\n%s %s → %s\n",
 				color.New(color.FgMagenta).Sprintf("A new release of %s is available:", config.Name),
 				config.Version,
 				// color.New(color.FgHiBlack).Sprint(config.Version),
 				color.New(color.FgCyan).Sprint(latest),
 			)
-			cmd.Printf("%s\n\n", "https://github.com/everdrone/grab/releases/latest")
+			fmt.Fprintf(humanOut, "%s\n\n", "https://github.com/everdrone/grab/releases/latest")
 		}
 
-		return nil
+		return result
 	},
 }
 
+// the configuration, the arguments or the flags are invalid
+var errConfig = &utils.ExitCodeError{Code: utils.ExitConfig, Err: utils.ErrSilent}
+
+// validates the flags of the run, registers the event writers selected by the --output and --print flags, and returns where
+// logs and messages for humans must go, so that they are not mixed with machine readable output
+func setupOutput(cmd *cobra.Command, g *instance.Grab) (io.Writer, error) {
+	switch {
+	case g.Flags.OnExists != "" && !utils.Contains(config.OnExistsPolicies, g.Flags.OnExists):
+		log.Error().Str("on-exists", g.Flags.OnExists).Msg("invalid on-exists policy, must be \"skip\", \"overwrite\", \"rename\", \"if_newer\" or \"if_changed\"")
+		return nil, errConfig
+	case g.Flags.Cache != "" && !utils.Contains(config.CacheModes, g.Flags.Cache):
+		log.Error().Str("cache", g.Flags.Cache).Msg("invalid cache mode, must be \"off\", \"use\" or \"refresh\"")
+		return nil, errConfig
+	case g.Flags.Output != "text" && g.Flags.Output != "json":
+		log.Error().Str("output", g.Flags.Output).Msg("invalid output format, must be \"text\" or \"json\"")
+		return nil, errConfig
+	case g.Flags.Output == "json" && g.Flags.Print != "":
+		log.Error().Msg("the --print flag cannot be used with --output json")
+		return nil, errConfig
+	case g.Flags.Output == "json":
+		g.OnEvent(instance.JSONEventWriter(cmd.OutOrStdout()))
+		return cmd.ErrOrStderr(), nil
+	case g.Flags.Print != "":
+		tmpl, err := template.New("print").Parse(g.Flags.Print)
+		if err != nil {
+			log.Err(err).Msg("invalid print template")
+			return nil, errConfig
+		}
+
+		g.OnEvent(instance.TemplateEventWriter(cmd.OutOrStdout(), tmpl))
+		return cmd.ErrOrStderr(), nil
+	}
+
+	return cmd.OutOrStderr(), nil
+}
+
+// loads the journal of the failed items of the location. Unless in dry run mode,
+// the failures of the run are recorded in it.
+func openJournal(g *instance.Grab) (*instance.Journal, error) {
+	journal, err := instance.LoadJournal(g.Config.Global.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	if !g.Flags.DryRun {
+		g.OnEvent(journal.Handle)
+	}
+
+	return journal, nil
+}
+
+// saves the journal, prints the summary of the run, writes the report and sends the notification, then returns
+// the error matching the outcome of the run. runErr is not nil if the run stopped early, e.g. at the first error in strict mode.
+func finishRun(cmd *cobra.Command, g *instance.Grab, report *instance.Report, journal *instance.Journal, humanOut io.Writer, runErr error) error {
+	report.Finish()
+
+	if !g.Flags.DryRun {
+		if err := journal.Save(); err != nil {
+			log.Err(err).Msg("could not write the journal of the failed items")
+		} else if len(journal.Entries) > 0 {
+			log.Warn().Str("journal", instance.JournalPath(g.Config.Global.Location)).Msgf("%d failed %s recorded, use --retry-failed to retry", len(journal.Entries), utils.Plural(len(journal.Entries), "item", "items"))
+		}
+	}
+
+	if !g.Flags.Quiet {
+		fmt.Fprintf(humanOut, "\n%s", report.Table())
+	}
+
+	if path, _ := cmd.Flags().GetString("report"); path != "" {
+		marshaled, err := report.JSON()
+		if err == nil {
+			err = writeFile(utils.Abs(path), marshaled)
+		}
+
+		if err != nil {
+			log.Err(err).Str("path", path).Msg("could not write the report")
+			return utils.ErrSilent
+		}
+	}
+
+	g.Notify("get", report, runErr)
+
+	code := report.ExitCode()
+	if runErr != nil && code == utils.ExitOK {
+		// the run stopped for a reason that is not a failed page or download
+		code = utils.ExitError
+	}
+
+	if code == utils.ExitOK {
+		return nil
+	}
+
+	return &utils.ExitCodeError{Code: code, Err: utils.ErrSilent}
+}
+
+// records the requests in the WARC file of the --warc flag, or replays them from the file of the --replay flag.
+// The returned function closes the WARC file and restores the network.
+func setupArchive(cmd *cobra.Command) (func(), error) {
+	record, _ := cmd.Flags().GetString("warc")
+	replay, _ := cmd.Flags().GetString("replay")
+
+	switch {
+	case record != "" && replay != "":
+		log.Error().Msg("the --warc flag cannot be used with --replay")
+		return nil, errConfig
+	case record != "":
+		writer, err := net.NewWARCWriter(utils.Abs(record))
+		if err != nil {
+			log.Err(err).Str("path", record).Msg("could not create the WARC file")
+			return nil, utils.ErrSilent
+		}
+
+		net.Transport = &net.WARCRecorder{Writer: writer}
+
+		return func() {
+			net.Transport = nil
+
+			if err := writer.Close(); err != nil {
+				log.Err(err).Str("path", record).Msg("could not write the WARC file")
+			}
+		}, nil
+	case replay != "":
+		replayer, err := net.NewWARCReplayer(utils.Abs(replay))
+		if err != nil {
+			log.Err(err).Str("path", replay).Msg("could not read the WARC file")
+			return nil, errConfig
+		}
+
+		net.Transport = replayer
+
+		return func() {
+			net.Transport = nil
+
+			if err := replayer.Close(); err != nil {
+				log.Err(err).Str("path", replay).Msg("could not remove the decompressed WARC file")
+			}
+		}, nil
+	}
+
+	return func() {}, nil
+}
+
+// reports whether the requests of the run are recorded or replayed
+func archived(cmd *cobra.Command) bool {
+	record, _ := cmd.Flags().GetString("warc")
+	replay, _ := cmd.Flags().GetString("replay")
+
+	return record != "" || replay != ""
+}
+
+func writePlan(g *instance.Grab, path string) error {
+	marshaled, err := g.Plan().JSON()
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, marshaled)
+}
+
+// writes data to path, creating the parent directories
+func writeFile(path string, data []byte) error {
+	if err := utils.Fs.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return utils.Io.WriteFile(utils.Fs, path, data, os.ModePerm)
+}
+
 func init() {
 	RootCmd.AddCommand(GetCmd)
 
 	GetCmd.Flags().BoolP("force", "f", false, "overwrite existing files")
+	GetCmd.Flags().String("on-exists", "", "what happens to the existing files (skip, overwrite, rename, if_newer or if_changed), replaces the on_exists attributes")
 	GetCmd.Flags().StringP("config", "c", "", "the path of the config file to use")
+	GetCmd.Flags().String("cache", "off", "how the pages are cached (off, use or refresh), use reads the fresh pages from the cache, refresh fetches them again")
 
 	GetCmd.Flags().BoolP("strict", "s", false, "fail on errors")
 	GetCmd.Flags().BoolP("dry-run", "n", false, "do not write on disk")
@@ -86,4 +325,16 @@ func init() {
 	GetCmd.Flags().BoolP("progress", "p", false, "show progress bars")
 	GetCmd.Flags().BoolP("quiet", "q", false, "do not emit any output")
 	GetCmd.Flags().CountP("verbose", "v", "verbosity level")
+
+	GetCmd.Flags().StringP("output", "o", "text", "the output format (text or json), json emits one event per line on stdout")
+	GetCmd.Flags().String("print", "", "a Go template printed on stdout for every downloaded file, e.g. '{{.Destination}}'")
+
+	GetCmd.Flags().StringArrayP("input", "i", nil, "read urls from a list, a local path, \"-\" or a remote url (can be repeated)")
+	GetCmd.Flags().String("column", "", "the column of the csv lists, a header name or a 1 based index (default \"url\" or the first column)")
+
+	GetCmd.Flags().String("report", "", "write the summary of the run to a JSON file")
+	GetCmd.Flags().Bool("retry-failed", false, "retry the pages and the downloads that failed in the previous runs")
+	GetCmd.Flags().String("plan-out", "", "write the downloads to a plan file instead of downloading them, see the apply command")
+	GetCmd.Flags().String("warc", "", "record every request and response in a WARC file, compressed if the name ends with .gz")
+	GetCmd.Flags().String("replay", "", "answer the requests with the responses of a WARC file written by --warc, without using the network")
 }
@@ -1,15 +1,19 @@
 package cmd
 
 import (
+	"encoding/json"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/net"
 	"github.com/everdrone/grab/internal/utils"
 	tu "github.com/everdrone/grab/testutils"
+	"github.com/spf13/pflag"
 )
 
 func TestGetCmd(t *testing.T) {
@@ -223,3 +227,509 @@ site "example" {
 		})
 	}
 }
+
+func TestGetCmdOutput(t *testing.T) {
+	root := tu.GetOSRoot()
+	globalLocation := filepath.Join(root, "global")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	config.LatestReleaseURL = ts.URL + "/bad_releases"
+
+	configPath := filepath.Join(root, "grab.hcl")
+	configContent := `
+global {
+	location = "` + tu.EscapeHCLString(globalLocation) + `"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/a[^\"]+)"
+		capture = 1
+	}
+
+	info "title" {
+		pattern = "<title>([^<]+)"
+		capture = 1
+	}
+}
+`
+
+	tests := []struct {
+		Name         string
+		Args         []string
+		WantLines    []string
+		WantContains []string
+		WantErr      bool
+	}{
+		{
+			Name: "json",
+			Args: []string{"-o", "json"},
+			WantContains: []string{
+				`"event":"page_fetched","time":`,
+				`"event":"asset_matched"`,
+				`"event":"download_started"`,
+				`"event":"download_finished"`,
+				`"event":"info_written"`,
+				`"source":"` + ts.URL + `/img/a.jpg"`,
+				`"bytes":6`,
+				`"duration_ms":`,
+			},
+		},
+		{
+			Name:      "print",
+			Args:      []string{"--print", "{{.Asset}} {{.Bytes}} {{.Destination}}"},
+			WantLines: []string{"image 6 " + filepath.Join(globalLocation, "example", "a.jpg")},
+		},
+		{
+			Name:    "invalid template",
+			Args:    []string{"--print", "{{.Asset"},
+			WantErr: true,
+		},
+		{
+			Name:    "invalid output",
+			Args:    []string{"-o", "xml"},
+			WantErr: true,
+		},
+		{
+			Name:    "invalid on-exists policy",
+			Args:    []string{"--on-exists", "replace"},
+			WantErr: true,
+		},
+		{
+			Name:    "json and print",
+			Args:    []string{"-o", "json", "--print", "{{.Asset}}"},
+			WantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			utils.Fs.MkdirAll(globalLocation, os.ModePerm)
+			utils.Io.WriteFile(utils.Fs, configPath, []byte(configContent), os.ModePerm)
+
+			// flags keep their values between executions
+			GetCmd.Flags().Set("output", "text")
+			GetCmd.Flags().Set("print", "")
+			GetCmd.Flags().Set("on-exists", "")
+
+			_, out, _, err := tu.ExecuteCommandErr(RootCmd, append([]string{"get", ts.URL + "/gallery/123/test"}, tt.Args...)...)
+
+			if (err != nil) != tt.WantErr {
+				tc.Fatalf("got: %v, want errors: %v", err, tt.WantErr)
+			}
+
+			if tt.WantErr {
+				return
+			}
+
+			lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+
+			if tt.WantLines != nil && !reflect.DeepEqual(lines, tt.WantLines) {
+				tc.Errorf("got: %q, want: %q", lines, tt.WantLines)
+			}
+
+			for _, want := range tt.WantContains {
+				if !strings.Contains(out, want) {
+					tc.Errorf("got: %s, does not contain: %s", out, want)
+				}
+			}
+
+			// every line must be a JSON object
+			if tt.WantContains != nil {
+				for _, line := range lines {
+					if !json.Valid([]byte(line)) {
+						tc.Errorf("got invalid json line: %s", line)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGetCmdReport(t *testing.T) {
+	root := tu.GetOSRoot()
+	globalLocation := filepath.Join(root, "global")
+	reportPath := filepath.Join(root, "report.json")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	config.LatestReleaseURL = ts.URL + "/bad_releases"
+
+	configContent := func(pattern string) string {
+		return `
+global {
+	location = "` + tu.EscapeHCLString(globalLocation) + `"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "` + pattern + `"
+		capture = 1
+		find_all = true
+	}
+}
+`
+	}
+
+	tests := []struct {
+		Name       string
+		Config     string
+		Args       []string
+		WantCode   int
+		WantTotals map[string]float64
+	}{
+		{
+			Name:       "success",
+			Config:     configContent(`<img src=\"([^\"]+/img/[^\"]+)`),
+			Args:       []string{ts.URL + "/gallery/123/test"},
+			WantCode:   utils.ExitOK,
+			WantTotals: map[string]float64{"pages_fetched": 1, "assets_downloaded": 3, "bytes": 18},
+		},
+		{
+			Name:       "partial failure",
+			Config:     configContent(`<img src=\"([^\"]+/img/[^\"]+)`),
+			Args:       []string{ts.URL + "/gallery/123/test", ts.URL + "/givesNotFound"},
+			WantCode:   utils.ExitPartial,
+			WantTotals: map[string]float64{"pages_fetched": 1, "pages_failed": 1, "assets_downloaded": 3},
+		},
+		{
+			Name:       "total failure",
+			Config:     configContent(`<a href=\"([^\"]*/broken/[^\"]+)`),
+			Args:       []string{ts.URL + "/gallery/123/test"},
+			WantCode:   utils.ExitFailure,
+			WantTotals: map[string]float64{"pages_fetched": 1, "assets_failed": 2},
+		},
+		{
+			Name:     "config error",
+			Config:   `global {`,
+			Args:     []string{ts.URL + "/gallery/123/test"},
+			WantCode: utils.ExitConfig,
+		},
+		{
+			Name:     "invalid flag",
+			Config:   configContent(`<img src=\"([^\"]+/img/[^\"]+)`),
+			Args:     []string{ts.URL + "/gallery/123/test", "--unknown"},
+			WantCode: utils.ExitConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			utils.Fs.MkdirAll(globalLocation, os.ModePerm)
+			utils.Io.WriteFile(utils.Fs, filepath.Join(root, "grab.hcl"), []byte(tt.Config), os.ModePerm)
+
+			GetCmd.Flags().Set("output", "text")
+			GetCmd.Flags().Set("print", "")
+			GetCmd.Flags().Set("on-exists", "")
+			GetCmd.Flags().Set("report", "")
+			GetCmd.Flags().Set("strict", "false")
+
+			_, out, _, err := tu.ExecuteCommandErr(RootCmd, append([]string{"get", "--report", "report.json"}, tt.Args...)...)
+
+			if got := utils.ExitCode(err); got != tt.WantCode {
+				tc.Fatalf("got: %d, want: %d", got, tt.WantCode)
+			}
+
+			if tt.WantTotals == nil {
+				return
+			}
+
+			if !strings.Contains(out, "SITE") || !strings.Contains(out, "finished in") {
+				tc.Errorf("got: %s, want the summary table", out)
+			}
+
+			marshaled, _ := utils.Io.ReadFile(utils.Fs, reportPath)
+
+			var report struct {
+				ExitCode int                `json:"exit_code"`
+				Totals   map[string]float64 `json:"totals"`
+			}
+			if err := json.Unmarshal(marshaled, &report); err != nil {
+				tc.Fatalf("got: %v, want a valid report", err)
+			}
+
+			if report.ExitCode != tt.WantCode {
+				tc.Errorf("got: %d, want: %d", report.ExitCode, tt.WantCode)
+			}
+
+			for key, want := range tt.WantTotals {
+				if report.Totals[key] != want {
+					tc.Errorf("got: %s = %v, want: %v", key, report.Totals[key], want)
+				}
+			}
+		})
+	}
+
+	GetCmd.Flags().Set("report", "")
+}
+
+func TestGetCmdRetryFailed(t *testing.T) {
+	root := tu.GetOSRoot()
+	globalLocation := filepath.Join(root, "global")
+	journalPath := filepath.Join(globalLocation, ".grab", "failed.json")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	config.LatestReleaseURL = ts.URL + "/bad_releases"
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+	utils.Fs.MkdirAll(globalLocation, os.ModePerm)
+	utils.Io.WriteFile(utils.Fs, filepath.Join(root, "grab.hcl"), []byte(`
+global {
+	location = "`+tu.EscapeHCLString(globalLocation)+`"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/a[^\"]+)"
+		capture = 1
+	}
+}
+`), os.ModePerm)
+
+	GetCmd.Flags().Set("output", "text")
+	GetCmd.Flags().Set("print", "")
+	GetCmd.Flags().Set("report", "")
+	GetCmd.Flags().Set("strict", "false")
+	defer GetCmd.Flags().Set("retry-failed", "false")
+
+	// the page fails, the journal records it
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "get", ts.URL+"/givesNotFound"); utils.ExitCode(err) != utils.ExitFailure {
+		t.Fatalf("got: %v, want: exit code %d", err, utils.ExitFailure)
+	}
+
+	journal, _ := utils.Io.ReadFile(utils.Fs, journalPath)
+	if !strings.Contains(string(journal), `"page": "`+ts.URL+`/givesNotFound"`) {
+		t.Fatalf("got: %s, want the failed page", journal)
+	}
+
+	// replace the failed page with one that works, and add a failed download
+	utils.Io.WriteFile(utils.Fs, journalPath, []byte(`{"entries": [
+	{"kind": "page", "site": "example", "page": "`+ts.URL+`/gallery/123/test", "error": "404"},
+	{"kind": "download", "site": "example", "asset": "image", "source": "`+ts.URL+`/img/b.jpg", "destination": "example/b.jpg", "error": "timeout"}
+]}`), os.ModePerm)
+
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "get", "--retry-failed"); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	for name, want := range map[string]string{"a.jpg": "imagea", "b.jpg": "imageb"} {
+		if got, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(globalLocation, "example", name)); string(got) != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+
+	if exists, _ := utils.Io.Exists(utils.Fs, journalPath); exists {
+		t.Errorf("got: %s, want: the journal to be removed", journalPath)
+	}
+
+	// only downloads to retry, no page is fetched: one of two failing is a partial failure
+	utils.Io.WriteFile(utils.Fs, journalPath, []byte(`{"entries": [
+	{"kind": "download", "site": "example", "asset": "image", "source": "`+ts.URL+`/img/c.jpg", "destination": "example/c.jpg", "error": "timeout"},
+	{"kind": "download", "site": "example", "asset": "image", "source": "`+ts.URL+`/givesNotFound", "destination": "example/d.jpg", "error": "timeout"}
+]}`), os.ModePerm)
+
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "get", "--retry-failed"); utils.ExitCode(err) != utils.ExitPartial {
+		t.Errorf("got: %v, want: exit code %d", err, utils.ExitPartial)
+	}
+
+	if got, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(globalLocation, "example", "c.jpg")); string(got) != "imagec" {
+		t.Errorf("got: %q, want: %q", got, "imagec")
+	}
+}
+
+func TestGetCmdWARC(t *testing.T) {
+	root := tu.GetOSRoot()
+	globalLocation := filepath.Join(root, "global")
+	archivePath := filepath.Join(root, "out.warc.gz")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+	utils.Fs.MkdirAll(globalLocation, os.ModePerm)
+	utils.Io.WriteFile(utils.Fs, filepath.Join(root, "grab.hcl"), []byte(`
+global {
+	location = "`+tu.EscapeHCLString(globalLocation)+`"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/[ab][^\"]+)"
+		capture = 1
+		find_all = true
+	}
+}
+`), os.ModePerm)
+
+	GetCmd.Flags().Set("output", "text")
+	GetCmd.Flags().Set("print", "")
+	GetCmd.Flags().Set("report", "")
+	GetCmd.Flags().Set("strict", "false")
+	defer GetCmd.Flags().Set("warc", "")
+	defer GetCmd.Flags().Set("replay", "")
+
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "get", "--warc", archivePath, ts.URL+"/gallery/123/test"); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	records, err := net.ReadWARC(archivePath)
+	if err != nil || len(records) != 7 {
+		t.Fatalf("got: %d records %v, want the warcinfo record and 3 exchanges", len(records), err)
+	}
+
+	// the files are downloaded again from the archive, without the server
+	ts.Close()
+	utils.Fs.RemoveAll(filepath.Join(globalLocation, "example"))
+	GetCmd.Flags().Set("warc", "")
+
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "get", "--replay", archivePath, ts.URL+"/gallery/123/test"); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	for name, want := range map[string]string{"a.jpg": "imagea", "b.jpg": "imageb"} {
+		if got, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(globalLocation, "example", name)); string(got) != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+
+	// a page that was not recorded fails
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "get", "--replay", archivePath, ts.URL+"/gallery/456/test"); utils.ExitCode(err) != utils.ExitFailure {
+		t.Errorf("got: %v, want: exit code %d", err, utils.ExitFailure)
+	}
+
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "get", "--replay", archivePath, "--warc", archivePath, ts.URL+"/gallery/123/test"); utils.ExitCode(err) != utils.ExitConfig {
+		t.Errorf("got: %v, want: exit code %d", err, utils.ExitConfig)
+	}
+}
+
+func TestGetCmdInputs(t *testing.T) {
+	root := tu.GetOSRoot()
+	globalLocation := filepath.Join(root, "global")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	config.LatestReleaseURL = ts.URL + "/bad_releases"
+
+	resetInput := func() {
+		GetCmd.Flags().Lookup("input").Value.(pflag.SliceValue).Replace([]string{})
+	}
+	defer resetInput()
+	defer RootCmd.SetIn(nil)
+
+	tests := []struct {
+		Name    string
+		Args    []string
+		Stdin   string
+		WantErr bool
+	}{
+		{
+			Name:  "stdin",
+			Args:  []string{"-"},
+			Stdin: `["` + ts.URL + `/gallery/123/test"]`,
+		},
+		{
+			Name: "remote sitemap index",
+			Args: []string{"-i", ts.URL + "/sitemap.xml"},
+		},
+		{
+			Name:    "invalid list on stdin",
+			Args:    []string{"-"},
+			Stdin:   `["` + ts.URL + `/gallery/123/test",`,
+			WantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			utils.Fs.MkdirAll(globalLocation, os.ModePerm)
+			utils.Io.WriteFile(utils.Fs, filepath.Join(root, "grab.hcl"), []byte(`
+global {
+	location = "`+tu.EscapeHCLString(globalLocation)+`"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+\\/gallery"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/a[^\"]+)"
+		capture = 1
+	}
+}
+`), os.ModePerm)
+
+			GetCmd.Flags().Set("output", "text")
+			GetCmd.Flags().Set("print", "")
+			GetCmd.Flags().Set("on-exists", "")
+			GetCmd.Flags().Set("report", "")
+			GetCmd.Flags().Set("strict", "true")
+			resetInput()
+			RootCmd.SetIn(strings.NewReader(tt.Stdin))
+
+			_, _, _, err := tu.ExecuteCommandErr(RootCmd, append([]string{"get"}, tt.Args...)...)
+
+			if (err != nil) != tt.WantErr {
+				tc.Fatalf("got: %v, want errors: %v", err, tt.WantErr)
+			}
+
+			if tt.WantErr {
+				if got := utils.ExitCode(err); got != utils.ExitConfig {
+					tc.Errorf("got: %d, want: %d", got, utils.ExitConfig)
+				}
+				return
+			}
+
+			if got, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(globalLocation, "example", "a.jpg")); string(got) != "imagea" {
+				tc.Errorf("got: %q, want: %q", got, "imagea")
+			}
+		})
+	}
+
+	GetCmd.Flags().Set("strict", "false")
+}
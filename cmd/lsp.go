@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/lsp"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spf13/cobra"
+)
+
+var LspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Start a language server for the configuration file",
+	Long: `Starts a language server speaking the Language Server Protocol over stdin and stdout.
+It publishes the errors of the configuration as you type, completes block and attribute names,
+shows the documentation of attributes on hover and jumps from a capture attribute
+to the named group it references in the pattern.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// stdout is reserved for the protocol, only warnings and errors are logged to stderr
+		log.Logger = log.Output(instance.DefaultLogger(cmd.ErrOrStderr())).Level(zerolog.WarnLevel)
+
+		return lsp.NewServer(cmd.InOrStdin(), cmd.OutOrStdout()).Run()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(LspCmd)
+}
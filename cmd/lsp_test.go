@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestLspCmd(t *testing.T) {
+	in := &bytes.Buffer{}
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		fmt.Fprintf(in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	RootCmd.SetIn(in)
+	defer RootCmd.SetIn(nil)
+
+	c, got, _, err := tu.ExecuteCommandErr(RootCmd, "lsp")
+
+	if c.Name() != LspCmd.Name() {
+		t.Fatalf("got: %s, want: %s", c.Name(), LspCmd.Name())
+	}
+
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	if !strings.HasPrefix(got, "Content-Length: ") || !strings.Contains(got, `"capabilities"`) {
+		t.Errorf("got: %s, want the initialize response", got)
+	}
+}
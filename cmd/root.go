@@ -31,7 +31,7 @@ var RootCmd = &cobra.Command{
 func Execute() {
 	err := RootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(utils.ExitCode(err))
 	}
 }
 
@@ -40,6 +40,6 @@ func init() {
 	RootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
 		cmd.PrintErrf("Error: %s\n", err)
 		cmd.Println(cmd.UsageString())
-		return utils.ErrSilent
+		return &utils.ExitCodeError{Code: utils.ExitConfig, Err: utils.ErrSilent}
 	})
 }
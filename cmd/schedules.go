@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spf13/cobra"
+)
+
+var RunSchedulesCmd = &cobra.Command{
+	Use:   "run-schedules",
+	Short: "Run the schedule blocks of the configuration at the times of their cron expressions",
+	Long: `Runs until interrupted, scraping the urls of every schedule block at the times of its cron expression,
+in the local time zone. Schedules run at the same time as each other, but a schedule is never started
+while its previous run is still going: that occurrence is skipped.
+
+On SIGINT or SIGTERM the running schedules are completed before exiting,
+send the signal again to exit immediately. Use --run to run some schedules once, right away.`,
+	Example: `  grab run-schedules
+  grab run-schedules --run news --run archives`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.Logger = log.Output(instance.DefaultLogger(cmd.OutOrStderr()))
+
+		g := instance.New(cmd)
+		g.ParseFlags()
+
+		humanOut, err := setupOutput(cmd, g)
+		if err != nil {
+			return err
+		}
+
+		log.Logger = log.Output(instance.DefaultLogger(humanOut))
+
+		if diags := g.ParseConfig(); diags.HasErrors() {
+			for _, diag := range diags.Errs() {
+				log.Err(diag).Msg("config error")
+			}
+			return errConfig
+		}
+
+		if len(g.Config.Schedules) == 0 {
+			log.Error().Str("path", g.Flags.ConfigPath).Msg("no schedule blocks in the configuration")
+			return errConfig
+		}
+
+		names, _ := cmd.Flags().GetStringArray("run")
+		for _, name := range names {
+			if !utils.Any(g.Config.Schedules, func(s config.ScheduleConfig) bool { return s.Name == name }) {
+				log.Error().Str("schedule", name).Msg("unknown schedule")
+				return errConfig
+			}
+		}
+
+		journal, err := openJournal(g)
+		if err != nil {
+			log.Err(err).Msg("could not read the journal of the failed items")
+			return utils.ErrSilent
+		}
+
+		scheduler, err := g.NewScheduler(time.Now())
+		if err != nil {
+			log.Err(err).Msg("config error")
+			return errConfig
+		}
+
+		// schedules can finish at the same time
+		var mu sync.Mutex
+		scheduler.AfterRun = func(name string, report *instance.Report) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if !g.Flags.DryRun {
+				if err := journal.Save(); err != nil {
+					log.Err(err).Msg("could not write the journal of the failed items")
+				}
+			}
+
+			if !g.Flags.Quiet {
+				fmt.Fprintf(humanOut, "\n%s: %s", name, report.Table())
+			}
+		}
+
+		if len(names) > 0 {
+			failed := false
+			for _, schedule := range g.Config.Schedules {
+				if !utils.Contains(names, schedule.Name) {
+					continue
+				}
+
+				if err := scheduler.RunSchedule(schedule); err != nil {
+					log.Err(err).Str("schedule", schedule.Name).Msg("the run stopped early")
+					failed = true
+				}
+			}
+
+			if failed {
+				return &utils.ExitCodeError{Code: utils.ExitError, Err: utils.ErrSilent}
+			}
+
+			return nil
+		}
+
+		for _, schedule := range g.Config.Schedules {
+			log.Info().Str("schedule", schedule.Name).Str("cron", schedule.Cron).Time("next", scheduler.Next(schedule.Name)).Msg("scheduled")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// the goroutine is stopped, and waited for, before stop cancels ctx
+		done := make(chan struct{})
+		exited := make(chan struct{})
+		defer func() {
+			close(done)
+			<-exited
+		}()
+
+		go func() {
+			defer close(exited)
+
+			select {
+			case <-ctx.Done():
+				// a second signal terminates the process
+				stop()
+				log.Warn().Msg("shutting down after the running schedules")
+			case <-done:
+			}
+		}()
+
+		return scheduler.Run(ctx)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(RunSchedulesCmd)
+
+	RunSchedulesCmd.Flags().BoolP("force", "f", false, "overwrite existing files")
+	RunSchedulesCmd.Flags().String("on-exists", "", "what happens to the existing files (skip, overwrite, rename, if_newer or if_changed), replaces the on_exists attributes")
+	RunSchedulesCmd.Flags().StringP("config", "c", "", "the path of the config file to use")
+	RunSchedulesCmd.Flags().String("cache", "off", "how the pages are cached (off, use or refresh), use reads the fresh pages from the cache, refresh fetches them again")
+
+	RunSchedulesCmd.Flags().BoolP("strict", "s", false, "stop a run at its first error")
+	RunSchedulesCmd.Flags().BoolP("dry-run", "n", false, "do not write on disk")
+	RunSchedulesCmd.Flags().StringArray("run", nil, "run this schedule once, right away, then exit (can be repeated)")
+
+	RunSchedulesCmd.Flags().BoolP("quiet", "q", false, "do not emit any output")
+	RunSchedulesCmd.Flags().CountP("verbose", "v", "verbosity level")
+
+	RunSchedulesCmd.Flags().StringP("output", "o", "text", "the output format (text or json), json emits one event per line on stdout")
+	RunSchedulesCmd.Flags().String("print", "", "a Go template printed on stdout for every downloaded file, e.g. '{{.Destination}}'")
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+	"github.com/spf13/pflag"
+)
+
+func TestRunSchedulesCmd(t *testing.T) {
+	root := tu.GetOSRoot()
+	globalLocation := filepath.Join(root, "global")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	config := `
+global {
+	location = "` + tu.EscapeHCLString(globalLocation) + `"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/a[^\"]+)"
+		capture = 1
+	}
+}
+`
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+	utils.Io.WriteFile(utils.Fs, filepath.Join(root, "grab.hcl"), []byte(config), os.ModePerm)
+
+	RunSchedulesCmd.Flags().Set("output", "text")
+	RunSchedulesCmd.Flags().Set("strict", "false")
+	defer RunSchedulesCmd.Flags().Lookup("run").Value.(pflag.SliceValue).Replace([]string{})
+
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "run-schedules"); utils.ExitCode(err) != utils.ExitConfig {
+		t.Fatalf("got: %v, want: exit code %d without schedule blocks", err, utils.ExitConfig)
+	}
+
+	utils.Io.WriteFile(utils.Fs, filepath.Join(root, "grab.hcl"), []byte(config+`
+schedule "gallery" {
+	cron = "@hourly"
+	urls = ["`+ts.URL+`/gallery/123/test"]
+}
+
+schedule "broken" {
+	cron = "@daily"
+	urls = ["`+ts.URL+`/givesNotFound"]
+}
+`), os.ModePerm)
+
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "run-schedules", "--run", "missing"); utils.ExitCode(err) != utils.ExitConfig {
+		t.Fatalf("got: %v, want: exit code %d for an unknown schedule", err, utils.ExitConfig)
+	}
+
+	RunSchedulesCmd.Flags().Lookup("run").Value.(pflag.SliceValue).Replace([]string{})
+
+	_, out, _, err := tu.ExecuteCommandErr(RootCmd, "run-schedules", "--run", "gallery")
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if !strings.Contains(out, "gallery: SITE") || strings.Contains(out, "broken") {
+		t.Errorf("got: %q, want the summary of the gallery schedule only", out)
+	}
+
+	if got, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(globalLocation, "example", "a.jpg")); string(got) != "imagea" {
+		t.Errorf("got: %q, want: %q", got, "imagea")
+	}
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var SchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the configuration file",
+	Long: `Prints a JSON Schema describing the grab.hcl.json and grab.yaml configuration formats.
+Editors can use it to provide completion and validation, for example:
+  # yaml-language-server: $schema=./grab.schema.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schema, err := config.JSONSchema()
+		if err != nil {
+			cmd.PrintErrf("could not encode the schema: %v\n", err)
+			return utils.ErrSilent
+		}
+
+		// the output is meant to be piped, so do not use cmd.Print (which writes to stderr)
+		fmt.Fprint(cmd.OutOrStdout(), string(schema))
+
+		return nil
+	},
+}
+
+func init() {
+	ConfigCmd.AddCommand(SchemaCmd)
+}
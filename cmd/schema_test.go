@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestSchemaCmd(t *testing.T) {
+	c, got, err := tu.ExecuteCommand(RootCmd, "config", "schema")
+
+	if c.Name() != SchemaCmd.Name() {
+		t.Fatalf("got: %s, want: %s", c.Name(), SchemaCmd.Name())
+	}
+
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &schema); err != nil {
+		t.Errorf("got invalid json %q: %v", got, err)
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/server"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spf13/cobra"
+)
+
+var ServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Start an HTTP API to submit and monitor download jobs",
+	Long: `Starts an HTTP API to queue urls from other tools, and to follow the progress of their downloads:
+
+  GET  /jobs             the jobs and their state
+  POST /jobs             submit {"urls": ["..."], "site": "optional site name"}
+  GET  /jobs/:id         a job with the progress of each asset
+  POST /jobs/:id/cancel  cancel a queued or running job
+  POST /config/reload    parse the configuration file again
+
+Every POST request must send the header Content-Type: application/json, even without a body.
+
+Jobs run like the arguments of the get command, --workers at a time, and all of them share
+--downloads slots to download their files. The queue is saved in .grab/jobs.json, inside the
+global location, and the jobs that did not finish are run again after a restart. On SIGINT or
+SIGTERM the running jobs are stopped and queued again.`,
+	Example: `  grab serve
+  grab serve --listen 127.0.0.1:9000 --workers 4
+  curl -d '{"urls": ["https://example.com/gallery/1"]}' -H 'Content-Type: application/json' http://127.0.0.1:8080/jobs`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.Logger = log.Output(instance.DefaultLogger(cmd.OutOrStderr()))
+
+		g := instance.New(cmd)
+		g.ParseFlags()
+
+		humanOut, err := setupOutput(cmd, g)
+		if err != nil {
+			return err
+		}
+
+		log.Logger = log.Output(instance.DefaultLogger(humanOut))
+
+		if diags := g.ParseConfig(); diags.HasErrors() {
+			for _, diag := range diags.Errs() {
+				log.Err(diag).Msg("config error")
+			}
+			return errConfig
+		}
+
+		journal, err := openJournal(g)
+		if err != nil {
+			log.Err(err).Msg("could not read the journal of the failed items")
+			return utils.ErrSilent
+		}
+
+		srv, err := server.New(g)
+		if err != nil {
+			log.Err(err).Msg("could not read the jobs")
+			return utils.ErrSilent
+		}
+
+		srv.Workers, _ = cmd.Flags().GetInt("workers")
+		srv.Downloads, _ = cmd.Flags().GetInt("downloads")
+		srv.Token, _ = cmd.Flags().GetString("token")
+		srv.AllowOrigin, _ = cmd.Flags().GetString("allow-origin")
+		srv.AfterRun = func(job *server.Job) {
+			if g.Flags.DryRun {
+				return
+			}
+
+			if err := journal.Save(); err != nil {
+				log.Err(err).Msg("could not write the journal of the failed items")
+			}
+		}
+
+		listen, _ := cmd.Flags().GetString("listen")
+
+		listener, err := net.Listen("tcp", listen)
+		if err != nil {
+			log.Err(err).Str("listen", listen).Msg("could not listen")
+			return errConfig
+		}
+
+		httpServer := &http.Server{
+			Handler:           srv.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		srv.Start()
+
+		go func() {
+			<-ctx.Done()
+			// a second signal terminates the process
+			stop()
+
+			shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			_ = httpServer.Shutdown(shutdown)
+		}()
+
+		log.Warn().Str("address", "http://"+listener.Addr().String()).Msg("listening")
+
+		err = httpServer.Serve(listener)
+
+		log.Warn().Msg("stopping the running jobs")
+		srv.Stop()
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Err(err).Msg("server error")
+			return utils.ErrSilent
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(ServeCmd)
+
+	ServeCmd.Flags().String("listen", "127.0.0.1:8080", "the address of the API")
+	ServeCmd.Flags().Int("workers", 2, "the number of jobs run at the same time")
+	ServeCmd.Flags().Int("downloads", 0, "the number of files downloaded at the same time by all the jobs, --workers if 0")
+	ServeCmd.Flags().String("token", "", "require this bearer token in the Authorization header of every request")
+	ServeCmd.Flags().String("allow-origin", "", "allow browsers to call the API from this origin, e.g. '*' for a bookmarklet")
+
+	ServeCmd.Flags().BoolP("force", "f", false, "overwrite existing files")
+	ServeCmd.Flags().String("on-exists", "", "what happens to the existing files (skip, overwrite, rename, if_newer or if_changed), replaces the on_exists attributes")
+	ServeCmd.Flags().StringP("config", "c", "", "the path of the config file to use")
+	ServeCmd.Flags().String("cache", "off", "how the pages are cached (off, use or refresh), use reads the fresh pages from the cache, refresh fetches them again")
+
+	ServeCmd.Flags().BoolP("strict", "s", false, "stop a job at its first error")
+	ServeCmd.Flags().BoolP("dry-run", "n", false, "do not write on disk")
+
+	ServeCmd.Flags().BoolP("quiet", "q", false, "do not emit any output")
+	ServeCmd.Flags().CountP("verbose", "v", "verbosity level")
+
+	ServeCmd.Flags().StringP("output", "o", "text", "the output format (text or json), json emits one event per line on stdout")
+	ServeCmd.Flags().String("print", "", "a Go template printed on stdout for every downloaded file, e.g. '{{.Destination}}'")
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestServeCmd(t *testing.T) {
+	root := tu.GetOSRoot()
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+	utils.Io.WriteFile(utils.Fs, filepath.Join(root, "grab.hcl"), []byte(`
+global {
+	location = "`+tu.EscapeHCLString(filepath.Join(root, "global"))+`"
+}
+
+site "example" {
+	test = "example"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+	}
+}
+`), os.ModePerm)
+
+	ServeCmd.Flags().Set("output", "text")
+	defer ServeCmd.Flags().Set("listen", "127.0.0.1:8080")
+
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "serve", "--listen", "invalid address"); utils.ExitCode(err) != utils.ExitConfig {
+		t.Errorf("got: %v, want: exit code %d", err, utils.ExitConfig)
+	}
+
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "serve", "--output", "xml"); utils.ExitCode(err) != utils.ExitConfig {
+		t.Errorf("got: %v, want: exit code %d", err, utils.ExitConfig)
+	}
+}
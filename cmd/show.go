@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/cobra"
+)
+
+var ShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the fully resolved configuration",
+	Long: `Prints the configuration after environment interpolation, network options
+inheritance and defaults. The global location is expanded to an absolute path.
+Sensitive headers and the path of the notify url are masked, unless the --unmask
+flag is set.`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return utils.Getwd()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		unmask, _ := cmd.Flags().GetBool("unmask")
+
+		log.Logger = log.Output(instance.DefaultLogger(cmd.ErrOrStderr()))
+
+		g := instance.New(cmd)
+		g.ParseFlags()
+
+		if diags := g.ParseConfig(); diags.HasErrors() {
+			for _, diag := range *diags {
+				utils.PrintDiag(cmd.ErrOrStderr(), diag)
+			}
+			return utils.ErrSilent
+		}
+
+		resolved := g.Resolved(unmask)
+
+		// the output is meant to be piped, so do not use cmd.Print (which writes to stderr)
+		out := cmd.OutOrStdout()
+
+		switch format {
+		case "hcl":
+			fmt.Fprint(out, string(resolved.HCL()))
+		case "json":
+			marshaled, err := resolved.JSON()
+			if err != nil {
+				cmd.PrintErrf("could not encode the configuration: %v\n", err)
+				return utils.ErrSilent
+			}
+			fmt.Fprintln(out, string(marshaled))
+		case "table":
+			fmt.Fprint(out, resolved.Table())
+		default:
+			utils.PrintDiag(cmd.ErrOrStderr(), &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid format",
+				Detail:   fmt.Sprintf("The format '%s' is not one of \"hcl\", \"json\" or \"table\".", format),
+			})
+			return utils.ErrSilent
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	ConfigCmd.AddCommand(ShowCmd)
+
+	ShowCmd.Flags().StringP("format", "o", "hcl", "the output format (hcl, json or table)")
+	ShowCmd.Flags().Bool("unmask", false, "print the values of sensitive headers")
+	ShowCmd.Flags().StringP("config", "c", "", "the path of the config file to use")
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestShowCmd(t *testing.T) {
+	root := tu.GetOSRoot()
+	configPath := filepath.Join(root, "grab.hcl")
+
+	config := `
+global {
+	location = "global"
+
+	network {
+		headers = {
+			"Authorization" = "Bearer secret"
+		}
+	}
+}
+
+site "example" {
+	test = "example\\.com"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+	}
+}
+`
+
+	tests := []struct {
+		Name         string
+		Args         []string
+		WantContains []string
+		WantErr      bool
+	}{
+		{
+			Name: "hcl",
+			Args: []string{},
+			WantContains: []string{
+				"location  = \"" + tu.EscapeHCLString(filepath.Join(root, "global")) + "\"\n",
+				"on_exists = \"skip\"\n",
+				"Authorization = \"********\"\n",
+				"asset \"image\" {\n",
+			},
+		},
+		{
+			Name: "json",
+			Args: []string{"-o", "json"},
+			WantContains: []string{
+				"\"location\": \"" + tu.EscapeHCLString(filepath.Join(root, "global")) + "\",\n",
+				"\"name\": \"image\",\n",
+			},
+		},
+		{
+			Name: "table unmasked",
+			Args: []string{"-o", "table", "--unmask"},
+			WantContains: []string{
+				"headers=Authorization=Bearer secret\n",
+			},
+		},
+		{
+			Name:    "invalid format",
+			Args:    []string{"-o", "yaml"},
+			WantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			utils.Io.WriteFile(utils.Fs, configPath, []byte(config), os.ModePerm)
+
+			// reset the flags, cobra keeps their values between executions
+			ShowCmd.Flags().Set("format", "hcl")
+			ShowCmd.Flags().Set("unmask", "false")
+
+			c, got, err := tu.ExecuteCommand(RootCmd, append([]string{"config", "show", "-c", configPath}, tt.Args...)...)
+
+			if c.Name() != ShowCmd.Name() {
+				tc.Fatalf("got: %s, want: %s", c.Name(), ShowCmd.Name())
+			}
+
+			if (err != nil) != tt.WantErr {
+				tc.Errorf("got: %v, want errors: %v", err, tt.WantErr)
+			}
+
+			for _, want := range tt.WantContains {
+				if !strings.Contains(got, want) {
+					tc.Errorf("got: %s, does not contain: %s", got, want)
+				}
+			}
+		})
+	}
+}
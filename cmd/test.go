@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spf13/cobra"
+)
+
+var TestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Run the fixtures defined in the configuration file",
+	Long: `Scrapes the saved page of every "fixture" block, without accessing the network,
+and compares the assets, filenames and info values with the expected ones.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return utils.Getwd()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.Logger = log.Output(instance.DefaultLogger(cmd.ErrOrStderr()))
+
+		g := instance.New(cmd)
+		g.ParseFlags()
+
+		if diags := g.ParseConfig(); diags.HasErrors() {
+			for _, diag := range *diags {
+				utils.PrintDiag(cmd.ErrOrStderr(), diag)
+			}
+			return utils.ErrSilent
+		}
+
+		results := g.RunFixtures()
+
+		failed := 0
+		for _, result := range results {
+			if result.Passed() {
+				if !g.Flags.Quiet {
+					cmd.Printf("%s %s/%s\n", color.New(color.FgGreen).Sprint("ok  "), result.Site, result.Name)
+				}
+				continue
+			}
+
+			failed++
+			cmd.Printf("%s %s/%s\n", color.New(color.FgRed).Sprint("FAIL"), result.Site, result.Name)
+
+			for _, diag := range result.Diagnostics {
+				cmd.Printf("    %s: %s\n", diag.Summary, diag.Detail)
+			}
+
+			for _, diff := range result.Diffs {
+				cmd.Printf("    %s \"%s\":\n", diff.Kind, diff.Name)
+				for _, v := range diff.Missing {
+					cmd.Printf("      %s\n", color.New(color.FgRed).Sprint("- "+v))
+				}
+				for _, v := range diff.Unexpected {
+					cmd.Printf("      %s\n", color.New(color.FgGreen).Sprint("+ "+v))
+				}
+			}
+		}
+
+		if !g.Flags.Quiet {
+			cmd.Printf("\n%d %s, %d failed\n", len(results), utils.Plural(len(results), "fixture", "fixtures"), failed)
+		}
+
+		if failed > 0 {
+			return utils.ErrSilent
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	ConfigCmd.AddCommand(TestCmd)
+
+	TestCmd.Flags().BoolP("quiet", "q", false, "only print failing fixtures")
+	TestCmd.Flags().StringP("config", "c", "", "the path of the config file to use")
+}
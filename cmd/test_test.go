@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestTestCmd(t *testing.T) {
+	root := tu.GetOSRoot()
+	configPath := filepath.Join(root, "config", "grab.hcl")
+
+	config := `
+global {
+	location = "` + tu.EscapeHCLString(filepath.Join(root, "global")) + `"
+}
+
+site "example" {
+	test = ":\\/\\/example\\.com"
+
+	asset "image" {
+		pattern  = "<img src=\"([^\"]+)"
+		capture  = 1
+		find_all = true
+	}
+
+	fixture "passing" {
+		url  = "https://example.com/gallery/1"
+		file = "page.html"
+
+		assets = {
+			image = ["https://example.com/a.jpg"]
+		}
+	}
+
+	fixture "failing" {
+		url  = "https://example.com/gallery/2"
+		file = "page.html"
+
+		filenames = {
+			image = ["example/b.jpg"]
+		}
+	}
+}
+`
+
+	tests := []struct {
+		Name         string
+		Args         []string
+		WantContains []string
+		WantErr      bool
+	}{
+		{
+			Name: "reports results",
+			Args: []string{"-c", configPath},
+			WantContains: []string{
+				"ok   example/passing\n",
+				"FAIL example/failing\n",
+				"    filename \"image\":\n",
+				"      - example/b.jpg\n",
+				"      + example/a.jpg\n",
+				"2 fixtures, 1 failed\n",
+			},
+			WantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			utils.Io.WriteFile(utils.Fs, configPath, []byte(config), os.ModePerm)
+			utils.Io.WriteFile(utils.Fs, filepath.Join(root, "config", "page.html"), []byte(`<img src="/a.jpg" />`), os.ModePerm)
+
+			c, got, _, err := tu.ExecuteCommandErr(RootCmd, append([]string{"config", "test"}, tt.Args...)...)
+
+			if c.Name() != TestCmd.Name() {
+				tc.Fatalf("got: %s, want: %s", c.Name(), TestCmd.Name())
+			}
+
+			if (err != nil) != tt.WantErr {
+				tc.Errorf("got: %v, want errors: %v", err, tt.WantErr)
+			}
+
+			for _, want := range tt.WantContains {
+				if !strings.Contains(got, want) {
+					tc.Errorf("got: %s, does not contain: %s", got, want)
+				}
+			}
+		})
+	}
+}
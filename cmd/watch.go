@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spf13/cobra"
+)
+
+var WatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Poll the lists of the watch blocks and download only the new pages",
+	Long: `Polls the lists of the watch blocks of the configuration, each at its own interval,
+and runs the pages that were not seen before through the same steps as the get command.
+What was seen is kept in .grab/watch.json, inside the global location, so that a restart
+does not scrape the pages again. The pages that could not be fetched are retried at the next poll.
+
+On SIGINT or SIGTERM the current run is completed and the state is saved before exiting,
+send the signal again to exit immediately.`,
+	Example: `  grab watch
+  grab watch --once -c feeds.hcl`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.Logger = log.Output(instance.DefaultLogger(cmd.OutOrStderr()))
+
+		g := instance.New(cmd)
+		g.ParseFlags()
+
+		humanOut, err := setupOutput(cmd, g)
+		if err != nil {
+			return err
+		}
+
+		log.Logger = log.Output(instance.DefaultLogger(humanOut))
+
+		if diags := g.ParseConfig(); diags.HasErrors() {
+			for _, diag := range diags.Errs() {
+				log.Err(diag).Msg("config error")
+			}
+			return errConfig
+		}
+
+		if len(g.Config.Watches) == 0 {
+			log.Error().Str("path", g.Flags.ConfigPath).Msg("no watch blocks in the configuration")
+			return errConfig
+		}
+
+		state, err := instance.LoadWatchState(g.Config.Global.Location)
+		if err != nil {
+			log.Err(err).Msg("could not read the watch state")
+			return utils.ErrSilent
+		}
+
+		journal, err := openJournal(g)
+		if err != nil {
+			log.Err(err).Msg("could not read the journal of the failed items")
+			return utils.ErrSilent
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// the goroutine is stopped, and waited for, before stop cancels ctx
+		done := make(chan struct{})
+		exited := make(chan struct{})
+		defer func() {
+			close(done)
+			<-exited
+		}()
+
+		go func() {
+			defer close(exited)
+
+			select {
+			case <-ctx.Done():
+				// a second signal terminates the process
+				stop()
+				log.Warn().Msg("shutting down after the current run")
+			case <-done:
+			}
+		}()
+
+		once, _ := cmd.Flags().GetBool("once")
+
+		err = g.Watch(ctx, state, once, func(name string, report *instance.Report) {
+			if !g.Flags.DryRun {
+				if err := journal.Save(); err != nil {
+					log.Err(err).Msg("could not write the journal of the failed items")
+				}
+			}
+
+			if !g.Flags.Quiet {
+				fmt.Fprintf(humanOut, "\n%s: %s", name, report.Table())
+			}
+		})
+		if err != nil {
+			log.Err(err).Msg("watch stopped")
+			return &utils.ExitCodeError{Code: utils.ExitError, Err: utils.ErrSilent}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(WatchCmd)
+
+	WatchCmd.Flags().BoolP("force", "f", false, "overwrite existing files")
+	WatchCmd.Flags().String("on-exists", "", "what happens to the existing files (skip, overwrite, rename, if_newer or if_changed), replaces the on_exists attributes")
+	WatchCmd.Flags().StringP("config", "c", "", "the path of the config file to use")
+	WatchCmd.Flags().String("cache", "off", "how the pages are cached (off, use or refresh), use reads the fresh pages from the cache, refresh fetches them again")
+
+	WatchCmd.Flags().BoolP("strict", "s", false, "stop at the first error")
+	WatchCmd.Flags().BoolP("dry-run", "n", false, "do not write on disk")
+	WatchCmd.Flags().Bool("once", false, "poll every list once, regardless of its interval, then exit")
+
+	WatchCmd.Flags().BoolP("quiet", "q", false, "do not emit any output")
+	WatchCmd.Flags().CountP("verbose", "v", "verbosity level")
+
+	WatchCmd.Flags().StringP("output", "o", "text", "the output format (text or json), json emits one event per line on stdout")
+	WatchCmd.Flags().String("print", "", "a Go template printed on stdout for every downloaded file, e.g. '{{.Destination}}'")
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestWatchCmd(t *testing.T) {
+	root := tu.GetOSRoot()
+	globalLocation := filepath.Join(root, "global")
+	list := filepath.Join(root, "list.txt")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	config := `
+global {
+	location = "` + tu.EscapeHCLString(globalLocation) + `"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/[^\"]+)"
+		capture = 1
+		find_all = true
+	}
+}
+`
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+	utils.Io.WriteFile(utils.Fs, filepath.Join(root, "grab.hcl"), []byte(config), os.ModePerm)
+
+	WatchCmd.Flags().Set("output", "text")
+	WatchCmd.Flags().Set("once", "true")
+	defer WatchCmd.Flags().Set("once", "false")
+
+	if _, _, _, err := tu.ExecuteCommandErr(RootCmd, "watch"); utils.ExitCode(err) != utils.ExitConfig {
+		t.Fatalf("got: %v, want: exit code %d without watch blocks", err, utils.ExitConfig)
+	}
+
+	utils.Io.WriteFile(utils.Fs, filepath.Join(root, "grab.hcl"), []byte(config+`
+watch "list" {
+	list = "`+tu.EscapeHCLString(list)+`"
+	interval = "1h"
+}
+`), os.ModePerm)
+	utils.Io.WriteFile(utils.Fs, list, []byte(ts.URL+"/gallery/123/test\n"), os.ModePerm)
+
+	_, out, _, err := tu.ExecuteCommandErr(RootCmd, "watch")
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if !strings.Contains(out, "list: SITE") {
+		t.Errorf("got: %q, want the summary of the run", out)
+	}
+
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		if exists, _ := utils.Io.Exists(utils.Fs, filepath.Join(globalLocation, "example", name)); !exists {
+			t.Errorf("got: no %s, want it to be downloaded", name)
+		}
+	}
+
+	// the page was seen, nothing is downloaded again
+	utils.Fs.Remove(filepath.Join(globalLocation, "example", "a.jpg"))
+
+	_, out, _, err = tu.ExecuteCommandErr(RootCmd, "watch")
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if strings.Contains(out, "SITE") {
+		t.Errorf("got: %q, want no run", out)
+	}
+
+	if exists, _ := utils.Io.Exists(utils.Fs, filepath.Join(globalLocation, "example", "a.jpg")); exists {
+		t.Errorf("got: a.jpg, want it not to be downloaded again")
+	}
+
+	state, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(globalLocation, ".grab", "watch.json"))
+	if !strings.Contains(string(state), ts.URL+"/gallery/123/test") {
+		t.Errorf("got: %s, want the page in the state", state)
+	}
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/net"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/cobra"
+)
+
+var WhichCmd = &cobra.Command{
+	Use:   "which <url>",
+	Short: "Explain which site handles a URL and with which options",
+	Long: `Prints every site whose test pattern matches the URL, the site that is selected,
+the effective network options of the site and of each asset (secrets are masked)
+and the directory where the assets will be downloaded.
+The network is never accessed.`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return utils.Getwd()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.Logger = log.Output(instance.DefaultLogger(cmd.ErrOrStderr()))
+
+		g := instance.New(cmd)
+		g.ParseFlags()
+
+		if diags := g.ParseConfig(); diags.HasErrors() {
+			for _, diag := range *diags {
+				utils.PrintDiag(cmd.ErrOrStderr(), diag)
+			}
+			return utils.ErrSilent
+		}
+
+		url := args[0]
+		if _, ok := utils.IsValidURL(url); !ok {
+			utils.PrintDiag(cmd.ErrOrStderr(), &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid argument",
+				Detail:   fmt.Sprintf("The argument '%s' is not a valid url.", url),
+			})
+			return utils.ErrSilent
+		}
+
+		explanation := g.Explain(url)
+
+		cmd.Printf("url: %s\n\n", explanation.URL)
+
+		if explanation.Site == nil {
+			cmd.Println("no site matches the url, tested patterns:")
+
+			tests := make(map[string]string, len(g.Config.Sites))
+			for _, site := range g.Config.Sites {
+				tests[site.Name] = site.Test
+			}
+			cmd.Print(indent(utils.FormatMap(tests, "  ", false), "  "))
+
+			return utils.ErrSilent
+		}
+
+		cmd.Println("matching sites:")
+		for i, match := range explanation.Matches {
+			marker := " "
+			if i == 0 {
+				marker = "*"
+			}
+			cmd.Printf("  %s %s  %s\n", marker, match.Name, match.Test)
+		}
+
+		reason := "the only site that matches"
+		if len(explanation.Matches) > 1 {
+			reason = "the first matching site in the configuration, the others are ignored"
+		}
+		cmd.Printf("\nselected site: %s (%s)\n", explanation.Site.Name, reason)
+
+		location := explanation.Location
+		if explanation.LocationFromBody {
+			location = filepath.Join(location, "<captured from the page body>")
+		}
+
+		if explanation.LocationError != nil {
+			// get stops on this page, there is no location to show
+			cmd.Printf("location: none, the subdirectory is not captured: %s\n", explanation.LocationError)
+		} else {
+			cmd.Printf("location: %s\n", location)
+		}
+
+		cmd.Printf("\npage network options:\n%s", indent(formatFetchOptions(explanation.Network), "  "))
+
+		for _, asset := range explanation.Assets {
+			cmd.Printf("\nasset \"%s\" network options:\n%s", asset.Name, indent(formatFetchOptions(asset.Network), "  "))
+		}
+
+		return nil
+	},
+}
+
+func formatFetchOptions(options *net.FetchOptions) string {
+	str := utils.FormatMap(map[string]string{
+		"timeout": fmt.Sprintf("%d", options.Timeout),
+		"retries": fmt.Sprintf("%d", options.Retries),
+	}, "  ", false)
+
+	if len(options.Headers) > 0 {
+		str += "headers:\n" + indent(utils.FormatMap(options.Headers, "  ", false), "  ")
+	}
+
+	return str
+}
+
+// adds the prefix to every non empty line of str
+func indent(str, prefix string) string {
+	lines := strings.SplitAfter(str, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = prefix + line
+		}
+	}
+
+	return strings.Join(lines, "")
+}
+
+func init() {
+	ConfigCmd.AddCommand(WhichCmd)
+
+	WhichCmd.Flags().StringP("config", "c", "", "the path of the config file to use")
+}
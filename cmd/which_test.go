@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestWhichCmd(t *testing.T) {
+	root := tu.GetOSRoot()
+	configPath := filepath.Join(root, "grab.hcl")
+	globalLocation := filepath.Join(root, "global")
+
+	config := `
+global {
+	location = "` + tu.EscapeHCLString(globalLocation) + `"
+}
+
+site "example" {
+	test = "example\\.com"
+
+	network {
+		headers = {
+			"Cookie" = "session=abc"
+		}
+	}
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+	}
+
+	subdirectory {
+		pattern = "gallery\\/(\\d+)"
+		capture = 1
+		from    = url
+	}
+}
+
+site "fallback" {
+	test = "."
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+	}
+}
+`
+
+	tests := []struct {
+		Name         string
+		Args         []string
+		WantContains []string
+		WantErr      bool
+	}{
+		{
+			Name: "explains the match",
+			Args: []string{"https://example.com/gallery/1337"},
+			WantContains: []string{
+				"  * example  example\\.com\n",
+				"    fallback  .\n",
+				"selected site: example (the first matching site in the configuration, the others are ignored)\n",
+				"location: " + filepath.Join(globalLocation, "example", "1337") + "\n",
+				"Cookie  ********\n",
+				"asset \"image\" network options:\n",
+			},
+		},
+		{
+			Name: "subdirectory not captured",
+			Args: []string{"https://example.com/gallery/new"},
+			WantContains: []string{
+				"selected site: example (the first matching site in the configuration, the others are ignored)\n",
+				"location: none, the subdirectory is not captured: no captures found for pattern `gallery\\/(\\d+)`, capture `1`\n",
+			},
+		},
+		{
+			Name:         "invalid url",
+			Args:         []string{"not a url"},
+			WantContains: []string{},
+			WantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			utils.Io.WriteFile(utils.Fs, configPath, []byte(config), os.ModePerm)
+
+			c, got, _, err := tu.ExecuteCommandErr(RootCmd, append([]string{"config", "which", "-c", configPath}, tt.Args...)...)
+
+			if c.Name() != WhichCmd.Name() {
+				tc.Fatalf("got: %s, want: %s", c.Name(), WhichCmd.Name())
+			}
+
+			if (err != nil) != tt.WantErr {
+				tc.Errorf("got: %v, want errors: %v", err, tt.WantErr)
+			}
+
+			for _, want := range tt.WantContains {
+				if !strings.Contains(got, want) {
+					tc.Errorf("got: %s, does not contain: %s", got, want)
+				}
+			}
+		})
+	}
+}
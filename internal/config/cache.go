@@ -0,0 +1,29 @@
+package config
+
+import "time"
+
+const (
+	// the pages are always fetched, nothing is cached
+	CacheOff = "off"
+	// the fresh pages are read from the cache, the stale ones are revalidated
+	CacheUse = "use"
+	// the pages are always fetched, and stored in the cache for the next runs
+	CacheRefresh = "refresh"
+)
+
+// CacheModes are the values of the --cache flag
+var CacheModes = []string{CacheOff, CacheUse, CacheRefresh}
+
+// CacheMaxAge returns how long the pages of the site stay fresh in the cache, 0 to use the headers of the responses
+func (s SiteConfig) CacheMaxAge() time.Duration {
+	if s.MaxAge == nil {
+		return 0
+	}
+
+	d, err := time.ParseDuration(*s.MaxAge)
+	if err != nil || d <= 0 {
+		return 0
+	}
+
+	return d
+}
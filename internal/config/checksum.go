@@ -0,0 +1,4 @@
+package config
+
+// ChecksumAlgorithms are the values of the "algorithm" attribute of the checksum blocks
+var ChecksumAlgorithms = []string{"md5", "sha1", "sha256", "sha512"}
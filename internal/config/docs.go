@@ -0,0 +1,99 @@
+package config
+
+import "strings"
+
+// Docs describes the blocks and attributes of the configuration.
+// The keys are the block types and the attribute name separated by dots, e.g. "site.asset.pattern".
+// Blocks that can appear at more than one level (network) are described without their parents.
+var Docs = map[string]string{
+	"global":           "Global settings. Must be defined exactly once.",
+	"global.location":  "The directory where the assets are downloaded. Every site has its own subdirectory, named after the site block. `~` and environment variables are expanded.",
+	"global.sidecar":   "Whether a `<file>.json` file describing the download is written next to every downloaded file. Can be overridden by sites and assets. Defaults to false.",
+	"global.on_exists": "What happens to the downloads whose file already exists: `skip` it, `overwrite` it, `rename` the new file by appending ` (1)` to its name, download it again `if_newer` than the last download (If-Modified-Since), or `if_changed` since the last download (If-None-Match). Can be overridden by sites and assets, and by the `--on-exists` flag. Defaults to `skip`.",
+
+	"global.index":        "Where the info of the pages is stored. Without this block, every subdirectory has its own `_info.json` file.",
+	"global.index.format": "`json` writes `_info.json` in every subdirectory, overwritten by the next run. `ndjson` appends one line per page to a single file, keeping the history. `csv` adds the pages to a single CSV file with a column per info value. `sqlite` inserts the pages in a single SQLite database.",
+	"global.index.path":   "The file of the `ndjson`, `csv` and `sqlite` formats, relative to global.location. Defaults to `_index.ndjson`, `_index.csv` and `_index.db`.",
+
+	"global.notify":     "A URL notified with a JSON summary of every run: the totals, the failures and the new files of each site. The request is sent with the network options of the block, which only inherits the global ones with `inherit = true`, and retried like the other requests.",
+	"global.notify.url": "The URL the summary is POSTed to.",
+	"global.notify.on":  "When the summary is sent: `run_done` after every run, `run_failed` only after the runs that had failures or stopped early. Defaults to `run_done`.",
+
+	"network":         "Network options used to fetch pages and assets. Options are inherited from the parent blocks (global → site → asset).",
+	"network.inherit": "Whether the options of the parent blocks are inherited. Defaults to true, except in the notify block.",
+	"network.timeout": "The timeout of each request, in milliseconds.",
+	"network.retries": "How many times a failed request is retried.",
+	"network.headers": "The headers sent with every request, as a map of strings.",
+
+	"hook":         "A command run after a download, a page or a run, e.g. to transcode or tag the new files. Hooks can be declared in the global, site and asset blocks, the global ones run first. Hooks do not run with `--dry-run`.",
+	"hook.on":      "When the command runs: `asset_downloaded` after every new file, `page_done` after the downloads of a page, `run_done` at the end of the run. Asset hooks only support `asset_downloaded`.",
+	"hook.command": "The program and its arguments, each one a Go template with the fields `.Site`, `.Asset`, `.Page`, `.Source`, `.Destination`, `.Location` and the info values of the page in `.Info`, e.g. `{{.Info.title}}`. No shell is involved.",
+	"hook.timeout": "How long the command can run, as a duration, e.g. `30s`. Defaults to `1m`.",
+
+	"site":           "A group of assets and info blocks, used for all the URLs matching the test pattern. The label is the name of the site, used as the name of its download directory.",
+	"site.test":      "A regular expression tested against the URL. The first site whose pattern matches handles the URL.",
+	"site.sidecar":   "Whether the downloads of the site have a `<file>.json` sidecar file. Overrides global.sidecar.",
+	"site.on_exists": "What happens to the downloads of the site whose file already exists. Overrides global.on_exists.",
+	"site.max_age":   "How long the pages of the site stay fresh in the page cache of `--cache use`, as a duration, e.g. `1h`. Replaces the lifetime given by the `Cache-Control` and `Expires` headers, the pages are cached even if the server forbids it.",
+
+	"site.asset":           "Something to download from the page. The label is the name of the asset.",
+	"site.asset.pattern":   "A regular expression matched against the page body to find the asset URLs.",
+	"site.asset.capture":   "The index or the name of the capture group of the pattern that contains the URL.",
+	"site.asset.find_all":  "Whether all the matches are downloaded, instead of only the first one. Defaults to false.",
+	"site.asset.sidecar":   "Whether the downloads of the asset have a `<file>.json` sidecar file. Overrides site.sidecar.",
+	"site.asset.on_exists": "What happens to the downloads of the asset whose file already exists. Overrides site.on_exists.",
+	"site.asset.include":   "A regular expression, only the URLs of the asset that match it are downloaded.",
+	"site.asset.exclude":   "A regular expression, the URLs of the asset that match it are not downloaded.",
+	"site.asset.accept":    "The content types downloaded, e.g. `[\"image/*\"]`. The other responses are skipped before their body is written.",
+	"site.asset.min_size":  "The smallest size downloaded, e.g. `10KB`. Smaller files are skipped.",
+	"site.asset.max_size":  "The largest size downloaded, e.g. `20MiB`. Larger files are skipped.",
+
+	"site.asset.transform":         "Replaces the URL (`transform url`) or the destination path (`transform filename`) of the asset before downloading it.",
+	"site.asset.transform.pattern": "A regular expression matched against the URL or the destination path.",
+	"site.asset.transform.replace": "The replacement, which can reference the capture groups of the pattern, e.g. `$${1}` or `$${name}`.",
+
+	"site.asset.checksum":           "Verifies the downloads against a hexadecimal digest found in the page. A file that does not match is deleted and downloaded again, up to the number of retries.",
+	"site.asset.checksum.algorithm": "The hash function of the digest: `md5`, `sha1`, `sha256` or `sha512`.",
+	"site.asset.checksum.pattern":   "A regular expression matched against the page body to find the digests, the first digest belongs to the first match of the asset, and so on. Without a pattern, the capture is taken from the asset pattern.",
+	"site.asset.checksum.capture":   "The index or the name of the capture group that contains the digest.",
+
+	"site.info":         "A string extracted from the page body and saved in `_info.json`. The label is the key of the value.",
+	"site.info.pattern": "A regular expression matched against the page body.",
+	"site.info.capture": "The index or the name of the capture group of the pattern that contains the value.",
+
+	"site.subdirectory":         "Organizes the downloads of the site in subdirectories named after a string captured from the page body or URL.",
+	"site.subdirectory.pattern": "A regular expression matched against the page body or URL.",
+	"site.subdirectory.capture": "The index or the name of the capture group of the pattern that contains the name of the subdirectory.",
+	"site.subdirectory.from":    "Where the pattern is matched: `body` or `url`.",
+	"site.subdirectory.merge":   "What is kept when several pages of a run have the same subdirectory: `first` keeps the info of the first page, `last` of the last page, `append` of every page, as an array in `_info.json` when there are several. Defaults to `append`.",
+
+	"site.fixture":           "A saved page used by `grab config test` to check the patterns of the site offline. The label is the name of the fixture.",
+	"site.fixture.url":       "The URL the page was saved from.",
+	"site.fixture.file":      "The path of the saved page, relative to the configuration file.",
+	"site.fixture.assets":    "The expected asset URLs, by asset name.",
+	"site.fixture.filenames": "The expected destinations, relative to global.location, by asset name.",
+	"site.fixture.info":      "The expected info values, by info name.",
+
+	"watch":          "A list of URLs polled by `grab watch`, only the pages that were not seen before are scraped. The label is the name of the source, used to keep its state.",
+	"watch.list":     "The path or the URL of the list: one URL per line, JSON, CSV, a sitemap or an RSS or Atom feed.",
+	"watch.interval": "How often the list is polled, as a duration, e.g. `30m` or `1h30m`.",
+	"watch.column":   "The column of the URLs, if the list is a CSV file: a header name or an index starting at 1.",
+
+	"schedule":       "URLs scraped by `grab run-schedules` at the times of a cron expression. The label is the name of the schedule.",
+	"schedule.cron":  "A cron expression with five fields (minute, hour, day of month, month, day of week), or a macro such as `@hourly` or `@weekly`.",
+	"schedule.urls":  "The URLs to scrape, or the paths of lists of URLs, like the arguments of `grab get`.",
+	"schedule.sites": "The names of the only sites that can handle the URLs. Defaults to all the sites.",
+}
+
+// Doc returns the documentation of the block or attribute at the given path,
+// falling back to the longest suffix of the path that is documented.
+// Returns an empty string if there is no documentation.
+func Doc(path ...string) string {
+	for i := 0; i < len(path); i++ {
+		if doc, ok := Docs[strings.Join(path[i:], ".")]; ok {
+			return doc
+		}
+	}
+
+	return ""
+}
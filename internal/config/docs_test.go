@@ -0,0 +1,35 @@
+package config
+
+import "testing"
+
+func TestDoc(t *testing.T) {
+	tests := []struct {
+		Name string
+		Path []string
+		Want string
+	}{
+		{
+			Name: "exact",
+			Path: []string{"site", "asset", "pattern"},
+			Want: Docs["site.asset.pattern"],
+		},
+		{
+			Name: "suffix",
+			Path: []string{"site", "asset", "network", "timeout"},
+			Want: Docs["network.timeout"],
+		},
+		{
+			Name: "missing",
+			Path: []string{"site", "unknown"},
+			Want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			if got := Doc(tt.Path...); got != tt.Want {
+				tc.Errorf("got: %q, want: %q", got, tt.Want)
+			}
+		})
+	}
+}
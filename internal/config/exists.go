@@ -0,0 +1,17 @@
+package config
+
+const (
+	// the existing file is kept
+	OnExistsSkip = "skip"
+	// the existing file is replaced
+	OnExistsOverwrite = "overwrite"
+	// the new file is written next to the existing one, with " (1)" appended to its name
+	OnExistsRename = "rename"
+	// the existing file is replaced if the server has a newer version, using If-Modified-Since
+	OnExistsIfNewer = "if_newer"
+	// the existing file is replaced if the server has a different version, using If-None-Match
+	OnExistsIfChanged = "if_changed"
+)
+
+// OnExistsPolicies are the values of the "on_exists" attribute and of the --on-exists flag
+var OnExistsPolicies = []string{OnExistsSkip, OnExistsOverwrite, OnExistsRename, OnExistsIfNewer, OnExistsIfChanged}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// the units of the "min_size" and "max_size" attributes, the longest suffixes first
+var sizeUnits = []struct {
+	Suffix string
+	Bytes  int64
+}{
+	{"KiB", 1 << 10},
+	{"MiB", 1 << 20},
+	{"GiB", 1 << 30},
+	{"KB", 1000},
+	{"MB", 1000 * 1000},
+	{"GB", 1000 * 1000 * 1000},
+	{"B", 1},
+}
+
+// ParseSize parses a size like "512", "10KB" or "1.5MiB" into a number of bytes
+func ParseSize(size string) (int64, error) {
+	str := strings.TrimSpace(size)
+
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(str, unit.Suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, unit.Suffix))
+			multiplier = unit.Bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(str, 64)
+	// "NaN", "Inf" and the sizes that do not fit in an int64 are not sizes either
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) || n < 0 || n*float64(multiplier) >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size: %q", size)
+	}
+
+	return int64(n * float64(multiplier)), nil
+}
+
+// ValidMediaRange reports whether pattern is a media type like "image/png", "image/*" or "*/*"
+func ValidMediaRange(pattern string) bool {
+	typ, subtype, found := strings.Cut(pattern, "/")
+	if !found || typ == "" || subtype == "" || strings.ContainsAny(subtype, "/;") {
+		return false
+	}
+
+	// a wildcard type needs a wildcard subtype
+	return typ != "*" || subtype == "*"
+}
+
+// SizeLimits returns the sizes in bytes of the "min_size" and "max_size" attributes of the asset, 0 when not set
+func (a AssetConfig) SizeLimits() (min int64, max int64) {
+	if a.MinSize != nil {
+		min, _ = ParseSize(*a.MinSize)
+	}
+
+	if a.MaxSize != nil {
+		max, _ = ParseSize(*a.MaxSize)
+	}
+
+	return min, max
+}
@@ -0,0 +1,64 @@
+package config
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		Input     string
+		Want      int64
+		WantError bool
+	}{
+		{Input: "512", Want: 512},
+		{Input: "512B", Want: 512},
+		{Input: "10KB", Want: 10000},
+		{Input: "10KiB", Want: 10240},
+		{Input: "1.5MB", Want: 1500000},
+		{Input: "2 MiB", Want: 2 << 20},
+		{Input: "1GB", Want: 1000000000},
+		{Input: "1GiB", Want: 1 << 30},
+		{Input: "big", WantError: true},
+		{Input: "-1KB", WantError: true},
+		{Input: "KB", WantError: true},
+		{Input: "NaN", WantError: true},
+		{Input: "NaNKB", WantError: true},
+		{Input: "Inf", WantError: true},
+		{Input: "+Inf", WantError: true},
+		{Input: "-Inf", WantError: true},
+		{Input: "infinityGB", WantError: true},
+		{Input: "-0.5", WantError: true},
+		{Input: "1e30GB", WantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Input, func(tc *testing.T) {
+			got, err := ParseSize(tt.Input)
+			if (err != nil) != tt.WantError {
+				tc.Fatalf("got: %v, want error: %v", err, tt.WantError)
+			}
+
+			if got != tt.Want {
+				tc.Errorf("got: %d, want: %d", got, tt.Want)
+			}
+		})
+	}
+}
+
+func TestValidMediaRange(t *testing.T) {
+	tests := map[string]bool{
+		"image/png":  true,
+		"image/*":    true,
+		"*/*":        true,
+		"image":      false,
+		"*/png":      false,
+		"image/":     false,
+		"/png":       false,
+		"a/b/c":      false,
+		"text/html;": false,
+	}
+
+	for input, want := range tests {
+		if got := ValidMediaRange(input); got != want {
+			t.Errorf("%s: got: %v, want: %v", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"bytes"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// the attributes containing regular expressions, their strings are rewritten with canonical escaping
+var regexAttributes = []string{"test", "pattern", "include", "exclude"}
+
+// Format returns the canonical formatting of a configuration file written in the HCL native syntax:
+//   - attributes are aligned and indented by hclwrite
+//   - the "global" block comes first, followed by the other blocks in their original order
+//   - quoted regular expressions (test, pattern, include and exclude attributes) use the canonical escape sequences
+//
+// Comments are preserved and move together with the block that follows them.
+func Format(src []byte, filename string) ([]byte, hcl.Diagnostics) {
+	// validate the syntax first, hclwrite is more permissive
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	src = reorderBlocks(src, file.Body.(*hclsyntax.Body))
+
+	f, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	normalizeRegexStrings(f.Body())
+
+	return hclwrite.Format(f.Bytes()), nil
+}
+
+// moves the "global" block (with the comments and blank lines before it) at the top of the file
+func reorderBlocks(src []byte, body *hclsyntax.Body) []byte {
+	globalIndex := -1
+	for i, block := range body.Blocks {
+		if block.Type == "global" {
+			globalIndex = i
+			break
+		}
+	}
+
+	if globalIndex <= 0 {
+		return src
+	}
+
+	// every segment starts where the previous block ends, so comments stay attached to the next block
+	segments := make([][]byte, 0, len(body.Blocks)+1)
+	start := 0
+	for _, block := range body.Blocks {
+		end := block.Range().End.Byte
+		segments = append(segments, src[start:end])
+		start = end
+	}
+	trailing := src[start:]
+
+	global := bytes.TrimLeft(segments[globalIndex], "\r\n")
+	// the first block loses the blank lines that separated it from the previous one
+	first := bytes.TrimLeft(segments[0], "\r\n")
+
+	buf := &bytes.Buffer{}
+	buf.Write(global)
+	buf.WriteString("\n\n")
+	buf.Write(first)
+
+	for i := 1; i < len(segments); i++ {
+		if i == globalIndex {
+			continue
+		}
+		buf.Write(segments[i])
+	}
+	buf.Write(trailing)
+
+	return buf.Bytes()
+}
+
+func normalizeRegexStrings(body *hclwrite.Body) {
+	for _, name := range regexAttributes {
+		attr := body.GetAttribute(name)
+		if attr == nil {
+			continue
+		}
+
+		if value, ok := quotedLiteral(attr.Expr().BuildTokens(nil).Bytes()); ok {
+			body.SetAttributeValue(name, cty.StringVal(value))
+		}
+	}
+
+	for _, block := range body.Blocks() {
+		normalizeRegexStrings(block.Body())
+	}
+}
+
+// returns the value of a quoted string without interpolations, heredocs and templates are left untouched
+func quotedLiteral(src []byte) (string, bool) {
+	src = bytes.TrimSpace(src)
+	if len(src) == 0 || src[0] != '"' {
+		return "", false
+	}
+
+	expr, diags := hclsyntax.ParseExpression(src, "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", false
+	}
+
+	template, ok := expr.(*hclsyntax.TemplateExpr)
+	if !ok || !template.IsStringLiteral() {
+		return "", false
+	}
+
+	value, diags := template.Value(nil)
+	if diags.HasErrors() || !value.Type().Equals(cty.String) || value.IsNull() {
+		return "", false
+	}
+
+	return value.AsString(), true
+}
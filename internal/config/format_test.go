@@ -0,0 +1,196 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Input     string
+		Want      string
+		WantError bool
+	}{
+		{
+			Name: "already formatted",
+			Input: `global {
+  location = "x"
+}
+
+site "a" {
+  test = "a"
+}
+`,
+			Want: `global {
+  location = "x"
+}
+
+site "a" {
+  test = "a"
+}
+`,
+		},
+		{
+			Name: "alignment",
+			Input: `global {
+location = "x"
+}
+
+site "a" {
+	test = "a"
+	asset "b" {
+		pattern = "b"
+		capture = 1
+		find_all     = true
+	}
+}
+`,
+			Want: `global {
+  location = "x"
+}
+
+site "a" {
+  test = "a"
+  asset "b" {
+    pattern  = "b"
+    capture  = 1
+    find_all = true
+  }
+}
+`,
+		},
+		{
+			Name: "global first with comments",
+			Input: `# first site
+site "a" {
+  test = "a"
+}
+
+site "b" {
+  test = "b"
+}
+
+// global settings
+global {
+  location = "x"
+}
+`,
+			Want: `// global settings
+global {
+  location = "x"
+}
+
+# first site
+site "a" {
+  test = "a"
+}
+
+site "b" {
+  test = "b"
+}
+`,
+		},
+		{
+			Name: "invalid escape sequence",
+			Input: `global {
+  location = "A"
+}
+
+site "a" {
+  test = "A\\.b"
+
+  info "c" {
+    pattern = "\x"
+  }
+}
+`,
+			WantError: true,
+		},
+		{
+			Name: "regex escaping of test, pattern, include and exclude",
+			Input: `global {
+  location = "\u0041"
+}
+
+site "a" {
+  test = "\u0041\\.b" # comment
+
+  info "c" {
+    pattern = "<a href=\"${"$"}{1}\">"
+    capture = "1"
+  }
+
+  info "d" {
+    pattern = <<EOT
+A
+EOT
+    capture = 1
+  }
+
+  asset "e" {
+    pattern = "src=\"([^\"]+)"
+    capture = 1
+    include = "\u0041\\.jpg"
+    exclude = "\u0042"
+  }
+}
+`,
+			Want: `global {
+  location = "\u0041"
+}
+
+site "a" {
+  test = "A\\.b" # comment
+
+  info "c" {
+    pattern = "<a href=\"${"$"}{1}\">"
+    capture = "1"
+  }
+
+  info "d" {
+    pattern = <<EOT
+A
+EOT
+    capture = 1
+  }
+
+  asset "e" {
+    pattern = "src=\"([^\"]+)"
+    capture = 1
+    include = "A\\.jpg"
+    exclude = "B"
+  }
+}
+`,
+		},
+		{
+			Name:      "syntax error",
+			Input:     `site "a {`,
+			WantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			got, diags := Format([]byte(tt.Input), "test.hcl")
+
+			if diags.HasErrors() != tt.WantError {
+				tc.Fatalf("got: %v, want errors: %v", diags, tt.WantError)
+			}
+
+			if string(got) != tt.Want {
+				tc.Errorf("got:\n%s\nwant:\n%s", got, tt.Want)
+			}
+
+			if tt.WantError {
+				return
+			}
+
+			// formatting must be idempotent
+			again, _ := Format(got, "test.hcl")
+			if string(again) != string(got) {
+				tc.Errorf("got:\n%s\nwant:\n%s", again, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"text/template"
+	"time"
+)
+
+const (
+	// after every asset written to the disk
+	HookAssetDownloaded = "asset_downloaded"
+	// after the downloads of a page, whatever their outcome
+	HookPageDone = "page_done"
+	// at the end of the run
+	HookRunDone = "run_done"
+)
+
+// HookEvents are the values of the "on" attribute of the hook blocks
+var HookEvents = []string{HookAssetDownloaded, HookPageDone, HookRunDone}
+
+// DefaultHookTimeout is used when the hook block has no timeout attribute
+const DefaultHookTimeout = time.Minute
+
+// ParseHookTemplate parses an argument of the command of a hook.
+// Missing info values are rendered as empty strings.
+func ParseHookTemplate(arg string) (*template.Template, error) {
+	return template.New("hook").Option("missingkey=zero").Parse(arg)
+}
+
+// Duration returns the timeout of the hook, DefaultHookTimeout if it is not set or not valid
+func (h HookConfig) Duration() time.Duration {
+	if h.Timeout == nil {
+		return DefaultHookTimeout
+	}
+
+	d, err := time.ParseDuration(*h.Timeout)
+	if err != nil || d <= 0 {
+		return DefaultHookTimeout
+	}
+
+	return d
+}
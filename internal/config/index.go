@@ -0,0 +1,74 @@
+package config
+
+import "path/filepath"
+
+const (
+	// an _info.json file in every subdirectory
+	IndexJSON = "json"
+	// a line per page, appended to a single file
+	IndexNDJSON = "ndjson"
+	// a row per page, in a single file
+	IndexCSV = "csv"
+	// a row per page, in a single database
+	IndexSQLite = "sqlite"
+)
+
+// IndexFormats are the values of the "format" attribute of the index block
+var IndexFormats = []string{IndexJSON, IndexNDJSON, IndexCSV, IndexSQLite}
+
+const (
+	// the info of the first page of a subdirectory is kept
+	InfoMergeFirst = "first"
+	// the info of the last page of a subdirectory is kept
+	InfoMergeLast = "last"
+	// the info of every page of a subdirectory is kept, one record per page
+	InfoMergeAppend = "append"
+)
+
+// InfoMergePolicies are the values of the "merge" attribute of the subdirectory block
+var InfoMergePolicies = []string{InfoMergeFirst, InfoMergeLast, InfoMergeAppend}
+
+// DefaultIndexPaths are the files of the single file formats, relative to the location
+var DefaultIndexPaths = map[string]string{
+	IndexNDJSON: "_index.ndjson",
+	IndexCSV:    "_index.csv",
+	IndexSQLite: "_index.db",
+}
+
+// IndexFormat returns the format of the index, IndexJSON if there is no index block
+func (g GlobalConfig) IndexFormat() string {
+	if g.Index == nil {
+		return IndexJSON
+	}
+
+	return g.Index.Format
+}
+
+// IndexPath returns the absolute path of the index file, empty for the json format
+func (g GlobalConfig) IndexPath() string {
+	format := g.IndexFormat()
+	if format == IndexJSON {
+		return ""
+	}
+
+	path := DefaultIndexPaths[format]
+	if g.Index.Path != nil {
+		path = *g.Index.Path
+	}
+
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(g.Location, path)
+}
+
+// InfoMerge returns the merge policy of the info of the pages with the same subdirectory.
+// InfoMergeAppend by default, so that the info of a page is never dropped silently.
+func (s SiteConfig) InfoMerge() string {
+	if s.Subdirectory == nil || s.Subdirectory.Merge == nil {
+		return InfoMergeAppend
+	}
+
+	return *s.Subdirectory.Merge
+}
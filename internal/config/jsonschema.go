@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// JSONSchema returns a JSON Schema (draft-07) describing the JSON and YAML configuration formats,
+// generated from ConfigSpec
+func JSONSchema() ([]byte, error) {
+	schema := bodySchema(ConfigSpec, nil)
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "grab configuration"
+
+	buf := &bytes.Buffer{}
+
+	// keep the output readable, do not escape angle brackets
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(schema); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type jsonSchema map[string]interface{}
+
+// returns the schema of the JSON object representing a block body, path contains the types of the parent blocks
+func bodySchema(spec *hcldec.ObjectSpec, path []string) jsonSchema {
+	properties := jsonSchema{
+		// the HCL JSON syntax allows comments as properties named "//"
+		"//": jsonSchema{"type": "string"},
+	}
+	required := make([]string, 0)
+
+	for _, child := range *spec {
+		switch s := child.(type) {
+		case *hcldec.AttrSpec:
+			properties[s.Name] = describe(typeSchema(s.Type), append(path, s.Name))
+			if s.Required {
+				required = append(required, s.Name)
+			}
+		case *hcldec.BlockSpec:
+			properties[s.TypeName] = describe(blockSchema(s.Nested, append(path, s.TypeName)), append(path, s.TypeName))
+			if s.Required {
+				required = append(required, s.TypeName)
+			}
+		case *hcldec.BlockTupleSpec:
+			properties[s.TypeName] = describe(blockSchema(s.Nested, append(path, s.TypeName)), append(path, s.TypeName))
+			if s.MinItems > 0 {
+				required = append(required, s.TypeName)
+			}
+		case *hcldec.BlockListSpec:
+			properties[s.TypeName] = describe(blockSchema(s.Nested, append(path, s.TypeName)), append(path, s.TypeName))
+			if s.MinItems > 0 {
+				required = append(required, s.TypeName)
+			}
+		}
+	}
+
+	schema := jsonSchema{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// returns the schema of one or more blocks: every label adds a level of nesting,
+// and at every level the value can also be an array of objects
+func blockSchema(nested hcldec.Spec, path []string) jsonSchema {
+	spec, ok := nested.(*hcldec.ObjectSpec)
+	if !ok {
+		return jsonSchema{"type": "object"}
+	}
+
+	labels := 0
+	for _, child := range *spec {
+		if _, ok := child.(*hcldec.BlockLabelSpec); ok {
+			labels++
+		}
+	}
+
+	schema := oneOrMany(bodySchema(spec, path))
+	for i := 0; i < labels; i++ {
+		schema = oneOrMany(jsonSchema{
+			"type":                 "object",
+			"additionalProperties": schema,
+		})
+	}
+
+	return schema
+}
+
+// adds the documentation of the block or attribute at path to schema, if any
+func describe(schema jsonSchema, path []string) jsonSchema {
+	if doc := Doc(path...); doc != "" {
+		schema["description"] = doc
+	}
+
+	return schema
+}
+
+func oneOrMany(schema jsonSchema) jsonSchema {
+	return jsonSchema{
+		"oneOf": []jsonSchema{
+			schema,
+			{"type": "array", "items": schema},
+		},
+	}
+}
+
+// returns the schema of an attribute value
+func typeSchema(t cty.Type) jsonSchema {
+	switch {
+	case t == cty.String:
+		// numbers are converted to strings, e.g. capture = 1
+		return jsonSchema{"type": []string{"string", "number"}}
+	case t == cty.Number:
+		// strings are converted to numbers, e.g. timeout = "1000"
+		return jsonSchema{"type": []string{"number", "string"}}
+	case t == cty.Bool:
+		return jsonSchema{"type": []string{"boolean", "string"}}
+	case t.IsMapType():
+		return jsonSchema{"type": "object", "additionalProperties": typeSchema(t.ElementType())}
+	case t.IsListType(), t.IsSetType():
+		return jsonSchema{"type": "array", "items": typeSchema(t.ElementType())}
+	default:
+		return jsonSchema{}
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	b, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatalf("got invalid json: %v", err)
+	}
+
+	if schema["$schema"] != "http://json-schema.org/draft-07/schema#" {
+		t.Errorf("got: %v, want draft-07", schema["$schema"])
+	}
+
+	required, _ := schema["required"].([]interface{})
+	if len(required) != 2 || required[0] != "global" || required[1] != "site" {
+		t.Errorf("got: %v, want: [global site]", required)
+	}
+
+	// site -> label -> body
+	site := schema["properties"].(map[string]interface{})["site"].(map[string]interface{})
+	labeled := site["oneOf"].([]interface{})[0].(map[string]interface{})
+	body := labeled["additionalProperties"].(map[string]interface{})["oneOf"].([]interface{})[0].(map[string]interface{})
+	properties := body["properties"].(map[string]interface{})
+
+	for _, name := range []string{"test", "network", "asset", "info", "subdirectory", "fixture", "//"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("site properties do not contain %q", name)
+		}
+	}
+
+	if properties["test"].(map[string]interface{})["description"] != Docs["site.test"] {
+		t.Errorf("got: %v, want the documentation of site.test", properties["test"])
+	}
+
+	if body["additionalProperties"] != false {
+		t.Errorf("got: %v, want no additional properties", body["additionalProperties"])
+	}
+}
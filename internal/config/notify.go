@@ -0,0 +1,20 @@
+package config
+
+const (
+	// after every run
+	NotifyRunDone = "run_done"
+	// after the runs with failures
+	NotifyRunFailed = "run_failed"
+)
+
+// NotifyEvents are the values of the "on" attribute of the notify block
+var NotifyEvents = []string{NotifyRunDone, NotifyRunFailed}
+
+// Event returns when the notification is sent, NotifyRunDone by default
+func (n NotifyConfig) Event() string {
+	if n.On == nil {
+		return NotifyRunDone
+	}
+
+	return *n.On
+}
@@ -2,9 +2,13 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/everdrone/grab/internal/context"
+	"github.com/everdrone/grab/internal/cron"
 	"github.com/everdrone/grab/internal/utils"
 	"github.com/rs/zerolog/log"
 
@@ -14,6 +18,7 @@ import (
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 )
 
 func ValidateSpec(body *hcl.Body, ctx *hcl.EvalContext) hcl.Diagnostics {
@@ -25,38 +30,131 @@ func ValidateSpec(body *hcl.Body, ctx *hcl.EvalContext) hcl.Diagnostics {
 	return nil
 }
 
-func ValidateConfig(root *hclsyntax.Body, ctx *hcl.EvalContext) hcl.Diagnostics {
+// returns the blocks of the given type declared in body.
+// Native syntax bodies are traversed directly, other syntaxes (JSON) need the spec to be interpreted.
+func blocksOfType(body hcl.Body, spec hcldec.Spec, typeName string) hcl.Blocks {
+	if syntaxBody, ok := body.(*hclsyntax.Body); ok {
+		blocks := make(hcl.Blocks, 0)
+		for _, block := range syntaxBody.Blocks {
+			if block.Type == typeName {
+				blocks = append(blocks, block.AsHCLBlock())
+			}
+		}
+		return blocks
+	}
+
+	// the body has already been validated against the spec, we don't need the diagnostics
+	content, _, _ := body.PartialContent(hcldec.ImpliedSchema(spec))
+	return content.Blocks.OfType(typeName)
+}
+
+// returns the attribute with the given name declared in body, nil if it is not declared
+func attributeOf(body hcl.Body, spec hcldec.Spec, name string) *hcl.Attribute {
+	if syntaxBody, ok := body.(*hclsyntax.Body); ok {
+		if attr, ok := syntaxBody.Attributes[name]; ok {
+			return attr.AsHCLAttribute()
+		}
+		return nil
+	}
+
+	content, _, _ := body.PartialContent(hcldec.ImpliedSchema(spec))
+	return content.Attributes[name]
+}
+
+// returns the range of the block body if available, otherwise the range of its definition
+func bodyRange(block *hcl.Block) *hcl.Range {
+	if body, ok := block.Body.(*hclsyntax.Body); ok {
+		return &body.SrcRange
+	}
+
+	return &block.DefRange
+}
+
+func ValidateConfig(root hcl.Body, ctx *hcl.EvalContext) hcl.Diagnostics {
 	var diags hcl.Diagnostics
 
-	sites := utils.Filter(root.Blocks, func(b *hclsyntax.Block) bool {
-		return b.Type == "site"
-	})
+	for _, global := range blocksOfType(root, ConfigSpec, "global") {
+		if diags := validateHooks(global.Body, GlobalSpec, ctx, HookEvents); diags.HasErrors() {
+			return diags
+		}
+
+		if diags := validateOnExists(global.Body, GlobalSpec, ctx); diags.HasErrors() {
+			return diags
+		}
+
+		for _, notify := range blocksOfType(global.Body, GlobalSpec, "notify") {
+			if diags := validateNotify(notify.Body, ctx); diags.HasErrors() {
+				return diags
+			}
+		}
+
+		for _, index := range blocksOfType(global.Body, GlobalSpec, "index") {
+			if diags := validateIndex(index.Body, ctx); diags.HasErrors() {
+				return diags
+			}
+		}
+	}
+
+	sites := blocksOfType(root, ConfigSpec, "site")
 
 	for _, site := range sites {
+		if diags := validateHooks(site.Body, SiteSpec, ctx, HookEvents); diags.HasErrors() {
+			return diags
+		}
+
+		if diags := validateOnExists(site.Body, SiteSpec, ctx); diags.HasErrors() {
+			return diags
+		}
+
+		if attr := attributeOf(site.Body, SiteSpec, "max_age"); attr != nil {
+			val, moreDiags := attr.Expr.Value(ctx)
+			diags = append(diags, moreDiags...)
+			if moreDiags.HasErrors() {
+				return diags
+			}
+
+			if str, ok := stringValue(val); ok {
+				if d, err := time.ParseDuration(str); err != nil || d <= 0 {
+					return append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid block attribute",
+						Detail:   "The \"max_age\" attribute must be a positive duration, e.g. \"30m\" or \"24h\".",
+						Subject:  attr.Expr.Range().Ptr(),
+					})
+				}
+			}
+		}
+
 		// validate that there is at least one "asset" or at least one "info" block inside every "site" block
-		assets := utils.Filter(site.Body.Blocks, func(b *hclsyntax.Block) bool {
-			return b.Type == "asset"
-		})
-		infos := utils.Filter(site.Body.Blocks, func(b *hclsyntax.Block) bool {
-			return b.Type == "info"
-		})
+		assets := blocksOfType(site.Body, SiteSpec, "asset")
+		infos := blocksOfType(site.Body, SiteSpec, "info")
 
 		if len(assets) == 0 && len(infos) == 0 {
 			return append(diags, &hcl.Diagnostic{
 				Severity: hcl.DiagError,
 				Summary:  "Insufficient \"site\" and \"info\" blocks",
 				Detail:   "At least one asset or one info block must be defined inside a \"site\" block.",
-				Subject:  &site.Body.SrcRange,
+				Subject:  bodyRange(site),
 			})
 		}
 
 		for _, asset := range assets {
+			if diags := validateHooks(asset.Body, AssetSpec, ctx, []string{HookAssetDownloaded}); diags.HasErrors() {
+				return diags
+			}
+
+			if diags := validateOnExists(asset.Body, AssetSpec, ctx); diags.HasErrors() {
+				return diags
+			}
+
+			if diags := validateFilters(asset.Body, ctx); diags.HasErrors() {
+				return diags
+			}
+
 			// if "transform" blocks are present:
 			//  - validate that the label is either "url" or "filename"
 			//  - validate that there is not more than one "transform" block with the same label
-			transforms := utils.Filter(asset.Body.Blocks, func(b *hclsyntax.Block) bool {
-				return b.Type == "transform"
-			})
+			transforms := blocksOfType(asset.Body, AssetSpec, "transform")
 
 			for _, transform := range transforms {
 				label := transform.Labels[0]
@@ -70,7 +168,7 @@ func ValidateConfig(root *hclsyntax.Body, ctx *hcl.EvalContext) hcl.Diagnostics
 					})
 				}
 
-				if len(utils.Filter(transforms, func(t *hclsyntax.Block) bool { return t.Labels[0] == label })) > 1 {
+				if len(utils.Filter(transforms, func(t *hcl.Block) bool { return t.Labels[0] == label })) > 1 {
 					return append(diags, &hcl.Diagnostic{
 						Severity: hcl.DiagError,
 						Summary:  "Duplicate block label",
@@ -80,15 +178,58 @@ func ValidateConfig(root *hclsyntax.Body, ctx *hcl.EvalContext) hcl.Diagnostics
 				}
 			}
 
+			// validate that the "algorithm" of the "checksum" block is supported
+			for _, checksum := range blocksOfType(asset.Body, AssetSpec, "checksum") {
+				algorithm := attributeOf(checksum.Body, ChecksumSpec, "algorithm")
+				if algorithm == nil {
+					// the spec already reports the missing attribute
+					continue
+				}
+
+				val, moreDiags := algorithm.Expr.Value(ctx)
+				diags = append(diags, moreDiags...)
+				if moreDiags.HasErrors() {
+					return diags
+				}
+
+				if name, ok := stringValue(val); !ok || !utils.Contains(ChecksumAlgorithms, name) {
+					return append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid block attribute",
+						Detail:   fmt.Sprintf("The \"algorithm\" attribute must be %s.", quoteAll(ChecksumAlgorithms)),
+						Subject:  algorithm.Expr.Range().Ptr(),
+					})
+				}
+			}
 		}
 
 		// validate that, inside all "subdirectory" blocks, the "from" attribute is either "body" or "url"
-		subdirectories := utils.Filter(site.Body.Blocks, func(b *hclsyntax.Block) bool {
-			return b.Type == "subdirectory"
-		})
+		// and that the "merge" attribute is a merge policy
+		subdirectories := blocksOfType(site.Body, SiteSpec, "subdirectory")
 
 		for _, subdirectory := range subdirectories {
-			from := subdirectory.Body.Attributes["from"]
+			if merge := attributeOf(subdirectory.Body, SubdirectorySpec, "merge"); merge != nil {
+				val, moreDiags := merge.Expr.Value(ctx)
+				diags = append(diags, moreDiags...)
+				if moreDiags.HasErrors() {
+					return diags
+				}
+
+				if policy, ok := stringValue(val); ok && !utils.Contains(InfoMergePolicies, policy) {
+					return append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid block attribute",
+						Detail:   fmt.Sprintf("The \"merge\" attribute must be %s.", quoteAll(InfoMergePolicies)),
+						Subject:  merge.Expr.Range().Ptr(),
+					})
+				}
+			}
+
+			from := attributeOf(subdirectory.Body, SubdirectorySpec, "from")
+			if from == nil {
+				// the spec already reports the missing attribute
+				continue
+			}
 
 			val, moreDiags := from.Expr.Value(ctx)
 			diags = append(diags, moreDiags...)
@@ -98,16 +239,420 @@ func ValidateConfig(root *hclsyntax.Body, ctx *hcl.EvalContext) hcl.Diagnostics
 					Severity: hcl.DiagError,
 					Summary:  "Invalid block attribute",
 					Detail:   "The \"from\" attribute must be either \"body\" or \"url\".",
-					Subject:  &from.EqualsRange,
+					Subject:  from.Expr.Range().Ptr(),
 				})
 			}
 		}
 	}
 
+	// validate that the "watch" blocks have unique names and a positive "interval"
+	watches := blocksOfType(root, ConfigSpec, "watch")
+
+	for i, watch := range watches {
+		name := watch.Labels[0]
+
+		for _, other := range watches[:i] {
+			if other.Labels[0] == name {
+				return append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate block label",
+					Detail:   fmt.Sprintf("No more than one \"watch\" block with the label \"%s\" is allowed.", name),
+					Subject:  &watch.LabelRanges[0],
+				})
+			}
+		}
+
+		interval := attributeOf(watch.Body, WatchSpec, "interval")
+		if interval == nil {
+			continue
+		}
+
+		val, moreDiags := interval.Expr.Value(ctx)
+		diags = append(diags, moreDiags...)
+		if moreDiags.HasErrors() {
+			return diags
+		}
+
+		// a null interval is not a duration either
+		str, _ := stringValue(val)
+		if d, err := time.ParseDuration(str); err != nil || d <= 0 {
+			return append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid block attribute",
+				Detail:   "The \"interval\" attribute must be a positive duration, e.g. \"30m\" or \"1h30m\".",
+				Subject:  interval.Expr.Range().Ptr(),
+			})
+		}
+	}
+
+	// validate that the "schedule" blocks have unique names, a valid "cron" expression
+	// and that their "sites" are defined
+	schedules := blocksOfType(root, ConfigSpec, "schedule")
+
+	for i, schedule := range schedules {
+		name := schedule.Labels[0]
+
+		for _, other := range schedules[:i] {
+			if other.Labels[0] == name {
+				return append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate block label",
+					Detail:   fmt.Sprintf("No more than one \"schedule\" block with the label \"%s\" is allowed.", name),
+					Subject:  &schedule.LabelRanges[0],
+				})
+			}
+		}
+
+		if expr := attributeOf(schedule.Body, ScheduleSpec, "cron"); expr != nil {
+			val, moreDiags := expr.Expr.Value(ctx)
+			diags = append(diags, moreDiags...)
+			if moreDiags.HasErrors() {
+				return diags
+			}
+
+			// a null expression is reported as an empty one
+			str, _ := stringValue(val)
+			if _, err := cron.Parse(str); err != nil {
+				return append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid cron expression",
+					Detail:   fmt.Sprintf("The \"cron\" attribute is invalid: %s.", err.Error()),
+					Subject:  expr.Expr.Range().Ptr(),
+				})
+			}
+		}
+
+		if attr := attributeOf(schedule.Body, ScheduleSpec, "sites"); attr != nil {
+			val, moreDiags := attr.Expr.Value(ctx)
+			diags = append(diags, moreDiags...)
+			if moreDiags.HasErrors() {
+				return diags
+			}
+
+			if !val.IsWhollyKnown() || val.IsNull() || !val.CanIterateElements() {
+				// the spec already reports the wrong type
+				continue
+			}
+
+			for it := val.ElementIterator(); it.Next(); {
+				_, v := it.Element()
+				if !v.Type().Equals(cty.String) || v.IsNull() {
+					// the spec already reports the wrong type
+					continue
+				}
+
+				if len(utils.Filter(sites, func(s *hcl.Block) bool { return s.Labels[0] == v.AsString() })) == 0 {
+					return append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Unknown site",
+						Detail:   fmt.Sprintf("There is no \"site\" block with the label \"%s\".", v.AsString()),
+						Subject:  attr.Expr.Range().Ptr(),
+					})
+				}
+			}
+		}
+	}
+
 	return nil
 }
 
+// validates the event, the command templates and the timeout of the "hook" blocks declared in body
+func validateHooks(body hcl.Body, spec hcldec.Spec, ctx *hcl.EvalContext, events []string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	for _, hook := range blocksOfType(body, spec, "hook") {
+		if attr := attributeOf(hook.Body, HookSpec, "on"); attr != nil {
+			val, moreDiags := attr.Expr.Value(ctx)
+			diags = append(diags, moreDiags...)
+			if moreDiags.HasErrors() {
+				return diags
+			}
+
+			if event, ok := stringValue(val); !ok || !utils.Contains(events, event) {
+				return append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   fmt.Sprintf("The \"on\" attribute must be %s.", quoteAll(events)),
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			}
+		}
+
+		if attr := attributeOf(hook.Body, HookSpec, "command"); attr != nil {
+			val, moreDiags := attr.Expr.Value(ctx)
+			diags = append(diags, moreDiags...)
+			if moreDiags.HasErrors() {
+				return diags
+			}
+
+			if !val.IsWhollyKnown() || val.IsNull() || !val.CanIterateElements() || val.LengthInt() == 0 {
+				return append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"command\" attribute must contain at least the name of the program.",
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			}
+
+			for it := val.ElementIterator(); it.Next(); {
+				_, v := it.Element()
+				if !v.Type().Equals(cty.String) || v.IsNull() {
+					// the spec already reports the wrong type
+					continue
+				}
+
+				if _, err := ParseHookTemplate(v.AsString()); err != nil {
+					return append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid template",
+						Detail:   fmt.Sprintf("The \"command\" attribute is invalid: %s.", err.Error()),
+						Subject:  attr.Expr.Range().Ptr(),
+					})
+				}
+			}
+		}
+
+		if attr := attributeOf(hook.Body, HookSpec, "timeout"); attr != nil {
+			val, moreDiags := attr.Expr.Value(ctx)
+			diags = append(diags, moreDiags...)
+			if moreDiags.HasErrors() {
+				return diags
+			}
+
+			if str, ok := stringValue(val); ok {
+				if d, err := time.ParseDuration(str); err != nil || d <= 0 {
+					return append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid block attribute",
+						Detail:   "The \"timeout\" attribute must be a positive duration, e.g. \"30s\" or \"5m\".",
+						Subject:  attr.Expr.Range().Ptr(),
+					})
+				}
+			}
+		}
+	}
+
+	return diags
+}
+
+// validates the url and the event of the "notify" block
+func validateNotify(body hcl.Body, ctx *hcl.EvalContext) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	if attr := attributeOf(body, NotifySpec, "url"); attr != nil {
+		val, moreDiags := attr.Expr.Value(ctx)
+		diags = append(diags, moreDiags...)
+		if moreDiags.HasErrors() {
+			return diags
+		}
+
+		url, ok := stringValue(val)
+		if _, valid := utils.IsValidURL(url); !ok || !valid {
+			return append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid block attribute",
+				Detail:   "The \"url\" attribute must be an absolute URL, e.g. \"https://example.com/notify\".",
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+	}
+
+	if attr := attributeOf(body, NotifySpec, "on"); attr != nil {
+		val, moreDiags := attr.Expr.Value(ctx)
+		diags = append(diags, moreDiags...)
+		if moreDiags.HasErrors() {
+			return diags
+		}
+
+		if event, ok := stringValue(val); ok && !utils.Contains(NotifyEvents, event) {
+			return append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid block attribute",
+				Detail:   fmt.Sprintf("The \"on\" attribute must be %s.", quoteAll(NotifyEvents)),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+	}
+
+	return diags
+}
+
+// validates the format of the "index" block, and that the json format has no path
+func validateIndex(body hcl.Body, ctx *hcl.EvalContext) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	format := attributeOf(body, IndexSpec, "format")
+	if format == nil {
+		// the spec already reports the missing attribute
+		return diags
+	}
+
+	val, moreDiags := format.Expr.Value(ctx)
+	diags = append(diags, moreDiags...)
+	if moreDiags.HasErrors() {
+		return diags
+	}
+
+	name, ok := stringValue(val)
+	if !ok || !utils.Contains(IndexFormats, name) {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid block attribute",
+			Detail:   fmt.Sprintf("The \"format\" attribute must be %s.", quoteAll(IndexFormats)),
+			Subject:  format.Expr.Range().Ptr(),
+		})
+	}
+
+	if path := attributeOf(body, IndexSpec, "path"); path != nil && name == IndexJSON {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported argument",
+			Detail:   "The \"json\" format writes a file in every subdirectory, it does not support the \"path\" attribute.",
+			Subject:  path.NameRange.Ptr(),
+		})
+	}
+
+	return diags
+}
+
+// validates the "on_exists" attribute of a global, site or asset block
+func validateOnExists(body hcl.Body, spec hcldec.Spec, ctx *hcl.EvalContext) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	onExists := attributeOf(body, spec, "on_exists")
+	if onExists == nil {
+		return diags
+	}
+
+	val, moreDiags := onExists.Expr.Value(ctx)
+	diags = append(diags, moreDiags...)
+	if moreDiags.HasErrors() {
+		return diags
+	}
+
+	if policy, ok := stringValue(val); ok && !utils.Contains(OnExistsPolicies, policy) {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid block attribute",
+			Detail:   fmt.Sprintf("The \"on_exists\" attribute must be %s.", quoteAll(OnExistsPolicies)),
+			Subject:  onExists.Expr.Range().Ptr(),
+		})
+	}
+
+	return diags
+}
+
+// validates the "accept", "min_size" and "max_size" attributes of an asset block
+func validateFilters(body hcl.Body, ctx *hcl.EvalContext) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	if accept := attributeOf(body, AssetSpec, "accept"); accept != nil {
+		val, moreDiags := accept.Expr.Value(ctx)
+		diags = append(diags, moreDiags...)
+		if moreDiags.HasErrors() {
+			return diags
+		}
+
+		if val.IsWhollyKnown() && !val.IsNull() && val.CanIterateElements() {
+			for it := val.ElementIterator(); it.Next(); {
+				_, element := it.Element()
+
+				if pattern, ok := stringValue(element); !ok || !ValidMediaRange(pattern) {
+					return append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid block attribute",
+						Detail:   "The \"accept\" attribute must be a list of content types, e.g. [\"image/*\", \"video/mp4\"].",
+						Subject:  accept.Expr.Range().Ptr(),
+					})
+				}
+			}
+		}
+	}
+
+	sizes := make(map[string]int64)
+
+	for _, name := range []string{"min_size", "max_size"} {
+		attr := attributeOf(body, AssetSpec, name)
+		if attr == nil {
+			continue
+		}
+
+		val, moreDiags := attr.Expr.Value(ctx)
+		diags = append(diags, moreDiags...)
+		if moreDiags.HasErrors() {
+			return diags
+		}
+
+		str, ok := stringValue(val)
+		if !ok {
+			continue
+		}
+
+		size, err := ParseSize(str)
+		if err != nil {
+			return append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid block attribute",
+				Detail:   fmt.Sprintf("The %q attribute must be a size, e.g. \"500KB\" or \"2MiB\".", name),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+
+		sizes[name] = size
+
+		if max, ok := sizes["max_size"]; ok && sizes["min_size"] > max {
+			return append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid block attribute",
+				Detail:   "The \"max_size\" attribute must not be smaller than the \"min_size\" attribute.",
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+	}
+
+	return diags
+}
+
+// returns the value of an optional string attribute, false if it is null or unknown
+func stringValue(val cty.Value) (string, bool) {
+	if !val.IsWhollyKnown() || val.IsNull() {
+		return "", false
+	}
+
+	converted, err := convert.Convert(val, cty.String)
+	if err != nil {
+		// the spec already reports the wrong type
+		return "", false
+	}
+
+	return converted.AsString(), true
+}
+
+// returns the strings quoted and separated by commas, e.g. "a", "b" or "c"
+func quoteAll(strs []string) string {
+	quoted := make([]string, len(strs))
+	for i, str := range strs {
+		quoted[i] = fmt.Sprintf("%q", str)
+	}
+
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
+// reports whether the value of an optional attribute is null, the same as a missing attribute
+func isNull(attr *hcl.Attribute, ctx *hcl.EvalContext) bool {
+	val, diags := attr.Expr.Value(ctx)
+	return !diags.HasErrors() && val.IsNull()
+}
+
 func EvaluateRegexPattern(attr *hclsyntax.Attribute, ctx *hcl.EvalContext) (string, *regexp.Regexp, hcl.Diagnostics) {
+	return EvaluateRegexAttribute(attr.AsHCLAttribute(), ctx)
+}
+
+func EvaluateRegexAttribute(attr *hcl.Attribute, ctx *hcl.EvalContext) (string, *regexp.Regexp, hcl.Diagnostics) {
 	val, diags := attr.Expr.Value(ctx)
 	if diags.HasErrors() {
 		return "", nil, diags
@@ -120,7 +665,7 @@ func EvaluateRegexPattern(attr *hclsyntax.Attribute, ctx *hcl.EvalContext) (stri
 			Severity: hcl.DiagError,
 			Summary:  "Invalid regex pattern",
 			Detail:   err.Error(),
-			Subject:  &attr.EqualsRange,
+			Subject:  attr.Expr.Range().Ptr(),
 		})
 	}
 
@@ -131,23 +676,24 @@ func EvaluateRegexPattern(attr *hclsyntax.Attribute, ctx *hcl.EvalContext) (stri
 // - site*.test
 // - site*.assets*.pattern
 // - site*.assets*.transform*.pattern
+// - site*.assets*.checksum.pattern
+// - site*.assets*.include
+// - site*.assets*.exclude
 // - site*.info*.pattern
 // - site*.subdirectory.pattern
-func BuildRegexCache(root *hclsyntax.Body, ctx *hcl.EvalContext) (RegexCacheMap, hcl.Diagnostics) {
+func BuildRegexCache(root hcl.Body, ctx *hcl.EvalContext) (RegexCacheMap, hcl.Diagnostics) {
 	log.Trace().Msg("building regex cache")
 
 	var regexCache = make(RegexCacheMap)
 
-	sites := utils.Filter(root.Blocks, func(b *hclsyntax.Block) bool {
-		return b.Type == "site"
-	})
+	sites := blocksOfType(root, ConfigSpec, "site")
 
 	for _, site := range sites {
 		log.Trace().Str("name", site.Labels[0]).Msg("processing site")
 
 		// test attribute is there
-		if site.Body.Attributes["test"] != nil {
-			str, re, diags := EvaluateRegexPattern(site.Body.Attributes["test"], ctx)
+		if test := attributeOf(site.Body, SiteSpec, "test"); test != nil {
+			str, re, diags := EvaluateRegexAttribute(test, ctx)
 			if diags.HasErrors() {
 				return nil, diags
 			}
@@ -157,23 +703,50 @@ func BuildRegexCache(root *hclsyntax.Body, ctx *hcl.EvalContext) (RegexCacheMap,
 			regexCache[str] = re
 		}
 
-		// gather all blocks that must contain the "pattern" attribute
-		assets := utils.Filter(site.Body.Blocks, func(b *hclsyntax.Block) bool { return b.Type == "asset" })
-		infos := utils.Filter(site.Body.Blocks, func(b *hclsyntax.Block) bool { return b.Type == "info" })
-		subdirectories := utils.Filter(site.Body.Blocks, func(b *hclsyntax.Block) bool { return b.Type == "subdirectory" })
+		// gather all blocks that must contain the "pattern" attribute, with their spec
+		type patternBlock struct {
+			block *hcl.Block
+			spec  hcldec.Spec
+		}
+
+		patternBlocks := make([]patternBlock, 0)
+		for _, asset := range blocksOfType(site.Body, SiteSpec, "asset") {
+			patternBlocks = append(patternBlocks, patternBlock{asset, AssetSpec})
 
-		transforms := make([]*hclsyntax.Block, 0)
-		for _, asset := range assets {
-			transforms = append(transforms, utils.Filter(asset.Body.Blocks, func(b *hclsyntax.Block) bool { return b.Type == "transform" })...)
+			for _, transform := range blocksOfType(asset.Body, AssetSpec, "transform") {
+				patternBlocks = append(patternBlocks, patternBlock{transform, TransformSpec})
+			}
+			for _, checksum := range blocksOfType(asset.Body, AssetSpec, "checksum") {
+				patternBlocks = append(patternBlocks, patternBlock{checksum, ChecksumSpec})
+			}
+		}
+		for _, info := range blocksOfType(site.Body, SiteSpec, "info") {
+			patternBlocks = append(patternBlocks, patternBlock{info, InfoSpec})
 		}
+		for _, subdirectory := range blocksOfType(site.Body, SiteSpec, "subdirectory") {
+			patternBlocks = append(patternBlocks, patternBlock{subdirectory, SubdirectorySpec})
+		}
+
+		// the include and exclude filters of the assets
+		for _, asset := range blocksOfType(site.Body, SiteSpec, "asset") {
+			for _, name := range []string{"include", "exclude"} {
+				if filter := attributeOf(asset.Body, AssetSpec, name); filter != nil && !isNull(filter, ctx) {
+					str, re, diags := EvaluateRegexAttribute(filter, ctx)
+					if diags.HasErrors() {
+						return nil, diags
+					}
 
-		patternBlocks := append(assets, infos...)
-		patternBlocks = append(patternBlocks, subdirectories...)
-		patternBlocks = append(patternBlocks, transforms...)
+					log.Trace().Str("name", site.Labels[0]).Str("pattern", str).Msgf("adding %s regex", name)
+
+					regexCache[str] = re
+				}
+			}
+		}
 
 		for _, pb := range patternBlocks {
-			if pb.Body.Attributes["pattern"] != nil {
-				str, re, diags := EvaluateRegexPattern(pb.Body.Attributes["pattern"], ctx)
+			// the pattern of a checksum block is optional, null is the same as missing
+			if pattern := attributeOf(pb.block.Body, pb.spec, "pattern"); pattern != nil && !isNull(pattern, ctx) {
+				str, re, diags := EvaluateRegexAttribute(pattern, ctx)
 				if diags.HasErrors() {
 					return nil, diags
 				}
@@ -193,10 +766,29 @@ func BuildRegexCache(root *hclsyntax.Body, ctx *hcl.EvalContext) (RegexCacheMap,
 	return regexCache, nil
 }
 
+// ParseFile parses the configuration syntax according to the extension of filename:
+// HCL native syntax by default, HCL JSON syntax for ".json" and YAML for ".yaml" or ".yml"
+func ParseFile(b []byte, filename string) (*hcl.File, hcl.Diagnostics) {
+	p := hclparse.NewParser()
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return p.ParseJSON(b, filename)
+	case ".yaml", ".yml":
+		converted, diags := YAMLToJSON(b, filename)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		return p.ParseJSON(converted, filename)
+	default:
+		return p.ParseHCL(b, filename)
+	}
+}
+
 func Parse(b []byte, filename string) (*Config, *hcl.EvalContext, RegexCacheMap, hcl.Diagnostics) {
 	// parse
-	p := hclparse.NewParser()
-	file, diags := p.ParseHCL(b, filename)
+	file, diags := ParseFile(b, filename)
 	if diags.HasErrors() {
 		return nil, nil, nil, diags
 	}
@@ -210,7 +802,7 @@ func Parse(b []byte, filename string) (*Config, *hcl.EvalContext, RegexCacheMap,
 		return nil, nil, nil, diags
 	}
 
-	root := file.Body.(*hclsyntax.Body)
+	root := file.Body
 
 	// validate what cannot be done in the spec
 	diags = ValidateConfig(root, ctx)
@@ -343,6 +343,668 @@ site "mysite" {
 		pattern = "x"
 		from = "body"
 	}
+}`,
+			HasErrors: false,
+			WantDiags: nil,
+		},
+		{
+			Name: "invalid watch interval",
+			Input: `
+watch "feed" {
+	list = "https://example.com/feed.rss"
+	interval = "hourly"
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"interval\" attribute must be a positive duration, e.g. \"30m\" or \"1h30m\".",
+				},
+			},
+		},
+		{
+			Name: "null watch interval",
+			Input: `
+watch "feed" {
+	list = "https://example.com/feed.rss"
+	interval = null
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"interval\" attribute must be a positive duration, e.g. \"30m\" or \"1h30m\".",
+				},
+			},
+		},
+		{
+			Name: "duplicate watch block label",
+			Input: `
+watch "feed" {
+	list = "https://example.com/feed.rss"
+	interval = "1h"
+}
+
+watch "feed" {
+	list = "https://example.com/sitemap.xml"
+	interval = "1h"
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate block label",
+					Detail:   "No more than one \"watch\" block with the label \"feed\" is allowed.",
+				},
+			},
+		},
+		{
+			Name: "ok watch blocks valid",
+			Input: `
+watch "feed" {
+	list = "https://example.com/feed.rss"
+	interval = "1h30m"
+}
+
+watch "sitemap" {
+	list = "https://example.com/sitemap.xml"
+	interval = "10m"
+}`,
+			HasErrors: false,
+			WantDiags: nil,
+		},
+		{
+			Name: "invalid schedule cron",
+			Input: `
+schedule "news" {
+	cron = "0 25 * * *"
+	urls = ["https://example.com"]
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid cron expression",
+					Detail:   "The \"cron\" attribute is invalid: invalid hour field \"25\": 25 is out of range [0, 23].",
+				},
+			},
+		},
+		{
+			Name: "null schedule cron",
+			Input: `
+schedule "news" {
+	cron = null
+	urls = ["https://example.com"]
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid cron expression",
+					Detail:   "The \"cron\" attribute is invalid: expected 5 fields, got 0.",
+				},
+			},
+		},
+		{
+			Name: "unknown schedule site",
+			Input: `
+site "mysite" {
+	test = "mypattern"
+
+	asset "myasset" {
+		pattern = "x"
+	}
+}
+
+schedule "news" {
+	cron = "@hourly"
+	urls = ["https://example.com"]
+	sites = ["mysite", "other"]
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unknown site",
+					Detail:   "There is no \"site\" block with the label \"other\".",
+				},
+			},
+		},
+		{
+			Name: "duplicate schedule block label",
+			Input: `
+schedule "news" {
+	cron = "@hourly"
+	urls = ["https://example.com"]
+}
+
+schedule "news" {
+	cron = "@weekly"
+	urls = ["https://example.com/archive"]
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate block label",
+					Detail:   "No more than one \"schedule\" block with the label \"news\" is allowed.",
+				},
+			},
+		},
+		{
+			Name: "ok schedule blocks valid",
+			Input: `
+site "mysite" {
+	test = "mypattern"
+
+	asset "myasset" {
+		pattern = "x"
+	}
+}
+
+schedule "news" {
+	cron = "0 * * * *"
+	urls = ["https://example.com", "lists/news.txt"]
+	sites = ["mysite"]
+}
+
+schedule "archives" {
+	cron = "@weekly"
+	urls = ["lists/archives.txt"]
+}`,
+			HasErrors: false,
+			WantDiags: nil,
+		},
+		{
+			Name: "invalid global hook event",
+			Input: `
+global {
+	location = "x"
+
+	hook {
+		on = "page_fetched"
+		command = ["echo"]
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"on\" attribute must be \"asset_downloaded\", \"page_done\" or \"run_done\".",
+				},
+			},
+		},
+		{
+			Name: "invalid asset hook event",
+			Input: `
+site "mysite" {
+	test = "mypattern"
+
+	asset "myasset" {
+		pattern = "x"
+
+		hook {
+			on = "page_done"
+			command = ["echo"]
+		}
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"on\" attribute must be \"asset_downloaded\".",
+				},
+			},
+		},
+		{
+			Name: "empty hook command",
+			Input: `
+site "mysite" {
+	test = "mypattern"
+
+	asset "myasset" {
+		pattern = "x"
+	}
+
+	hook {
+		on = "run_done"
+		command = []
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"command\" attribute must contain at least the name of the program.",
+				},
+			},
+		},
+		{
+			Name: "invalid hook template",
+			Input: `
+site "mysite" {
+	test = "mypattern"
+
+	asset "myasset" {
+		pattern = "x"
+	}
+
+	hook {
+		on = "page_done"
+		command = ["echo", "{{.Page"]
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid template",
+					Detail:   "The \"command\" attribute is invalid: template: hook:1: unclosed action.",
+				},
+			},
+		},
+		{
+			Name: "invalid hook timeout",
+			Input: `
+site "mysite" {
+	test = "mypattern"
+
+	asset "myasset" {
+		pattern = "x"
+	}
+
+	hook {
+		on = "run_done"
+		command = ["echo"]
+		timeout = "-1s"
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"timeout\" attribute must be a positive duration, e.g. \"30s\" or \"5m\".",
+				},
+			},
+		},
+		{
+			Name: "invalid checksum algorithm",
+			Input: `
+site "example" {
+	test = "example"
+
+	asset "file" {
+		pattern = "href=\"([^\"]+)\" data-sha=\"(\\w+)\""
+		capture = 1
+
+		checksum {
+			algorithm = "crc32"
+			capture = 2
+		}
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"algorithm\" attribute must be \"md5\", \"sha1\", \"sha256\" or \"sha512\".",
+				},
+			},
+		},
+		{
+			Name: "invalid subdirectory merge",
+			Input: `
+site "example" {
+	test = "example"
+
+	info "title" {
+		pattern = "<title>([^<]+)"
+		capture = 1
+	}
+
+	subdirectory {
+		pattern = "(\\w+)"
+		capture = 1
+		from = url
+		merge = "union"
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"merge\" attribute must be \"first\", \"last\" or \"append\".",
+				},
+			},
+		},
+		{
+			Name: "invalid on_exists policy",
+			Input: `
+site "example" {
+	test = "example"
+	on_exists = "replace"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"on_exists\" attribute must be \"skip\", \"overwrite\", \"rename\", \"if_newer\" or \"if_changed\".",
+				},
+			},
+		},
+		{
+			Name: "invalid max_age",
+			Input: `
+site "example" {
+	test = "example"
+	max_age = "forever"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"max_age\" attribute must be a positive duration, e.g. \"30m\" or \"24h\".",
+				},
+			},
+		},
+		{
+			Name: "invalid accept content type",
+			Input: `
+site "example" {
+	test = "example"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+		accept = ["image"]
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"accept\" attribute must be a list of content types, e.g. [\"image/*\", \"video/mp4\"].",
+				},
+			},
+		},
+		{
+			Name: "invalid min_size",
+			Input: `
+site "example" {
+	test = "example"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+		min_size = "big"
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"min_size\" attribute must be a size, e.g. \"500KB\" or \"2MiB\".",
+				},
+			},
+		},
+		{
+			Name: "max_size smaller than min_size",
+			Input: `
+site "example" {
+	test = "example"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+		min_size = "2MB"
+		max_size = "1MB"
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"max_size\" attribute must not be smaller than the \"min_size\" attribute.",
+				},
+			},
+		},
+		{
+			Name: "null hook event",
+			Input: `
+site "example" {
+	test = "example"
+
+	hook {
+		on = null
+		command = ["echo"]
+	}
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"on\" attribute must be \"asset_downloaded\", \"page_done\" or \"run_done\".",
+				},
+			},
+		},
+		{
+			Name: "null notify url",
+			Input: `
+global {
+	location = "x"
+
+	notify {
+		url = null
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"url\" attribute must be an absolute URL, e.g. \"https://example.com/notify\".",
+				},
+			},
+		},
+		{
+			Name: "null checksum algorithm",
+			Input: `
+site "example" {
+	test = "example"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+
+		checksum {
+			algorithm = null
+			capture = 1
+		}
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"algorithm\" attribute must be \"md5\", \"sha1\", \"sha256\" or \"sha512\".",
+				},
+			},
+		},
+		{
+			Name: "null index format",
+			Input: `
+global {
+	location = "x"
+
+	index {
+		format = null
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"format\" attribute must be \"json\", \"ndjson\", \"csv\" or \"sqlite\".",
+				},
+			},
+		},
+		{
+			Name: "invalid index format",
+			Input: `
+global {
+	location = "x"
+
+	index {
+		format = "xml"
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"format\" attribute must be \"json\", \"ndjson\", \"csv\" or \"sqlite\".",
+				},
+			},
+		},
+		{
+			Name: "json index with a path",
+			Input: `
+global {
+	location = "x"
+
+	index {
+		format = "json"
+		path = "index.json"
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unsupported argument",
+					Detail:   "The \"json\" format writes a file in every subdirectory, it does not support the \"path\" attribute.",
+				},
+			},
+		},
+		{
+			Name: "invalid notify url",
+			Input: `
+global {
+	location = "x"
+
+	notify {
+		url = "/notify"
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"url\" attribute must be an absolute URL, e.g. \"https://example.com/notify\".",
+				},
+			},
+		},
+		{
+			Name: "invalid notify event",
+			Input: `
+global {
+	location = "x"
+
+	notify {
+		url = "https://example.com/notify"
+		on = "page_done"
+	}
+}`,
+			HasErrors: true,
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid block attribute",
+					Detail:   "The \"on\" attribute must be \"run_done\" or \"run_failed\".",
+				},
+			},
+		},
+		{
+			Name: "ok notify block valid",
+			Input: `
+global {
+	location = "x"
+
+	notify {
+		url = "https://example.com/notify"
+		on = "run_failed"
+
+		network {
+			retries = 3
+		}
+	}
+}`,
+			HasErrors: false,
+			WantDiags: nil,
+		},
+		{
+			Name: "ok hook blocks valid",
+			Input: `
+global {
+	location = "x"
+
+	hook {
+		on = "run_done"
+		command = ["sync-library"]
+	}
+}
+
+site "mysite" {
+	test = "mypattern"
+
+	asset "myasset" {
+		pattern = "x"
+
+		hook {
+			on = "asset_downloaded"
+			command = ["ffmpeg", "-i", "{{.Destination}}", "{{.Destination}}.mp3"]
+			timeout = "10m"
+		}
+	}
+
+	hook {
+		on = "page_done"
+		command = ["tag", "--title", "{{.Info.title}}", "{{.Page}}"]
+	}
 }`,
 			HasErrors: false,
 			WantDiags: nil,
@@ -517,6 +1179,65 @@ site "foo" {
 				},
 			},
 		},
+		{
+			Name: "checksum pattern",
+			Input: `
+site "foo" {
+	test = "^abc$"
+
+	asset "bar" {
+		pattern = "^abc$"
+
+		checksum {
+			pattern = "sha1 ([0-9a-f]+)"
+		}
+	}
+}`,
+			Want: RegexCacheMap{
+				"^abc$":            regexp.MustCompile("^abc$"),
+				"sha1 ([0-9a-f]+)": regexp.MustCompile("sha1 ([0-9a-f]+)"),
+			},
+			WantDiags: nil,
+		},
+		{
+			Name: "include and exclude",
+			Input: `
+site "foo" {
+	test = "^abc$"
+
+	asset "bar" {
+		pattern = "^abc$"
+		include = "\\.jpg$"
+		exclude = "thumb"
+	}
+}`,
+			Want: RegexCacheMap{
+				"^abc$":   regexp.MustCompile("^abc$"),
+				"\\.jpg$": regexp.MustCompile("\\.jpg$"),
+				"thumb":   regexp.MustCompile("thumb"),
+			},
+			WantDiags: nil,
+		},
+		{
+			Name: "invalid include",
+			Input: `
+site "foo" {
+	test = "^abc$"
+
+	asset "bar" {
+		pattern = "^abc$"
+		include = "[a-z"
+	}
+}`,
+			Want: RegexCacheMap(nil),
+			WantDiags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid regex pattern",
+					Detail:   "error parsing regexp: missing closing ]: `[a-z`",
+				},
+			},
+		},
 		{
 			Name: "ok all blocks ok",
 			Input: `
@@ -750,3 +1471,360 @@ site "foo" {
 		})
 	}
 }
+
+// optional attributes set to null are the same as missing ones
+func TestParseNullAttributes(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Input string
+	}{
+		{
+			Name: "on_exists",
+			Input: `
+global {
+	location = "x"
+	on_exists = null
+}
+
+site "foo" {
+	test = "x"
+	on_exists = null
+
+	asset "bar" {
+		pattern = "x"
+		capture = 0
+		on_exists = null
+	}
+}`,
+		},
+		{
+			Name: "asset filters",
+			Input: `
+global {
+	location = "x"
+}
+
+site "foo" {
+	test = "x"
+
+	asset "bar" {
+		pattern = "x"
+		capture = 0
+		include = null
+		exclude = null
+		accept = null
+		min_size = null
+		max_size = null
+	}
+}`,
+		},
+		{
+			Name: "max_size with a null min_size",
+			Input: `
+global {
+	location = "x"
+}
+
+site "foo" {
+	test = "x"
+
+	asset "bar" {
+		pattern = "x"
+		capture = 0
+		min_size = null
+		max_size = "1MB"
+	}
+}`,
+		},
+		{
+			Name: "max_age",
+			Input: `
+global {
+	location = "x"
+}
+
+site "foo" {
+	test = "x"
+	max_age = null
+
+	asset "bar" {
+		pattern = "x"
+		capture = 0
+	}
+}`,
+		},
+		{
+			Name: "subdirectory merge",
+			Input: `
+global {
+	location = "x"
+}
+
+site "foo" {
+	test = "x"
+
+	subdirectory {
+		pattern = "x"
+		capture = 0
+		from = "url"
+		merge = null
+	}
+
+	info "bar" {
+		pattern = "x"
+		capture = 0
+	}
+}`,
+		},
+		{
+			Name: "checksum pattern",
+			Input: `
+global {
+	location = "x"
+}
+
+site "foo" {
+	test = "x"
+
+	asset "bar" {
+		pattern = "x"
+		capture = 0
+
+		checksum {
+			algorithm = "sha256"
+			capture = 1
+			pattern = null
+		}
+	}
+}`,
+		},
+		{
+			Name: "hook timeout",
+			Input: `
+global {
+	location = "x"
+}
+
+site "foo" {
+	test = "x"
+
+	hook {
+		on = "page_done"
+		command = ["echo"]
+		timeout = null
+	}
+
+	asset "bar" {
+		pattern = "x"
+		capture = 0
+	}
+}`,
+		},
+		{
+			Name: "notify on",
+			Input: `
+global {
+	location = "x"
+
+	notify {
+		url = "https://example.com/notify"
+		on = null
+	}
+}
+
+site "foo" {
+	test = "x"
+
+	asset "bar" {
+		pattern = "x"
+		capture = 0
+	}
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			if _, _, _, diags := Parse([]byte(tt.Input), "test.hcl"); diags.HasErrors() {
+				tc.Errorf("got: %v, want no errors", diags)
+			}
+		})
+	}
+}
+
+func TestParseFormats(t *testing.T) {
+	want := &Config{
+		Global: GlobalConfig{
+			Location: "some location",
+		},
+		Sites: []SiteConfig{
+			{
+				Name: "foo",
+				Test: "a(x)b",
+				Assets: []AssetConfig{
+					{
+						Name:    "bar",
+						Pattern: "<img src=\"([^\"]+)",
+						Capture: "1",
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		Name     string
+		Filename string
+		Input    string
+	}{
+		{
+			Name:     "hcl",
+			Filename: "grab.hcl",
+			Input: `
+global {
+	location = "some location"
+}
+
+site "foo" {
+	test = "a(x)b"
+
+	asset "bar" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+	}
+}`,
+		},
+		{
+			Name:     "json",
+			Filename: "grab.hcl.json",
+			Input: `{
+	"global": {
+		"location": "some location"
+	},
+	"site": {
+		"foo": {
+			"//": "comments are allowed",
+			"test": "a(x)b",
+			"asset": {
+				"bar": {
+					"pattern": "<img src=\"([^\"]+)",
+					"capture": 1
+				}
+			}
+		}
+	}
+}`,
+		},
+		{
+			Name:     "yaml",
+			Filename: "grab.yaml",
+			Input: `
+global:
+  location: some location
+
+site:
+  foo:
+    test: a(x)b
+    asset:
+      bar:
+        pattern: <img src="([^"]+)
+        capture: 1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			got, _, _, diags := Parse([]byte(tt.Input), tt.Filename)
+			if diags.HasErrors() {
+				tc.Fatalf("got: %v, want no errors", diags)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				tc.Errorf("got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseDiagnosticRanges(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Filename string
+		Input    string
+		WantLine int
+	}{
+		{
+			Name:     "json invalid regex",
+			Filename: "grab.hcl.json",
+			Input: `{
+	"global": { "location": "." },
+	"site": {
+		"foo": {
+			"test": "a(b",
+			"info": { "bar": { "pattern": "x", "capture": 0 } }
+		}
+	}
+}`,
+			WantLine: 5,
+		},
+		{
+			Name:     "yaml invalid regex",
+			Filename: "grab.yml",
+			Input: `global:
+  location: .
+site:
+  foo:
+    test: x
+    info:
+      bar:
+        pattern: a(b
+        capture: 0
+`,
+			WantLine: 8,
+		},
+		{
+			Name:     "yaml missing attribute",
+			Filename: "grab.yaml",
+			Input: `global:
+  location: .
+site:
+  foo:
+    test: x
+    info:
+      bar:
+        capture: 0
+`,
+			// the body of the block starts with its first attribute
+			WantLine: 8,
+		},
+		{
+			Name:     "yaml syntax error",
+			Filename: "grab.yaml",
+			Input: `global:
+  location: .
+site:
+  foo: [
+`,
+			WantLine: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			_, _, _, diags := Parse([]byte(tt.Input), tt.Filename)
+			if !diags.HasErrors() {
+				tc.Fatalf("got no errors, want errors")
+			}
+
+			subject := diags[0].Subject
+			if subject == nil {
+				tc.Fatalf("got: %v, want a subject", diags[0])
+			}
+
+			if subject.Start.Line != tt.WantLine {
+				tc.Errorf("got: line %d, want: line %d (%v)", subject.Start.Line, tt.WantLine, diags[0])
+			}
+		})
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/everdrone/grab/internal/utils"
 )
 
+// ConfigFilenames are the names of the configuration file, in order of precedence
+var ConfigFilenames = []string{"grab.hcl", "grab.hcl.json", "grab.yaml", "grab.yml"}
+
 func getAncestors(path string) []string {
 	ancestors := []string{
 		path,
@@ -44,3 +47,27 @@ func Resolve(filename, path string) (string, error) {
 
 	return "", fmt.Errorf("could not resolve %s", filename)
 }
+
+// ResolveConfig returns the closest configuration file, written in any of the supported formats.
+// When more than one variant exists in the same directory, the order of ConfigFilenames decides.
+// NOTE: the path must be absolute!
+func ResolveConfig(path string) (string, error) {
+	ancestors := getAncestors(path)
+
+	for _, ancestor := range ancestors {
+		for _, filename := range ConfigFilenames {
+			current := filepath.Join(ancestor, filename)
+
+			exists, err := utils.Io.Exists(utils.Fs, current)
+			if err != nil {
+				return "", fmt.Errorf("could not check existence of %q: %s", current, err)
+			}
+
+			if exists {
+				return current, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("could not resolve %s", ConfigFilenames[0])
+}
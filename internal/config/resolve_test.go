@@ -99,3 +99,85 @@ func TestResolve(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveConfig(t *testing.T) {
+	root := tu.GetOSRoot()
+
+	tests := []struct {
+		Name  string
+		Files []string
+		Wd    string
+		Want  string
+		Err   string
+	}{
+		{
+			Name:  "hcl",
+			Files: []string{filepath.Join(root, "project", "grab.hcl")},
+			Wd:    filepath.Join(root, "project", "nested"),
+			Want:  filepath.Join(root, "project", "grab.hcl"),
+		},
+		{
+			Name:  "json",
+			Files: []string{filepath.Join(root, "project", "grab.hcl.json")},
+			Wd:    filepath.Join(root, "project"),
+			Want:  filepath.Join(root, "project", "grab.hcl.json"),
+		},
+		{
+			Name:  "yml in parent directory",
+			Files: []string{filepath.Join(root, "grab.yml")},
+			Wd:    filepath.Join(root, "project", "nested"),
+			Want:  filepath.Join(root, "grab.yml"),
+		},
+		{
+			Name: "hcl has precedence in the same directory",
+			Files: []string{
+				filepath.Join(root, "project", "grab.yaml"),
+				filepath.Join(root, "project", "grab.hcl"),
+			},
+			Wd:   filepath.Join(root, "project"),
+			Want: filepath.Join(root, "project", "grab.hcl"),
+		},
+		{
+			Name: "closest directory has precedence",
+			Files: []string{
+				filepath.Join(root, "grab.hcl"),
+				filepath.Join(root, "project", "grab.yaml"),
+			},
+			Wd:   filepath.Join(root, "project", "nested"),
+			Want: filepath.Join(root, "project", "grab.yaml"),
+		},
+		{
+			Name: "not found",
+			Wd:   filepath.Join(root, "project"),
+			Err:  "could not resolve grab.hcl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			utils.Fs.MkdirAll(filepath.Join(root, "project", "nested"), os.ModePerm)
+
+			for _, file := range tt.Files {
+				utils.Io.WriteFile(utils.Fs, file, []byte("something"), os.ModePerm)
+			}
+
+			got, err := ResolveConfig(tt.Wd)
+
+			if tt.Err != "" {
+				if !strings.HasPrefix(fmt.Sprint(err), tt.Err) {
+					tc.Errorf("got: %q, want: %q", err, tt.Err)
+				}
+				return
+			}
+
+			if err != nil {
+				tc.Fatalf("got error %q", err)
+			}
+
+			if got != tt.Want {
+				tc.Errorf("got: %s, want %s", got, tt.Want)
+			}
+		})
+	}
+}
@@ -3,13 +3,20 @@ package config
 import "regexp"
 
 type Config struct {
-	Global GlobalConfig `hcl:"global,block"`
-	Sites  []SiteConfig `hcl:"site,block"`
+	Global    GlobalConfig     `hcl:"global,block"`
+	Sites     []SiteConfig     `hcl:"site,block"`
+	Watches   []WatchConfig    `hcl:"watch,block"`
+	Schedules []ScheduleConfig `hcl:"schedule,block"`
 }
 
 type GlobalConfig struct {
 	Location string             `hcl:"location"`
 	Network  *RootNetworkConfig `hcl:"network,block"`
+	Hooks    []HookConfig       `hcl:"hook,block"`
+	Notify   *NotifyConfig      `hcl:"notify,block"`
+	Sidecar  *bool              `hcl:"sidecar"`
+	OnExists *string            `hcl:"on_exists"`
+	Index    *IndexConfig       `hcl:"index,block"`
 }
 
 type RootNetworkConfig struct {
@@ -25,16 +32,22 @@ type SiteConfig struct {
 	Subdirectory *SubdirectoryConfig `hcl:"subdirectory,block"`
 	Assets       []AssetConfig       `hcl:"asset,block"`
 	Infos        []InfoConfig        `hcl:"info,block"`
+	Fixtures     []FixtureConfig     `hcl:"fixture,block"`
+	Hooks        []HookConfig        `hcl:"hook,block"`
+	Sidecar      *bool               `hcl:"sidecar"`
+	OnExists     *string             `hcl:"on_exists"`
+	MaxAge       *string             `hcl:"max_age"`
 	// computed
 	URLs       []string
-	InfoMap    InfoCacheMap // location -> info -> value
+	InfoMap    InfoCacheMap // location -> pages -> info -> value
 	HasMatches bool         // does the site download anything?
 }
 
 type SubdirectoryConfig struct {
-	Pattern string `hcl:"pattern"`
-	Capture string `hcl:"capture"`
-	From    string `hcl:"from"`
+	Pattern string  `hcl:"pattern"`
+	Capture string  `hcl:"capture"`
+	From    string  `hcl:"from"`
+	Merge   *string `hcl:"merge"`
 }
 
 type AssetConfig struct {
@@ -44,8 +57,19 @@ type AssetConfig struct {
 	FindAll    *bool             `hcl:"find_all"`
 	Network    *NetworkConfig    `hcl:"network,block"`
 	Transforms []TransformConfig `hcl:"transform,block"`
+	Hooks      []HookConfig      `hcl:"hook,block"`
+	Checksum   *ChecksumConfig   `hcl:"checksum,block"`
+	Sidecar    *bool             `hcl:"sidecar"`
+	OnExists   *string           `hcl:"on_exists"`
+	Include    *string           `hcl:"include"`
+	Exclude    *string           `hcl:"exclude"`
+	Accept     *[]string         `hcl:"accept"`
+	MinSize    *string           `hcl:"min_size"`
+	MaxSize    *string           `hcl:"max_size"`
 	// computed
 	Downloads map[string]string
+	Pages     map[string]string // source -> url of the page it was found in
+	Checksums map[string]string // source -> expected checksum, "algorithm:digest"
 }
 
 type InfoConfig struct {
@@ -67,6 +91,52 @@ type TransformConfig struct {
 	Replace string `hcl:"replace"`
 }
 
+type FixtureConfig struct {
+	Name      string               `hcl:"name,label"`
+	URL       string               `hcl:"url"`
+	File      string               `hcl:"file"`
+	Assets    *map[string][]string `hcl:"assets"`
+	Filenames *map[string][]string `hcl:"filenames"`
+	Info      *map[string]string   `hcl:"info"`
+}
+
+type ChecksumConfig struct {
+	Algorithm string  `hcl:"algorithm"`
+	Pattern   *string `hcl:"pattern"`
+	Capture   string  `hcl:"capture"`
+}
+
+type HookConfig struct {
+	On      string   `hcl:"on"`
+	Command []string `hcl:"command"`
+	Timeout *string  `hcl:"timeout"`
+}
+
+type NotifyConfig struct {
+	URL     string         `hcl:"url"`
+	On      *string        `hcl:"on"`
+	Network *NetworkConfig `hcl:"network,block"`
+}
+
+type IndexConfig struct {
+	Format string  `hcl:"format"`
+	Path   *string `hcl:"path"`
+}
+
+type WatchConfig struct {
+	Name     string  `hcl:"name,label"`
+	List     string  `hcl:"list"`
+	Interval string  `hcl:"interval"`
+	Column   *string `hcl:"column"`
+}
+
+type ScheduleConfig struct {
+	Name  string    `hcl:"name,label"`
+	Cron  string    `hcl:"cron"`
+	URLs  []string  `hcl:"urls"`
+	Sites *[]string `hcl:"sites"`
+}
+
 type RegexCacheMap map[string]*regexp.Regexp
 
-type InfoCacheMap map[string]map[string]string
+type InfoCacheMap map[string][]map[string]string
@@ -16,6 +16,16 @@ var ConfigSpec = &hcldec.ObjectSpec{
 		MinItems: 1,
 		Nested:   SiteSpec,
 	},
+	"watches": &hcldec.BlockTupleSpec{
+		TypeName: "watch",
+		MinItems: 0,
+		Nested:   WatchSpec,
+	},
+	"schedules": &hcldec.BlockTupleSpec{
+		TypeName: "schedule",
+		MinItems: 0,
+		Nested:   ScheduleSpec,
+	},
 }
 
 var GlobalSpec = &hcldec.ObjectSpec{
@@ -29,6 +39,44 @@ var GlobalSpec = &hcldec.ObjectSpec{
 		Required: false,
 		Nested:   RootNetworkSpec,
 	},
+	"hooks": &hcldec.BlockTupleSpec{
+		TypeName: "hook",
+		MinItems: 0,
+		Nested:   HookSpec,
+	},
+	"notify": &hcldec.BlockSpec{
+		TypeName: "notify",
+		Required: false,
+		Nested:   NotifySpec,
+	},
+	"sidecar": &hcldec.AttrSpec{
+		Name:     "sidecar",
+		Type:     cty.Bool,
+		Required: false,
+	},
+	"on_exists": &hcldec.AttrSpec{
+		Name:     "on_exists",
+		Type:     cty.String,
+		Required: false,
+	},
+	"index": &hcldec.BlockSpec{
+		TypeName: "index",
+		Required: false,
+		Nested:   IndexSpec,
+	},
+}
+
+var IndexSpec = &hcldec.ObjectSpec{
+	"format": &hcldec.AttrSpec{
+		Name:     "format",
+		Type:     cty.String,
+		Required: true,
+	},
+	"path": &hcldec.AttrSpec{
+		Name:     "path",
+		Type:     cty.String,
+		Required: false,
+	},
 }
 
 var RootNetworkSpec = &hcldec.ObjectSpec{
@@ -80,6 +128,31 @@ var SiteSpec = &hcldec.ObjectSpec{
 		Required: false,
 		Nested:   SubdirectorySpec,
 	},
+	"fixtures": &hcldec.BlockTupleSpec{
+		TypeName: "fixture",
+		MinItems: 0,
+		Nested:   FixtureSpec,
+	},
+	"hooks": &hcldec.BlockTupleSpec{
+		TypeName: "hook",
+		MinItems: 0,
+		Nested:   HookSpec,
+	},
+	"sidecar": &hcldec.AttrSpec{
+		Name:     "sidecar",
+		Type:     cty.Bool,
+		Required: false,
+	},
+	"on_exists": &hcldec.AttrSpec{
+		Name:     "on_exists",
+		Type:     cty.String,
+		Required: false,
+	},
+	"max_age": &hcldec.AttrSpec{
+		Name:     "max_age",
+		Type:     cty.String,
+		Required: false,
+	},
 }
 
 var NetworkSpec = &hcldec.ObjectSpec{
@@ -136,9 +209,72 @@ var AssetSpec = &hcldec.ObjectSpec{
 		MaxItems: 2,
 		Nested:   TransformSpec,
 	},
+	"hooks": &hcldec.BlockTupleSpec{
+		TypeName: "hook",
+		MinItems: 0,
+		Nested:   HookSpec,
+	},
+	"checksum": &hcldec.BlockSpec{
+		TypeName: "checksum",
+		Required: false,
+		Nested:   ChecksumSpec,
+	},
+	"sidecar": &hcldec.AttrSpec{
+		Name:     "sidecar",
+		Type:     cty.Bool,
+		Required: false,
+	},
+	"on_exists": &hcldec.AttrSpec{
+		Name:     "on_exists",
+		Type:     cty.String,
+		Required: false,
+	},
+	"include": &hcldec.AttrSpec{
+		Name:     "include",
+		Type:     cty.String,
+		Required: false,
+	},
+	"exclude": &hcldec.AttrSpec{
+		Name:     "exclude",
+		Type:     cty.String,
+		Required: false,
+	},
+	"accept": &hcldec.AttrSpec{
+		Name:     "accept",
+		Type:     cty.List(cty.String),
+		Required: false,
+	},
+	"min_size": &hcldec.AttrSpec{
+		Name:     "min_size",
+		Type:     cty.String,
+		Required: false,
+	},
+	"max_size": &hcldec.AttrSpec{
+		Name:     "max_size",
+		Type:     cty.String,
+		Required: false,
+	},
 	// TODO: allow setting a subdirectory for the asset
 }
 
+var ChecksumSpec = &hcldec.ObjectSpec{
+	"algorithm": &hcldec.AttrSpec{
+		Name:     "algorithm",
+		Type:     cty.String,
+		Required: true,
+	},
+	"pattern": &hcldec.AttrSpec{
+		Name:     "pattern",
+		Type:     cty.String,
+		Required: false,
+	},
+	"capture": &hcldec.AttrSpec{
+		Name:     "capture",
+		Type:     cty.String,
+		Required: true,
+	},
+}
+
 var InfoSpec = &hcldec.ObjectSpec{
 	"name": &hcldec.BlockLabelSpec{
 		Index: 0,
@@ -191,4 +327,135 @@ var SubdirectorySpec = &hcldec.ObjectSpec{
 		Type:     cty.String,
 		Required: true,
 	},
+	"merge": &hcldec.AttrSpec{
+		Name:     "merge",
+		Type:     cty.String,
+		Required: false,
+	},
+}
+
+var FixtureSpec = &hcldec.ObjectSpec{
+	"name": &hcldec.BlockLabelSpec{
+		Index: 0,
+		Name:  "name",
+	},
+	"url": &hcldec.AttrSpec{
+		Name:     "url",
+		Type:     cty.String,
+		Required: true,
+	},
+	// the path of the saved page, relative to the config file
+	"file": &hcldec.AttrSpec{
+		Name:     "file",
+		Type:     cty.String,
+		Required: true,
+	},
+	// asset name -> expected urls
+	"assets": &hcldec.AttrSpec{
+		Name:     "assets",
+		Type:     cty.Map(cty.List(cty.String)),
+		Required: false,
+	},
+	// asset name -> expected destinations, relative to global.location
+	"filenames": &hcldec.AttrSpec{
+		Name:     "filenames",
+		Type:     cty.Map(cty.List(cty.String)),
+		Required: false,
+	},
+	// info name -> expected value
+	"info": &hcldec.AttrSpec{
+		Name:     "info",
+		Type:     cty.Map(cty.String),
+		Required: false,
+	},
+}
+
+// must validate the event and that only "asset_downloaded" hooks are declared in assets
+var HookSpec = &hcldec.ObjectSpec{
+	// "asset_downloaded", "page_done" or "run_done"
+	"on": &hcldec.AttrSpec{
+		Name:     "on",
+		Type:     cty.String,
+		Required: true,
+	},
+	// the program and its arguments, each one a Go template
+	"command": &hcldec.AttrSpec{
+		Name:     "command",
+		Type:     cty.List(cty.String),
+		Required: true,
+	},
+	// a duration, e.g. "30s"
+	"timeout": &hcldec.AttrSpec{
+		Name:     "timeout",
+		Type:     cty.String,
+		Required: false,
+	},
+}
+
+// must validate the url and the event
+var NotifySpec = &hcldec.ObjectSpec{
+	"url": &hcldec.AttrSpec{
+		Name:     "url",
+		Type:     cty.String,
+		Required: true,
+	},
+	// "run_done" or "run_failed"
+	"on": &hcldec.AttrSpec{
+		Name:     "on",
+		Type:     cty.String,
+		Required: false,
+	},
+	"network": &hcldec.BlockSpec{
+		TypeName: "network",
+		Required: false,
+		Nested:   NetworkSpec,
+	},
+}
+
+var WatchSpec = &hcldec.ObjectSpec{
+	"name": &hcldec.BlockLabelSpec{
+		Index: 0,
+		Name:  "name",
+	},
+	// a path or a url, see utils.URLReader
+	"list": &hcldec.AttrSpec{
+		Name:     "list",
+		Type:     cty.String,
+		Required: true,
+	},
+	// a duration, e.g. "1h30m"
+	"interval": &hcldec.AttrSpec{
+		Name:     "interval",
+		Type:     cty.String,
+		Required: true,
+	},
+	"column": &hcldec.AttrSpec{
+		Name:     "column",
+		Type:     cty.String,
+		Required: false,
+	},
+}
+
+var ScheduleSpec = &hcldec.ObjectSpec{
+	"name": &hcldec.BlockLabelSpec{
+		Index: 0,
+		Name:  "name",
+	},
+	"cron": &hcldec.AttrSpec{
+		Name:     "cron",
+		Type:     cty.String,
+		Required: true,
+	},
+	// urls or paths of lists, like the arguments of the get command
+	"urls": &hcldec.AttrSpec{
+		Name:     "urls",
+		Type:     cty.List(cty.String),
+		Required: true,
+	},
+	// the names of the sites allowed to handle the urls
+	"sites": &hcldec.AttrSpec{
+		Name:     "sites",
+		Type:     cty.List(cty.String),
+		Required: false,
+	},
 }
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/hcl/v2"
+	"gopkg.in/yaml.v3"
+)
+
+var yamlErrorLineRegex = regexp.MustCompile(`line (\d+)`)
+
+// YAMLToJSON converts a YAML document to the HCL JSON syntax.
+// Every value is written at the same line (and, when possible, the same column) it had in the
+// YAML source, so that the diagnostics produced by the JSON parser point to the original file.
+func YAMLToJSON(b []byte, filename string) ([]byte, hcl.Diagnostics) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		line := 1
+		if m := yamlErrorLineRegex.FindStringSubmatch(err.Error()); m != nil {
+			line, _ = strconv.Atoi(m[1])
+		}
+
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid YAML",
+			Detail:   err.Error(),
+			Subject: &hcl.Range{
+				Filename: filename,
+				Start:    hcl.Pos{Line: line, Column: 1},
+				End:      hcl.Pos{Line: line, Column: 1},
+			},
+		}}
+	}
+
+	w := &positionWriter{line: 1, column: 1}
+
+	// an empty document is an empty configuration
+	if len(doc.Content) == 0 {
+		w.write("{}")
+		return w.buf.Bytes(), nil
+	}
+
+	if diags := w.node(doc.Content[0], filename); diags.HasErrors() {
+		return nil, diags
+	}
+
+	return w.buf.Bytes(), nil
+}
+
+type positionWriter struct {
+	buf    bytes.Buffer
+	line   int
+	column int
+}
+
+// pads the output with new lines and spaces until the given position is reached
+func (w *positionWriter) moveTo(line, column int) {
+	for w.line < line {
+		w.buf.WriteByte('\n')
+		w.line++
+		w.column = 1
+	}
+
+	for w.line == line && w.column < column {
+		w.buf.WriteByte(' ')
+		w.column++
+	}
+}
+
+// writes a token that does not contain new lines
+func (w *positionWriter) write(token string) {
+	w.buf.WriteString(token)
+	w.column += len(token)
+}
+
+func (w *positionWriter) node(node *yaml.Node, filename string) hcl.Diagnostics {
+	if node.Kind == yaml.AliasNode {
+		return w.node(node.Alias, filename)
+	}
+
+	// collections are opened right after the parent key, so that their first key
+	// (whose position is also the position of the collection) can be aligned
+	if node.Kind == yaml.ScalarNode {
+		w.moveTo(node.Line, node.Column)
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		w.write("{")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			if key.Kind != yaml.ScalarNode {
+				return hcl.Diagnostics{{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid YAML key",
+					Detail:   "Only scalar values can be used as mapping keys.",
+					Subject:  yamlRange(key, filename),
+				}}
+			}
+
+			if i > 0 {
+				w.write(",")
+			}
+
+			// the opening quote takes the column before the key, so that the closing quote and the colon
+			// take the place of the YAML colon and space, and the value keeps its column
+			w.moveTo(key.Line, key.Column-1)
+			w.write(quote(key.Value))
+			w.write(":")
+
+			if diags := w.node(value, filename); diags.HasErrors() {
+				return diags
+			}
+		}
+		w.write("}")
+
+	case yaml.SequenceNode:
+		w.write("[")
+		for i, item := range node.Content {
+			if i > 0 {
+				w.write(",")
+			}
+
+			if diags := w.node(item, filename); diags.HasErrors() {
+				return diags
+			}
+		}
+		w.write("]")
+
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!null":
+			w.write("null")
+		case "!!bool":
+			var v bool
+			if err := node.Decode(&v); err != nil {
+				return invalidYAMLValue(node, filename, err)
+			}
+			w.write(strconv.FormatBool(v))
+		case "!!int", "!!float":
+			var v float64
+			if err := node.Decode(&v); err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+				// not representable as a JSON number, keep it as it was written
+				w.write(quote(node.Value))
+				break
+			}
+			w.write(strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			w.write(quote(node.Value))
+		}
+
+	default:
+		return invalidYAMLValue(node, filename, fmt.Errorf("unsupported node kind %d", node.Kind))
+	}
+
+	return nil
+}
+
+func invalidYAMLValue(node *yaml.Node, filename string, err error) hcl.Diagnostics {
+	return hcl.Diagnostics{{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid YAML value",
+		Detail:   err.Error(),
+		Subject:  yamlRange(node, filename),
+	}}
+}
+
+func yamlRange(node *yaml.Node, filename string) *hcl.Range {
+	return &hcl.Range{
+		Filename: filename,
+		Start:    hcl.Pos{Line: node.Line, Column: node.Column},
+		End:      hcl.Pos{Line: node.Line, Column: node.Column + len(node.Value)},
+	}
+}
+
+// returns the JSON representation of str, without escaping angle brackets and ampersands
+func quote(str string) string {
+	buf := &bytes.Buffer{}
+
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(str)
+
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYAMLToJSON(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Input     string
+		Want      interface{}
+		WantError bool
+	}{
+		{
+			Name:  "empty",
+			Input: "",
+			Want:  map[string]interface{}{},
+		},
+		{
+			Name: "scalars",
+			Input: `
+string: <a href="x">
+number: 1
+float: 1.5
+bool: true
+null: ~
+hex: 0x1F
+`,
+			Want: map[string]interface{}{
+				"string": "<a href=\"x\">",
+				"number": 1.0,
+				"float":  1.5,
+				"bool":   true,
+				"null":   nil,
+				"hex":    31.0,
+			},
+		},
+		{
+			Name: "nested and aliases",
+			Input: `
+headers: &h
+  User-Agent: grab
+list: [a, b]
+other: *h
+`,
+			Want: map[string]interface{}{
+				"headers": map[string]interface{}{"User-Agent": "grab"},
+				"list":    []interface{}{"a", "b"},
+				"other":   map[string]interface{}{"User-Agent": "grab"},
+			},
+		},
+		{
+			Name:      "complex keys",
+			Input:     "? [a, b]\n: c\n",
+			WantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			got, diags := YAMLToJSON([]byte(tt.Input), "test.yaml")
+			if diags.HasErrors() != tt.WantError {
+				tc.Fatalf("got: %v, want errors: %v", diags, tt.WantError)
+			}
+
+			if tt.WantError {
+				return
+			}
+
+			var decoded interface{}
+			if err := json.Unmarshal(got, &decoded); err != nil {
+				tc.Fatalf("got invalid json %q: %v", got, err)
+			}
+
+			if !reflect.DeepEqual(decoded, tt.Want) {
+				tc.Errorf("got: %v, want: %v", decoded, tt.Want)
+			}
+		})
+	}
+}
+
+func TestYAMLToJSONPreservesLines(t *testing.T) {
+	input := "a:\n  b: 1\n\n  c:\n    - x\n    - y\n"
+
+	got, diags := YAMLToJSON([]byte(input), "test.yaml")
+	if diags.HasErrors() {
+		t.Fatalf("got: %v, want no errors", diags)
+	}
+
+	lines := strings.Split(string(got), "\n")
+
+	for value, line := range map[string]int{`"b"`: 2, `"c"`: 4, `"x"`: 5, `"y"`: 6} {
+		if !strings.Contains(lines[line-1], value) {
+			t.Errorf("got: %q, want %s at line %d", got, value, line)
+		}
+	}
+}
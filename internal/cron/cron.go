@@ -0,0 +1,201 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// whether the day of the month or the day of the week is "*", see matchDay
+	domStar, dowStar bool
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is sunday too
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a standard cron expression with five fields (minute, hour, day of month, month and day of week),
+// or one of the macros @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly.
+// Fields can be "*", values, names (jan-dec, sun-sat), ranges, lists and steps, e.g. "1-5", "mon,wed" or "*/15".
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+
+	if strings.HasPrefix(expr, "@") {
+		expanded, ok := macros[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("unknown macro %q", expr)
+		}
+		expr = expanded
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	// like in vixie cron, "*/2" counts as a star
+	s := &Schedule{
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}
+
+	var err error
+	for i, target := range []struct {
+		bits *uint64
+		f    field
+	}{
+		{&s.minute, minuteField},
+		{&s.hour, hourField},
+		{&s.dom, domField},
+		{&s.month, monthField},
+		{&s.dow, dowField},
+	} {
+		if *target.bits, err = parseField(fields[i], target.f); err != nil {
+			return nil, err
+		}
+	}
+
+	// sunday can be written as 0 or 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	return s, nil
+}
+
+func parseField(str string, f field) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(str, ",") {
+		rng, step := part, 1
+
+		if i := strings.Index(part, "/"); i != -1 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", f.name, str)
+			}
+			rng, step = part[:i], n
+		}
+
+		start, end := f.min, f.max
+
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+
+			var err error
+			if start, err = value(bounds[0], f); err != nil {
+				return 0, fmt.Errorf("invalid %s field %q: %w", f.name, str, err)
+			}
+			if end, err = value(bounds[1], f); err != nil {
+				return 0, fmt.Errorf("invalid %s field %q: %w", f.name, str, err)
+			}
+		default:
+			var err error
+			if start, err = value(rng, f); err != nil {
+				return 0, fmt.Errorf("invalid %s field %q: %w", f.name, str, err)
+			}
+
+			// "5/10" means from 5 to the maximum, every 10
+			if step == 1 {
+				end = start
+			}
+		}
+
+		if start > end {
+			return 0, fmt.Errorf("invalid %s field %q: the range is reversed", f.name, str)
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << i
+		}
+	}
+
+	return bits, nil
+}
+
+func value(str string, f field) (int, error) {
+	if n, ok := f.names[strings.ToLower(str)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", str)
+	}
+
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("%d is out of range [%d, %d]", n, f.min, f.max)
+	}
+
+	return n, nil
+}
+
+// Next returns the first time after t matching the schedule, in the location of t.
+// It returns the zero time if there is none in the next five years, e.g. for "0 0 30 2 *".
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// like in cron, if both the day of the month and the day of the week are restricted,
+// a day matches if either of them does
+func (s *Schedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
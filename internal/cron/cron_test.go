@@ -0,0 +1,75 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Expr    string
+		WantErr string
+	}{
+		{Name: "every minute", Expr: "* * * * *"},
+		{Name: "lists ranges and steps", Expr: "*/15 9-17 1,15 jan-jun mon-fri"},
+		{Name: "macro", Expr: "@weekly"},
+		{Name: "sunday as 7", Expr: "0 0 * * 7"},
+		{Name: "too few fields", Expr: "* * * *", WantErr: "expected 5 fields, got 4"},
+		{Name: "unknown macro", Expr: "@sometimes", WantErr: `unknown macro "@sometimes"`},
+		{Name: "out of range", Expr: "60 * * * *", WantErr: `invalid minute field "60": 60 is out of range [0, 59]`},
+		{Name: "not a number", Expr: "* x * * *", WantErr: `invalid hour field "x": "x" is not a number`},
+		{Name: "invalid step", Expr: "*/0 * * * *", WantErr: `invalid step in minute field "*/0"`},
+		{Name: "reversed range", Expr: "* * * dec-jan *", WantErr: `invalid month field "dec-jan": the range is reversed`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			_, err := Parse(tt.Expr)
+
+			if tt.WantErr == "" && err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			if tt.WantErr != "" && (err == nil || err.Error() != tt.WantErr) {
+				tc.Errorf("got: %v, want: %s", err, tt.WantErr)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// a wednesday
+	from := time.Date(2022, time.August, 17, 10, 42, 30, 0, time.UTC)
+
+	tests := []struct {
+		Name string
+		Expr string
+		Want time.Time
+	}{
+		{Name: "every minute", Expr: "* * * * *", Want: time.Date(2022, time.August, 17, 10, 43, 0, 0, time.UTC)},
+		{Name: "every 15 minutes", Expr: "*/15 * * * *", Want: time.Date(2022, time.August, 17, 10, 45, 0, 0, time.UTC)},
+		{Name: "hourly", Expr: "@hourly", Want: time.Date(2022, time.August, 17, 11, 0, 0, 0, time.UTC)},
+		{Name: "daily at 9", Expr: "0 9 * * *", Want: time.Date(2022, time.August, 18, 9, 0, 0, 0, time.UTC)},
+		{Name: "weekly on sunday", Expr: "30 2 * * sun", Want: time.Date(2022, time.August, 21, 2, 30, 0, 0, time.UTC)},
+		{Name: "sunday as 7", Expr: "30 2 * * 7", Want: time.Date(2022, time.August, 21, 2, 30, 0, 0, time.UTC)},
+		{Name: "monthly", Expr: "@monthly", Want: time.Date(2022, time.September, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "next year", Expr: "0 0 1 feb *", Want: time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "day of month or day of week", Expr: "0 0 20 * fri", Want: time.Date(2022, time.August, 19, 0, 0, 0, 0, time.UTC)},
+		{Name: "leap day", Expr: "0 0 29 2 *", Want: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{Name: "never", Expr: "0 0 30 2 *", Want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			s, err := Parse(tt.Expr)
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			if got := s.Next(from); !got.Equal(tt.Want) {
+				tc.Errorf("got: %v, want: %v", got, tt.Want)
+			}
+		})
+	}
+}
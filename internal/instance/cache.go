@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -16,17 +17,78 @@ import (
 
 func (s *Grab) BuildSiteCache() {
 	for _, url := range s.URLs {
-		for i, site := range s.Config.Sites {
-			if s.RegexCache[site.Test].MatchString(url) {
-				if s.Config.Sites[i].URLs == nil {
-					s.Config.Sites[i].URLs = make([]string, 0)
-				}
+		// only the first matching site handles the url
+		if matches := s.MatchSites(url); len(matches) > 0 {
+			i := matches[0]
 
-				s.Config.Sites[i].URLs = append(s.Config.Sites[i].URLs, url)
-				break
+			if s.Config.Sites[i].URLs == nil {
+				s.Config.Sites[i].URLs = make([]string, 0)
 			}
+
+			s.Config.Sites[i].URLs = append(s.Config.Sites[i].URLs, url)
+		}
+	}
+}
+
+// MatchSites returns the indices of all the sites whose test pattern matches the url,
+// in the order they are defined in the configuration.
+func (s *Grab) MatchSites(url string) []int {
+	matches := make([]int, 0)
+
+	for i, site := range s.Config.Sites {
+		if s.RegexCache[site.Test].MatchString(url) {
+			matches = append(matches, i)
 		}
 	}
+
+	return matches
+}
+
+// Subdirectory returns the directory where the assets of a page will be downloaded
+func (s *Grab) Subdirectory(siteIndex int, pageUrl, body string) (string, error) {
+	site := s.Config.Sites[siteIndex]
+
+	if site.Subdirectory == nil {
+		// we have no subdirectory block, just use the site name
+		subdirectory := filepath.Join(s.Config.Global.Location, site.Name)
+
+		log.Trace().Str("site", site.Name).Str("subdirectory", subdirectory).Msg("no subdirectory block")
+
+		return subdirectory, nil
+	}
+
+	// we have a subdirectory block
+
+	log.Trace().Str("site", site.Name).Msg("visiting subdirectory block")
+
+	var source string
+	if site.Subdirectory.From == "url" {
+		source = pageUrl
+	} else {
+		source = body
+	}
+
+	subDirs, err := utils.GetCaptures(s.RegexCache[site.Subdirectory.Pattern], false, site.Subdirectory.Capture, source)
+	if err != nil {
+		return "", err
+	}
+
+	// never an empty path, the assets would be downloaded to the working directory
+	if len(subDirs) == 0 {
+		return "", fmt.Errorf("no subdirectory captured for pattern `%s`, capture `%s`", site.Subdirectory.Pattern, site.Subdirectory.Capture)
+	}
+
+	var subdirectory string
+	// do not append if the path is absolute
+	if filepath.IsAbs(subDirs[0]) {
+		subdirectory = subDirs[0]
+	} else {
+		subdirectory = filepath.Join(s.Config.Global.Location, site.Name, subDirs[0])
+	}
+
+	log.Trace().Str("site", site.Name).Str("subdirectory", subdirectory).Msg("subdirectory path")
+
+	return subdirectory, nil
 }
 
 func removePathFromURL(str string) (*url.URL, error) {
@@ -51,10 +113,15 @@ func (s *Grab) BuildAssetCache() *hcl.Diagnostics {
 		log.Trace().Str("site", site.Name).Msg("visiting site block")
 
 		for _, pageUrl := range site.URLs {
-			log.Trace().Str("url", pageUrl).Msg("processing url")
+			if err := s.canceled(); err != nil {
+				return &hcl.Diagnostics{{
+					Severity: hcl.DiagError,
+					Summary:  "Canceled",
+					Detail:   err.Error(),
+				}}
+			}
 
-			// we already checked this url before, so we can skip the error
-			base, _ := removePathFromURL(pageUrl)
+			log.Trace().Str("url", pageUrl).Msg("processing url")
 
 			options := net.MergeFetchOptionsChain(s.Config.Global.Network, site.Network)
 
@@ -62,8 +129,11 @@ func (s *Grab) BuildAssetCache() *hcl.Diagnostics {
 
 			// MARK: - get the page body
 
-			body, err := net.Fetch(pageUrl, options)
+			start := time.Now()
+			body, err := s.fetchPage(site, pageUrl, options)
 			if err != nil {
+				s.emit(&Event{Type: EventPageFailed, Site: site.Name, Page: pageUrl, Duration: time.Since(start), Error: err.Error()})
+
 				diags = &hcl.Diagnostics{{
 					Severity: hcl.DiagError,
 					Summary:  "Failed to fetch page",
@@ -74,251 +144,352 @@ func (s *Grab) BuildAssetCache() *hcl.Diagnostics {
 				if s.Flags.Strict {
 					return diags
 				} else {
-					// FIXME: warn the user that we are skipping this page
+					log.Warn().Err(err).Str("url", pageUrl).Msg("failed to fetch page, skipping")
 					continue
 				}
 			}
 
-			// MARK: - get the destination path (subdirectory)
+			s.emit(&Event{Type: EventPageFetched, Site: site.Name, Page: pageUrl, Bytes: int64(len(body)), Duration: time.Since(start)})
 
-			var subdirectory string
-			if site.Subdirectory != nil {
-				// we have a subdirectory block
+			if diags := s.ScrapePage(siteIndex, pageUrl, body); diags.HasErrors() {
+				return diags
+			}
+		}
+	}
 
-				log.Trace().Str("site", site.Name).Msg("visiting subdirectory block")
+	return &hcl.Diagnostics{}
+}
 
-				var source string
-				if site.Subdirectory.From == "url" {
-					source = pageUrl
-				} else {
-					source = body
-				}
+// ScrapePage extracts the assets and the info of a single page of the site at siteIndex,
+// storing the results in the computed fields of the site configuration.
+func (s *Grab) ScrapePage(siteIndex int, pageUrl, body string) *hcl.Diagnostics {
+	site := s.Config.Sites[siteIndex]
 
-				subDirs, err := utils.GetCaptures(s.RegexCache[site.Subdirectory.Pattern], false, site.Subdirectory.Capture, source)
-				if err != nil {
-					return &hcl.Diagnostics{{
-						Severity: hcl.DiagError,
-						Summary:  "Failed to get subdirectory",
-						Detail:   err.Error(),
-					}}
-				}
+	// we already checked this url before, so we can skip the error
+	base, _ := removePathFromURL(pageUrl)
 
-				if len(subDirs) > 0 {
-					// do not append if the path is absolute
-					if filepath.IsAbs(subDirs[0]) {
-						subdirectory = subDirs[0]
-					} else {
-						subdirectory = filepath.Join(s.Config.Global.Location, site.Name, subDirs[0])
-					}
+	// MARK: - get the destination path (subdirectory)
 
-					log.Trace().Str("site", site.Name).Str("subdirectory", subdirectory).Msg("subdirectory path")
-				}
-			} else {
-				// we have no subdirectory block, just use the site name
-				subdirectory = filepath.Join(s.Config.Global.Location, site.Name)
+	subdirectory, err := s.Subdirectory(siteIndex, pageUrl, body)
+	if err != nil {
+		return &hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to get subdirectory",
+			Detail:   err.Error(),
+		}}
+	}
 
-				log.Trace().Str("site", site.Name).Str("subdirectory", subdirectory).Msg("no subdirectory block")
-			}
+	// MARK: - loop through the asset blocks
 
-			// MARK: - loop through the asset blocks
+	for assetIndex, asset := range site.Assets {
+		log.Debug().Str("site", site.Name).Str("asset", asset.Name).Msg("visiting asset block")
 
-			for assetIndex, asset := range site.Assets {
-				log.Debug().Str("site", site.Name).Str("asset", asset.Name).Msg("visiting asset block")
+		// match against body
+		if s.RegexCache[asset.Pattern].MatchString(body) {
+			findAll := false
+			if asset.FindAll != nil {
+				findAll = *asset.FindAll
+			}
 
-				// match against body
-				if s.RegexCache[asset.Pattern].MatchString(body) {
-					findAll := false
-					if asset.FindAll != nil {
-						findAll = *asset.FindAll
-					}
+			// get capture groups
+			captures, err := utils.GetCaptures(s.RegexCache[asset.Pattern], findAll, asset.Capture, body)
+			if err != nil {
+				return &hcl.Diagnostics{{
+					Severity: hcl.DiagError,
+					Summary:  "Failed to get captures",
+					Detail:   fmt.Sprintf("%s: %s", pageUrl, err.Error()),
+				}}
+			}
 
-					// get capture groups
-					captures, err := utils.GetCaptures(s.RegexCache[asset.Pattern], findAll, asset.Capture, body)
-					if err != nil {
-						return &hcl.Diagnostics{{
-							Severity: hcl.DiagError,
-							Summary:  "Failed to get captures",
-							Detail:   fmt.Sprintf("%s: %s", pageUrl, err.Error()),
-						}}
-					}
+			// MARK: - expected checksums
 
-					// remove duplicates
-					captures = utils.Unique(captures)
+			checksums, diags := s.checksums(site.Name, asset, findAll, captures, pageUrl, body)
+			if diags.HasErrors() {
+				return diags
+			}
+
+			// remove duplicates
+			captures = utils.Unique(captures)
 
-					log.Trace().Str("site", site.Name).Str("asset", asset.Name).Strs("matches", captures).Msgf("%d %s found", len(captures), utils.Plural(len(captures), "match", "matches"))
+			log.Trace().Str("site", site.Name).Str("asset", asset.Name).Strs("matches", captures).Msgf("%d %s found", len(captures), utils.Plural(len(captures), "match", "matches"))
 
-					// MARK: - transform url
+			// MARK: - transform url
 
-					// TODO: we should change the config schema to store transforms as a map
-					// where the key is the transform label, so we don't end up looping through an array
-					transformUrl := utils.Filter(asset.Transforms, func(t config.TransformConfig) bool {
-						return t.Name == "url"
-					})
+			// TODO: we should change the config schema to store transforms as a map
+			// where the key is the transform label, so we don't end up looping through an array
+			transformUrl := utils.Filter(asset.Transforms, func(t config.TransformConfig) bool {
+				return t.Name == "url"
+			})
 
-					if len(transformUrl) > 0 {
-						log.Trace().Str("site", site.Name).Str("asset", asset.Name).Msg("visiting transforming url block")
+			if len(transformUrl) > 0 {
+				log.Trace().Str("site", site.Name).Str("asset", asset.Name).Msg("visiting transforming url block")
 
-						// we have a transform url block
-						t := transformUrl[0]
-						for i, src := range captures {
-							captures[i] = s.RegexCache[t.Pattern].ReplaceAllString(src, t.Replace)
-						}
+				// we have a transform url block
+				t := transformUrl[0]
+				for i, src := range captures {
+					captures[i] = s.RegexCache[t.Pattern].ReplaceAllString(src, t.Replace)
 
-						log.Trace().Str("site", site.Name).Str("asset", asset.Name).Strs("matches", captures).Msgf("%d matched %s replaced", len(captures), utils.Plural(len(captures), "url", "urls"))
+					if checksum, ok := checksums[src]; ok {
+						checksums[captures[i]] = checksum
 					}
+				}
+
+				log.Trace().Str("site", site.Name).Str("asset", asset.Name).Strs("matches", captures).Msgf("%d matched %s replaced", len(captures), utils.Plural(len(captures), "url", "urls"))
+			}
 
-					// MARK: - transform filename
-
-					transformFilename := utils.Filter(asset.Transforms, func(t config.TransformConfig) bool {
-						return t.Name == "filename"
-					})
-
-					destinations := make(map[string]string, 0)
-
-					if len(transformFilename) > 0 {
-						log.Trace().Str("site", site.Name).Str("asset", asset.Name).Msg("visiting transforming filename block")
-
-						// we have a transform filename block
-						t := transformFilename[0]
-						for _, src := range captures {
-							fileName := s.RegexCache[t.Pattern].ReplaceAllString(src, t.Replace)
-
-							// NOTE: the result of "transform filename" could be an absolute path!
-							//       so we should not append if absolute
-							if filepath.IsAbs(fileName) {
-								// FIXME: we should disallow absolute paths
-								// it's dangerous and they should be avoided
-								destinations[src] = fileName
-							} else {
-								destinations[src] = filepath.Join(subdirectory, fileName)
-							}
-
-							// unescape the filename to write on disk
-							unescaped, err := url.QueryUnescape(destinations[src])
-							if err != nil {
-								return &hcl.Diagnostics{{
-									Severity: hcl.DiagError,
-									Summary:  "Failed to unescape filename",
-									Detail:   fmt.Sprintf("%s: %s", fileName, err.Error()),
-								}}
-							}
-
-							destinations[src] = unescaped
-
-							log.Trace().Str("site", site.Name).Str("asset", asset.Name).Str("source", src).Str("destination", destinations[src]).Msg("transformed filename")
-						}
+			// MARK: - transform filename
+
+			transformFilename := utils.Filter(asset.Transforms, func(t config.TransformConfig) bool {
+				return t.Name == "filename"
+			})
+
+			destinations := make(map[string]string, 0)
+
+			if len(transformFilename) > 0 {
+				log.Trace().Str("site", site.Name).Str("asset", asset.Name).Msg("visiting transforming filename block")
+
+				// we have a transform filename block
+				t := transformFilename[0]
+				for _, src := range captures {
+					fileName := s.RegexCache[t.Pattern].ReplaceAllString(src, t.Replace)
+
+					// NOTE: the result of "transform filename" could be an absolute path!
+					//       so we should not append if absolute
+					if filepath.IsAbs(fileName) {
+						// FIXME: we should disallow absolute paths
+						// it's dangerous and they should be avoided
+						destinations[src] = fileName
 					} else {
-						// we don't have any transform filename blocks
-						for _, src := range captures {
-							// simply get the filename from the url path
-							fileName := filepath.Base(src)
-							destinations[src] = filepath.Join(subdirectory, fileName)
-
-							// unescape the filename to write on disk
-							unescaped, err := url.QueryUnescape(destinations[src])
-							if err != nil {
-								return &hcl.Diagnostics{{
-									Severity: hcl.DiagError,
-									Summary:  "Failed to unescape filename",
-									Detail:   fmt.Sprintf("%s: %s", fileName, err.Error()),
-								}}
-							}
-
-							destinations[src] = unescaped
-
-							log.Trace().Str("site", site.Name).Str("asset", asset.Name).Str("source", src).Str("destination", destinations[src]).Msg("transformed filename")
-						}
+						destinations[src] = filepath.Join(subdirectory, fileName)
 					}
 
-					// MARK: - loop through the map to check for relative urls
-
-					resolvedDestinations := make(map[string]string, 0)
-					for src, dst := range destinations {
-						parsed, err := url.Parse(src)
-						if err != nil {
-							return &hcl.Diagnostics{{
-								Severity: hcl.DiagError,
-								Summary:  "Failed to parse url",
-								Detail:   fmt.Sprintf("%s: %s", src, err.Error()),
-							}}
-						}
-
-						// if path is still relative, append it to the scheme://domain.name of the page
-						if !parsed.IsAbs() {
-							resolved, err := base.Parse(src)
-							if err != nil {
-								return &hcl.Diagnostics{{
-									Severity: hcl.DiagError,
-									Summary:  "Failed to resolve relative url",
-									Detail:   fmt.Sprintf("%s: %s", src, err.Error()),
-								}}
-							}
-
-							resolvedDestinations[resolved.String()] = dst
-
-							log.Trace().Str("site", site.Name).Str("asset", asset.Name).Str("source", src).Str("destination", resolved.String()).Msg("resolved relative url")
-						} else {
-							// nothing to do, the url is already absolute
-							resolvedDestinations[src] = dst
-						}
+					// unescape the filename to write on disk
+					unescaped, err := url.QueryUnescape(destinations[src])
+					if err != nil {
+						return &hcl.Diagnostics{{
+							Severity: hcl.DiagError,
+							Summary:  "Failed to unescape filename",
+							Detail:   fmt.Sprintf("%s: %s", fileName, err.Error()),
+						}}
 					}
 
-					// initialize the map if nil
-					if s.Config.Sites[siteIndex].Assets[assetIndex].Downloads == nil {
-						s.Config.Sites[siteIndex].Assets[assetIndex].Downloads = make(map[string]string, 0)
-					}
+					destinations[src] = unescaped
 
-					// add the destinations to the asset
-					for src, dst := range resolvedDestinations {
-						s.Config.Sites[siteIndex].Assets[assetIndex].Downloads[src] = dst
+					log.Trace().Str("site", site.Name).Str("asset", asset.Name).Str("source", src).Str("destination", destinations[src]).Msg("transformed filename")
+				}
+			} else {
+				// we don't have any transform filename blocks
+				for _, src := range captures {
+					// simply get the filename from the url path
+					fileName := filepath.Base(src)
+					destinations[src] = filepath.Join(subdirectory, fileName)
+
+					// unescape the filename to write on disk
+					unescaped, err := url.QueryUnescape(destinations[src])
+					if err != nil {
+						return &hcl.Diagnostics{{
+							Severity: hcl.DiagError,
+							Summary:  "Failed to unescape filename",
+							Detail:   fmt.Sprintf("%s: %s", fileName, err.Error()),
+						}}
 					}
 
-					// is this site going to perform downloads?
-					// if len(resolvedDestinations) > 0 {
-					// 	s.Config.Sites[siteIndex].HasMatches = true
-					// }
+					destinations[src] = unescaped
 
-					s.TotalAssets += int64(len(resolvedDestinations))
+					log.Trace().Str("site", site.Name).Str("asset", asset.Name).Str("source", src).Str("destination", destinations[src]).Msg("transformed filename")
 				}
 			}
 
-			// MARK: - Indexing
-
-			// store the url and the timestamp by default
-			infoMap := make(map[string]string, 0)
-			infoMap["url"] = pageUrl
-			infoMap["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+			// MARK: - loop through the map to check for relative urls
 
-			// loop through index blocks
-			for _, info := range site.Infos {
-				log.Trace().Str("site", site.Name).Str("info", info.Name).Msg("visiting info block")
-
-				key := info.Name
+			resolvedDestinations := make(map[string]string, 0)
+			for src, dst := range destinations {
+				parsed, err := url.Parse(src)
+				if err != nil {
+					return &hcl.Diagnostics{{
+						Severity: hcl.DiagError,
+						Summary:  "Failed to parse url",
+						Detail:   fmt.Sprintf("%s: %s", src, err.Error()),
+					}}
+				}
 
-				if s.RegexCache[info.Pattern].MatchString(body) {
-					captures, err := utils.GetCaptures(s.RegexCache[info.Pattern], false, info.Capture, body)
+				// if path is still relative, append it to the scheme://domain.name of the page
+				if !parsed.IsAbs() {
+					resolved, err := base.Parse(src)
 					if err != nil {
 						return &hcl.Diagnostics{{
 							Severity: hcl.DiagError,
-							Summary:  "Failed to get capture",
-							Detail:   fmt.Sprintf("%s: %s", pageUrl, err.Error()),
+							Summary:  "Failed to resolve relative url",
+							Detail:   fmt.Sprintf("%s: %s", src, err.Error()),
 						}}
 					}
 
-					if len(captures) > 0 {
-						infoMap[key] = captures[0]
-						log.Trace().Str("site", site.Name).Str("info", info.Name).Strs("matches", captures).Msgf("%d %s found", len(captures), utils.Plural(len(captures), "match", "matches"))
+					resolvedDestinations[resolved.String()] = dst
+
+					if checksum, ok := checksums[src]; ok {
+						checksums[resolved.String()] = checksum
 					}
+
+					log.Trace().Str("site", site.Name).Str("asset", asset.Name).Str("source", src).Str("destination", resolved.String()).Msg("resolved relative url")
+				} else {
+					// nothing to do, the url is already absolute
+					resolvedDestinations[src] = dst
 				}
 			}
 
-			if s.Config.Sites[siteIndex].InfoMap == nil {
-				s.Config.Sites[siteIndex].InfoMap = make(config.InfoCacheMap, 0)
+			// MARK: - skip the urls filtered by include and exclude
+
+			for _, src := range sortedKeys(resolvedDestinations) {
+				if reason := s.filtered(asset, src); reason != "" {
+					log.Info().Str("site", site.Name).Str("asset", asset.Name).Str("url", src).Str("reason", reason).Msg("filtered")
+
+					s.emit(&Event{Type: EventDownloadSkipped, Site: site.Name, Asset: asset.Name, Page: pageUrl, Source: src, Destination: resolvedDestinations[src], Reason: reason})
+
+					delete(resolvedDestinations, src)
+				}
+			}
+
+			// initialize the maps if nil
+			if s.Config.Sites[siteIndex].Assets[assetIndex].Downloads == nil {
+				s.Config.Sites[siteIndex].Assets[assetIndex].Downloads = make(map[string]string, 0)
+			}
+
+			if s.Config.Sites[siteIndex].Assets[assetIndex].Pages == nil {
+				s.Config.Sites[siteIndex].Assets[assetIndex].Pages = make(map[string]string, 0)
 			}
 
-			s.Config.Sites[siteIndex].InfoMap[subdirectory] = infoMap
+			if s.Config.Sites[siteIndex].Assets[assetIndex].Checksums == nil && len(checksums) > 0 {
+				s.Config.Sites[siteIndex].Assets[assetIndex].Checksums = make(map[string]string, 0)
+			}
+
+			// add the destinations to the asset
+			for _, src := range sortedKeys(resolvedDestinations) {
+				dst := resolvedDestinations[src]
+				s.Config.Sites[siteIndex].Assets[assetIndex].Downloads[src] = dst
+				s.Config.Sites[siteIndex].Assets[assetIndex].Pages[src] = pageUrl
+
+				checksum, ok := checksums[src]
+				if ok {
+					s.Config.Sites[siteIndex].Assets[assetIndex].Checksums[src] = checksum
+				}
+
+				s.emit(&Event{Type: EventAssetMatched, Site: site.Name, Asset: asset.Name, Page: pageUrl, Source: src, Destination: dst, Checksum: checksum})
+			}
+
+			// is this site going to perform downloads?
+			// if len(resolvedDestinations) > 0 {
+			// 	s.Config.Sites[siteIndex].HasMatches = true
+			// }
+
+			s.TotalAssets += int64(len(resolvedDestinations))
 		}
 	}
 
+	// MARK: - Indexing
+
+	// store the url and the timestamp by default
+	infoMap := make(map[string]string, 0)
+	infoMap["url"] = pageUrl
+	infoMap["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+
+	// loop through index blocks
+	for _, info := range site.Infos {
+		log.Trace().Str("site", site.Name).Str("info", info.Name).Msg("visiting info block")
+
+		key := info.Name
+
+		if s.RegexCache[info.Pattern].MatchString(body) {
+			captures, err := utils.GetCaptures(s.RegexCache[info.Pattern], false, info.Capture, body)
+			if err != nil {
+				return &hcl.Diagnostics{{
+					Severity: hcl.DiagError,
+					Summary:  "Failed to get capture",
+					Detail:   fmt.Sprintf("%s: %s", pageUrl, err.Error()),
+				}}
+			}
+
+			if len(captures) > 0 {
+				infoMap[key] = captures[0]
+				log.Trace().Str("site", site.Name).Str("info", info.Name).Strs("matches", captures).Msgf("%d %s found", len(captures), utils.Plural(len(captures), "match", "matches"))
+			}
+		}
+	}
+
+	if s.Config.Sites[siteIndex].InfoMap == nil {
+		s.Config.Sites[siteIndex].InfoMap = make(config.InfoCacheMap, 0)
+	}
+
+	infos := s.Config.Sites[siteIndex].InfoMap[subdirectory]
+
+	switch site.InfoMerge() {
+	case config.InfoMergeFirst:
+		if len(infos) == 0 {
+			infos = append(infos, infoMap)
+		}
+	case config.InfoMergeLast:
+		infos = []map[string]string{infoMap}
+	default:
+		// a page scraped again replaces its previous info
+		replaced := false
+		for i, info := range infos {
+			if info["url"] == pageUrl {
+				infos[i] = infoMap
+				replaced = true
+			}
+		}
+
+		if !replaced {
+			infos = append(infos, infoMap)
+		}
+	}
+
+	s.Config.Sites[siteIndex].InfoMap[subdirectory] = infos
+
 	return &hcl.Diagnostics{}
 }
+
+// checksums returns the expected checksums of the captures of the asset, as "algorithm:digest" by capture.
+// The digests are captured from the asset pattern, or from the checksum pattern in the order of the matches.
+func (s *Grab) checksums(siteName string, asset config.AssetConfig, findAll bool, captures []string, pageUrl, body string) (map[string]string, *hcl.Diagnostics) {
+	checksums := make(map[string]string, 0)
+
+	if asset.Checksum == nil {
+		return checksums, &hcl.Diagnostics{}
+	}
+
+	re := s.RegexCache[asset.Pattern]
+	if asset.Checksum.Pattern != nil {
+		re = s.RegexCache[*asset.Checksum.Pattern]
+	}
+
+	if !re.MatchString(body) {
+		log.Warn().Str("site", siteName).Str("asset", asset.Name).Str("url", pageUrl).Msg("no checksums found, the downloads will not be verified")
+		return checksums, &hcl.Diagnostics{}
+	}
+
+	digests, err := utils.GetCaptures(re, findAll, asset.Checksum.Capture, body)
+	if err != nil {
+		return nil, &hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to get checksums",
+			Detail:   fmt.Sprintf("%s: %s", pageUrl, err.Error()),
+		}}
+	}
+
+	for i, src := range captures {
+		if i >= len(digests) {
+			break
+		}
+
+		digest := strings.ToLower(strings.TrimSpace(digests[i]))
+		if digest == "" {
+			continue
+		}
+
+		checksums[src] = asset.Checksum.Algorithm + ":" + digest
+	}
+
+	log.Trace().Str("site", siteName).Str("asset", asset.Name).Int("checksums", len(checksums)).Msg("checksums found")
+
+	return checksums, &hcl.Diagnostics{}
+}
@@ -5,9 +5,11 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
 	tu "github.com/everdrone/grab/testutils"
 )
 
@@ -296,10 +298,10 @@ site "example" {
 								},
 							},
 						},
-						InfoMap: map[string]map[string]string{
-							filepath.Join(globalLocation, "example"): {
+						InfoMap: config.InfoCacheMap{
+							filepath.Join(globalLocation, "example"): {{
 								"url": ts.URL + testPath,
-							},
+							}},
 						},
 					},
 				},
@@ -335,10 +337,10 @@ site "example" {
 								},
 							},
 						},
-						InfoMap: map[string]map[string]string{
-							filepath.Join(globalLocation, "example"): {
+						InfoMap: config.InfoCacheMap{
+							filepath.Join(globalLocation, "example"): {{
 								"url": ts.URL + testPath,
-							},
+							}},
 						},
 					},
 				},
@@ -374,10 +376,10 @@ site "example" {
 								},
 							},
 						},
-						InfoMap: map[string]map[string]string{
-							filepath.Join(globalLocation, "example"): {
+						InfoMap: config.InfoCacheMap{
+							filepath.Join(globalLocation, "example"): {{
 								"url": ts.URL + testPath,
-							},
+							}},
 						},
 					},
 				},
@@ -419,10 +421,10 @@ site "example" {
 								},
 							},
 						},
-						InfoMap: map[string]map[string]string{
-							filepath.Join(globalLocation, "example", "123"): {
+						InfoMap: config.InfoCacheMap{
+							filepath.Join(globalLocation, "example", "123"): {{
 								"url": ts.URL + testPath,
-							},
+							}},
 						},
 					},
 				},
@@ -594,10 +596,10 @@ site "example" {
 								},
 							},
 						},
-						InfoMap: map[string]map[string]string{
-							filepath.Join(globalLocation, "example", "everdrone"): {
+						InfoMap: config.InfoCacheMap{
+							filepath.Join(globalLocation, "example", "everdrone"): {{
 								"url": ts.URL + testPath,
-							},
+							}},
 						},
 					},
 				},
@@ -638,10 +640,10 @@ site "example" {
 								},
 							},
 						},
-						InfoMap: map[string]map[string]string{
-							filepath.Join(globalLocation, "example"): {
+						InfoMap: config.InfoCacheMap{
+							filepath.Join(globalLocation, "example"): {{
 								"url": ts.URL + testPath,
-							},
+							}},
 						},
 					},
 				},
@@ -682,10 +684,10 @@ site "example" {
 								},
 							},
 						},
-						InfoMap: map[string]map[string]string{
-							filepath.Join(globalLocation, "example"): {
+						InfoMap: config.InfoCacheMap{
+							filepath.Join(globalLocation, "example"): {{
 								"url": ts.URL + testPath,
-							},
+							}},
 						},
 					},
 				},
@@ -726,10 +728,10 @@ site "example" {
 								},
 							},
 						},
-						InfoMap: map[string]map[string]string{
-							filepath.Join(globalLocation, "example"): {
+						InfoMap: config.InfoCacheMap{
+							filepath.Join(globalLocation, "example"): {{
 								"url": ts.URL + testPath,
-							},
+							}},
 						},
 					},
 				},
@@ -765,12 +767,12 @@ site "example" {
 								Downloads: map[string]string(nil),
 							},
 						},
-						InfoMap: map[string]map[string]string{
-							filepath.Join(globalLocation, "example"): {
+						InfoMap: config.InfoCacheMap{
+							filepath.Join(globalLocation, "example"): {{
 								"url":    ts.URL + testPath,
 								"author": "everdrone",
 								"title":  "Grab Test Server",
-							},
+							}},
 						},
 					},
 				},
@@ -823,13 +825,20 @@ func compareInfoMaps(t *testing.T, got, want config.InfoCacheMap) {
 	}
 
 	// check that the values are the same but ignore the timestamp
-	for k, v := range got {
-		for k2, v2 := range v {
-			if k2 == "timestamp" {
-				continue
-			}
-			if want[k][k2] != v2 {
-				t.Errorf("got: %+v, want: %+v", got, want)
+	for k, infos := range got {
+		if len(infos) != len(want[k]) {
+			t.Errorf("got: %+v, want: %+v", got, want)
+			continue
+		}
+
+		for i, v := range infos {
+			for k2, v2 := range v {
+				if k2 == "timestamp" {
+					continue
+				}
+				if want[k][i][k2] != v2 {
+					t.Errorf("got: %+v, want: %+v", got, want)
+				}
 			}
 		}
 	}
@@ -842,3 +851,98 @@ func getMapKeys[T any](m map[string]T) []string {
 	}
 	return keys
 }
+
+func TestInfoMerge(t *testing.T) {
+	root := tu.GetOSRoot()
+	global := filepath.Join(root, "global")
+	directory := filepath.Join(global, "example", "user")
+
+	pages := [][2]string{
+		{"https://example.com/user/1", "<title>One</title>"},
+		{"https://example.com/user/2", "<title>Two</title>"},
+		// scraped again
+		{"https://example.com/user/1", "<title>One, again</title>"},
+	}
+
+	tests := []struct {
+		Merge string
+		// the titles kept in the directory
+		Want []string
+	}{
+		// no page is dropped by default
+		{Merge: "", Want: []string{"One, again", "Two"}},
+		{Merge: "first", Want: []string{"One"}},
+		{Merge: "last", Want: []string{"One, again"}},
+		{Merge: "append", Want: []string{"One, again", "Two"}},
+	}
+
+	for _, tt := range tests {
+		name := tt.Merge
+		if name == "" {
+			name = "default"
+		}
+
+		t.Run(name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+			merge := ""
+			if tt.Merge != "" {
+				merge = `merge = "` + tt.Merge + `"`
+			}
+
+			cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(global)+`"
+}
+
+site "example" {
+	test = "example\\.com"
+
+	info "title" {
+		pattern = "<title>([^<]+)"
+		capture = 1
+	}
+
+	subdirectory {
+		pattern = "(user)"
+		capture = 1
+		from = url
+		`+merge+`
+	}
+}`), "test.hcl")
+			if diags.HasErrors() {
+				tc.Fatal(diags)
+			}
+
+			g := &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{}}
+
+			for _, page := range pages {
+				if diags := g.ScrapePage(0, page[0], page[1]); diags.HasErrors() {
+					tc.Fatal(diags)
+				}
+			}
+
+			got := make([]string, 0)
+			for _, info := range g.Config.Sites[0].InfoMap[directory] {
+				got = append(got, info["title"])
+			}
+
+			if !reflect.DeepEqual(got, tt.Want) {
+				tc.Errorf("got: %q, want: %q", got, tt.Want)
+			}
+
+			// the plan keeps the policy, for the format of _info.json
+			applied := &Grab{Flags: &FlagsState{}}
+			applied.ApplyPlan(g.Plan(), "")
+
+			if err := applied.Download(); err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			fc, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(directory, InfoFileName))
+			if isArray := strings.HasPrefix(string(fc), "["); isArray != (len(tt.Want) > 1) {
+				tc.Errorf("got: %s, want an array only when several pages are kept", fc)
+			}
+		})
+	}
+}
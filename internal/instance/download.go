@@ -1,12 +1,15 @@
 package instance
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/everdrone/grab/internal/config"
 	"github.com/everdrone/grab/internal/net"
 	"github.com/everdrone/grab/internal/utils"
 	"github.com/rs/zerolog/log"
@@ -24,14 +27,18 @@ func (s *Grab) Download() error {
 			// 	continue
 			// }
 
-			for location, infoMap := range site.InfoMap {
-				log.Info().Fields(infoMap).Str("site", site.Name).Str("location", location).Msg("indexing")
+			for location, infos := range site.InfoMap {
+				for _, infoMap := range infos {
+					log.Info().Fields(infoMap).Str("site", site.Name).Str("location", location).Msg("indexing")
+				}
 			}
 
 			for _, asset := range site.Assets {
 				for src, dst := range asset.Downloads {
 					rel, _ := filepath.Rel(s.Config.Global.Location, dst)
 					log.Info().Str("source", src).Str("destination", rel).Str("site", site.Name).Str("asset", asset.Name).Msg("downloading")
+
+					s.emit(&Event{Type: EventDownloadSkipped, Site: site.Name, Asset: asset.Name, Source: src, Destination: dst, Reason: "dry run"})
 				}
 
 			}
@@ -40,40 +47,73 @@ func (s *Grab) Download() error {
 		return nil
 	}
 
+	index, err := NewIndex(s.Config.Global)
+	if err != nil {
+		return &hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid index",
+			Detail:   err.Error(),
+		}}
+	}
+
 	for _, site := range s.Config.Sites {
 
-		// MARK: - Download info file
+		// MARK: - Write info records
 
-		for subdirectory, infoMap := range site.InfoMap {
-			// create directory
-			if err := utils.Fs.MkdirAll(subdirectory, os.ModePerm); err != nil {
-				return &hcl.Diagnostics{{
-					Severity: hcl.DiagError,
-					Summary:  "Failed to create directory",
-					Detail:   fmt.Sprintf("%s: %s", subdirectory, err.Error()),
-				}}
+		if len(site.InfoMap) > 0 {
+			records := make([]InfoRecord, 0, len(site.InfoMap))
+			for _, subdirectory := range sortedKeys(site.InfoMap) {
+				for _, infoMap := range site.InfoMap[subdirectory] {
+					records = append(records, InfoRecord{Site: site.Name, Directory: s.relativeToLocation(subdirectory), Values: infoMap})
+				}
 			}
 
-			marshaled, err := json.MarshalIndent(infoMap, "", "  ")
-			if err != nil {
-				// this should never happen, since infoMap is encodable
+			if err := index.Write(records, site.InfoMerge()); err != nil {
 				return &hcl.Diagnostics{{
 					Severity: hcl.DiagError,
-					Summary:  "Failed to marshal info",
-					Detail:   fmt.Sprintf("%+v: %s", infoMap, err.Error()),
+					Summary:  "Failed to write info",
+					Detail:   fmt.Sprintf("%s: %s", index.Destination(records[0]), err.Error()),
 				}}
 			}
 
-			dst := filepath.Join(subdirectory, "_info.json")
+			for _, record := range records {
+				dst := index.Destination(record)
 
-			log.Info().Str("destination", dst).Msg("indexing")
+				log.Info().Str("destination", dst).Str("url", record.Values["url"]).Msg("indexing")
 
-			if err := utils.Io.WriteFile(utils.Fs, dst, marshaled, os.ModePerm); err != nil {
-				return &hcl.Diagnostics{{
-					Severity: hcl.DiagError,
-					Summary:  "Failed to write info file",
-					Detail:   fmt.Sprintf("%s: %s", dst, err.Error()),
-				}}
+				s.emit(&Event{Type: EventInfoWritten, Site: site.Name, Page: record.Values["url"], Destination: dst})
+			}
+		}
+
+		// MARK: - Page hooks
+
+		// the info of the pages and the number of their downloads left
+		pageInfo := make(map[string]map[string]string, len(site.InfoMap))
+		for _, infos := range site.InfoMap {
+			for _, infoMap := range infos {
+				pageInfo[infoMap["url"]] = infoMap
+			}
+		}
+
+		pending := make(map[string]int)
+		for _, asset := range site.Assets {
+			for src := range asset.Downloads {
+				if page := asset.Pages[src]; page != "" {
+					pending[page]++
+				}
+			}
+		}
+
+		pageDone := func(page string) error {
+			return s.runHooks(config.HookPageDone, &HookData{Site: site.Name, Page: page, Info: pageInfo[page]}, s.Config.Global.Hooks, site.Hooks)
+		}
+
+		// the pages without downloads are done once their info is written
+		for _, page := range sortedKeys(pageInfo) {
+			if pending[page] == 0 {
+				if err := pageDone(page); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -81,6 +121,12 @@ func (s *Grab) Download() error {
 
 		for _, asset := range site.Assets {
 			for src, dst := range asset.Downloads {
+				if err := s.canceled(); err != nil {
+					return err
+				}
+
+				page := asset.Pages[src]
+
 				// create directory
 				dir := filepath.Dir(dst)
 				if err := utils.Fs.MkdirAll(dir, os.ModePerm); err != nil {
@@ -96,16 +142,65 @@ func (s *Grab) Download() error {
 				// log.Debug().Str("site", site.Name).Str("asset", asset.Name).Str("source", src).Interface("options", options).Msg("network options")
 
 				// check if file exists
+				policy := s.onExists(site, asset)
 				performWrite := true
+				var validators *net.Validators
+				var renameErr error
+
 				if exists, err := utils.Io.Exists(utils.Fs, dst); err != nil || exists {
-					performWrite = false
+					switch policy {
+					case config.OnExistsOverwrite:
+					case config.OnExistsRename:
+						var free string
+						if free, renameErr = renamed(dst); renameErr == nil {
+							dst = free
+						}
+					case config.OnExistsIfNewer, config.OnExistsIfChanged:
+						validators = storedValidators(dst, policy)
+					default:
+						performWrite = false
+					}
 				}
 
-				// if force or file does not exist, write to disk
-				if s.Flags.Force || performWrite {
+				if renameErr != nil {
+					log.Err(renameErr).Str("source", src).Str("destination", strings.TrimPrefix(dst, s.Config.Global.Location)).Msg("failed to rename download")
+
+					s.emit(&Event{Type: EventDownloadFailed, Site: site.Name, Asset: asset.Name, Page: page, Source: src, Destination: dst, Checksum: asset.Checksums[src], Error: renameErr.Error()})
+
+					if s.Flags.Strict {
+						return renameErr
+					}
+				} else if performWrite {
+					// the run is canceled while waiting for a free slot
+					if err := s.acquireSlot(); err != nil {
+						return err
+					}
+
 					log.Info().Str("url", src).Str("file", filepath.Base(dst)).Msg("downloading")
 
-					if err := net.Download(src, dst, options); err != nil {
+					s.emit(&Event{Type: EventDownloadStarted, Site: site.Name, Asset: asset.Name, Page: page, Source: src, Destination: dst})
+
+					start := time.Now()
+					result, err := download(src, dst, options, asset.Checksums[src], validators, downloadFilter(asset))
+					s.releaseSlot()
+
+					var rejected *net.FilterError
+					if err == nil && result.Status == http.StatusNotModified {
+						log.Info().Str("destination", strings.TrimPrefix(dst, s.Config.Global.Location)).Msg("file not modified")
+
+						s.emit(&Event{Type: EventDownloadSkipped, Site: site.Name, Asset: asset.Name, Page: page, Source: src, Destination: dst, Duration: time.Since(start), Reason: "not modified"})
+					} else if errors.As(err, &rejected) {
+						log.Info().Str("url", src).Str("reason", rejected.Reason).Msg("filtered")
+
+						s.emit(&Event{Type: EventDownloadSkipped, Site: site.Name, Asset: asset.Name, Page: page, Source: src, Destination: dst, Duration: time.Since(start), Reason: rejected.Reason})
+					} else if err != nil {
+						written := int64(0)
+						if result != nil {
+							written = result.Size
+						}
+
+						s.emit(&Event{Type: EventDownloadFailed, Site: site.Name, Asset: asset.Name, Page: page, Source: src, Destination: dst, Checksum: asset.Checksums[src], Bytes: written, Duration: time.Since(start), Error: err.Error()})
+
 						// return now if we are in strict mode
 						if s.Flags.Strict {
 							log.Err(err).Str("source", src).Str("destination", strings.TrimPrefix(dst, s.Config.Global.Location)).Msg("failed to download asset")
@@ -113,13 +208,66 @@ func (s *Grab) Download() error {
 						} else {
 							log.Err(err).Str("source", src).Str("destination", strings.TrimPrefix(dst, s.Config.Global.Location)).Msg("failed to download asset")
 						}
+					} else {
+						s.emit(&Event{Type: EventDownloadFinished, Site: site.Name, Asset: asset.Name, Page: page, Source: src, Destination: dst, Bytes: result.Size, Duration: time.Since(start)})
+
+						// the sidecar file stores the validators of the conditional policies
+						if utils.Inherit(false, s.Config.Global.Sidecar, site.Sidecar, asset.Sidecar) || conditional(policy) {
+							if diags := writeSidecar(dst, NewSidecar(src, page, result, pageInfo[page])); diags.HasErrors() {
+								return diags
+							}
+						}
+
+						data := &HookData{Site: site.Name, Asset: asset.Name, Page: page, Source: src, Destination: dst, Info: pageInfo[page]}
+						if err := s.runHooks(config.HookAssetDownloaded, data, s.Config.Global.Hooks, site.Hooks, asset.Hooks); err != nil {
+							return err
+						}
 					}
 				} else {
 					log.Warn().Str("destination", strings.TrimPrefix(dst, s.Config.Global.Location)).Msg("file already exists")
+
+					s.emit(&Event{Type: EventDownloadSkipped, Site: site.Name, Asset: asset.Name, Page: page, Source: src, Destination: dst, Reason: "file already exists"})
+				}
+
+				if page == "" {
+					continue
+				}
+
+				if pending[page]--; pending[page] == 0 {
+					if err := pageDone(page); err != nil {
+						return err
+					}
 				}
 			}
 		}
 	}
 
-	return nil
+	// MARK: - Run hooks
+
+	for _, site := range s.Config.Sites {
+		if len(site.InfoMap) == 0 && !utils.Any(site.Assets, func(a config.AssetConfig) bool { return len(a.Downloads) > 0 }) {
+			continue
+		}
+
+		if err := s.runHooks(config.HookRunDone, &HookData{Site: site.Name}, site.Hooks); err != nil {
+			return err
+		}
+	}
+
+	return s.runHooks(config.HookRunDone, &HookData{}, s.Config.Global.Hooks)
+}
+
+// downloads src to dst, verifying it if checksum is not empty ("algorithm:digest"),
+// only if it changed since the version described by validators if not nil, and only if filter accepts it
+func download(src, dst string, options *net.FetchOptions, checksum string, validators *net.Validators, filter *net.Filter) (*net.DownloadResult, error) {
+	if checksum == "" {
+		return net.Download(src, dst, options, nil, validators, filter)
+	}
+
+	parsed, err := net.ParseChecksum(checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	return net.Download(src, dst, options, parsed, validators, filter)
 }
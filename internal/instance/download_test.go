@@ -1,9 +1,11 @@
 package instance
 
 import (
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/everdrone/grab/internal/config"
@@ -274,3 +276,114 @@ site "example" {
 		})
 	}
 }
+
+func TestDownloadChecksum(t *testing.T) {
+	root := tu.GetOSRoot()
+	global := filepath.Join(root, "global")
+
+	// the digest of b.txt is wrong
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/files/a.txt":
+			w.Write([]byte("filea"))
+		case "/files/b.txt":
+			w.Write([]byte("fileb"))
+		default:
+			w.Write([]byte(`<ul>
+	<li><a href="/files/a">a</a> <code>BB0642D15AF235B1AEAEFF8768ED6A07DBB9123B5A04959451EB5AAF12B35653</code></li>
+	<li><a href="/files/b">b</a> <code>bb0642d15af235b1aeaeff8768ed6a07dbb9123b5a04959451eb5aaf12b35653</code></li>
+</ul>`))
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		Name  string
+		Asset string
+	}{
+		{
+			Name: "capture of the asset pattern",
+			Asset: `pattern = "<a href=\"([^\"]+)\">\\w</a> <code>(\\w+)</code>"
+		capture = 1
+
+		checksum {
+			algorithm = "sha256"
+			capture = 2
+		}`,
+		},
+		{
+			Name: "checksum pattern",
+			Asset: `pattern = "<a href=\"([^\"]+)\">"
+		capture = 1
+
+		checksum {
+			algorithm = "sha256"
+			pattern = "<code>(?P<digest>\\w+)</code>"
+			capture = "digest"
+		}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+			cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(global)+`"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "file" {
+		`+tt.Asset+`
+		find_all = true
+
+		transform url {
+			pattern = ".+"
+			replace = "$${0}.txt"
+		}
+	}
+}`), "test.hcl")
+			if diags.HasErrors() {
+				tc.Fatal(diags)
+			}
+
+			g := &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{}, URLs: []string{ts.URL}}
+
+			failures := make([]string, 0)
+			g.OnEvent(func(e *Event) {
+				if e.Type == EventDownloadFailed {
+					failures = append(failures, e.Error)
+				}
+			})
+
+			g.BuildSiteCache()
+			if diags := g.BuildAssetCache(); diags.HasErrors() {
+				tc.Fatal(diags)
+			}
+
+			plan := g.Plan()
+			if got := plan.Sites[0].Assets[0].Downloads[0].Checksum; got != "sha256:bb0642d15af235b1aeaeff8768ed6a07dbb9123b5a04959451eb5aaf12b35653" {
+				tc.Errorf("got: %q, want the checksum of a.txt in the plan", got)
+			}
+
+			if err := g.Download(); err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			if got, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(global, "example", "a.txt")); string(got) != "filea" {
+				tc.Errorf("got: %q, want: %q", got, "filea")
+			}
+
+			if exists, _ := utils.Io.Exists(utils.Fs, filepath.Join(global, "example", "b.txt")); exists {
+				tc.Errorf("got: b.txt, want the corrupted file to be removed")
+			}
+
+			if len(failures) != 1 || !strings.Contains(failures[0], "checksum mismatch") {
+				tc.Errorf("got: %q, want a checksum mismatch", failures)
+			}
+		})
+	}
+}
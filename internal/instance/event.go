@@ -0,0 +1,139 @@
+package instance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type EventType string
+
+const (
+	// a page was fetched, Bytes is the size of the body
+	EventPageFetched EventType = "page_fetched"
+	// a page could not be fetched
+	EventPageFailed EventType = "page_failed"
+	// an asset url was found in a page
+	EventAssetMatched    EventType = "asset_matched"
+	EventDownloadStarted EventType = "download_started"
+	// an asset was written to the disk, Bytes is the size of the file
+	EventDownloadFinished EventType = "download_finished"
+	// an asset was not downloaded, the reason is in Reason
+	EventDownloadSkipped EventType = "download_skipped"
+	EventDownloadFailed  EventType = "download_failed"
+	// an info file was written
+	EventInfoWritten EventType = "info_written"
+	// the command of a hook failed or timed out
+	EventHookFailed EventType = "hook_failed"
+)
+
+// Event describes a step of the scraping and downloading process
+type Event struct {
+	Type EventType `json:"event"`
+	Time time.Time `json:"time"`
+
+	// the name of the site block
+	Site string `json:"site,omitempty"`
+	// the name of the asset block
+	Asset string `json:"asset,omitempty"`
+	// the url of the page
+	Page string `json:"page,omitempty"`
+	// the url of the asset
+	Source string `json:"source,omitempty"`
+	// the path of the written file
+	Destination string `json:"destination,omitempty"`
+	// the expected checksum of the asset, "algorithm:digest"
+	Checksum string `json:"checksum,omitempty"`
+	// the number of bytes fetched or written
+	Bytes int64 `json:"bytes,omitempty"`
+	// how long the step took
+	Duration time.Duration `json:"-"`
+	// why a download was skipped
+	Reason string `json:"reason,omitempty"`
+	// the error that made the step fail
+	Error string `json:"error,omitempty"`
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	// avoid infinite recursion
+	type event Event
+
+	return json.Marshal(struct {
+		event
+		DurationMs float64 `json:"duration_ms,omitempty"`
+	}{
+		event:      event(e),
+		DurationMs: float64(e.Duration) / float64(time.Millisecond),
+	})
+}
+
+// EventHandler is called for every event, in the order the events happen
+type EventHandler func(e *Event)
+
+// OnEvent registers a handler that receives all the events emitted by the instance
+func (s *Grab) OnEvent(handler EventHandler) {
+	s.eventHandlers = append(s.eventHandlers, handler)
+}
+
+func (s *Grab) emit(e *Event) {
+	if len(s.eventHandlers) == 0 {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	for _, handler := range s.eventHandlers {
+		handler(e)
+	}
+}
+
+// JSONEventWriter returns a handler that writes every event to w as a line of JSON (NDJSON)
+func JSONEventWriter(w io.Writer) EventHandler {
+	var mu sync.Mutex
+
+	return func(e *Event) {
+		marshaled, err := json.Marshal(e)
+		if err != nil {
+			// this should never happen, since events are encodable
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		fmt.Fprintf(w, "%s\n", marshaled)
+	}
+}
+
+// TemplateEventWriter returns a handler that renders tmpl with every finished download, followed by a new line.
+// The errors of the template are logged, w only gets the complete lines.
+func TemplateEventWriter(w io.Writer, tmpl *template.Template) EventHandler {
+	var mu sync.Mutex
+
+	return func(e *Event) {
+		if e.Type != EventDownloadFinished {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		// rendered first, a failing template must not leave half a line in w
+		buf := &bytes.Buffer{}
+		if err := tmpl.Execute(buf, e); err != nil {
+			log.Warn().Err(err).Str("source", e.Source).Msg("could not render the print template")
+			return
+		}
+
+		buf.WriteString("\n")
+		w.Write(buf.Bytes())
+	}
+}
@@ -0,0 +1,161 @@
+package instance
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestEventJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := JSONEventWriter(buf)
+
+	handler(&Event{
+		Type:     EventDownloadFinished,
+		Time:     time.Date(2022, 8, 17, 0, 0, 0, 0, time.UTC),
+		Site:     "example",
+		Asset:    "image",
+		Source:   "https://example.com/a.jpg",
+		Bytes:    1024,
+		Duration: 1500 * time.Microsecond,
+	})
+	handler(&Event{
+		Type: EventPageFailed,
+		Time: time.Date(2022, 8, 17, 0, 0, 0, 0, time.UTC),
+		Page: "https://example.com",
+	})
+
+	want := `{"event":"download_finished","time":"2022-08-17T00:00:00Z","site":"example","asset":"image","source":"https://example.com/a.jpg","bytes":1024,"duration_ms":1.5}
+{"event":"page_failed","time":"2022-08-17T00:00:00Z","page":"https://example.com"}
+`
+
+	if got := buf.String(); got != want {
+		t.Errorf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestTemplateEventWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := TemplateEventWriter(buf, template.Must(template.New("").Parse("{{.Site}}/{{.Asset}} {{.Bytes}}")))
+
+	handler(&Event{Type: EventDownloadStarted, Site: "example", Asset: "image"})
+	handler(&Event{Type: EventDownloadFinished, Site: "example", Asset: "image", Bytes: 3})
+	handler(&Event{Type: EventDownloadSkipped, Site: "example", Asset: "image"})
+
+	if got, want := buf.String(), "example/image 3\n"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestTemplateEventWriterError(t *testing.T) {
+	logs := &bytes.Buffer{}
+	defer func(logger zerolog.Logger) { log.Logger = logger }(log.Logger)
+	log.Logger = zerolog.New(logs)
+
+	buf := &bytes.Buffer{}
+	handler := TemplateEventWriter(buf, template.Must(template.New("").Parse("{{.Site}} {{.Missing}}")))
+
+	handler(&Event{Type: EventDownloadFinished, Site: "example", Source: "https://example.com/a.jpg"})
+
+	if buf.Len() != 0 {
+		t.Errorf("got: %q, want nothing on the output", buf.String())
+	}
+
+	if got := logs.String(); !strings.Contains(got, "can't evaluate field Missing") || !strings.Contains(got, "https://example.com/a.jpg") {
+		t.Errorf("got: %q, want the error of the template in the logs", got)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	root := tu.GetOSRoot()
+	global := filepath.Join(root, "global")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+	// a.jpg already exists
+	utils.Fs.MkdirAll(filepath.Join(global, "example"), os.ModePerm)
+	utils.Io.WriteFile(utils.Fs, filepath.Join(global, "example", "a.jpg"), []byte("imagea"), os.ModePerm)
+
+	g := New(nil)
+	g.Flags = &FlagsState{}
+
+	cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(global)+`"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/[ab][^\"]+)"
+		capture = 1
+		find_all = true
+	}
+}`), "test.hcl")
+	if diags.HasErrors() {
+		t.Fatalf("got errors: %+v", diags)
+	}
+
+	g.Config = cfg
+	g.RegexCache = regexCache
+	g.URLs = []string{ts.URL + "/gallery/123/test", ts.URL + "/givesNotFound"}
+
+	events := make([]*Event, 0)
+	g.OnEvent(func(e *Event) {
+		events = append(events, e)
+	})
+
+	g.BuildSiteCache()
+	g.BuildAssetCache()
+	g.Download()
+
+	got := make([]EventType, 0)
+	for _, e := range events {
+		if e.Time.IsZero() {
+			t.Errorf("event %s has no time", e.Type)
+		}
+
+		// downloads are not ordered
+		if e.Type == EventDownloadSkipped || e.Type == EventDownloadStarted || e.Type == EventDownloadFinished {
+			continue
+		}
+		got = append(got, e.Type)
+	}
+
+	want := []EventType{EventPageFetched, EventAssetMatched, EventAssetMatched, EventPageFailed, EventInfoWritten}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+
+	for _, e := range events {
+		switch {
+		case e.Type == EventDownloadSkipped && e.Source != ts.URL+"/img/a.jpg":
+			t.Errorf("got: %s skipped, want: a.jpg", e.Source)
+		case e.Type == EventDownloadFinished && (e.Source != ts.URL+"/img/b.jpg" || e.Bytes != 6):
+			t.Errorf("got: %s finished with %d bytes, want: b.jpg with 6 bytes", e.Source, e.Bytes)
+		case e.Type == EventPageFetched && (e.Bytes == 0 || e.Site != "example"):
+			t.Errorf("got: %+v, want the size of the page and the site", e)
+		}
+	}
+}
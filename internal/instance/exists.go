@@ -0,0 +1,80 @@
+package instance
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/net"
+	"github.com/everdrone/grab/internal/utils"
+)
+
+// onExists returns what happens to the downloads of the asset whose file already exists.
+// The flags win over the configuration, --force is the same as --on-exists overwrite.
+func (s *Grab) onExists(site config.SiteConfig, asset config.AssetConfig) string {
+	if s.Flags.Force {
+		return config.OnExistsOverwrite
+	}
+
+	if s.Flags.OnExists != "" {
+		return s.Flags.OnExists
+	}
+
+	return utils.Inherit(config.OnExistsSkip, s.Config.Global.OnExists, site.OnExists, asset.OnExists)
+}
+
+// conditional reports whether the policy downloads the existing files again only when they changed
+func conditional(policy string) bool {
+	return policy == config.OnExistsIfNewer || policy == config.OnExistsIfChanged
+}
+
+// maxRenames is the number of names tried by renamed before giving up
+const maxRenames = 10000
+
+// renamed returns the first path that does not exist, appending " (1)", " (2)"... to the name of dst
+func renamed(dst string) (string, error) {
+	ext := filepath.Ext(dst)
+	base := strings.TrimSuffix(dst, ext)
+
+	for i := 1; i <= maxRenames; i++ {
+		path := fmt.Sprintf("%s (%d)%s", base, i, ext)
+
+		exists, err := utils.Io.Exists(utils.Fs, path)
+		if err != nil {
+			return "", err
+		}
+
+		if !exists {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s: no free name after %d tries", dst, maxRenames)
+}
+
+// storedValidators describe the version of the existing file at dst, with the validators of its sidecar file.
+// if_changed sends the ETag, if_newer the Last-Modified date. Without them, the modification time of the file is sent.
+func storedValidators(dst, policy string) *net.Validators {
+	sidecar := &Sidecar{}
+	if fc, err := utils.Io.ReadFile(utils.Fs, dst+SidecarExtension); err == nil {
+		// an invalid sidecar file is the same as no sidecar file
+		json.Unmarshal(fc, sidecar)
+	}
+
+	if policy == config.OnExistsIfChanged && sidecar.ETag != "" {
+		return &net.Validators{ETag: sidecar.ETag}
+	}
+
+	if sidecar.LastModified != "" {
+		return &net.Validators{LastModified: sidecar.LastModified}
+	}
+
+	if info, err := utils.Fs.Stat(dst); err == nil {
+		return &net.Validators{LastModified: info.ModTime().UTC().Format(http.TimeFormat)}
+	}
+
+	return &net.Validators{}
+}
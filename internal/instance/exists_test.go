@@ -0,0 +1,193 @@
+package instance
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+	"github.com/spf13/afero"
+)
+
+func TestOnExists(t *testing.T) {
+	root := tu.GetOSRoot()
+	global := filepath.Join(root, "global")
+	directory := filepath.Join(global, "example")
+
+	// the version of the file served, changed between the runs
+	version := "1"
+	modified := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/file.txt" {
+			w.Write([]byte(`<a href="/file.txt">file</a>`))
+			return
+		}
+
+		w.Header().Set("ETag", `"`+version+`"`)
+		http.ServeContent(w, r, "file.txt", modified, strings.NewReader("version "+version))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		Name     string
+		OnExists string
+		Flag     string
+		// whether the server has a new version for the second run
+		Change    bool
+		WantFiles map[string]string
+		// the reason of the skipped download of the second run, if any
+		WantReason string
+	}{
+		{
+			Name:       "skip",
+			OnExists:   "skip",
+			Change:     true,
+			WantFiles:  map[string]string{"file.txt": "version 1"},
+			WantReason: "file already exists",
+		},
+		{
+			Name:      "overwrite",
+			OnExists:  "overwrite",
+			Change:    true,
+			WantFiles: map[string]string{"file.txt": "version 2"},
+		},
+		{
+			Name:      "rename",
+			OnExists:  "rename",
+			Change:    true,
+			WantFiles: map[string]string{"file.txt": "version 1", "file (1).txt": "version 2"},
+		},
+		{
+			Name:       "if_changed, same version",
+			OnExists:   "if_changed",
+			WantFiles:  map[string]string{"file.txt": "version 1"},
+			WantReason: "not modified",
+		},
+		{
+			Name:      "if_changed, new version",
+			OnExists:  "if_changed",
+			Change:    true,
+			WantFiles: map[string]string{"file.txt": "version 2"},
+		},
+		{
+			Name:       "if_newer, same version",
+			OnExists:   "if_newer",
+			WantFiles:  map[string]string{"file.txt": "version 1"},
+			WantReason: "not modified",
+		},
+		{
+			Name:      "if_newer, new version",
+			OnExists:  "if_newer",
+			Change:    true,
+			WantFiles: map[string]string{"file.txt": "version 2"},
+		},
+		{
+			Name:      "the flag replaces the attribute",
+			OnExists:  "skip",
+			Flag:      "overwrite",
+			Change:    true,
+			WantFiles: map[string]string{"file.txt": "version 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+			version = "1"
+			modified = time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+
+			cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(global)+`"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "file" {
+		pattern = "<a href=\"([^\"]+)\">"
+		capture = 1
+		on_exists = "`+tt.OnExists+`"
+	}
+}`), "test.hcl")
+			if diags.HasErrors() {
+				tc.Fatal(diags)
+			}
+
+			g := &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{OnExists: tt.Flag}}
+
+			reasons := make([]string, 0)
+			g.OnEvent(func(e *Event) {
+				switch e.Type {
+				case EventDownloadSkipped:
+					reasons = append(reasons, e.Reason)
+				case EventDownloadFailed:
+					tc.Errorf("got: %s, want no failures", e.Error)
+				}
+			})
+
+			if err := g.Run([]string{ts.URL}); err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			if tt.Change {
+				version = "2"
+				modified = modified.Add(time.Hour)
+			}
+
+			if err := g.Run([]string{ts.URL}); err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			for name, want := range tt.WantFiles {
+				if got, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(directory, name)); string(got) != want {
+					tc.Errorf("got: %q, want %s to contain %q", got, name, want)
+				}
+			}
+
+			if tt.WantReason == "" && len(reasons) > 0 || tt.WantReason != "" && (len(reasons) != 1 || reasons[0] != tt.WantReason) {
+				tc.Errorf("got: %q, want: %q", reasons, tt.WantReason)
+			}
+		})
+	}
+}
+
+// statErrorFs fails to tell whether any file exists, like a directory without permissions
+type statErrorFs struct {
+	afero.Fs
+}
+
+func (statErrorFs) Stat(name string) (os.FileInfo, error) {
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrPermission}
+}
+
+func TestRenamed(t *testing.T) {
+	root := tu.GetOSRoot()
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+	utils.Io.WriteFile(utils.Fs, filepath.Join(root, "a.jpg"), []byte("a"), 0644)
+	utils.Io.WriteFile(utils.Fs, filepath.Join(root, "a (1).jpg"), []byte("a"), 0644)
+
+	if got, err := renamed(filepath.Join(root, "a.jpg")); err != nil || got != filepath.Join(root, "a (2).jpg") {
+		t.Errorf("got: %s, %v, want: %s", got, err, filepath.Join(root, "a (2).jpg"))
+	}
+
+	if got, err := renamed(filepath.Join(root, "b")); err != nil || got != filepath.Join(root, "b (1)") {
+		t.Errorf("got: %s, %v, want: %s", got, err, filepath.Join(root, "b (1)"))
+	}
+
+	utils.Fs = statErrorFs{utils.Fs}
+
+	if got, err := renamed(filepath.Join(root, "a.jpg")); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("got: %s, %v, want: %v", got, err, os.ErrPermission)
+	}
+}
@@ -0,0 +1,62 @@
+package instance
+
+import (
+	"strconv"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/net"
+)
+
+// filtered returns why the url of the asset is not downloaded because of its "include" and "exclude"
+// attributes, an empty string if it is downloaded
+func (s *Grab) filtered(asset config.AssetConfig, url string) string {
+	if asset.Include != nil && !s.RegexCache[*asset.Include].MatchString(url) {
+		return "not matched by include"
+	}
+
+	if asset.Exclude != nil && s.RegexCache[*asset.Exclude].MatchString(url) {
+		return "matched by exclude"
+	}
+
+	return ""
+}
+
+// downloadFilter returns the filter of the "accept", "min_size" and "max_size" attributes of the asset,
+// nil if it has none
+func downloadFilter(asset config.AssetConfig) *net.Filter {
+	filter := &net.Filter{}
+
+	if asset.Accept != nil {
+		filter.Accept = *asset.Accept
+	}
+
+	filter.MinSize, filter.MaxSize = asset.SizeLimits()
+
+	if len(filter.Accept) == 0 && filter.MinSize == 0 && filter.MaxSize == 0 {
+		return nil
+	}
+
+	return filter
+}
+
+// filterConfig sets the attributes of the asset from a filter of a plan
+func filterConfig(asset *config.AssetConfig, filter *net.Filter) {
+	if filter == nil {
+		return
+	}
+
+	if len(filter.Accept) > 0 {
+		accept := append([]string{}, filter.Accept...)
+		asset.Accept = &accept
+	}
+
+	if filter.MinSize > 0 {
+		minSize := strconv.FormatInt(filter.MinSize, 10)
+		asset.MinSize = &minSize
+	}
+
+	if filter.MaxSize > 0 {
+		maxSize := strconv.FormatInt(filter.MaxSize, 10)
+		asset.MaxSize = &maxSize
+	}
+}
@@ -0,0 +1,111 @@
+package instance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+	"golang.org/x/exp/slices"
+)
+
+func TestAssetFilters(t *testing.T) {
+	root := tu.GetOSRoot()
+	global := filepath.Join(root, "global")
+	directory := filepath.Join(global, "example")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a.png", "/thumb_a.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(strings.Repeat("a", 2000)))
+		case "/b.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("b"))
+		case "/c.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(strings.Repeat("c", 2000)))
+		default:
+			w.Write([]byte(`<a href="/a.png"></a><a href="/thumb_a.png"></a><a href="/b.png"></a><a href="/c.txt"></a><a href="/d.zip"></a>`))
+		}
+	}))
+	defer ts.Close()
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+	cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(global)+`"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "file" {
+		pattern = "<a href=\"([^\"]+)\">"
+		capture = 1
+		find_all = true
+
+		include = "\\.(png|txt)$"
+		exclude = "thumb_"
+		accept = ["image/*"]
+		min_size = "1KB"
+		max_size = "1MiB"
+	}
+}`), "test.hcl")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	g := &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{}}
+
+	reasons := make([]string, 0)
+	g.OnEvent(func(e *Event) {
+		switch e.Type {
+		case EventDownloadSkipped:
+			reasons = append(reasons, filepath.Base(e.Source)+": "+e.Reason)
+		case EventDownloadFailed:
+			t.Errorf("got: %s, want no failures", e.Error)
+		}
+	})
+
+	if err := g.Run([]string{ts.URL}); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	wantReasons := []string{
+		"b.png: size of 1 bytes is below min_size",
+		"c.txt: content type text/plain is not accepted",
+		"d.zip: not matched by include",
+		"thumb_a.png: matched by exclude",
+	}
+
+	sort.Strings(reasons)
+	if !slices.Equal(reasons, wantReasons) {
+		t.Errorf("got: %q, want: %q", reasons, wantReasons)
+	}
+
+	for _, name := range []string{"a.png", "thumb_a.png", "b.png", "c.txt", "d.zip"} {
+		if exists, _ := utils.Io.Exists(utils.Fs, filepath.Join(directory, name)); exists != (name == "a.png") {
+			t.Errorf("got: %v, want %s to exist: %v", exists, name, name == "a.png")
+		}
+	}
+
+	// the filters survive a plan
+	plan := g.Plan()
+	if got := plan.Sites[0].Assets[0].Filter; got == nil || got.MinSize != 1000 || got.MaxSize != 1<<20 || !slices.Equal(got.Accept, []string{"image/*"}) {
+		t.Errorf("got: %+v, want the filter of the asset", got)
+	}
+
+	applied := &Grab{Flags: &FlagsState{}}
+	applied.ApplyPlan(plan, global)
+
+	if got := downloadFilter(applied.Config.Sites[0].Assets[0]); got == nil || got.MinSize != 1000 || got.MaxSize != 1<<20 {
+		t.Errorf("got: %+v, want the filter of the plan", got)
+	}
+}
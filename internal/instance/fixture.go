@@ -0,0 +1,209 @@
+package instance
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/exp/slices"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+type FixtureDiff struct {
+	// the kind of value being compared ("asset", "filename" or "info")
+	Kind string
+	// the name of the asset or info block
+	Name string
+	// values that were expected but not produced
+	Missing []string
+	// values that were produced but not expected
+	Unexpected []string
+}
+
+type FixtureResult struct {
+	// the name of the site block
+	Site string
+	// the name of the fixture block
+	Name string
+	// errors that prevented the fixture from running
+	Diagnostics hcl.Diagnostics
+	// differences between the expected and the actual values
+	Diffs []FixtureDiff
+}
+
+func (r *FixtureResult) Passed() bool {
+	return !r.Diagnostics.HasErrors() && len(r.Diffs) == 0
+}
+
+// returns the path of the saved page of the fixture, relative paths start from the directory of the configuration
+func (s *Grab) fixturePath(fixture config.FixtureConfig) string {
+	if filepath.IsAbs(fixture.File) {
+		return fixture.File
+	}
+
+	return filepath.Join(filepath.Dir(s.Flags.ConfigPath), fixture.File)
+}
+
+// RunFixtures scrapes the saved page of every fixture block, without touching the network,
+// and compares the results with the expected values.
+func (s *Grab) RunFixtures() []*FixtureResult {
+	results := make([]*FixtureResult, 0)
+
+	for siteIndex, site := range s.Config.Sites {
+		for _, fixture := range site.Fixtures {
+			log.Trace().Str("site", site.Name).Str("fixture", fixture.Name).Msg("running fixture")
+
+			results = append(results, s.runFixture(siteIndex, fixture))
+		}
+	}
+
+	return results
+}
+
+func (s *Grab) runFixture(siteIndex int, fixture config.FixtureConfig) *FixtureResult {
+	site := &s.Config.Sites[siteIndex]
+
+	result := &FixtureResult{
+		Site: site.Name,
+		Name: fixture.Name,
+	}
+
+	// the url must be handled by the site that declares the fixture
+	matches := s.MatchSites(fixture.URL)
+	if !slices.Contains(matches, siteIndex) {
+		result.Diagnostics = append(result.Diagnostics, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Fixture url not matched",
+			Detail:   fmt.Sprintf("The url '%s' does not match the test pattern of the site \"%s\".", fixture.URL, site.Name),
+		})
+		return result
+	}
+
+	if matches[0] != siteIndex {
+		result.Diagnostics = append(result.Diagnostics, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Fixture url matched by another site",
+			Detail:   fmt.Sprintf("The url '%s' is matched by the site \"%s\" first.", fixture.URL, s.Config.Sites[matches[0]].Name),
+		})
+		return result
+	}
+
+	fc, err := utils.Io.ReadFile(utils.Fs, s.fixturePath(fixture))
+	if err != nil {
+		result.Diagnostics = append(result.Diagnostics, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Could not read fixture file",
+			Detail:   err.Error(),
+		})
+		return result
+	}
+
+	// start from a clean state, previous fixtures could have filled the computed fields
+	for i := range site.Assets {
+		site.Assets[i].Downloads = nil
+	}
+	site.InfoMap = nil
+
+	if diags := s.ScrapePage(siteIndex, fixture.URL, string(fc)); diags.HasErrors() {
+		result.Diagnostics = append(result.Diagnostics, *diags...)
+		return result
+	}
+
+	if fixture.Assets != nil {
+		for _, name := range sortedKeys(*fixture.Assets) {
+			got := make([]string, 0)
+			for _, asset := range site.Assets {
+				if asset.Name == name {
+					for src := range asset.Downloads {
+						got = append(got, src)
+					}
+				}
+			}
+
+			result.compare("asset", name, (*fixture.Assets)[name], got)
+		}
+	}
+
+	if fixture.Filenames != nil {
+		for _, name := range sortedKeys(*fixture.Filenames) {
+			got := make([]string, 0)
+			for _, asset := range site.Assets {
+				if asset.Name == name {
+					for _, dst := range asset.Downloads {
+						got = append(got, s.relativeToLocation(dst))
+					}
+				}
+			}
+
+			result.compare("filename", name, (*fixture.Filenames)[name], got)
+		}
+	}
+
+	if fixture.Info != nil {
+		// a single page was scraped, so there is exactly one info map
+		infoMap := make(map[string]string, 0)
+		for _, infos := range site.InfoMap {
+			if len(infos) > 0 {
+				infoMap = infos[0]
+			}
+		}
+
+		for _, name := range sortedKeys(*fixture.Info) {
+			got := make([]string, 0)
+			if value, ok := infoMap[name]; ok {
+				got = append(got, value)
+			}
+
+			result.compare("info", name, []string{(*fixture.Info)[name]}, got)
+		}
+	}
+
+	return result
+}
+
+func (r *FixtureResult) compare(kind, name string, want, got []string) {
+	diff := FixtureDiff{Kind: kind, Name: name}
+
+	for _, w := range want {
+		if !utils.Contains(got, w) {
+			diff.Missing = append(diff.Missing, w)
+		}
+	}
+
+	for _, g := range got {
+		if !utils.Contains(want, g) {
+			diff.Unexpected = append(diff.Unexpected, g)
+		}
+	}
+
+	sort.Strings(diff.Missing)
+	sort.Strings(diff.Unexpected)
+
+	if len(diff.Missing) > 0 || len(diff.Unexpected) > 0 {
+		r.Diffs = append(r.Diffs, diff)
+	}
+}
+
+// returns the slash separated path of dst relative to global.location
+func (s *Grab) relativeToLocation(dst string) string {
+	rel, err := filepath.Rel(s.Config.Global.Location, dst)
+	if err != nil {
+		return filepath.ToSlash(dst)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
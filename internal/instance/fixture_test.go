@@ -0,0 +1,205 @@
+package instance
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+const fixturePage = `<html>
+<head><title>My awesome gallery</title></head>
+<body>
+  <p>Curated by @everdrone</p>
+  <img src="https://cdn.example.com/img/a.jpg" />
+  <img src="/img/b.jpg" />
+</body>
+</html>`
+
+func TestRunFixtures(t *testing.T) {
+	root := tu.GetOSRoot()
+	globalLocation := filepath.Join(root, "global")
+	configPath := filepath.Join(root, "config", "grab.hcl")
+
+	site := `
+global {
+	location = "` + tu.EscapeHCLString(globalLocation) + `"
+}
+
+site "example" {
+	test = ":\\/\\/example\\.com"
+
+	asset "image" {
+		pattern  = "<img src=\"([^\"]+)"
+		capture  = 1
+		find_all = true
+	}
+
+	info "title" {
+		pattern = "<title>([^<]+)"
+		capture = 1
+	}
+
+	subdirectory {
+		pattern = "gallery\\/(\\d+)"
+		capture = 1
+		from    = url
+	}
+`
+
+	tests := []struct {
+		Name   string
+		Config string
+		Want   []*FixtureResult
+	}{
+		{
+			Name: "passes",
+			Config: site + `
+	fixture "gallery" {
+		url  = "https://example.com/gallery/1337"
+		file = "fixtures/gallery.html"
+
+		assets = {
+			image = ["https://cdn.example.com/img/a.jpg", "https://example.com/img/b.jpg"]
+		}
+
+		filenames = {
+			image = ["example/1337/a.jpg", "example/1337/b.jpg"]
+		}
+
+		info = {
+			title = "My awesome gallery"
+		}
+	}
+}`,
+			Want: []*FixtureResult{
+				{Site: "example", Name: "gallery"},
+			},
+		},
+		{
+			Name: "reports differences",
+			Config: site + `
+	fixture "gallery" {
+		url  = "https://example.com/gallery/1337"
+		file = "fixtures/gallery.html"
+
+		assets = {
+			image = ["https://cdn.example.com/img/a.jpg", "https://cdn.example.com/img/c.jpg"]
+		}
+
+		filenames = {
+			image = ["example/1337/a.jpg", "example/1337/b.jpg"]
+		}
+
+		info = {
+			title  = "Another gallery"
+			author = "everdrone"
+		}
+	}
+}`,
+			Want: []*FixtureResult{
+				{
+					Site: "example",
+					Name: "gallery",
+					Diffs: []FixtureDiff{
+						{
+							Kind:       "asset",
+							Name:       "image",
+							Missing:    []string{"https://cdn.example.com/img/c.jpg"},
+							Unexpected: []string{"https://example.com/img/b.jpg"},
+						},
+						{
+							Kind:    "info",
+							Name:    "author",
+							Missing: []string{"everdrone"},
+						},
+						{
+							Kind:       "info",
+							Name:       "title",
+							Missing:    []string{"Another gallery"},
+							Unexpected: []string{"My awesome gallery"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			utils.Io.WriteFile(utils.Fs, filepath.Join(root, "config", "fixtures", "gallery.html"), []byte(fixturePage), os.ModePerm)
+
+			g := New(nil)
+			g.Flags = &FlagsState{ConfigPath: configPath}
+
+			config, _, regexCache, diags := config.Parse([]byte(tt.Config), configPath)
+			if diags.HasErrors() {
+				tc.Fatalf("got errors: %+v", diags)
+			}
+			g.Config = config
+			g.RegexCache = regexCache
+
+			got := g.RunFixtures()
+
+			if !reflect.DeepEqual(got, tt.Want) {
+				tc.Errorf("got: %+v, want: %+v", got, tt.Want)
+			}
+		})
+	}
+
+	t.Run("fails without fixture file", func(tc *testing.T) {
+		utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+		g := New(nil)
+		g.Flags = &FlagsState{ConfigPath: configPath}
+
+		config, _, regexCache, diags := config.Parse([]byte(site+`
+	fixture "missing" {
+		url  = "https://example.com/gallery/1"
+		file = "fixtures/missing.html"
+	}
+}`), configPath)
+		if diags.HasErrors() {
+			tc.Fatalf("got errors: %+v", diags)
+		}
+		g.Config = config
+		g.RegexCache = regexCache
+
+		got := g.RunFixtures()
+
+		if len(got) != 1 || got[0].Passed() {
+			tc.Fatalf("got: %+v, want a failing result", got)
+		}
+	})
+
+	t.Run("fails when the url is not matched", func(tc *testing.T) {
+		utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+		utils.Io.WriteFile(utils.Fs, filepath.Join(root, "config", "fixtures", "gallery.html"), []byte(fixturePage), os.ModePerm)
+
+		g := New(nil)
+		g.Flags = &FlagsState{ConfigPath: configPath}
+
+		config, _, regexCache, diags := config.Parse([]byte(site+`
+	fixture "other" {
+		url  = "https://other.com/gallery/1"
+		file = "fixtures/gallery.html"
+	}
+}`), configPath)
+		if diags.HasErrors() {
+			tc.Fatalf("got errors: %+v", diags)
+		}
+		g.Config = config
+		g.RegexCache = regexCache
+
+		got := g.RunFixtures()
+
+		if len(got) != 1 || got[0].Passed() {
+			tc.Fatalf("got: %+v, want a failing result", got)
+		}
+	})
+}
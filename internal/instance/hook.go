@@ -0,0 +1,100 @@
+package instance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// HookData is rendered in the arguments of the hook commands
+type HookData struct {
+	Site        string
+	Asset       string
+	Page        string
+	Source      string
+	Destination string
+	// global.location
+	Location string
+	// the info values of the page, empty for run_done
+	Info map[string]string
+}
+
+// runHooks runs the hooks for the event on, in the order of the lists and of their declaration.
+// A failure is returned in strict mode, otherwise it is logged and the next hooks run anyway.
+func (s *Grab) runHooks(on string, data *HookData, lists ...[]config.HookConfig) error {
+	data.Location = s.Config.Global.Location
+	if data.Info == nil {
+		data.Info = make(map[string]string)
+	}
+
+	for _, hooks := range lists {
+		for _, hook := range hooks {
+			if hook.On != on {
+				continue
+			}
+
+			if err := s.runHook(hook, data); err != nil {
+				s.emit(&Event{Type: EventHookFailed, Site: data.Site, Asset: data.Asset, Page: data.Page, Source: data.Source, Destination: data.Destination, Error: err.Error()})
+
+				if s.Flags.Strict {
+					log.Err(err).Str("on", on).Msg("hook failed")
+					return err
+				}
+
+				log.Warn().Err(err).Str("on", on).Msg("hook failed")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Grab) runHook(hook config.HookConfig, data *HookData) error {
+	argv := make([]string, 0, len(hook.Command))
+
+	for _, arg := range hook.Command {
+		tmpl, err := config.ParseHookTemplate(arg)
+		if err != nil {
+			return err
+		}
+
+		buf := &bytes.Buffer{}
+		if err := tmpl.Execute(buf, data); err != nil {
+			return err
+		}
+
+		argv = append(argv, buf.String())
+	}
+
+	parent := s.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	ctx, cancel := context.WithTimeout(parent, hook.Duration())
+	defer cancel()
+
+	log.Info().Strs("command", argv).Msg("running hook")
+
+	output, err := exec.CommandContext(ctx, argv[0], argv[1:]...).CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s: timed out after %s", argv[0], hook.Duration())
+	}
+
+	if err != nil {
+		if trimmed := strings.TrimSpace(string(output)); trimmed != "" {
+			return fmt.Errorf("%s: %w: %s", argv[0], err, trimmed)
+		}
+
+		return fmt.Errorf("%s: %w", argv[0], err)
+	}
+
+	log.Debug().Strs("command", argv).Str("output", string(output)).Msg("hook finished")
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package instance
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+// TestHookHelper is the command of the hooks: when GRAB_HOOK_LOG is set, it appends its arguments to that file.
+// The argument "fail" makes it exit with an error, "sleep" makes it hang.
+func TestHookHelper(t *testing.T) {
+	path := os.Getenv("GRAB_HOOK_LOG")
+	if path == "" {
+		return
+	}
+
+	args := os.Args
+	for i, arg := range os.Args {
+		if arg == "--" {
+			args = os.Args[i+1:]
+			break
+		}
+	}
+
+	switch args[0] {
+	case "fail":
+		os.Stdout.WriteString("something went wrong")
+		os.Exit(1)
+	case "sleep":
+		time.Sleep(10 * time.Second)
+	}
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f.WriteString(strings.Join(args, " ") + "\n")
+	f.Close()
+
+	os.Exit(0)
+}
+
+// returns the command that runs TestHookHelper with the arguments
+func helperCommand(args ...string) string {
+	quoted := []string{os.Args[0], "-test.run=^TestHookHelper$", "--"}
+	quoted = append(quoted, args...)
+
+	for i, arg := range quoted {
+		quoted[i] = `"` + tu.EscapeHCLString(arg) + `"`
+	}
+
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func TestHooks(t *testing.T) {
+	root := tu.GetOSRoot()
+	location := filepath.Join(root, "global")
+	log := filepath.Join(t.TempDir(), "hooks.log")
+
+	t.Setenv("GRAB_HOOK_LOG", log)
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	parse := func(hooks string) *Grab {
+		t.Helper()
+
+		cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(location)+`"
+
+	hook {
+		on = "run_done"
+		command = `+helperCommand("run", "{{.Location}}")+`
+	}
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/[ab][^\"]+)"
+		capture = 1
+		find_all = true
+
+		hook {
+			on = "asset_downloaded"
+			command = `+helperCommand("asset", "{{.Asset}}", "{{.Destination}}")+`
+		}
+	}
+
+	info "title" {
+		pattern = "<title>([^<]+)"
+		capture = 1
+	}
+
+	`+hooks+`
+}
+`), "grab.hcl")
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+
+		return &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{}}
+	}
+
+	readLog := func() []string {
+		t.Helper()
+
+		fc, _ := os.ReadFile(log)
+		os.Remove(log)
+
+		return strings.Split(strings.TrimSpace(string(fc)), "\n")
+	}
+
+	t.Run("events", func(t *testing.T) {
+		utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+		g := parse(`hook {
+		on = "page_done"
+		command = ` + helperCommand("page", "{{.Info.title}}", "{{.Info.missing}}", "{{.Page}}") + `
+	}`)
+
+		if err := g.Run([]string{ts.URL + "/gallery/123/test"}); err != nil {
+			t.Fatalf("got: %v, want: nil", err)
+		}
+
+		lines := readLog()
+		want := []string{
+			"asset image " + filepath.Join(location, "example", "a.jpg"),
+			"asset image " + filepath.Join(location, "example", "b.jpg"),
+			"page Grab Test Server  " + ts.URL + "/gallery/123/test",
+			"run " + location,
+		}
+
+		if len(lines) != len(want) {
+			t.Fatalf("got: %q, want: %q", lines, want)
+		}
+
+		// the downloads of a page are not ordered
+		if !utils.Contains(lines[:2], want[0]) || !utils.Contains(lines[:2], want[1]) || lines[2] != want[2] || lines[3] != want[3] {
+			t.Errorf("got: %q, want: %q", lines, want)
+		}
+
+		// the files exist already, the page and the run are done anyway
+		if err := g.Run([]string{ts.URL + "/gallery/123/test"}); err != nil {
+			t.Fatalf("got: %v, want: nil", err)
+		}
+
+		if lines := readLog(); len(lines) != 2 || lines[0] != want[2] || lines[1] != want[3] {
+			t.Errorf("got: %q, want: %q", lines, want[2:])
+		}
+
+		g.Flags.DryRun = true
+		if err := g.Run([]string{ts.URL + "/gallery/123/test"}); err != nil {
+			t.Fatalf("got: %v, want: nil", err)
+		}
+
+		if lines := readLog(); len(lines) != 1 || lines[0] != "" {
+			t.Errorf("got: %q, want no hooks in a dry run", lines)
+		}
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+		g := parse(`hook {
+		on = "page_done"
+		command = ` + helperCommand("fail") + `
+	}
+
+	hook {
+		on = "page_done"
+		command = ` + helperCommand("sleep") + `
+		timeout = "100ms"
+	}`)
+
+		failures := make([]string, 0)
+		g.OnEvent(func(e *Event) {
+			if e.Type == EventHookFailed {
+				failures = append(failures, e.Error)
+			}
+		})
+
+		if err := g.Run([]string{ts.URL + "/gallery/123/test"}); err != nil {
+			t.Fatalf("got: %v, want: nil", err)
+		}
+
+		if len(failures) != 2 || !strings.HasSuffix(failures[0], "exit status 1: something went wrong") || !strings.HasSuffix(failures[1], "timed out after 100ms") {
+			t.Errorf("got: %q, want an error and a timeout", failures)
+		}
+
+		// the run goes on
+		if lines := readLog(); len(lines) != 3 || lines[2] != "run "+location {
+			t.Errorf("got: %q, want the asset and run hooks", lines)
+		}
+
+		g.Flags.Strict = true
+		g.Flags.Force = true
+		if err := g.Run([]string{ts.URL + "/gallery/123/test"}); err == nil || !strings.Contains(err.Error(), "something went wrong") {
+			t.Errorf("got: %v, want the error of the hook", err)
+		}
+
+		if lines := readLog(); len(lines) != 2 {
+			t.Errorf("got: %q, want the asset hooks only", lines)
+		}
+	})
+}
@@ -0,0 +1,481 @@
+package instance
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/spf13/afero"
+
+	// registers the "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+// InfoFileName is the name of the info files of the json index
+const InfoFileName = "_info.json"
+
+// InfoRecord is the info of a page, as stored in the index
+type InfoRecord struct {
+	Site string `json:"site"`
+	// slash separated, relative to global.location
+	Directory string `json:"directory"`
+	// the info values, with the url of the page and the time it was scraped
+	Values map[string]string `json:"info"`
+}
+
+// Index stores the info records of the runs
+type Index interface {
+	// Write stores the records of a site, merged with the policy of its subdirectory block
+	Write(records []InfoRecord, merge string) error
+	// Read returns all the stored records
+	Read() ([]InfoRecord, error)
+	// Destination returns the file the record is written to
+	Destination(record InfoRecord) string
+}
+
+// NewIndex returns the index selected by the global block
+func NewIndex(global config.GlobalConfig) (Index, error) {
+	switch format := global.IndexFormat(); format {
+	case config.IndexJSON:
+		return &jsonIndex{location: global.Location}, nil
+	case config.IndexNDJSON:
+		return &ndjsonIndex{path: global.IndexPath()}, nil
+	case config.IndexCSV:
+		return &csvIndex{path: global.IndexPath()}, nil
+	case config.IndexSQLite:
+		return &sqliteIndex{path: global.IndexPath()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported index format %q", format)
+	}
+}
+
+// EncodeRecords writes the records to w in one of the export formats: csv, json or ndjson
+func EncodeRecords(w io.Writer, format string, records []InfoRecord) error {
+	switch format {
+	case "csv":
+		return encodeCSV(w, records)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(records)
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// returns the absolute directory of the record
+func recordDirectory(location string, record InfoRecord) string {
+	dir := filepath.FromSlash(record.Directory)
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+
+	return filepath.Join(location, dir)
+}
+
+// MARK: - json
+
+// an _info.json file in every directory, overwritten by every run.
+// The file contains an object, or an array of objects if the append policy kept several pages.
+type jsonIndex struct {
+	location string
+}
+
+func (i *jsonIndex) Destination(record InfoRecord) string {
+	return filepath.Join(recordDirectory(i.location, record), InfoFileName)
+}
+
+func (i *jsonIndex) Write(records []InfoRecord, merge string) error {
+	// the values of every file, in the order of the records
+	files := make(map[string][]map[string]string)
+	order := make([]string, 0)
+
+	for _, record := range records {
+		dst := i.Destination(record)
+		if _, ok := files[dst]; !ok {
+			order = append(order, dst)
+		}
+
+		files[dst] = append(files[dst], record.Values)
+	}
+
+	for _, dst := range order {
+		if err := utils.Fs.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+			return err
+		}
+
+		var content interface{} = files[dst]
+		if merge != config.InfoMergeAppend || len(files[dst]) == 1 {
+			// the records are already merged, or the page is alone in its directory
+			content = files[dst][len(files[dst])-1]
+		}
+
+		marshaled, err := json.MarshalIndent(content, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if err := utils.Io.WriteFile(utils.Fs, dst, marshaled, os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *jsonIndex) Read() ([]InfoRecord, error) {
+	records := make([]InfoRecord, 0)
+
+	if exists, _ := utils.Io.Exists(utils.Fs, i.location); !exists {
+		return records, nil
+	}
+
+	err := afero.Walk(utils.Fs, i.location, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || info.Name() != InfoFileName {
+			return nil
+		}
+
+		fc, err := utils.Io.ReadFile(utils.Fs, path)
+		if err != nil {
+			return err
+		}
+
+		// an object, or an array of objects with the append policy
+		list := make([]map[string]string, 0)
+		if err := json.Unmarshal(fc, &list); err != nil {
+			values := make(map[string]string)
+			if err := json.Unmarshal(fc, &values); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+
+			list = append(list, values)
+		}
+
+		rel, _ := filepath.Rel(i.location, filepath.Dir(path))
+		rel = filepath.ToSlash(rel)
+
+		// the sites are the first level of directories
+		site, _, _ := strings.Cut(rel, "/")
+
+		for _, values := range list {
+			records = append(records, InfoRecord{Site: site, Directory: rel, Values: values})
+		}
+
+		return nil
+	})
+
+	return records, err
+}
+
+// MARK: - ndjson
+
+// a line per record, appended to a single file
+type ndjsonIndex struct {
+	path string
+}
+
+func (i *ndjsonIndex) Destination(InfoRecord) string {
+	return i.path
+}
+
+func (i *ndjsonIndex) Write(records []InfoRecord, _ string) error {
+	buf := &bytes.Buffer{}
+	if err := EncodeRecords(buf, "ndjson", records); err != nil {
+		return err
+	}
+
+	if err := utils.Fs.MkdirAll(filepath.Dir(i.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := utils.Fs.OpenFile(i.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	// a single write, so that concurrent runs do not mix their lines
+	_, err = file.Write(buf.Bytes())
+	return err
+}
+
+func (i *ndjsonIndex) Read() ([]InfoRecord, error) {
+	records := make([]InfoRecord, 0)
+
+	file, err := utils.Fs.Open(i.path)
+	if os.IsNotExist(err) {
+		return records, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var record InfoRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", i.path, line, err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+// MARK: - csv
+
+// a row per record in a single file, rewritten with a column per info value
+type csvIndex struct {
+	path string
+}
+
+func (i *csvIndex) Destination(InfoRecord) string {
+	return i.path
+}
+
+func (i *csvIndex) Write(records []InfoRecord, _ string) error {
+	// the new values can add columns, so the file is written again
+	existing, err := i.Read()
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := encodeCSV(buf, append(existing, records...)); err != nil {
+		return err
+	}
+
+	if err := utils.Fs.MkdirAll(filepath.Dir(i.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return utils.Io.WriteFile(utils.Fs, i.path, buf.Bytes(), os.ModePerm)
+}
+
+func (i *csvIndex) Read() ([]InfoRecord, error) {
+	records := make([]InfoRecord, 0)
+
+	fc, err := utils.Io.ReadFile(utils.Fs, i.path)
+	if os.IsNotExist(err) {
+		return records, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(fc)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", i.path, err)
+	}
+
+	if len(rows) == 0 {
+		return records, nil
+	}
+
+	header := rows[0]
+	if len(header) < 2 || header[0] != "site" || header[1] != "directory" {
+		return nil, fmt.Errorf("%s: the first columns must be \"site\" and \"directory\"", i.path)
+	}
+
+	for _, row := range rows[1:] {
+		record := InfoRecord{Site: row[0], Directory: row[1], Values: make(map[string]string)}
+
+		for j, key := range header[2:] {
+			// empty cells are values missing from the page
+			if value := row[j+2]; value != "" {
+				record.Values[key] = value
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// writes a header and a row per record, the columns are the site, the directory,
+// the url, the timestamp and the other info values sorted by name
+func encodeCSV(w io.Writer, records []InfoRecord) error {
+	keys := make(map[string]bool)
+	for _, record := range records {
+		for key := range record.Values {
+			keys[key] = true
+		}
+	}
+
+	columns := make([]string, 0, len(keys))
+	for key := range keys {
+		if key != "url" && key != "timestamp" {
+			columns = append(columns, key)
+		}
+	}
+
+	sort.Strings(columns)
+	columns = append([]string{"url", "timestamp"}, columns...)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append([]string{"site", "directory"}, columns...)); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := []string{record.Site, record.Directory}
+		for _, column := range columns {
+			row = append(row, record.Values[column])
+		}
+
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// MARK: - sqlite
+
+// a row per record in a single database, the info values are stored as a JSON object
+type sqliteIndex struct {
+	path string
+}
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS info (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	site      TEXT NOT NULL,
+	directory TEXT NOT NULL,
+	url       TEXT NOT NULL,
+	timestamp TEXT NOT NULL,
+	info      TEXT NOT NULL
+)`
+
+func (i *sqliteIndex) Destination(InfoRecord) string {
+	return i.path
+}
+
+// opens the database, creating it if needed. The database is always on the disk of the OS.
+func (i *sqliteIndex) open() (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(i.path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	// wait for the other runs writing at the same time
+	db, err := sql.Open("sqlite", "file:"+filepath.ToSlash(i.path)+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func (i *sqliteIndex) Write(records []InfoRecord, _ string) error {
+	db, err := i.open()
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		values, err := json.Marshal(record.Values)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if _, err := tx.Exec(
+			"INSERT INTO info (site, directory, url, timestamp, info) VALUES (?, ?, ?, ?, ?)",
+			record.Site, record.Directory, record.Values["url"], record.Values["timestamp"], string(values),
+		); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (i *sqliteIndex) Read() ([]InfoRecord, error) {
+	records := make([]InfoRecord, 0)
+
+	if _, err := os.Stat(i.path); os.IsNotExist(err) {
+		return records, nil
+	}
+
+	db, err := i.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer db.Close()
+
+	rows, err := db.Query("SELECT site, directory, info FROM info ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var record InfoRecord
+		var values string
+
+		if err := rows.Scan(&record.Site, &record.Directory, &values); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(values), &record.Values); err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
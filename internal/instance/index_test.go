@@ -0,0 +1,197 @@
+package instance
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestIndex(t *testing.T) {
+	root := tu.GetOSRoot()
+	location := filepath.Join(root, "global")
+
+	first := []InfoRecord{
+		{Site: "example", Directory: "example/1", Values: map[string]string{"url": "https://example.com/1", "timestamp": "t1", "title": "One"}},
+	}
+	second := []InfoRecord{
+		{Site: "example", Directory: "example/1", Values: map[string]string{"url": "https://example.com/1", "timestamp": "t2", "title": "One, again"}},
+		{Site: "other", Directory: "other", Values: map[string]string{"url": "https://other.com/2", "timestamp": "t3", "author": "someone"}},
+	}
+
+	tests := []struct {
+		Name   string
+		Format string
+		Path   string
+		Merge  string
+		// the records read after the two writes
+		Want []InfoRecord
+	}{
+		{
+			Name:   "json keeps the last record of a directory",
+			Format: config.IndexJSON,
+			Want:   second,
+		},
+		{
+			Name:   "json keeps every record of a directory with the append policy",
+			Format: config.IndexJSON,
+			Merge:  config.InfoMergeAppend,
+			Want: []InfoRecord{
+				second[0],
+				{Site: "example", Directory: "example/1", Values: map[string]string{"url": "https://example.com/3", "timestamp": "t4"}},
+				second[1],
+			},
+		},
+		{
+			Name:   "ndjson keeps the history",
+			Format: config.IndexNDJSON,
+			Want:   append(append([]InfoRecord{}, first...), second...),
+		},
+		{
+			Name:   "csv keeps the history",
+			Format: config.IndexCSV,
+			Path:   "data/index.csv",
+			Want:   append(append([]InfoRecord{}, first...), second...),
+		},
+		{
+			Name:   "sqlite keeps the history",
+			Format: config.IndexSQLite,
+			// the database is always on the disk of the OS
+			Path: filepath.Join(t.TempDir(), "index.db"),
+			Want: append(append([]InfoRecord{}, first...), second...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+			global := config.GlobalConfig{Location: location, Index: &config.IndexConfig{Format: tt.Format}}
+			if tt.Path != "" {
+				global.Index.Path = &tt.Path
+			}
+
+			index, err := NewIndex(global)
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			if got, err := index.Read(); err != nil || len(got) != 0 {
+				tc.Fatalf("got: %v %v, want an empty index", got, err)
+			}
+
+			merge := config.InfoMergeLast
+			if tt.Merge != "" {
+				merge = tt.Merge
+			}
+
+			if err := index.Write(first, merge); err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			records := second
+			if merge == config.InfoMergeAppend {
+				records = tt.Want
+			}
+
+			if err := index.Write(records, merge); err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			got, err := index.Read()
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.Want) {
+				tc.Errorf("got: %+v, want: %+v", got, tt.Want)
+			}
+		})
+	}
+}
+
+func TestDownloadIndex(t *testing.T) {
+	root := tu.GetOSRoot()
+	global := filepath.Join(root, "global")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+	cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(global)+`"
+
+	index {
+		format = "ndjson"
+	}
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	info "title" {
+		pattern = "<title>([^<]+)"
+		capture = 1
+	}
+}`), "test.hcl")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	g := &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{}, URLs: []string{ts.URL + "/gallery/123/test"}}
+
+	destinations := make([]string, 0)
+	g.OnEvent(func(e *Event) {
+		if e.Type == EventInfoWritten {
+			destinations = append(destinations, e.Destination)
+		}
+	})
+
+	g.BuildSiteCache()
+	if diags := g.BuildAssetCache(); diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	// the plan keeps the index
+	plan := g.Plan()
+	if plan.Index == nil || *plan.Index != (PlanIndex{Format: "ndjson", Path: "_index.ndjson"}) {
+		t.Errorf("got: %+v, want the ndjson index", plan.Index)
+	}
+
+	applied := &Grab{Flags: &FlagsState{}}
+	applied.ApplyPlan(plan, filepath.Join(root, "elsewhere"))
+
+	if got := applied.Config.Global.IndexPath(); got != filepath.Join(root, "elsewhere", "_index.ndjson") {
+		t.Errorf("got: %s, want the index in the new location", got)
+	}
+
+	if err := g.Download(); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	path := filepath.Join(global, "_index.ndjson")
+	if len(destinations) != 1 || destinations[0] != path {
+		t.Errorf("got: %v, want: %s", destinations, path)
+	}
+
+	fc, _ := utils.Io.ReadFile(utils.Fs, path)
+	if !strings.HasPrefix(string(fc), `{"site":"example","directory":"example","info":{`) || !strings.Contains(string(fc), `"title":"Grab Test Server"`) {
+		t.Errorf("got: %s, want the record of the page", fc)
+	}
+
+	if exists, _ := utils.Io.Exists(utils.Fs, filepath.Join(global, "example", InfoFileName)); exists {
+		t.Errorf("got: %s, want no info file", InfoFileName)
+	}
+}
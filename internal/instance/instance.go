@@ -1,6 +1,8 @@
 package instance
 
 import (
+	"context"
+
 	"github.com/everdrone/grab/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -12,6 +14,10 @@ type FlagsState struct {
 	Verbosity int
 	// force overwrite of downloaded assets
 	Force bool
+	// what happens to the existing files, replaces the on_exists attributes if not empty
+	OnExists string
+	// how the page cache is used ("off", "use" or "refresh"), empty for off
+	Cache string
 	// stop at the first error
 	Strict bool
 	// skip writing to the disk
@@ -20,6 +26,14 @@ type FlagsState struct {
 	Quiet bool
 	// display the progress bar
 	Progress bool
+	// the output format of the "get" command ("text" or "json")
+	Output string
+	// a Go template rendered for every downloaded file
+	Print string
+	// lists of urls read in addition to the arguments, they can be urls
+	Inputs []string
+	// the column of the csv lists
+	Column string
 }
 
 type Grab struct {
@@ -36,6 +50,13 @@ type Grab struct {
 	TotalAssets int64
 	// a map of all the regular expressions to be used
 	RegexCache config.RegexCacheMap
+	// when done, the run stops before the next page or download. Optional.
+	Context context.Context
+	// shared with the other instances to limit the downloads running at the same time. Optional.
+	Pool Pool
+
+	// the handlers registered with OnEvent
+	eventHandlers []EventHandler
 }
 
 func New(cmd *cobra.Command) *Grab {
@@ -43,3 +64,12 @@ func New(cmd *cobra.Command) *Grab {
 		Command: cmd,
 	}
 }
+
+// returns the error of the context of the instance if it is done, nil otherwise
+func (s *Grab) canceled() error {
+	if s.Context == nil {
+		return nil
+	}
+
+	return s.Context.Err()
+}
@@ -0,0 +1,255 @@
+package instance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	JournalPage     = "page"
+	JournalDownload = "download"
+)
+
+// JournalPath returns the path of the journal of the failed items of the downloads in location
+func JournalPath(location string) string {
+	return filepath.Join(location, ".grab", "failed.json")
+}
+
+// JournalEntry is a page that could not be fetched or an asset that could not be downloaded
+type JournalEntry struct {
+	// JournalPage or JournalDownload
+	Kind  string `json:"kind"`
+	Site  string `json:"site"`
+	Asset string `json:"asset,omitempty"`
+	// the url of the page that failed, or of the page where the asset was found
+	Page   string `json:"page,omitempty"`
+	Source string `json:"source,omitempty"`
+	// slash separated, relative to the location
+	Destination string `json:"destination,omitempty"`
+	// the expected checksum of the asset, "algorithm:digest", so that the retry is verified too
+	Checksum string    `json:"checksum,omitempty"`
+	Error    string    `json:"error"`
+	Time     time.Time `json:"time"`
+}
+
+// Journal keeps the failures of the previous runs until they succeed. Register its Handle method
+// with OnEvent to record the failures of the current run and to forget the items that succeed.
+type Journal struct {
+	Entries []*JournalEntry `json:"entries"`
+
+	location string
+	// the page where each asset was found, by source
+	pages map[string]string
+	mu    sync.Mutex
+}
+
+// LoadJournal reads the journal of location, an empty journal is returned if there is none
+func LoadJournal(location string) (*Journal, error) {
+	journal := &Journal{
+		Entries:  make([]*JournalEntry, 0),
+		location: location,
+		pages:    make(map[string]string),
+	}
+
+	path := JournalPath(location)
+
+	if exists, err := utils.Io.Exists(utils.Fs, path); err != nil || !exists {
+		return journal, err
+	}
+
+	fc, err := utils.Io.ReadFile(utils.Fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(fc, journal); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return journal, nil
+}
+
+// Save writes the journal, or removes it if there are no failures left
+func (j *Journal) Save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	path := JournalPath(j.location)
+
+	if len(j.Entries) == 0 {
+		if exists, _ := utils.Io.Exists(utils.Fs, path); exists {
+			return utils.Fs.Remove(path)
+		}
+		return nil
+	}
+
+	marshaled, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := utils.Fs.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return utils.Io.WriteFile(utils.Fs, path, marshaled, os.ModePerm)
+}
+
+// Handle records the failed pages and downloads, and removes the entries of the items that succeeded.
+// It is an EventHandler.
+func (j *Journal) Handle(e *Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	switch e.Type {
+	case EventAssetMatched:
+		j.pages[e.Source] = e.Page
+
+	case EventPageFetched:
+		j.remove(JournalPage, e.Page, "")
+
+	case EventPageFailed:
+		j.remove(JournalPage, e.Page, "")
+		j.Entries = append(j.Entries, &JournalEntry{
+			Kind:  JournalPage,
+			Site:  e.Site,
+			Page:  e.Page,
+			Error: e.Error,
+			Time:  e.Time,
+		})
+
+	case EventDownloadFinished, EventDownloadSkipped:
+		j.remove(JournalDownload, e.Source, j.relative(e.Destination))
+
+	case EventDownloadFailed:
+		destination := j.relative(e.Destination)
+
+		// keep the page of the previous entry when retrying, the page is not scraped again
+		page, ok := j.pages[e.Source]
+		if !ok {
+			for _, entry := range j.Entries {
+				if entry.Kind == JournalDownload && entry.Source == e.Source && entry.Destination == destination {
+					page = entry.Page
+					break
+				}
+			}
+		}
+
+		j.remove(JournalDownload, e.Source, destination)
+		j.Entries = append(j.Entries, &JournalEntry{
+			Kind:        JournalDownload,
+			Site:        e.Site,
+			Asset:       e.Asset,
+			Page:        page,
+			Source:      e.Source,
+			Destination: destination,
+			Checksum:    e.Checksum,
+			Error:       e.Error,
+			Time:        e.Time,
+		})
+	}
+}
+
+// removes the page entries of url, or the download entries of url and destination
+func (j *Journal) remove(kind, url, destination string) {
+	j.Entries = utils.Filter(j.Entries, func(entry *JournalEntry) bool {
+		if entry.Kind != kind {
+			return true
+		}
+
+		if kind == JournalPage {
+			return entry.Page != url
+		}
+
+		return entry.Source != url || entry.Destination != destination
+	})
+}
+
+func (j *Journal) relative(path string) string {
+	rel, err := filepath.Rel(j.location, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// Pages returns the urls of the pages that could not be fetched
+func (j *Journal) Pages() []string {
+	pages := make([]string, 0)
+	for _, entry := range j.Entries {
+		if entry.Kind == JournalPage {
+			pages = append(pages, entry.Page)
+		}
+	}
+
+	return pages
+}
+
+// RetryFailed adds the failed downloads of the journal to the downloads of their assets, with their page
+// and their checksum, so that the pages where they were found do not need to be scraped again.
+// The entries whose site or asset is not in the configuration anymore are skipped.
+func (s *Grab) RetryFailed(journal *Journal) {
+	for _, entry := range journal.Entries {
+		if entry.Kind != JournalDownload {
+			continue
+		}
+
+		found := false
+		for siteIndex, site := range s.Config.Sites {
+			if site.Name != entry.Site {
+				continue
+			}
+
+			for assetIndex, asset := range site.Assets {
+				if asset.Name != entry.Asset {
+					continue
+				}
+
+				destination := filepath.FromSlash(entry.Destination)
+				if !filepath.IsAbs(destination) {
+					destination = filepath.Join(s.Config.Global.Location, destination)
+				}
+
+				target := &s.Config.Sites[siteIndex].Assets[assetIndex]
+
+				if target.Downloads == nil {
+					target.Downloads = make(map[string]string, 0)
+				}
+
+				if _, ok := target.Downloads[entry.Source]; !ok {
+					s.TotalAssets++
+				}
+
+				target.Downloads[entry.Source] = destination
+
+				if entry.Page != "" {
+					if target.Pages == nil {
+						target.Pages = make(map[string]string, 0)
+					}
+					target.Pages[entry.Source] = entry.Page
+				}
+
+				if entry.Checksum != "" {
+					if target.Checksums == nil {
+						target.Checksums = make(map[string]string, 0)
+					}
+					target.Checksums[entry.Source] = entry.Checksum
+				}
+
+				found = true
+			}
+		}
+
+		if !found {
+			log.Warn().Str("site", entry.Site).Str("asset", entry.Asset).Str("source", entry.Source).Msg("cannot retry, the asset is not in the configuration anymore")
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package instance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestJournal(t *testing.T) {
+	root := tu.GetOSRoot()
+	location := filepath.Join(root, "global")
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+	journal, err := LoadJournal(location)
+	if err != nil || len(journal.Entries) != 0 {
+		t.Fatalf("got: %v, %v, want: an empty journal", journal, err)
+	}
+
+	for _, e := range []*Event{
+		{Type: EventPageFailed, Site: "foo", Page: "https://a.com/1", Error: "404"},
+		{Type: EventPageFetched, Site: "foo", Page: "https://a.com/2"},
+		{Type: EventAssetMatched, Site: "foo", Asset: "img", Page: "https://a.com/2", Source: "https://a.com/x.jpg"},
+		{Type: EventAssetMatched, Site: "foo", Asset: "img", Page: "https://a.com/2", Source: "https://a.com/y.jpg"},
+		{Type: EventDownloadFailed, Site: "foo", Asset: "img", Source: "https://a.com/x.jpg", Destination: filepath.Join(location, "foo", "x.jpg"), Checksum: "sha256:abc", Error: "timeout"},
+		{Type: EventDownloadFailed, Site: "foo", Asset: "img", Source: "https://a.com/y.jpg", Destination: filepath.Join(location, "foo", "y.jpg"), Error: "timeout"},
+	} {
+		journal.Handle(e)
+	}
+
+	if err := journal.Save(); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	loaded, err := LoadJournal(location)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if len(loaded.Entries) != 3 {
+		t.Fatalf("got: %d entries, want: 3", len(loaded.Entries))
+	}
+
+	want := JournalEntry{Kind: JournalDownload, Site: "foo", Asset: "img", Page: "https://a.com/2", Source: "https://a.com/x.jpg", Destination: "foo/x.jpg", Checksum: "sha256:abc", Error: "timeout"}
+	if got := *loaded.Entries[1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+
+	if got := loaded.Pages(); !reflect.DeepEqual(got, []string{"https://a.com/1"}) {
+		t.Errorf("got: %v, want: the failed page", got)
+	}
+
+	// the next run retries: the page works, x.jpg fails again, y.jpg works
+	for _, e := range []*Event{
+		{Type: EventPageFetched, Site: "foo", Page: "https://a.com/1"},
+		{Type: EventDownloadFailed, Site: "foo", Asset: "img", Source: "https://a.com/x.jpg", Destination: filepath.Join(location, "foo", "x.jpg"), Error: "reset"},
+		{Type: EventDownloadFinished, Site: "foo", Asset: "img", Source: "https://a.com/y.jpg", Destination: filepath.Join(location, "foo", "y.jpg")},
+	} {
+		loaded.Handle(e)
+	}
+
+	if len(loaded.Entries) != 1 {
+		t.Fatalf("got: %d entries, want: 1", len(loaded.Entries))
+	}
+
+	// the page where the asset was found is kept
+	if got := loaded.Entries[0]; got.Source != "https://a.com/x.jpg" || got.Page != "https://a.com/2" || got.Error != "reset" {
+		t.Errorf("got: %+v, want: x.jpg failed again", got)
+	}
+
+	loaded.Entries = loaded.Entries[:0]
+	if err := loaded.Save(); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if exists, _ := utils.Io.Exists(utils.Fs, JournalPath(location)); exists {
+		t.Errorf("got: %s, want: the empty journal to be removed", JournalPath(location))
+	}
+}
+
+func TestRetryFailed(t *testing.T) {
+	root := tu.GetOSRoot()
+	location := filepath.Join(root, "global")
+
+	cfg, _, _, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(location)+`"
+}
+
+site "foo" {
+	test = "a\\.com"
+
+	asset "img" {
+		pattern = "x"
+		capture = 0
+	}
+}`), "test.hcl")
+	if diags.HasErrors() {
+		t.Fatalf("got errors: %+v", diags)
+	}
+
+	g := New(nil)
+	g.Flags = &FlagsState{}
+	g.Config = cfg
+
+	g.RetryFailed(&Journal{Entries: []*JournalEntry{
+		{Kind: JournalPage, Site: "foo", Page: "https://a.com/1"},
+		{Kind: JournalDownload, Site: "foo", Asset: "img", Page: "https://a.com/1", Source: "https://a.com/x.jpg", Destination: "foo/x.jpg", Checksum: "sha256:abc"},
+		{Kind: JournalDownload, Site: "foo", Asset: "removed", Source: "https://a.com/y.jpg", Destination: "foo/y.jpg"},
+	}})
+
+	want := map[string]string{"https://a.com/x.jpg": filepath.Join(location, "foo", "x.jpg")}
+	if got := g.Config.Sites[0].Assets[0].Downloads; !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+
+	// the page and the checksum are restored
+	if got := g.Config.Sites[0].Assets[0].Pages; !reflect.DeepEqual(got, map[string]string{"https://a.com/x.jpg": "https://a.com/1"}) {
+		t.Errorf("got: %v, want the page of x.jpg", got)
+	}
+
+	if got := g.Config.Sites[0].Assets[0].Checksums; !reflect.DeepEqual(got, map[string]string{"https://a.com/x.jpg": "sha256:abc"}) {
+		t.Errorf("got: %v, want the checksum of x.jpg", got)
+	}
+
+	if g.TotalAssets != 1 {
+		t.Errorf("got: %d, want: 1", g.TotalAssets)
+	}
+}
+
+func TestRetryFailedChecksum(t *testing.T) {
+	root := tu.GetOSRoot()
+	location := filepath.Join(root, "global")
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+	// the file is still corrupted
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("corrupted"))
+	}))
+	defer ts.Close()
+
+	cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(location)+`"
+}
+
+site "foo" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "file" {
+		pattern = "x"
+		capture = 0
+	}
+}`), "test.hcl")
+	if diags.HasErrors() {
+		t.Fatalf("got errors: %+v", diags)
+	}
+
+	journal := &Journal{location: location, pages: make(map[string]string), Entries: []*JournalEntry{
+		{
+			Kind:        JournalDownload,
+			Site:        "foo",
+			Asset:       "file",
+			Page:        ts.URL + "/page",
+			Source:      ts.URL + "/file.txt",
+			Destination: "foo/file.txt",
+			Checksum:    "sha256:bb0642d15af235b1aeaeff8768ed6a07dbb9123b5a04959451eb5aaf12b35653",
+			Error:       "checksum mismatch",
+		},
+	}}
+
+	g := &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{}}
+	g.OnEvent(journal.Handle)
+
+	report := NewReport()
+	g.OnEvent(report.Handle)
+
+	g.RetryFailed(journal)
+
+	if err := g.Download(); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if exists, _ := utils.Io.Exists(utils.Fs, filepath.Join(location, "foo", "file.txt")); exists {
+		t.Errorf("got: file.txt, want the corrupted file to be rejected again")
+	}
+
+	if report.Totals.AssetsFailed != 1 || report.Totals.AssetsDownloaded != 0 {
+		t.Errorf("got: %+v, want the download to fail", report.Totals)
+	}
+
+	// the entry keeps its page and its checksum for the next retry
+	if len(journal.Entries) != 1 || journal.Entries[0].Page != ts.URL+"/page" || !strings.HasPrefix(journal.Entries[0].Checksum, "sha256:") || !strings.Contains(journal.Entries[0].Error, "checksum mismatch") {
+		t.Errorf("got: %+v, want the failure to be recorded again", journal.Entries)
+	}
+}
@@ -0,0 +1,109 @@
+package instance
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/net"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	StatusOK      = "ok"
+	StatusPartial = "partial"
+	StatusFailed  = "failed"
+)
+
+// Notification is the JSON body sent to the url of the notify block
+type Notification struct {
+	// what ran, e.g. "get", "watch feed" or "schedule news"
+	Run string `json:"run"`
+	// one of StatusOK, StatusPartial or StatusFailed
+	Status string `json:"status"`
+	// why the run stopped early
+	Error     string        `json:"error,omitempty"`
+	Started   time.Time     `json:"started"`
+	ElapsedMs float64       `json:"elapsed_ms"`
+	Totals    Counts        `json:"totals"`
+	Sites     []*SiteReport `json:"sites"`
+	Failures  []Failure     `json:"failures"`
+}
+
+// NewNotification summarizes the run in report. runErr is not nil if the run stopped early.
+func NewNotification(run string, report *Report, runErr error) *Notification {
+	n := &Notification{
+		Run:       run,
+		Status:    StatusOK,
+		Started:   report.Started,
+		ElapsedMs: float64(report.Elapsed) / float64(time.Millisecond),
+		Totals:    report.Totals,
+		Sites:     report.Sites,
+		Failures:  report.Failures,
+	}
+
+	switch {
+	case runErr != nil:
+		n.Status = StatusFailed
+		n.Error = runErr.Error()
+	case report.ExitCode() == utils.ExitFailure:
+		n.Status = StatusFailed
+	case report.ExitCode() == utils.ExitPartial:
+		n.Status = StatusPartial
+	}
+
+	return n
+}
+
+// Notify sends the summary of the run to the url of the notify block, if any, unless in dry run mode.
+// Errors are logged, they do not change the outcome of the run.
+func (s *Grab) Notify(run string, report *Report, runErr error) error {
+	notify := s.Config.Global.Notify
+	if notify == nil || s.Flags.DryRun {
+		return nil
+	}
+
+	notification := NewNotification(run, report, runErr)
+	if notify.Event() == config.NotifyRunFailed && notification.Status == StatusOK {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+
+	// urls can contain ampersands, do not escape them
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(notification); err != nil {
+		return err
+	}
+
+	// the url can contain a secret, only its host is logged
+	host := notify.URL
+	if parsed, err := url.Parse(notify.URL); err == nil {
+		host = parsed.Host
+	}
+
+	if err := net.Post(notify.URL, "application/json", buf.Bytes(), notifyOptions(s.Config.Global.Network, notify)); err != nil {
+		log.Warn().Err(err).Str("host", host).Msg("could not send the notification")
+		return err
+	}
+
+	log.Info().Str("host", host).Str("status", notification.Status).Msg("notification sent")
+
+	return nil
+}
+
+// notifyOptions returns the network options of the notification. Unlike the other network blocks, the one of
+// notify only inherits the global options with "inherit = true": the headers meant for the sites, like cookies,
+// must not be sent to the webhook by default.
+func notifyOptions(global *config.RootNetworkConfig, notify *config.NotifyConfig) *net.FetchOptions {
+	if notify.Network == nil || notify.Network.Inherit == nil || !*notify.Network.Inherit {
+		return net.MergeFetchOptionsChain(nil, notify.Network)
+	}
+
+	return net.MergeFetchOptionsChain(global, notify.Network)
+}
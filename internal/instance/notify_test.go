@@ -0,0 +1,187 @@
+package instance
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestNotify(t *testing.T) {
+	root := tu.GetOSRoot()
+	location := filepath.Join(root, "global")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	var mu sync.Mutex
+	attempts := 0
+	received := make([]*Notification, 0)
+	headers := make([]http.Header, 0)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// every other request fails, and is retried
+		if attempts++; attempts%2 == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+
+		notification := &Notification{}
+		if err := json.Unmarshal(body, notification); err != nil {
+			t.Errorf("got invalid json %q: %v", body, err)
+		}
+
+		received = append(received, notification)
+		headers = append(headers, r.Header)
+	}))
+	defer receiver.Close()
+
+	parse := func(on string) *Grab {
+		t.Helper()
+
+		cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(location)+`"
+
+	network {
+		headers = {
+			"Cookie" = "session=secret"
+		}
+	}
+
+	notify {
+		url = "`+receiver.URL+`/notify"
+		on = "`+on+`"
+
+		network {
+			retries = 2
+			headers = {
+				"Authorization" = "Bearer token"
+			}
+		}
+	}
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/a[^\"]+)"
+		capture = 1
+	}
+}
+`), "grab.hcl")
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+
+		return &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{}}
+	}
+
+	run := func(g *Grab, urls ...string) error {
+		report := NewReport()
+		g.OnEvent(report.Handle)
+
+		err := g.Run(urls)
+		report.Finish()
+
+		return g.Notify("test", report, err)
+	}
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+	g := parse("run_done")
+	if err := run(g, ts.URL+"/gallery/123/test", ts.URL+"/givesNotFound"); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if len(received) != 1 {
+		t.Fatalf("got: %d notifications, want: 1", len(received))
+	}
+
+	got := received[0]
+	if got.Run != "test" || got.Status != StatusPartial || got.Totals.AssetsDownloaded != 1 || got.Totals.PagesFailed != 1 {
+		t.Errorf("got: %+v, want a partial run", got)
+	}
+
+	if len(got.Sites) != 1 || len(got.Sites[0].Files) != 1 || got.Sites[0].Files[0] != filepath.Join(location, "example", "a.jpg") {
+		t.Errorf("got: %+v, want the new file", got.Sites)
+	}
+
+	if len(got.Failures) != 1 || got.Failures[0].Type != EventPageFailed || got.Failures[0].Page != ts.URL+"/givesNotFound" {
+		t.Errorf("got: %+v, want the failed page", got.Failures)
+	}
+
+	if headers[0].Get("Authorization") != "Bearer token" || headers[0].Get("Cookie") != "" || headers[0].Get("Content-Type") != "application/json" {
+		t.Errorf("got: %v, want the headers of the notify block only", headers[0])
+	}
+
+	// the successful runs are not notified
+	g = parse("run_failed")
+	if err := run(g, ts.URL+"/gallery/123/test"); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if len(received) != 1 {
+		t.Errorf("got: %d notifications, want: 1", len(received))
+	}
+
+	g.Flags.Strict = true
+	if err := run(g, ts.URL+"/givesNotFound"); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if len(received) != 2 || received[1].Status != StatusFailed || received[1].Error == "" {
+		t.Errorf("got: %+v, want a failed run with its error", received[1:])
+	}
+
+	g.Flags.DryRun = true
+	if err := run(g, ts.URL+"/givesNotFound"); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if len(received) != 2 {
+		t.Errorf("got: %d notifications, want none in a dry run", len(received)-2)
+	}
+
+	// the global headers are only sent when inherited explicitly
+	g = parse("run_done")
+	inherit := true
+	g.Config.Global.Notify.Network.Inherit = &inherit
+
+	if err := run(g, ts.URL+"/gallery/123/test"); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if last := headers[len(headers)-1]; last.Get("Authorization") != "Bearer token" || last.Get("Cookie") != "session=secret" {
+		t.Errorf("got: %v, want the global and the notify headers", last)
+	}
+
+	// the receiver keeps failing
+	g = parse("run_done")
+	retries := 1
+	g.Config.Global.Notify.Network.Retries = &retries
+	attempts = 0
+
+	if err := run(g, ts.URL+"/gallery/123/test"); err == nil {
+		t.Errorf("got: nil, want an error")
+	}
+}
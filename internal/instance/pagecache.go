@@ -0,0 +1,26 @@
+package instance
+
+import (
+	"path/filepath"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/net"
+	"github.com/rs/zerolog/log"
+)
+
+// CacheDir returns the directory of the page cache of a location
+func CacheDir(location string) string {
+	return filepath.Join(location, ".grab", "cache")
+}
+
+// fetchPage returns the body of a page of the site, from the page cache if the --cache flag allows it
+func (s *Grab) fetchPage(site config.SiteConfig, pageUrl string, options *net.FetchOptions) (string, error) {
+	cache := &net.Cache{Dir: CacheDir(s.Config.Global.Location), Mode: s.Flags.Cache}
+
+	body, status, err := cache.Fetch(pageUrl, options, site.CacheMaxAge())
+	if err == nil && status != net.CacheMiss {
+		log.Info().Str("url", pageUrl).Str("cache", string(status)).Msg("page read from cache")
+	}
+
+	return body, err
+}
@@ -0,0 +1,84 @@
+package instance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestPageCache(t *testing.T) {
+	root := tu.GetOSRoot()
+	global := filepath.Join(root, "global")
+
+	pages := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		// the pages are not cacheable, only max_age makes them so
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte(`<title>Page</title>`))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		Name      string
+		Mode      string
+		MaxAge    string
+		WantPages int
+	}{
+		{Name: "off", Mode: "off", MaxAge: "1h", WantPages: 3},
+		{Name: "use", Mode: "use", MaxAge: "1h", WantPages: 1},
+		{Name: "use without max_age", Mode: "use", WantPages: 3},
+		{Name: "refresh", Mode: "refresh", MaxAge: "1h", WantPages: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			pages = 0
+
+			maxAge := ""
+			if tt.MaxAge != "" {
+				maxAge = `max_age = "` + tt.MaxAge + `"`
+			}
+
+			cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(global)+`"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+	`+maxAge+`
+
+	info "title" {
+		pattern = "<title>([^<]+)"
+		capture = 1
+	}
+}`), "test.hcl")
+			if diags.HasErrors() {
+				tc.Fatal(diags)
+			}
+
+			g := &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{Cache: tt.Mode}}
+
+			for i := 0; i < 3; i++ {
+				if err := g.Run([]string{ts.URL}); err != nil {
+					tc.Fatalf("got: %v, want: nil", err)
+				}
+
+				if got := g.Config.Sites[0].InfoMap[filepath.Join(global, "example")][0]["title"]; got != "Page" {
+					tc.Errorf("got: %q, want: %q", got, "Page")
+				}
+			}
+
+			if pages != tt.WantPages {
+				tc.Errorf("got: %d, want: %d requests", pages, tt.WantPages)
+			}
+		})
+	}
+}
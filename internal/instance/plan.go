@@ -0,0 +1,282 @@
+package instance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/net"
+	"github.com/everdrone/grab/internal/utils"
+)
+
+// PlanVersion is the version of the plan format, incremented on incompatible changes
+const PlanVersion = 1
+
+// Plan is the result of the scraping phase: everything needed to download the assets and
+// write the info files later, without the configuration file and without fetching the pages again.
+type Plan struct {
+	Version int `json:"version"`
+	// global.location at the time of scraping, destinations are relative to it
+	Location string     `json:"location"`
+	Sites    []PlanSite `json:"sites"`
+	// where the info is written, nil for the _info.json files
+	Index *PlanIndex `json:"index,omitempty"`
+}
+
+type PlanIndex struct {
+	Format string `json:"format"`
+	// slash separated, relative to the location of the plan
+	Path string `json:"path"`
+}
+
+type PlanSite struct {
+	Name   string      `json:"name"`
+	Assets []PlanAsset `json:"assets"`
+	// one per page, in the order they were scraped
+	Infos []PlanInfo `json:"infos"`
+	// the merge policy of the subdirectory block, empty for the default
+	InfoMerge string `json:"info_merge,omitempty"`
+}
+
+type PlanAsset struct {
+	Name string `json:"name"`
+	// the effective network options, secrets included
+	Network   *net.FetchOptions `json:"network"`
+	Downloads []PlanDownload    `json:"downloads"`
+	// whether the downloads have a sidecar file
+	Sidecar bool `json:"sidecar,omitempty"`
+	// what happens to the existing files, empty for the default
+	OnExists string `json:"on_exists,omitempty"`
+	// the content type and size filters of the downloads
+	Filter *net.Filter `json:"filter,omitempty"`
+}
+
+type PlanDownload struct {
+	Source string `json:"source"`
+	// slash separated, relative to the location of the plan
+	Destination string `json:"destination"`
+	// the url of the page the asset was found in
+	Page string `json:"page,omitempty"`
+	// the expected checksum, "algorithm:digest"
+	Checksum string `json:"checksum,omitempty"`
+}
+
+type PlanInfo struct {
+	// slash separated, relative to the location of the plan
+	Directory string            `json:"directory"`
+	Values    map[string]string `json:"values"`
+}
+
+// Plan returns the downloads and the info computed by BuildAssetCache
+func (s *Grab) Plan() *Plan {
+	plan := &Plan{
+		Version:  PlanVersion,
+		Location: s.Config.Global.Location,
+		Sites:    make([]PlanSite, 0),
+	}
+
+	if format := s.Config.Global.IndexFormat(); format != config.IndexJSON {
+		plan.Index = &PlanIndex{Format: format, Path: s.relativeToLocation(s.Config.Global.IndexPath())}
+	}
+
+	for _, site := range s.Config.Sites {
+		ps := PlanSite{
+			Name:   site.Name,
+			Assets: make([]PlanAsset, 0, len(site.Assets)),
+			Infos:  make([]PlanInfo, 0, len(site.InfoMap)),
+		}
+
+		for _, asset := range site.Assets {
+			pa := PlanAsset{
+				Name:      asset.Name,
+				Network:   net.MergeFetchOptionsChain(s.Config.Global.Network, site.Network, asset.Network),
+				Downloads: make([]PlanDownload, 0, len(asset.Downloads)),
+				Sidecar:   utils.Inherit(false, s.Config.Global.Sidecar, site.Sidecar, asset.Sidecar),
+				Filter:    downloadFilter(asset),
+			}
+
+			if onExists := utils.Inherit(config.OnExistsSkip, s.Config.Global.OnExists, site.OnExists, asset.OnExists); onExists != config.OnExistsSkip {
+				pa.OnExists = onExists
+			}
+
+			for _, src := range sortedKeys(asset.Downloads) {
+				pa.Downloads = append(pa.Downloads, PlanDownload{
+					Source:      src,
+					Destination: s.relativeToLocation(asset.Downloads[src]),
+					Page:        asset.Pages[src],
+					Checksum:    asset.Checksums[src],
+				})
+			}
+
+			ps.Assets = append(ps.Assets, pa)
+		}
+
+		for _, directory := range sortedKeys(site.InfoMap) {
+			for _, values := range site.InfoMap[directory] {
+				ps.Infos = append(ps.Infos, PlanInfo{
+					Directory: s.relativeToLocation(directory),
+					Values:    values,
+				})
+			}
+		}
+
+		if merge := site.InfoMerge(); merge != config.InfoMergeAppend {
+			ps.InfoMerge = merge
+		}
+
+		plan.Sites = append(plan.Sites, ps)
+	}
+
+	return plan
+}
+
+func (p *Plan) JSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	// urls can contain ampersands, do not escape them
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(p); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// LoadPlan decodes a plan written by Plan.JSON
+func LoadPlan(b []byte) (*Plan, error) {
+	var plan Plan
+	if err := json.Unmarshal(b, &plan); err != nil {
+		return nil, err
+	}
+
+	if plan.Version != PlanVersion {
+		return nil, fmt.Errorf("unsupported plan version %d, expected %d", plan.Version, PlanVersion)
+	}
+
+	return &plan, nil
+}
+
+// ApplyPlan replaces the configuration with the content of the plan, so that Download can run.
+// If location is empty, the location of the plan is used.
+func (s *Grab) ApplyPlan(plan *Plan, location string) {
+	if location == "" {
+		location = plan.Location
+	}
+
+	resolve := func(rel string) string {
+		path := filepath.FromSlash(rel)
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(location, path)
+	}
+
+	s.Config = &config.Config{
+		Global: config.GlobalConfig{Location: location},
+		Sites:  make([]config.SiteConfig, 0, len(plan.Sites)),
+	}
+
+	if plan.Index != nil {
+		path := resolve(plan.Index.Path)
+		s.Config.Global.Index = &config.IndexConfig{Format: plan.Index.Format, Path: &path}
+	}
+	s.TotalAssets = 0
+
+	for _, ps := range plan.Sites {
+		site := config.SiteConfig{
+			Name:    ps.Name,
+			Assets:  make([]config.AssetConfig, 0, len(ps.Assets)),
+			InfoMap: make(config.InfoCacheMap, len(ps.Infos)),
+		}
+
+		for _, pa := range ps.Assets {
+			asset := config.AssetConfig{
+				Name:      pa.Name,
+				Network:   networkConfig(pa.Network),
+				Downloads: make(map[string]string, len(pa.Downloads)),
+				Pages:     make(map[string]string, len(pa.Downloads)),
+				Checksums: make(map[string]string),
+			}
+
+			if pa.Sidecar {
+				sidecar := true
+				asset.Sidecar = &sidecar
+			}
+
+			if pa.OnExists != "" {
+				onExists := pa.OnExists
+				asset.OnExists = &onExists
+			}
+
+			filterConfig(&asset, pa.Filter)
+
+			for _, download := range pa.Downloads {
+				asset.Downloads[download.Source] = resolve(download.Destination)
+
+				if download.Page != "" {
+					asset.Pages[download.Source] = download.Page
+				}
+
+				if download.Checksum != "" {
+					asset.Checksums[download.Source] = download.Checksum
+				}
+			}
+
+			s.TotalAssets += int64(len(asset.Downloads))
+			site.Assets = append(site.Assets, asset)
+		}
+
+		for _, info := range ps.Infos {
+			directory := resolve(info.Directory)
+			site.InfoMap[directory] = append(site.InfoMap[directory], info.Values)
+		}
+
+		if ps.InfoMerge != "" {
+			// only the merge policy is used to download
+			merge := ps.InfoMerge
+			site.Subdirectory = &config.SubdirectoryConfig{Merge: &merge}
+		}
+
+		s.Config.Sites = append(s.Config.Sites, site)
+	}
+}
+
+// returns the network block that reproduces the options exactly, without inheriting anything
+func networkConfig(options *net.FetchOptions) *config.NetworkConfig {
+	if options == nil {
+		return nil
+	}
+
+	inherit := false
+	timeout := options.Timeout
+	retries := options.Retries
+
+	headers := make(map[string]string, len(options.Headers))
+	for k, v := range options.Headers {
+		headers[k] = v
+	}
+
+	return &config.NetworkConfig{
+		Inherit: &inherit,
+		Timeout: &timeout,
+		Retries: &retries,
+		Headers: &headers,
+	}
+}
+
+// Count returns the number of downloads in the plan
+func (p *Plan) Count() int {
+	count := 0
+	for _, site := range p.Sites {
+		for _, asset := range site.Assets {
+			count += len(asset.Downloads)
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,139 @@
+package instance
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestPlan(t *testing.T) {
+	root := tu.GetOSRoot()
+	global := filepath.Join(root, "global")
+	elsewhere := filepath.Join(root, "elsewhere")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+	g := New(nil)
+	g.Flags = &FlagsState{Strict: true}
+
+	cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(global)+`"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "secure" {
+		pattern = "<img src=\"([^\"]+/secure/[ab][^\"]+)"
+		capture = 1
+		find_all = true
+
+		network {
+			headers = {
+				"custom_header" = "123"
+			}
+		}
+	}
+
+	info "title" {
+		pattern = "<title>([^<]+)"
+		capture = 1
+	}
+}`), "test.hcl")
+	if diags.HasErrors() {
+		t.Fatalf("got errors: %+v", diags)
+	}
+
+	g.Config = cfg
+	g.RegexCache = regexCache
+	g.URLs = []string{ts.URL + "/gallery/123/test"}
+
+	g.BuildSiteCache()
+	if diags := g.BuildAssetCache(); diags.HasErrors() {
+		t.Fatalf("got errors: %+v", diags)
+	}
+
+	marshaled, err := g.Plan().JSON()
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	plan, err := LoadPlan(marshaled)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if plan.Count() != 2 {
+		t.Errorf("got: %d downloads, want: 2", plan.Count())
+	}
+
+	asset := plan.Sites[0].Assets[0]
+	if got := asset.Downloads[0]; got.Source != ts.URL+"/secure/a.jpg" || got.Destination != "example/a.jpg" {
+		t.Errorf("got: %+v, want: a.jpg in example", got)
+	}
+
+	if got := plan.Sites[0].Infos[0]; got.Directory != "example" || got.Values["title"] == "" {
+		t.Errorf("got: %+v, want: the title in example", got)
+	}
+
+	// a new instance, without the config file, downloads to another location
+	applied := New(nil)
+	applied.Flags = &FlagsState{Strict: true}
+	applied.ApplyPlan(plan, elsewhere)
+
+	if applied.TotalAssets != 2 {
+		t.Errorf("got: %d total assets, want: 2", applied.TotalAssets)
+	}
+
+	// the secure assets require the header of the asset network block
+	if err := applied.Download(); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	for name, want := range map[string]string{"a.jpg": "securea", "b.jpg": "secureb"} {
+		if got, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(elsewhere, "example", name)); string(got) != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+
+	if exists, _ := utils.Io.Exists(utils.Fs, filepath.Join(elsewhere, "example", "_info.json")); !exists {
+		t.Errorf("got: no info file, want: %s", filepath.Join(elsewhere, "example", "_info.json"))
+	}
+
+	if exists, _ := utils.Io.Exists(utils.Fs, global); exists {
+		t.Errorf("got: %s, want: nothing written to the original location", global)
+	}
+}
+
+func TestLoadPlan(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Input   string
+		WantErr bool
+	}{
+		{Name: "valid", Input: `{"version": 1, "location": "/tmp", "sites": []}`},
+		{Name: "invalid json", Input: `{"version": 1,`, WantErr: true},
+		{Name: "unsupported version", Input: `{"version": 2, "sites": []}`, WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			if _, err := LoadPlan([]byte(tt.Input)); (err != nil) != tt.WantErr {
+				tc.Errorf("got: %v, want errors: %v", err, tt.WantErr)
+			}
+		})
+	}
+}
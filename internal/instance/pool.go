@@ -0,0 +1,48 @@
+package instance
+
+import "context"
+
+// Pool limits the number of files downloaded at the same time by all the instances that share it
+type Pool chan struct{}
+
+// NewPool returns a pool of size slots, at least one
+func NewPool(size int) Pool {
+	if size < 1 {
+		size = 1
+	}
+
+	return make(Pool, size)
+}
+
+// acquire waits for a free slot, it returns the error of ctx if ctx is done first
+func (p Pool) acquire(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	select {
+	case p <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p Pool) release() {
+	<-p
+}
+
+// waits for a slot of the pool of the instance, if any. The slot must be released with releaseSlot.
+func (s *Grab) acquireSlot() error {
+	if s.Pool == nil {
+		return nil
+	}
+
+	return s.Pool.acquire(s.Context)
+}
+
+func (s *Grab) releaseSlot() {
+	if s.Pool != nil {
+		s.Pool.release()
+	}
+}
@@ -0,0 +1,113 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestPool(t *testing.T) {
+	pool := NewPool(0)
+	if cap(pool) != 1 {
+		t.Fatalf("got: %d, want: %d", cap(pool), 1)
+	}
+
+	if err := pool.acquire(nil); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := pool.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got: %v, want: %v", err, context.DeadlineExceeded)
+	}
+
+	pool.release()
+
+	if err := pool.acquire(context.Background()); err != nil {
+		t.Errorf("got: %v, want: nil", err)
+	}
+}
+
+func TestDownloadPool(t *testing.T) {
+	root := tu.GetOSRoot()
+	global := filepath.Join(root, "global")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+	cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(global)+`"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/a[^\"]+)"
+		capture = 1
+	}
+}`), "test.hcl")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	pool := NewPool(1)
+
+	g := &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{}, URLs: []string{ts.URL + "/gallery/123/test"}, Pool: pool}
+	g.BuildSiteCache()
+	if diags := g.BuildAssetCache(); diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	dst := filepath.Join(global, "example", "a.jpg")
+
+	// another instance holds the only slot
+	if err := pool.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	g.Context = ctx
+
+	if err := g.Download(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got: %v, want: %v", err, context.DeadlineExceeded)
+	}
+
+	if exists, _ := utils.Io.Exists(utils.Fs, dst); exists {
+		t.Errorf("got: %s, want no download without a free slot", dst)
+	}
+
+	pool.release()
+	g.Context = context.Background()
+
+	if err := g.Download(); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if got, _ := utils.Io.ReadFile(utils.Fs, dst); string(got) != "imagea" {
+		t.Errorf("got: %q, want: %q", got, "imagea")
+	}
+
+	if len(pool) != 0 {
+		t.Errorf("got: %d, want the slots to be released", len(pool))
+	}
+}
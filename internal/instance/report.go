@@ -0,0 +1,202 @@
+package instance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/everdrone/grab/internal/utils"
+)
+
+// Counts are the outcomes of the pages and of the downloads of a run
+type Counts struct {
+	PagesFetched     int   `json:"pages_fetched"`
+	PagesFailed      int   `json:"pages_failed"`
+	AssetsDownloaded int   `json:"assets_downloaded"`
+	AssetsSkipped    int   `json:"assets_skipped"`
+	AssetsFailed     int   `json:"assets_failed"`
+	Bytes            int64 `json:"bytes"`
+}
+
+type SiteReport struct {
+	Name string `json:"name"`
+	Counts
+	// the files written by the run
+	Files []string `json:"files,omitempty"`
+}
+
+// Failure is a page, a download or a hook that failed during a run
+type Failure struct {
+	// the type of the event, e.g. EventPageFailed
+	Type        EventType `json:"type"`
+	Site        string    `json:"site,omitempty"`
+	Asset       string    `json:"asset,omitempty"`
+	Page        string    `json:"page,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Destination string    `json:"destination,omitempty"`
+	Error       string    `json:"error"`
+}
+
+// Report is the summary of a run, built from the events of the instance
+type Report struct {
+	Started  time.Time     `json:"started"`
+	Elapsed  time.Duration `json:"-"`
+	Totals   Counts        `json:"totals"`
+	Sites    []*SiteReport `json:"sites"`
+	Failures []Failure     `json:"failures"`
+
+	mu sync.Mutex
+}
+
+// NewReport returns an empty report, register its Handle method with OnEvent to fill it
+func NewReport() *Report {
+	return &Report{
+		Started:  time.Now().UTC(),
+		Sites:    make([]*SiteReport, 0),
+		Failures: make([]Failure, 0),
+	}
+}
+
+func (r *Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Started   time.Time     `json:"started"`
+		ElapsedMs float64       `json:"elapsed_ms"`
+		Totals    Counts        `json:"totals"`
+		Sites     []*SiteReport `json:"sites"`
+		Failures  []Failure     `json:"failures"`
+		ExitCode  int           `json:"exit_code"`
+	}{
+		Started:   r.Started,
+		ElapsedMs: float64(r.Elapsed) / float64(time.Millisecond),
+		Totals:    r.Totals,
+		Sites:     r.Sites,
+		Failures:  r.Failures,
+		ExitCode:  r.ExitCode(),
+	})
+}
+
+// Handle counts the event and records the new files and the failures, it is an EventHandler
+func (r *Report) Handle(e *Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch e.Type {
+	case EventPageFailed, EventDownloadFailed, EventHookFailed:
+		r.Failures = append(r.Failures, Failure{Type: e.Type, Site: e.Site, Asset: e.Asset, Page: e.Page, Source: e.Source, Destination: e.Destination, Error: e.Error})
+	}
+
+	// the global hooks do not belong to a site
+	if e.Site == "" {
+		return
+	}
+
+	site := r.site(e.Site)
+
+	if e.Type == EventDownloadFinished {
+		site.Files = append(site.Files, e.Destination)
+	}
+
+	for _, counts := range []*Counts{&r.Totals, &site.Counts} {
+		switch e.Type {
+		case EventPageFetched:
+			counts.PagesFetched++
+		case EventPageFailed:
+			counts.PagesFailed++
+		case EventDownloadFinished:
+			counts.AssetsDownloaded++
+			counts.Bytes += e.Bytes
+		case EventDownloadSkipped:
+			counts.AssetsSkipped++
+		case EventDownloadFailed:
+			counts.AssetsFailed++
+		}
+	}
+}
+
+// returns the report of the site, creating it if needed. The sites are sorted by name.
+func (r *Report) site(name string) *SiteReport {
+	i := sort.Search(len(r.Sites), func(i int) bool { return r.Sites[i].Name >= name })
+	if i < len(r.Sites) && r.Sites[i].Name == name {
+		return r.Sites[i]
+	}
+
+	site := &SiteReport{Name: name}
+	r.Sites = append(r.Sites, nil)
+	copy(r.Sites[i+1:], r.Sites[i:])
+	r.Sites[i] = site
+
+	return site
+}
+
+// Finish sets the elapsed time
+func (r *Report) Finish() {
+	r.Elapsed = time.Since(r.Started)
+}
+
+// ExitCode returns utils.ExitOK if nothing failed, utils.ExitFailure if nothing succeeded or
+// if downloads failed and none succeeded, utils.ExitPartial otherwise.
+// Runs from a plan or a journal fetch no pages, their downloads alone tell whether they succeeded.
+func (r *Report) ExitCode() int {
+	t := r.Totals
+
+	switch {
+	case t.PagesFailed == 0 && t.AssetsFailed == 0:
+		return utils.ExitOK
+	case t.PagesFetched+t.AssetsDownloaded+t.AssetsSkipped == 0:
+		return utils.ExitFailure
+	case t.AssetsFailed > 0 && t.AssetsDownloaded+t.AssetsSkipped == 0:
+		return utils.ExitFailure
+	default:
+		return utils.ExitPartial
+	}
+}
+
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// Table returns the per site breakdown followed by the totals
+func (r *Report) Table() string {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "SITE\tPAGES\tPAGES FAILED\tDOWNLOADED\tSKIPPED\tFAILED\tSIZE")
+
+	row := func(name string, c Counts) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n", name, c.PagesFetched, c.PagesFailed, c.AssetsDownloaded, c.AssetsSkipped, c.AssetsFailed, formatBytes(c.Bytes))
+	}
+
+	for _, site := range r.Sites {
+		name := site.Name
+		if name == "" {
+			name = "-"
+		}
+		row(name, site.Counts)
+	}
+	row("total", r.Totals)
+
+	w.Flush()
+
+	fmt.Fprintf(buf, "\nfinished in %s\n", r.Elapsed.Round(time.Millisecond))
+
+	return buf.String()
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
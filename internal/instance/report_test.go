@@ -0,0 +1,115 @@
+package instance
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/utils"
+)
+
+func TestReport(t *testing.T) {
+	report := NewReport()
+
+	for _, e := range []*Event{
+		{Type: EventPageFetched, Site: "foo"},
+		{Type: EventPageFetched, Site: "bar"},
+		{Type: EventPageFailed, Site: "bar"},
+		{Type: EventAssetMatched, Site: "foo"},
+		{Type: EventDownloadFinished, Site: "foo", Bytes: 2048},
+		{Type: EventDownloadFinished, Site: "foo", Bytes: 1024},
+		{Type: EventDownloadSkipped, Site: "foo"},
+		{Type: EventDownloadFailed, Site: "bar", Source: "https://bar.com/a.jpg", Error: "404 Not Found"},
+		{Type: EventHookFailed, Error: "exit status 1"},
+	} {
+		report.Handle(e)
+	}
+	report.Finish()
+
+	want := Counts{PagesFetched: 2, PagesFailed: 1, AssetsDownloaded: 2, AssetsSkipped: 1, AssetsFailed: 1, Bytes: 3072}
+	if report.Totals != want {
+		t.Errorf("got: %+v, want: %+v", report.Totals, want)
+	}
+
+	if len(report.Sites) != 2 || report.Sites[0].Name != "bar" || report.Sites[1].Name != "foo" {
+		t.Fatalf("got: %+v, want: bar and foo", report.Sites)
+	}
+
+	if report.Sites[0].AssetsFailed != 1 || report.Sites[1].AssetsDownloaded != 2 {
+		t.Errorf("got: %+v, %+v, want the counts of each site", report.Sites[0].Counts, report.Sites[1].Counts)
+	}
+
+	if len(report.Failures) != 3 || report.Failures[1].Source != "https://bar.com/a.jpg" || report.Failures[2].Type != EventHookFailed {
+		t.Errorf("got: %+v, want the failed page, download and hook", report.Failures)
+	}
+
+	if len(report.Sites[1].Files) != 2 {
+		t.Errorf("got: %v, want the two downloaded files", report.Sites[1].Files)
+	}
+
+	table := report.Table()
+	lines := strings.Split(table, "\n")
+
+	rows := make([]string, 0)
+	for _, line := range lines[1:4] {
+		rows = append(rows, strings.Join(strings.Fields(line), " "))
+	}
+
+	wantRows := []string{"bar 1 1 0 0 1 0 B", "foo 1 0 2 1 0 3.0 KiB", "total 2 1 2 1 1 3.0 KiB"}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("got: %q, want: %q", rows, wantRows)
+	}
+
+	if !strings.Contains(table, "finished in") {
+		t.Errorf("got: %s, want the elapsed time", table)
+	}
+
+	marshaled, err := report.JSON()
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(marshaled, &decoded); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	for _, key := range []string{"started", "elapsed_ms", "totals", "sites", "failures", "exit_code"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("got: %s, missing key: %s", marshaled, key)
+		}
+	}
+
+	if decoded["exit_code"] != float64(utils.ExitPartial) {
+		t.Errorf("got: %v, want: %d", decoded["exit_code"], utils.ExitPartial)
+	}
+}
+
+func TestReportExitCode(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Counts Counts
+		Want   int
+	}{
+		{Name: "nothing to do", Counts: Counts{}, Want: utils.ExitOK},
+		{Name: "success", Counts: Counts{PagesFetched: 1, AssetsDownloaded: 3}, Want: utils.ExitOK},
+		{Name: "no page fetched", Counts: Counts{PagesFailed: 2}, Want: utils.ExitFailure},
+		{Name: "all downloads failed", Counts: Counts{PagesFetched: 1, AssetsFailed: 3}, Want: utils.ExitFailure},
+		{Name: "some downloads failed", Counts: Counts{PagesFetched: 1, AssetsDownloaded: 1, AssetsFailed: 2}, Want: utils.ExitPartial},
+		{Name: "some pages failed", Counts: Counts{PagesFetched: 1, PagesFailed: 1}, Want: utils.ExitPartial},
+		{Name: "no page to fetch, some downloads failed", Counts: Counts{AssetsDownloaded: 9, AssetsFailed: 1}, Want: utils.ExitPartial},
+		{Name: "no page to fetch, some downloads skipped", Counts: Counts{AssetsSkipped: 1, AssetsFailed: 1}, Want: utils.ExitPartial},
+		{Name: "no page to fetch, all downloads failed", Counts: Counts{AssetsFailed: 2}, Want: utils.ExitFailure},
+		{Name: "pages failed, downloads of the journal succeeded", Counts: Counts{PagesFailed: 1, AssetsDownloaded: 2}, Want: utils.ExitPartial},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			report := &Report{Totals: tt.Counts}
+			if got := report.ExitCode(); got != tt.Want {
+				tc.Errorf("got: %d, want: %d", got, tt.Want)
+			}
+		})
+	}
+}
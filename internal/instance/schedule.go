@@ -0,0 +1,221 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/cron"
+	"github.com/everdrone/grab/internal/net"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// Clone returns a copy of the instance that can run at the same time as the original: the configuration
+// is copied without the computed fields, while the flags, the regular expressions, the pool and the event handlers are shared
+func (s *Grab) Clone() *Grab {
+	cfg := *s.Config
+	cfg.Sites = make([]config.SiteConfig, len(s.Config.Sites))
+
+	for i, site := range s.Config.Sites {
+		site.URLs = nil
+		site.InfoMap = nil
+		site.Assets = make([]config.AssetConfig, len(site.Assets))
+
+		for j, asset := range s.Config.Sites[i].Assets {
+			asset.Downloads = nil
+			asset.Pages = nil
+			asset.Checksums = nil
+			site.Assets[j] = asset
+		}
+
+		cfg.Sites[i] = site
+	}
+
+	return &Grab{
+		Config:        &cfg,
+		Flags:         s.Flags,
+		Command:       s.Command,
+		RegexCache:    s.RegexCache,
+		Context:       s.Context,
+		Pool:          s.Pool,
+		eventHandlers: append(make([]EventHandler, 0, len(s.eventHandlers)), s.eventHandlers...),
+	}
+}
+
+// Scheduler runs the schedule blocks at the times of their cron expressions, each on its own copy of the instance.
+// A schedule is never started while its previous run is still going, that occurrence is skipped instead.
+type Scheduler struct {
+	// called after every run with the name of the schedule and the summary of the run
+	AfterRun func(name string, report *Report)
+
+	grab  *Grab
+	crons []*cron.Schedule
+	// the next time of each schedule, zero if it will never run
+	next    []time.Time
+	running map[string]bool
+
+	mu sync.Mutex
+	wg sync.WaitGroup
+}
+
+// NewScheduler returns a scheduler for the schedule blocks of the configuration, whose first runs are the first
+// times after now matching their cron expressions
+func (s *Grab) NewScheduler(now time.Time) (*Scheduler, error) {
+	sc := &Scheduler{
+		grab:    s,
+		crons:   make([]*cron.Schedule, len(s.Config.Schedules)),
+		next:    make([]time.Time, len(s.Config.Schedules)),
+		running: make(map[string]bool),
+	}
+
+	for i, schedule := range s.Config.Schedules {
+		parsed, err := cron.Parse(schedule.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", schedule.Name, err)
+		}
+
+		sc.crons[i] = parsed
+		sc.next[i] = parsed.Next(now)
+	}
+
+	return sc, nil
+}
+
+// Next returns the next time of the schedule name, zero if it will never run
+func (sc *Scheduler) Next(name string) time.Time {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	for i, schedule := range sc.grab.Config.Schedules {
+		if schedule.Name == name {
+			return sc.next[i]
+		}
+	}
+
+	return time.Time{}
+}
+
+// Run starts the schedules when they are due, until ctx is done. It then waits for the running schedules to finish.
+func (sc *Scheduler) Run(ctx context.Context) error {
+	for {
+		sc.mu.Lock()
+		var next time.Time
+		for _, t := range sc.next {
+			if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+				next = t
+			}
+		}
+		sc.mu.Unlock()
+
+		if next.IsZero() {
+			log.Warn().Msg("no schedule will ever run")
+			<-ctx.Done()
+			sc.wg.Wait()
+			return nil
+		}
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			sc.wg.Wait()
+			return nil
+		case <-timer.C:
+			sc.Tick(time.Now())
+		}
+	}
+}
+
+// Tick starts the schedules that are due at now, in the background, and computes their next time.
+// The schedules whose previous run is still going are skipped.
+func (sc *Scheduler) Tick(now time.Time) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	for i, schedule := range sc.grab.Config.Schedules {
+		if sc.next[i].IsZero() || sc.next[i].After(now) {
+			continue
+		}
+
+		sc.next[i] = sc.crons[i].Next(now)
+
+		if sc.running[schedule.Name] {
+			log.Warn().Str("schedule", schedule.Name).Msg("the previous run is still going, skipping")
+			continue
+		}
+
+		sc.running[schedule.Name] = true
+		sc.wg.Add(1)
+
+		go func(schedule config.ScheduleConfig) {
+			defer sc.wg.Done()
+
+			if err := sc.RunSchedule(schedule); err != nil {
+				log.Warn().Err(err).Str("schedule", schedule.Name).Msg("the run stopped early")
+			}
+
+			sc.mu.Lock()
+			delete(sc.running, schedule.Name)
+			sc.mu.Unlock()
+		}(schedule)
+	}
+}
+
+// Wait waits for the running schedules to finish
+func (sc *Scheduler) Wait() {
+	sc.wg.Wait()
+}
+
+// RunSchedule scrapes the urls of the schedule with the sites it allows, and calls AfterRun.
+// The lists of urls are read again at every run, relative paths are relative to the configuration file.
+func (sc *Scheduler) RunSchedule(schedule config.ScheduleConfig) error {
+	g := sc.grab.Clone()
+
+	if schedule.Sites != nil {
+		g.Config.Sites = utils.Filter(g.Config.Sites, func(site config.SiteConfig) bool {
+			return utils.Contains(*schedule.Sites, site.Name)
+		})
+	}
+
+	reader := &utils.URLReader{
+		Fetch: func(url string) (string, error) {
+			return net.Fetch(url, g.listFetchOptions())
+		},
+	}
+
+	args := make([]string, 0, len(schedule.URLs))
+	for _, arg := range schedule.URLs {
+		if _, ok := utils.IsValidURL(arg); !ok && arg != "-" && !filepath.IsAbs(arg) && g.Flags.ConfigPath != "" {
+			arg = filepath.Join(filepath.Dir(g.Flags.ConfigPath), arg)
+		}
+
+		args = append(args, arg)
+	}
+
+	urls, diags := reader.FromArgs(args)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	log.Info().Str("schedule", schedule.Name).Msgf("running, %d %s", len(urls), utils.Plural(len(urls), "url", "urls"))
+
+	report := NewReport()
+	g.OnEvent(report.Handle)
+
+	err := g.Run(utils.Unique(urls))
+
+	report.Finish()
+
+	if sc.AfterRun != nil {
+		sc.AfterRun(schedule.Name, report)
+	}
+
+	g.Notify("schedule "+schedule.Name, report, err)
+
+	return err
+}
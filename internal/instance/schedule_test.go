@@ -0,0 +1,163 @@
+package instance
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestClone(t *testing.T) {
+	g := &Grab{
+		Config: &config.Config{Sites: []config.SiteConfig{{
+			Name:   "foo",
+			URLs:   []string{"https://a.com"},
+			Assets: []config.AssetConfig{{Name: "img", Downloads: map[string]string{"https://a.com/x.jpg": "x.jpg"}}},
+		}}},
+		Flags: &FlagsState{},
+	}
+	g.OnEvent(func(e *Event) {})
+
+	clone := g.Clone()
+	clone.OnEvent(func(e *Event) {})
+	clone.Config.Sites[0].Assets[0].Downloads = map[string]string{}
+
+	if clone.Config.Sites[0].URLs != nil || clone.Config.Sites[0].Name != "foo" {
+		t.Errorf("got: %+v, want the site without its urls", clone.Config.Sites[0])
+	}
+
+	if len(g.Config.Sites[0].Assets[0].Downloads) != 1 {
+		t.Errorf("got: %v, want the downloads of the original to be untouched", g.Config.Sites[0].Assets[0].Downloads)
+	}
+
+	if len(g.eventHandlers) != 1 || len(clone.eventHandlers) != 2 {
+		t.Errorf("got: %d and %d handlers, want: 1 and 2", len(g.eventHandlers), len(clone.eventHandlers))
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	root := tu.GetOSRoot()
+	location := filepath.Join(root, "global")
+	configPath := filepath.Join(root, "grab.hcl")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+	utils.Fs.MkdirAll(filepath.Join(root, "lists"), os.ModePerm)
+	utils.Io.WriteFile(utils.Fs, filepath.Join(root, "lists", "pages.txt"), []byte(ts.URL+"/gallery/123/test\n"), os.ModePerm)
+
+	cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(location)+`"
+}
+
+site "other" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/b[^\"]+)"
+		capture = 1
+	}
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/a[^\"]+)"
+		capture = 1
+	}
+}
+
+schedule "pages" {
+	cron = "*/5 * * * *"
+	urls = ["lists/pages.txt"]
+	sites = ["example"]
+}
+
+schedule "daily" {
+	cron = "@daily"
+	urls = ["`+ts.URL+`/givesNotFound"]
+}
+`), configPath)
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	g := &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{ConfigPath: configPath}}
+
+	now := time.Date(2022, time.August, 17, 10, 42, 0, 0, time.UTC)
+
+	sc, err := g.NewScheduler(now)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	var mu sync.Mutex
+	runs := make(map[string]Counts)
+	sc.AfterRun = func(name string, report *Report) {
+		mu.Lock()
+		defer mu.Unlock()
+		runs[name] = report.Totals
+	}
+
+	if got, want := sc.Next("pages"), time.Date(2022, time.August, 17, 10, 45, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+
+	// nothing is due yet
+	sc.Tick(now)
+	sc.Wait()
+
+	if len(runs) != 0 {
+		t.Fatalf("got: %v, want no runs", runs)
+	}
+
+	sc.Tick(time.Date(2022, time.August, 17, 10, 45, 0, 0, time.UTC))
+	sc.Wait()
+
+	// only the allowed site handles the urls
+	if want := (Counts{PagesFetched: 1, AssetsDownloaded: 1, Bytes: 6}); len(runs) != 1 || runs["pages"] != want {
+		t.Fatalf("got: %+v, want: %+v", runs, want)
+	}
+
+	if exists, _ := utils.Io.Exists(utils.Fs, filepath.Join(location, "other")); exists {
+		t.Errorf("got: a directory for other, want only example")
+	}
+
+	if got, want := sc.Next("pages"), time.Date(2022, time.August, 17, 10, 50, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+
+	// a schedule whose previous run is still going is skipped
+	sc.running["pages"] = true
+	delete(runs, "pages")
+
+	sc.Tick(time.Date(2022, time.August, 18, 0, 0, 0, 0, time.UTC))
+	sc.Wait()
+
+	if _, ok := runs["pages"]; ok {
+		t.Errorf("got: %v, want pages to be skipped", runs)
+	}
+
+	if want := (Counts{PagesFailed: 1}); runs["daily"] != want {
+		t.Errorf("got: %+v, want: %+v", runs["daily"], want)
+	}
+
+	if got, want := sc.Next("pages"), time.Date(2022, time.August, 18, 0, 5, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 
 	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/net"
 	"github.com/everdrone/grab/internal/utils"
 	"github.com/mitchellh/go-homedir"
 	"github.com/rs/zerolog"
@@ -16,12 +17,21 @@ func (s *Grab) ParseFlags() {
 	flags := &FlagsState{}
 
 	flags.Force, _ = s.Command.Flags().GetBool("force")
+	flags.OnExists, _ = s.Command.Flags().GetString("on-exists")
+	flags.Cache, _ = s.Command.Flags().GetString("cache")
 	flags.Quiet, _ = s.Command.Flags().GetBool("quiet")
 	flags.Strict, _ = s.Command.Flags().GetBool("strict")
 	flags.DryRun, _ = s.Command.Flags().GetBool("dry-run")
 	flags.Progress, _ = s.Command.Flags().GetBool("progress")
 	flags.Verbosity, _ = s.Command.Flags().GetCount("verbose")
 	flags.ConfigPath, _ = s.Command.Flags().GetString("config")
+	flags.Output, _ = s.Command.Flags().GetString("output")
+	flags.Print, _ = s.Command.Flags().GetString("print")
+	flags.Column, _ = s.Command.Flags().GetString("column")
+
+	if inputs, _ := s.Command.Flags().GetStringArray("input"); len(inputs) > 0 {
+		flags.Inputs = inputs
+	}
 
 	// if both quiet and verbose are set, quiet wins
 	if flags.Quiet {
@@ -53,7 +63,7 @@ func (s *Grab) ParseConfig() *hcl.Diagnostics {
 	if s.Flags.ConfigPath == "" {
 		log.Trace().Msg("no config file specified, resolving")
 
-		resolved, err := config.Resolve("grab.hcl", utils.Wd)
+		resolved, err := config.ResolveConfig(utils.Wd)
 		if err != nil {
 			return &hcl.Diagnostics{{
 				Severity: hcl.DiagError,
@@ -79,8 +89,13 @@ func (s *Grab) ParseConfig() *hcl.Diagnostics {
 		}}
 	}
 
+	return s.LoadConfig(fc, s.Flags.ConfigPath)
+}
+
+// LoadConfig parses the configuration src, read from filename, and resolves the global location
+func (s *Grab) LoadConfig(src []byte, filename string) *hcl.Diagnostics {
 	// parse config and get regexCache
-	config, _, regexCache, diags := config.Parse(fc, s.Flags.ConfigPath)
+	config, _, regexCache, diags := config.Parse(src, filename)
 	if diags.HasErrors() {
 		return &diags
 	}
@@ -114,14 +129,48 @@ func (s *Grab) ParseURLs(args []string) *hcl.Diagnostics {
 
 	args = utils.Unique(args)
 
-	urls, diags := utils.GetURLsFromArgs(args)
+	reader := &utils.URLReader{
+		Fetch: func(url string) (string, error) {
+			return net.Fetch(url, s.listFetchOptions())
+		},
+	}
+
+	if s.Command != nil {
+		reader.Stdin = s.Command.InOrStdin()
+	}
+
+	inputs := make([]string, 0)
+	if s.Flags != nil {
+		reader.Column = s.Flags.Column
+		inputs = s.Flags.Inputs
+	}
+
+	urls, diags := reader.FromArgs(args)
 	if diags.HasErrors() {
 		return &diags
 	}
 
+	for _, input := range inputs {
+		listed, diags := reader.FromList(input)
+		if diags.HasErrors() {
+			return &diags
+		}
+
+		urls = append(urls, listed...)
+	}
+
 	s.URLs = utils.Unique(urls)
 
 	log.Trace().Strs("urls", s.URLs).Msgf("found %d %s", len(s.URLs), utils.Plural(len(s.URLs), "url", "urls"))
 
 	return &hcl.Diagnostics{}
 }
+
+// the lists of urls are fetched with the global network options
+func (s *Grab) listFetchOptions() *net.FetchOptions {
+	if s.Config == nil {
+		return net.MergeFetchOptionsChain(nil)
+	}
+
+	return net.MergeFetchOptionsChain(s.Config.Global.Network)
+}
@@ -0,0 +1,770 @@
+package instance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/net"
+	"github.com/everdrone/grab/internal/utils"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type ResolvedConfig struct {
+	Location  string             `json:"location"`
+	Network   *net.FetchOptions  `json:"network"`
+	Hooks     []ResolvedHook     `json:"hooks,omitempty"`
+	Notify    *ResolvedNotify    `json:"notify,omitempty"`
+	Sidecar   bool               `json:"sidecar"`
+	OnExists  string             `json:"on_exists"`
+	Index     *ResolvedIndex     `json:"index"`
+	Sites     []ResolvedSite     `json:"sites"`
+	Watches   []ResolvedWatch    `json:"watches,omitempty"`
+	Schedules []ResolvedSchedule `json:"schedules,omitempty"`
+}
+
+type ResolvedSite struct {
+	Name         string                `json:"name"`
+	Test         string                `json:"test"`
+	Network      *net.FetchOptions     `json:"network"`
+	Subdirectory *ResolvedSubdirectory `json:"subdirectory,omitempty"`
+	Assets       []ResolvedAsset       `json:"assets"`
+	Infos        []ResolvedInfo        `json:"infos"`
+	Fixtures     []ResolvedFixture     `json:"fixtures,omitempty"`
+	Hooks        []ResolvedHook        `json:"hooks,omitempty"`
+	Sidecar      bool                  `json:"sidecar"`
+	OnExists     string                `json:"on_exists"`
+	// how long the pages stay fresh in the cache, empty when the headers of the responses decide
+	MaxAge string `json:"max_age,omitempty"`
+}
+
+type ResolvedSubdirectory struct {
+	Pattern string `json:"pattern"`
+	Capture string `json:"capture"`
+	From    string `json:"from"`
+	// how the info of the pages with the same subdirectory is merged
+	Merge string `json:"merge"`
+}
+
+type ResolvedAsset struct {
+	Name       string              `json:"name"`
+	Pattern    string              `json:"pattern"`
+	Capture    string              `json:"capture"`
+	FindAll    bool                `json:"find_all"`
+	Network    *net.FetchOptions   `json:"network"`
+	Transforms []ResolvedTransform `json:"transforms"`
+	Hooks      []ResolvedHook      `json:"hooks,omitempty"`
+	Checksum   *ResolvedChecksum   `json:"checksum,omitempty"`
+	Sidecar    bool                `json:"sidecar"`
+	OnExists   string              `json:"on_exists"`
+	Include    string              `json:"include,omitempty"`
+	Exclude    string              `json:"exclude,omitempty"`
+	Accept     []string            `json:"accept,omitempty"`
+	// the limits of the size of the downloads in bytes, 0 for no limit
+	MinSize int64 `json:"min_size,omitempty"`
+	MaxSize int64 `json:"max_size,omitempty"`
+}
+
+type ResolvedTransform struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+}
+
+type ResolvedChecksum struct {
+	Algorithm string `json:"algorithm"`
+	// empty when the digests are captured by the asset pattern
+	Pattern string `json:"pattern,omitempty"`
+	Capture string `json:"capture"`
+}
+
+type ResolvedInfo struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Capture string `json:"capture"`
+}
+
+type ResolvedFixture struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// the path of the saved page, relative paths are resolved from the directory of the configuration file
+	File string `json:"file"`
+	// the expected values, only the listed assets and infos are compared
+	Assets    map[string][]string `json:"assets,omitempty"`
+	Filenames map[string][]string `json:"filenames,omitempty"`
+	Info      map[string]string   `json:"info,omitempty"`
+}
+
+type ResolvedHook struct {
+	On      string   `json:"on"`
+	Command []string `json:"command"`
+	// the effective timeout, e.g. "1m0s"
+	Timeout string `json:"timeout"`
+}
+
+type ResolvedNotify struct {
+	// the path and the query of the url are masked, they can contain a secret
+	URL     string            `json:"url"`
+	On      string            `json:"on"`
+	Network *net.FetchOptions `json:"network"`
+}
+
+type ResolvedIndex struct {
+	Format string `json:"format"`
+	// the absolute path of the index file, empty for the json format
+	Path string `json:"path,omitempty"`
+}
+
+type ResolvedWatch struct {
+	Name     string `json:"name"`
+	List     string `json:"list"`
+	Interval string `json:"interval"`
+	Column   string `json:"column,omitempty"`
+}
+
+type ResolvedSchedule struct {
+	Name string   `json:"name"`
+	Cron string   `json:"cron"`
+	URLs []string `json:"urls"`
+	// all the sites if empty
+	Sites []string `json:"sites,omitempty"`
+}
+
+// Resolved returns the effective configuration, with the network options
+// merged for every site and asset. Sensitive headers and the notify url are masked
+// unless unmask is set.
+func (s *Grab) Resolved(unmask bool) *ResolvedConfig {
+	mask := func(options *net.FetchOptions) *net.FetchOptions {
+		if unmask {
+			return options
+		}
+		return options.Masked()
+	}
+
+	resolved := &ResolvedConfig{
+		Location: s.Config.Global.Location,
+		Network:  mask(net.MergeFetchOptionsChain(s.Config.Global.Network)),
+		Hooks:    resolveHooks(s.Config.Global.Hooks),
+		Sidecar:  utils.Inherit(false, s.Config.Global.Sidecar),
+		OnExists: utils.Inherit(config.OnExistsSkip, s.Config.Global.OnExists),
+		Index: &ResolvedIndex{
+			Format: s.Config.Global.IndexFormat(),
+			Path:   s.Config.Global.IndexPath(),
+		},
+		Sites: make([]ResolvedSite, 0, len(s.Config.Sites)),
+	}
+
+	if notify := s.Config.Global.Notify; notify != nil {
+		resolved.Notify = &ResolvedNotify{
+			URL:     notify.URL,
+			On:      notify.Event(),
+			Network: mask(notifyOptions(s.Config.Global.Network, notify)),
+		}
+
+		if !unmask {
+			resolved.Notify.URL = maskURL(notify.URL)
+		}
+	}
+
+	for _, site := range s.Config.Sites {
+		rs := ResolvedSite{
+			Name:     site.Name,
+			Test:     site.Test,
+			Network:  mask(net.MergeFetchOptionsChain(s.Config.Global.Network, site.Network)),
+			Assets:   make([]ResolvedAsset, 0, len(site.Assets)),
+			Infos:    make([]ResolvedInfo, 0, len(site.Infos)),
+			Hooks:    resolveHooks(site.Hooks),
+			Sidecar:  utils.Inherit(false, s.Config.Global.Sidecar, site.Sidecar),
+			OnExists: utils.Inherit(config.OnExistsSkip, s.Config.Global.OnExists, site.OnExists),
+		}
+
+		if maxAge := site.CacheMaxAge(); maxAge > 0 {
+			rs.MaxAge = maxAge.String()
+		}
+
+		if site.Subdirectory != nil {
+			rs.Subdirectory = &ResolvedSubdirectory{
+				Pattern: site.Subdirectory.Pattern,
+				Capture: site.Subdirectory.Capture,
+				From:    site.Subdirectory.From,
+				Merge:   site.InfoMerge(),
+			}
+		}
+
+		for _, asset := range site.Assets {
+			ra := ResolvedAsset{
+				Name:       asset.Name,
+				Pattern:    asset.Pattern,
+				Capture:    asset.Capture,
+				FindAll:    asset.FindAll != nil && *asset.FindAll,
+				Network:    mask(net.MergeFetchOptionsChain(s.Config.Global.Network, site.Network, asset.Network)),
+				Transforms: make([]ResolvedTransform, 0, len(asset.Transforms)),
+				Hooks:      resolveHooks(asset.Hooks),
+				Sidecar:    utils.Inherit(false, s.Config.Global.Sidecar, site.Sidecar, asset.Sidecar),
+				OnExists:   utils.Inherit(config.OnExistsSkip, s.Config.Global.OnExists, site.OnExists, asset.OnExists),
+			}
+
+			for _, transform := range asset.Transforms {
+				ra.Transforms = append(ra.Transforms, ResolvedTransform{
+					Name:    transform.Name,
+					Pattern: transform.Pattern,
+					Replace: transform.Replace,
+				})
+			}
+
+			if asset.Include != nil {
+				ra.Include = *asset.Include
+			}
+			if asset.Exclude != nil {
+				ra.Exclude = *asset.Exclude
+			}
+			if asset.Accept != nil {
+				ra.Accept = *asset.Accept
+			}
+			ra.MinSize, ra.MaxSize = asset.SizeLimits()
+
+			if asset.Checksum != nil {
+				ra.Checksum = &ResolvedChecksum{
+					Algorithm: asset.Checksum.Algorithm,
+					Capture:   asset.Checksum.Capture,
+				}
+
+				if asset.Checksum.Pattern != nil {
+					ra.Checksum.Pattern = *asset.Checksum.Pattern
+				}
+			}
+
+			rs.Assets = append(rs.Assets, ra)
+		}
+
+		for _, info := range site.Infos {
+			rs.Infos = append(rs.Infos, ResolvedInfo{
+				Name:    info.Name,
+				Pattern: info.Pattern,
+				Capture: info.Capture,
+			})
+		}
+
+		for _, fixture := range site.Fixtures {
+			rf := ResolvedFixture{
+				Name: fixture.Name,
+				URL:  fixture.URL,
+				File: s.fixturePath(fixture),
+			}
+
+			if fixture.Assets != nil {
+				rf.Assets = *fixture.Assets
+			}
+			if fixture.Filenames != nil {
+				rf.Filenames = *fixture.Filenames
+			}
+			if fixture.Info != nil {
+				rf.Info = *fixture.Info
+			}
+
+			rs.Fixtures = append(rs.Fixtures, rf)
+		}
+
+		resolved.Sites = append(resolved.Sites, rs)
+	}
+
+	for _, watch := range s.Config.Watches {
+		rw := ResolvedWatch{
+			Name:     watch.Name,
+			List:     watch.List,
+			Interval: watch.Interval,
+		}
+
+		if watch.Column != nil {
+			rw.Column = *watch.Column
+		}
+
+		resolved.Watches = append(resolved.Watches, rw)
+	}
+
+	for _, schedule := range s.Config.Schedules {
+		rs := ResolvedSchedule{
+			Name: schedule.Name,
+			Cron: schedule.Cron,
+			URLs: schedule.URLs,
+		}
+
+		if schedule.Sites != nil {
+			rs.Sites = *schedule.Sites
+		}
+
+		resolved.Schedules = append(resolved.Schedules, rs)
+	}
+
+	return resolved
+}
+
+// maskURL keeps the scheme and the host of the url, the rest is replaced
+func maskURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return "********"
+	}
+
+	if parsed.User == nil && strings.Trim(parsed.Path, "/") == "" && parsed.RawQuery == "" {
+		return rawURL
+	}
+
+	return parsed.Scheme + "://" + parsed.Host + "/********"
+}
+
+// returns nil without hooks, so that they are omitted
+func resolveHooks(hooks []config.HookConfig) []ResolvedHook {
+	var resolved []ResolvedHook
+
+	for _, hook := range hooks {
+		resolved = append(resolved, ResolvedHook{
+			On:      hook.On,
+			Command: hook.Command,
+			Timeout: hook.Duration().String(),
+		})
+	}
+
+	return resolved
+}
+
+func (r *ResolvedConfig) JSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	// patterns are full of angle brackets, do not escape them
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(r); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// HCL renders the resolved configuration as a valid configuration file
+func (r *ResolvedConfig) HCL() []byte {
+	f := hclwrite.NewEmptyFile()
+	root := f.Body()
+
+	global := root.AppendNewBlock("global", nil).Body()
+	global.SetAttributeValue("location", cty.StringVal(r.Location))
+	global.SetAttributeValue("sidecar", cty.BoolVal(r.Sidecar))
+	global.SetAttributeValue("on_exists", cty.StringVal(r.OnExists))
+	appendNetworkBlock(global, r.Network)
+	appendHookBlocks(global, r.Hooks)
+
+	global.AppendNewline()
+
+	ib := global.AppendNewBlock("index", nil).Body()
+	ib.SetAttributeValue("format", cty.StringVal(r.Index.Format))
+	if r.Index.Path != "" {
+		ib.SetAttributeValue("path", cty.StringVal(r.Index.Path))
+	}
+
+	if r.Notify != nil {
+		global.AppendNewline()
+
+		nb := global.AppendNewBlock("notify", nil).Body()
+		nb.SetAttributeValue("url", cty.StringVal(r.Notify.URL))
+		nb.SetAttributeValue("on", cty.StringVal(r.Notify.On))
+		appendNetworkBlock(nb, r.Notify.Network)
+	}
+
+	for _, site := range r.Sites {
+		root.AppendNewline()
+
+		sb := root.AppendNewBlock("site", []string{site.Name}).Body()
+		sb.SetAttributeValue("test", cty.StringVal(site.Test))
+		sb.SetAttributeValue("sidecar", cty.BoolVal(site.Sidecar))
+		sb.SetAttributeValue("on_exists", cty.StringVal(site.OnExists))
+		if site.MaxAge != "" {
+			sb.SetAttributeValue("max_age", cty.StringVal(site.MaxAge))
+		}
+		appendNetworkBlock(sb, site.Network)
+		appendHookBlocks(sb, site.Hooks)
+
+		for _, asset := range site.Assets {
+			sb.AppendNewline()
+
+			ab := sb.AppendNewBlock("asset", []string{asset.Name}).Body()
+			ab.SetAttributeValue("pattern", cty.StringVal(asset.Pattern))
+			ab.SetAttributeValue("capture", cty.StringVal(asset.Capture))
+			ab.SetAttributeValue("find_all", cty.BoolVal(asset.FindAll))
+			ab.SetAttributeValue("sidecar", cty.BoolVal(asset.Sidecar))
+			ab.SetAttributeValue("on_exists", cty.StringVal(asset.OnExists))
+			appendFilterAttributes(ab, asset)
+			appendNetworkBlock(ab, asset.Network)
+			appendHookBlocks(ab, asset.Hooks)
+
+			for _, transform := range asset.Transforms {
+				ab.AppendNewline()
+
+				tb := ab.AppendNewBlock("transform", []string{transform.Name}).Body()
+				tb.SetAttributeValue("pattern", cty.StringVal(transform.Pattern))
+				tb.SetAttributeValue("replace", cty.StringVal(transform.Replace))
+			}
+
+			if asset.Checksum != nil {
+				ab.AppendNewline()
+
+				cb := ab.AppendNewBlock("checksum", nil).Body()
+				cb.SetAttributeValue("algorithm", cty.StringVal(asset.Checksum.Algorithm))
+				if asset.Checksum.Pattern != "" {
+					cb.SetAttributeValue("pattern", cty.StringVal(asset.Checksum.Pattern))
+				}
+				cb.SetAttributeValue("capture", cty.StringVal(asset.Checksum.Capture))
+			}
+		}
+
+		for _, info := range site.Infos {
+			sb.AppendNewline()
+
+			ib := sb.AppendNewBlock("info", []string{info.Name}).Body()
+			ib.SetAttributeValue("pattern", cty.StringVal(info.Pattern))
+			ib.SetAttributeValue("capture", cty.StringVal(info.Capture))
+		}
+
+		for _, fixture := range site.Fixtures {
+			sb.AppendNewline()
+
+			fb := sb.AppendNewBlock("fixture", []string{fixture.Name}).Body()
+			fb.SetAttributeValue("url", cty.StringVal(fixture.URL))
+			fb.SetAttributeValue("file", cty.StringVal(fixture.File))
+			if fixture.Assets != nil {
+				fb.SetAttributeValue("assets", stringListMap(fixture.Assets))
+			}
+			if fixture.Filenames != nil {
+				fb.SetAttributeValue("filenames", stringListMap(fixture.Filenames))
+			}
+			if fixture.Info != nil {
+				fb.SetAttributeValue("info", stringMap(fixture.Info))
+			}
+		}
+
+		if site.Subdirectory != nil {
+			sb.AppendNewline()
+
+			db := sb.AppendNewBlock("subdirectory", nil).Body()
+			db.SetAttributeValue("pattern", cty.StringVal(site.Subdirectory.Pattern))
+			db.SetAttributeValue("capture", cty.StringVal(site.Subdirectory.Capture))
+			db.SetAttributeValue("from", cty.StringVal(site.Subdirectory.From))
+			db.SetAttributeValue("merge", cty.StringVal(site.Subdirectory.Merge))
+		}
+	}
+
+	for _, watch := range r.Watches {
+		root.AppendNewline()
+
+		wb := root.AppendNewBlock("watch", []string{watch.Name}).Body()
+		wb.SetAttributeValue("list", cty.StringVal(watch.List))
+		wb.SetAttributeValue("interval", cty.StringVal(watch.Interval))
+
+		if watch.Column != "" {
+			wb.SetAttributeValue("column", cty.StringVal(watch.Column))
+		}
+	}
+
+	for _, schedule := range r.Schedules {
+		root.AppendNewline()
+
+		sb := root.AppendNewBlock("schedule", []string{schedule.Name}).Body()
+		sb.SetAttributeValue("cron", cty.StringVal(schedule.Cron))
+		sb.SetAttributeValue("urls", stringList(schedule.URLs))
+
+		if len(schedule.Sites) > 0 {
+			sb.SetAttributeValue("sites", stringList(schedule.Sites))
+		}
+	}
+
+	return hclwrite.Format(f.Bytes())
+}
+
+func stringList(strs []string) cty.Value {
+	if len(strs) == 0 {
+		return cty.ListValEmpty(cty.String)
+	}
+
+	values := make([]cty.Value, 0, len(strs))
+	for _, str := range strs {
+		values = append(values, cty.StringVal(str))
+	}
+
+	return cty.ListVal(values)
+}
+
+func stringMap(strs map[string]string) cty.Value {
+	if len(strs) == 0 {
+		return cty.MapValEmpty(cty.String)
+	}
+
+	values := make(map[string]cty.Value, len(strs))
+	for k, v := range strs {
+		values[k] = cty.StringVal(v)
+	}
+
+	return cty.MapVal(values)
+}
+
+func stringListMap(lists map[string][]string) cty.Value {
+	if len(lists) == 0 {
+		return cty.MapValEmpty(cty.List(cty.String))
+	}
+
+	values := make(map[string]cty.Value, len(lists))
+	for k, v := range lists {
+		values[k] = stringList(v)
+	}
+
+	return cty.MapVal(values)
+}
+
+func appendHookBlocks(body *hclwrite.Body, hooks []ResolvedHook) {
+	for _, hook := range hooks {
+		body.AppendNewline()
+
+		hb := body.AppendNewBlock("hook", nil).Body()
+		hb.SetAttributeValue("on", cty.StringVal(hook.On))
+		hb.SetAttributeValue("command", stringList(hook.Command))
+		hb.SetAttributeValue("timeout", cty.StringVal(hook.Timeout))
+	}
+}
+
+func appendFilterAttributes(body *hclwrite.Body, asset ResolvedAsset) {
+	if asset.Include != "" {
+		body.SetAttributeValue("include", cty.StringVal(asset.Include))
+	}
+	if asset.Exclude != "" {
+		body.SetAttributeValue("exclude", cty.StringVal(asset.Exclude))
+	}
+	if len(asset.Accept) > 0 {
+		body.SetAttributeValue("accept", stringList(asset.Accept))
+	}
+	if asset.MinSize > 0 {
+		body.SetAttributeValue("min_size", cty.StringVal(strconv.FormatInt(asset.MinSize, 10)))
+	}
+	if asset.MaxSize > 0 {
+		body.SetAttributeValue("max_size", cty.StringVal(strconv.FormatInt(asset.MaxSize, 10)))
+	}
+}
+
+func appendNetworkBlock(body *hclwrite.Body, options *net.FetchOptions) {
+	nb := body.AppendNewBlock("network", nil).Body()
+	nb.SetAttributeValue("timeout", cty.NumberIntVal(int64(options.Timeout)))
+	nb.SetAttributeValue("retries", cty.NumberIntVal(int64(options.Retries)))
+
+	if len(options.Headers) == 0 {
+		nb.SetAttributeValue("headers", cty.MapValEmpty(cty.String))
+		return
+	}
+
+	headers := make(map[string]cty.Value, len(options.Headers))
+	for k, v := range options.Headers {
+		headers[k] = cty.StringVal(v)
+	}
+	nb.SetAttributeValue("headers", cty.MapVal(headers))
+}
+
+// Table renders the resolved configuration as human readable tables
+func (r *ResolvedConfig) Table() string {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "location: %s\n", r.Location)
+	fmt.Fprintf(buf, "network:  %s\n", formatOptionsInline(r.Network))
+	fmt.Fprintf(buf, "sidecar:  %t\n", r.Sidecar)
+	fmt.Fprintf(buf, "index:    %s\n", strings.TrimSpace(r.Index.Format+" "+r.Index.Path))
+	if r.Notify != nil {
+		fmt.Fprintf(buf, "notify:   %s %s %s\n", r.Notify.On, r.Notify.URL, formatOptionsInline(r.Notify.Network))
+	}
+
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(buf)
+	fmt.Fprintln(w, "SITE\tTEST\tSUBDIRECTORY\tMAX AGE\tNETWORK")
+	for _, site := range r.Sites {
+		subdirectory := "-"
+		if site.Subdirectory != nil {
+			subdirectory = fmt.Sprintf("%s %s [%s] merge=%s", site.Subdirectory.From, site.Subdirectory.Pattern, site.Subdirectory.Capture, site.Subdirectory.Merge)
+		}
+
+		maxAge := site.MaxAge
+		if maxAge == "" {
+			maxAge = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", site.Name, site.Test, subdirectory, maxAge, formatOptionsInline(site.Network))
+	}
+	w.Flush()
+
+	fmt.Fprintln(buf)
+	fmt.Fprintln(w, "SITE\tASSET\tPATTERN\tCAPTURE\tFIND ALL\tTRANSFORMS\tNETWORK")
+	for _, site := range r.Sites {
+		for _, asset := range site.Assets {
+			transforms := make([]string, 0, len(asset.Transforms))
+			for _, transform := range asset.Transforms {
+				transforms = append(transforms, fmt.Sprintf("%s: %s -> %s", transform.Name, transform.Pattern, transform.Replace))
+			}
+
+			if len(transforms) == 0 {
+				transforms = append(transforms, "-")
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\t%s\n", site.Name, asset.Name, asset.Pattern, asset.Capture, asset.FindAll, strings.Join(transforms, ", "), formatOptionsInline(asset.Network))
+		}
+	}
+	w.Flush()
+
+	// how the downloads of every asset are verified
+	fmt.Fprintln(buf)
+	fmt.Fprintln(w, "SITE\tASSET\tCHECKSUM\tSIDECAR\tON EXISTS\tFILTERS")
+	for _, site := range r.Sites {
+		for _, asset := range site.Assets {
+			checksum := "-"
+			if asset.Checksum != nil {
+				pattern := asset.Checksum.Pattern
+				if pattern == "" {
+					pattern = asset.Pattern
+				}
+				checksum = fmt.Sprintf("%s %s [%s]", asset.Checksum.Algorithm, pattern, asset.Checksum.Capture)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\t%s\n", site.Name, asset.Name, checksum, asset.Sidecar, asset.OnExists, formatFilters(asset))
+		}
+	}
+	w.Flush()
+
+	fmt.Fprintln(buf)
+	fmt.Fprintln(w, "SITE\tINFO\tPATTERN\tCAPTURE")
+	for _, site := range r.Sites {
+		for _, info := range site.Infos {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", site.Name, info.Name, info.Pattern, info.Capture)
+		}
+	}
+	w.Flush()
+
+	// the saved pages checked by "grab config test"
+	fixtures := make([]string, 0)
+	for _, site := range r.Sites {
+		for _, fixture := range site.Fixtures {
+			fixtures = append(fixtures, fmt.Sprintf("%s\t%s\t%s\t%s", site.Name, fixture.Name, fixture.URL, fixture.File))
+		}
+	}
+
+	if len(fixtures) > 0 {
+		fmt.Fprintln(buf)
+		fmt.Fprintln(w, "SITE\tFIXTURE\tURL\tFILE")
+		for _, fixture := range fixtures {
+			fmt.Fprintln(w, fixture)
+		}
+		w.Flush()
+	}
+
+	// the hooks of the global block, then of every site and asset
+	hooks := make([][2]string, 0)
+	for _, hook := range r.Hooks {
+		hooks = append(hooks, [2]string{"global", formatHook(hook)})
+	}
+	for _, site := range r.Sites {
+		for _, hook := range site.Hooks {
+			hooks = append(hooks, [2]string{site.Name, formatHook(hook)})
+		}
+		for _, asset := range site.Assets {
+			for _, hook := range asset.Hooks {
+				hooks = append(hooks, [2]string{site.Name + "/" + asset.Name, formatHook(hook)})
+			}
+		}
+	}
+
+	if len(hooks) > 0 {
+		fmt.Fprintln(buf)
+		fmt.Fprintln(w, "SCOPE\tON\tCOMMAND\tTIMEOUT")
+		for _, hook := range hooks {
+			fmt.Fprintf(w, "%s\t%s\n", hook[0], hook[1])
+		}
+		w.Flush()
+	}
+
+	if len(r.Watches) > 0 {
+		fmt.Fprintln(buf)
+		fmt.Fprintln(w, "WATCH\tLIST\tINTERVAL\tCOLUMN")
+		for _, watch := range r.Watches {
+			column := watch.Column
+			if column == "" {
+				column = "-"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", watch.Name, watch.List, watch.Interval, column)
+		}
+		w.Flush()
+	}
+
+	if len(r.Schedules) > 0 {
+		fmt.Fprintln(buf)
+		fmt.Fprintln(w, "SCHEDULE\tCRON\tURLS\tSITES")
+		for _, schedule := range r.Schedules {
+			sites := "all"
+			if len(schedule.Sites) > 0 {
+				sites = strings.Join(schedule.Sites, ", ")
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", schedule.Name, schedule.Cron, strings.Join(schedule.URLs, ", "), sites)
+		}
+		w.Flush()
+	}
+
+	return buf.String()
+}
+
+// the event, the command and the timeout of the hook, separated by tabs
+func formatHook(hook ResolvedHook) string {
+	return fmt.Sprintf("%s\t%s\t%s", hook.On, strings.Join(hook.Command, " "), hook.Timeout)
+}
+
+// the filters of the asset on a single line, "-" without filters
+func formatFilters(asset ResolvedAsset) string {
+	filters := make([]string, 0)
+
+	if asset.Include != "" {
+		filters = append(filters, "include="+asset.Include)
+	}
+	if asset.Exclude != "" {
+		filters = append(filters, "exclude="+asset.Exclude)
+	}
+	if len(asset.Accept) > 0 {
+		filters = append(filters, "accept="+strings.Join(asset.Accept, ","))
+	}
+	if asset.MinSize > 0 {
+		filters = append(filters, fmt.Sprintf("min_size=%d", asset.MinSize))
+	}
+	if asset.MaxSize > 0 {
+		filters = append(filters, fmt.Sprintf("max_size=%d", asset.MaxSize))
+	}
+
+	if len(filters) == 0 {
+		return "-"
+	}
+
+	return strings.Join(filters, " ")
+}
+
+func formatOptionsInline(options *net.FetchOptions) string {
+	headers := make([]string, 0, len(options.Headers))
+	for _, k := range sortedKeys(options.Headers) {
+		headers = append(headers, fmt.Sprintf("%s=%s", k, options.Headers[k]))
+	}
+
+	str := fmt.Sprintf("timeout=%d retries=%d", options.Timeout, options.Retries)
+	if len(headers) > 0 {
+		str += " headers=" + strings.Join(headers, ",")
+	}
+
+	return str
+}
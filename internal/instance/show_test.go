@@ -0,0 +1,319 @@
+package instance
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/net"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestResolved(t *testing.T) {
+	root := tu.GetOSRoot()
+	globalLocation := filepath.Join(root, "global")
+
+	cfg := `
+global {
+	location  = "` + tu.EscapeHCLString(globalLocation) + `"
+	on_exists = "overwrite"
+
+	network {
+		timeout = 5000
+		headers = {
+			"User-Agent" = "grab"
+			"Cookie"     = "session=abc"
+		}
+	}
+
+	hook {
+		on      = "run_done"
+		command = ["notify-send", "grab", "done"]
+	}
+
+	index {
+		format = "csv"
+	}
+
+	notify {
+		url = "https://hooks.example.com/services/secret?token=abc"
+		on  = "run_failed"
+
+		network {
+			retries = 2
+		}
+	}
+}
+
+site "example" {
+	test    = "example\\.com"
+	sidecar = true
+	max_age = "24h"
+
+	asset "video" {
+		pattern  = "<video src=\"([^\"]+)"
+		capture  = 1
+		find_all = true
+
+		network {
+			retries = 3
+		}
+
+		transform url {
+			pattern = "(.+)small(.*)"
+			replace = "$${1}large$${2}"
+		}
+
+		hook {
+			on      = "asset_downloaded"
+			command = ["ffprobe", "{{ .Destination }}"]
+			timeout = "30s"
+		}
+
+		checksum {
+			algorithm = "sha256"
+			pattern   = "sha256=([a-f0-9]{64})"
+			capture   = "1"
+		}
+
+		on_exists = "rename"
+		include   = "large"
+		exclude   = "thumb"
+		accept    = ["video/*"]
+		min_size  = "1KiB"
+		max_size  = "2MB"
+	}
+
+	info "title" {
+		pattern = "<title>([^<]+)"
+		capture = 1
+	}
+
+	subdirectory {
+		pattern = "gallery\\/(\\d+)"
+		capture = 1
+		from    = url
+		merge   = "append"
+	}
+
+	fixture "gallery" {
+		url       = "https://example.com/gallery/1"
+		file      = "fixtures/gallery.html"
+		assets    = { video = ["https://example.com/large.mp4"] }
+		filenames = { video = ["large.mp4"] }
+		info      = { title = "Gallery" }
+	}
+
+	fixture "empty" {
+		url  = "https://example.com/gallery/2"
+		file = "` + tu.EscapeHCLString(filepath.Join(root, "empty.html")) + `"
+	}
+}
+
+watch "feed" {
+	list     = "https://example.com/feed.rss"
+	interval = "1h"
+	column   = "link"
+}
+
+schedule "nightly" {
+	cron  = "0 3 * * *"
+	urls  = ["https://example.com/gallery/1", "https://example.com/gallery/2"]
+	sites = ["example"]
+}
+`
+
+	parse := func(t *testing.T, src string) *Grab {
+		g := New(nil)
+		g.Flags = &FlagsState{ConfigPath: filepath.Join(root, "grab.hcl")}
+
+		config, _, regexCache, diags := config.Parse([]byte(src), "test.hcl")
+		if diags.HasErrors() {
+			t.Fatalf("got errors: %+v", diags)
+		}
+		g.Config = config
+		g.RegexCache = regexCache
+
+		return g
+	}
+
+	want := &ResolvedConfig{
+		Location: globalLocation,
+		Network: &net.FetchOptions{
+			Timeout: 5000,
+			Retries: 1,
+			Headers: map[string]string{"User-Agent": "grab", "Cookie": "********"},
+		},
+		Hooks: []ResolvedHook{
+			{On: "run_done", Command: []string{"notify-send", "grab", "done"}, Timeout: "1m0s"},
+		},
+		Notify: &ResolvedNotify{
+			URL: "https://hooks.example.com/********",
+			On:  "run_failed",
+			// the global options are not inherited
+			Network: &net.FetchOptions{
+				Timeout: 3000,
+				Retries: 2,
+				Headers: map[string]string{},
+			},
+		},
+		OnExists: "overwrite",
+		Index: &ResolvedIndex{
+			Format: "csv",
+			Path:   filepath.Join(globalLocation, "_index.csv"),
+		},
+		Sites: []ResolvedSite{
+			{
+				Name:     "example",
+				Test:     "example\\.com",
+				Sidecar:  true,
+				OnExists: "overwrite",
+				MaxAge:   "24h0m0s",
+				Network: &net.FetchOptions{
+					Timeout: 5000,
+					Retries: 1,
+					Headers: map[string]string{"User-Agent": "grab", "Cookie": "********"},
+				},
+				Subdirectory: &ResolvedSubdirectory{
+					Pattern: "gallery\\/(\\d+)",
+					Capture: "1",
+					From:    "url",
+					Merge:   "append",
+				},
+				Assets: []ResolvedAsset{
+					{
+						Name:    "video",
+						Pattern: "<video src=\"([^\"]+)",
+						Capture: "1",
+						FindAll: true,
+						Network: &net.FetchOptions{
+							Timeout: 5000,
+							Retries: 3,
+							Headers: map[string]string{"User-Agent": "grab", "Cookie": "********"},
+						},
+						Transforms: []ResolvedTransform{
+							{Name: "url", Pattern: "(.+)small(.*)", Replace: "${1}large${2}"},
+						},
+						Hooks: []ResolvedHook{
+							{On: "asset_downloaded", Command: []string{"ffprobe", "{{ .Destination }}"}, Timeout: "30s"},
+						},
+						Checksum: &ResolvedChecksum{
+							Algorithm: "sha256",
+							Pattern:   "sha256=([a-f0-9]{64})",
+							Capture:   "1",
+						},
+						Sidecar:  true,
+						OnExists: "rename",
+						Include:  "large",
+						Exclude:  "thumb",
+						Accept:   []string{"video/*"},
+						MinSize:  1024,
+						MaxSize:  2000000,
+					},
+				},
+				Infos: []ResolvedInfo{
+					{Name: "title", Pattern: "<title>([^<]+)", Capture: "1"},
+				},
+				Fixtures: []ResolvedFixture{
+					{
+						Name: "gallery",
+						URL:  "https://example.com/gallery/1",
+						// relative to the configuration file
+						File:      filepath.Join(root, "fixtures", "gallery.html"),
+						Assets:    map[string][]string{"video": {"https://example.com/large.mp4"}},
+						Filenames: map[string][]string{"video": {"large.mp4"}},
+						Info:      map[string]string{"title": "Gallery"},
+					},
+					{Name: "empty", URL: "https://example.com/gallery/2", File: filepath.Join(root, "empty.html")},
+				},
+			},
+		},
+		Watches: []ResolvedWatch{
+			{Name: "feed", List: "https://example.com/feed.rss", Interval: "1h", Column: "link"},
+		},
+		Schedules: []ResolvedSchedule{
+			{
+				Name:  "nightly",
+				Cron:  "0 3 * * *",
+				URLs:  []string{"https://example.com/gallery/1", "https://example.com/gallery/2"},
+				Sites: []string{"example"},
+			},
+		},
+	}
+
+	t.Run("resolves and masks", func(tc *testing.T) {
+		got := parse(tc, cfg).Resolved(false)
+
+		if !reflect.DeepEqual(got, want) {
+			tc.Errorf("got: %+v, want: %+v", got, want)
+		}
+	})
+
+	t.Run("unmasks", func(tc *testing.T) {
+		got := parse(tc, cfg).Resolved(true)
+
+		if got.Network.Headers["Cookie"] != "session=abc" {
+			tc.Errorf("got: %s, want: %s", got.Network.Headers["Cookie"], "session=abc")
+		}
+
+		if want := "https://hooks.example.com/services/secret?token=abc"; got.Notify.URL != want {
+			tc.Errorf("got: %s, want: %s", got.Notify.URL, want)
+		}
+	})
+
+	t.Run("hcl output is a valid configuration", func(tc *testing.T) {
+		got := parse(tc, string(parse(tc, cfg).Resolved(false).HCL())).Resolved(false)
+
+		if !reflect.DeepEqual(got, want) {
+			tc.Errorf("got: %+v, want: %+v", got, want)
+		}
+	})
+
+	t.Run("json output", func(tc *testing.T) {
+		marshaled, err := parse(tc, cfg).Resolved(false).JSON()
+		if err != nil {
+			tc.Fatal(err)
+		}
+
+		var got *ResolvedConfig
+		if err := json.Unmarshal(marshaled, &got); err != nil {
+			tc.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			tc.Errorf("got: %+v, want: %+v", got, want)
+		}
+
+		if !strings.Contains(string(marshaled), `"pattern": "<title>([^<]+)"`) {
+			tc.Errorf("got: %s, want unescaped patterns", string(marshaled))
+		}
+	})
+
+	t.Run("table output", func(tc *testing.T) {
+		got := parse(tc, cfg).Resolved(false).Table()
+
+		for _, line := range []string{
+			"location: " + globalLocation + "\n",
+			"notify:   run_failed https://hooks.example.com/******** timeout=3000 retries=2\n",
+			"example  video  <video src=\"([^\"]+)  1        true      url: (.+)small(.*) -> ${1}large${2}  timeout=5000 retries=3 headers=Cookie=********,User-Agent=grab\n",
+			"example  example\\.com  url gallery\\/(\\d+) [1] merge=append  24h0m0s  timeout=5000 retries=1 headers=Cookie=********,User-Agent=grab\n",
+			"example  title  <title>([^<]+)  1\n",
+			"sidecar:  false\n",
+			"index:    csv " + filepath.Join(globalLocation, "_index.csv") + "\n",
+			"example  video  sha256 sha256=([a-f0-9]{64}) [1]  true     rename     include=large exclude=thumb accept=video/* min_size=1024 max_size=2000000\n",
+			"global         run_done          notify-send grab done       1m0s\n",
+			"example/video  asset_downloaded  ffprobe {{ .Destination }}  30s\n",
+			"example  gallery  https://example.com/gallery/1  " + filepath.Join(root, "fixtures", "gallery.html") + "\n",
+			"example  empty    https://example.com/gallery/2  " + filepath.Join(root, "empty.html") + "\n",
+			"feed   https://example.com/feed.rss  1h        link\n",
+			"nightly   0 3 * * *  https://example.com/gallery/1, https://example.com/gallery/2  example\n",
+		} {
+			if !strings.Contains(got, line) {
+				tc.Errorf("got: %s, does not contain: %s", got, line)
+			}
+		}
+	})
+}
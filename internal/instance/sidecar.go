@@ -0,0 +1,84 @@
+package instance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/everdrone/grab/internal/net"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// SidecarExtension is appended to the name of a downloaded file to get the name of its sidecar file
+const SidecarExtension = ".json"
+
+// Sidecar describes a downloaded file, it is written next to it when the sidecar attribute is true
+type Sidecar struct {
+	Source string `json:"source"`
+	// the url of the page the asset was found in
+	Page string `json:"page,omitempty"`
+	// the url of the response, after the redirects
+	URL          string    `json:"url"`
+	Status       int       `json:"status"`
+	ContentType  string    `json:"content_type,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+	Downloaded   time.Time `json:"downloaded"`
+	// the info values of the page
+	Info map[string]string `json:"info,omitempty"`
+}
+
+func NewSidecar(src, page string, result *net.DownloadResult, info map[string]string) *Sidecar {
+	return &Sidecar{
+		Source:       src,
+		Page:         page,
+		URL:          result.URL,
+		Status:       result.Status,
+		ContentType:  result.ContentType,
+		ETag:         result.ETag,
+		LastModified: result.LastModified,
+		Size:         result.Size,
+		SHA256:       result.SHA256,
+		Downloaded:   time.Now().UTC(),
+		Info:         info,
+	}
+}
+
+// writes the sidecar of the file at dst
+func writeSidecar(dst string, sidecar *Sidecar) *hcl.Diagnostics {
+	buf := &bytes.Buffer{}
+
+	// urls can contain ampersands, do not escape them
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(sidecar); err != nil {
+		// this should never happen, since the sidecar is encodable
+		return &hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to marshal sidecar",
+			Detail:   fmt.Sprintf("%+v: %s", sidecar, err.Error()),
+		}}
+	}
+
+	path := dst + SidecarExtension
+
+	log.Debug().Str("destination", path).Msg("writing sidecar")
+
+	if err := utils.Io.WriteFile(utils.Fs, path, buf.Bytes(), os.ModePerm); err != nil {
+		return &hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to write sidecar file",
+			Detail:   fmt.Sprintf("%s: %s", path, err.Error()),
+		}}
+	}
+
+	return &hcl.Diagnostics{}
+}
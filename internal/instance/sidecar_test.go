@@ -0,0 +1,99 @@
+package instance
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestSidecar(t *testing.T) {
+	root := tu.GetOSRoot()
+	global := filepath.Join(root, "global")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+	cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(global)+`"
+	sidecar = true
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/a[^\"]+)"
+		capture = 1
+	}
+
+	asset "other" {
+		pattern = "<img src=\"([^\"]+/img/b[^\"]+)"
+		capture = 1
+		sidecar = false
+	}
+
+	info "title" {
+		pattern = "<title>([^<]+)"
+		capture = 1
+	}
+}`), "test.hcl")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	g := &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{}, URLs: []string{ts.URL + "/gallery/123/test"}}
+
+	g.BuildSiteCache()
+	if diags := g.BuildAssetCache(); diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	plan := g.Plan()
+	if !plan.Sites[0].Assets[0].Sidecar || plan.Sites[0].Assets[1].Sidecar {
+		t.Errorf("got: %+v, want the sidecar of the first asset only", plan.Sites[0].Assets)
+	}
+
+	if err := g.Download(); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	fc, err := utils.Io.ReadFile(utils.Fs, filepath.Join(global, "example", "a.jpg.json"))
+	if err != nil {
+		t.Fatalf("got: %v, want the sidecar of a.jpg", err)
+	}
+
+	var got Sidecar
+	if err := json.Unmarshal(fc, &got); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if got.Source != ts.URL+"/img/a.jpg" || got.URL != got.Source || got.Page != ts.URL+"/gallery/123/test" || got.Status != 200 {
+		t.Errorf("got: %+v, want the urls and the status of a.jpg", got)
+	}
+
+	// sha256 of "imagea"
+	if got.Size != 6 || got.SHA256 != "ae3f5abf4a40773c0da063d133877df8689ba45872d5bfee2dd2a0e3d203def8" {
+		t.Errorf("got: %+v, want the size and the digest of a.jpg", got)
+	}
+
+	if got.Info["title"] != "Grab Test Server" || got.Downloaded.IsZero() {
+		t.Errorf("got: %+v, want the info of the page and the time of the download", got)
+	}
+
+	if exists, _ := utils.Io.Exists(utils.Fs, filepath.Join(global, "example", "b.jpg.json")); exists {
+		t.Errorf("got: b.jpg.json, want no sidecar for the other asset")
+	}
+}
@@ -0,0 +1,327 @@
+package instance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/net"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// WatchStatePath returns the path of the state of the watch blocks of the downloads in location
+func WatchStatePath(location string) string {
+	return filepath.Join(location, ".grab", "watch.json")
+}
+
+// WatchSource is the state of a watch block
+type WatchSource struct {
+	// the last time the list was read
+	Polled time.Time `json:"polled"`
+	// the urls of the list that were already processed, with the time they were first seen
+	Seen map[string]time.Time `json:"seen"`
+}
+
+// WatchState keeps what was seen in the lists of the watch blocks, by name
+type WatchState struct {
+	Sources map[string]*WatchSource `json:"sources"`
+
+	location string
+}
+
+// LoadWatchState reads the state of the watch blocks of location, an empty state is returned if there is none
+func LoadWatchState(location string) (*WatchState, error) {
+	state := &WatchState{
+		Sources:  make(map[string]*WatchSource),
+		location: location,
+	}
+
+	path := WatchStatePath(location)
+
+	if exists, err := utils.Io.Exists(utils.Fs, path); err != nil || !exists {
+		return state, err
+	}
+
+	fc, err := utils.Io.ReadFile(utils.Fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(fc, state); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if state.Sources == nil {
+		state.Sources = make(map[string]*WatchSource)
+	}
+
+	return state, nil
+}
+
+func (w *WatchState) Save() error {
+	marshaled, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := WatchStatePath(w.location)
+
+	if err := utils.Fs.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return utils.Io.WriteFile(utils.Fs, path, marshaled, os.ModePerm)
+}
+
+// Source returns the state of the watch block name, creating it if needed
+func (w *WatchState) Source(name string) *WatchSource {
+	source, ok := w.Sources[name]
+	if !ok {
+		source = &WatchSource{Seen: make(map[string]time.Time)}
+		w.Sources[name] = source
+	}
+
+	if source.Seen == nil {
+		source.Seen = make(map[string]time.Time)
+	}
+
+	return source
+}
+
+// Reset clears the urls, the downloads and the info computed by BuildSiteCache and BuildAssetCache,
+// so that the instance can process other urls
+func (s *Grab) Reset() {
+	s.URLs = nil
+	s.TotalAssets = 0
+
+	for i := range s.Config.Sites {
+		s.Config.Sites[i].URLs = nil
+		s.Config.Sites[i].InfoMap = nil
+
+		for j := range s.Config.Sites[i].Assets {
+			s.Config.Sites[i].Assets[j].Downloads = nil
+			s.Config.Sites[i].Assets[j].Pages = nil
+			s.Config.Sites[i].Assets[j].Checksums = nil
+		}
+	}
+}
+
+// Run scrapes the urls and downloads their assets, like the get command
+func (s *Grab) Run(urls []string) error {
+	s.Reset()
+	s.URLs = urls
+
+	s.BuildSiteCache()
+	if diags := s.BuildAssetCache(); diags.HasErrors() {
+		return diags
+	}
+
+	return s.Download()
+}
+
+// RunForSite is like Run, but all the urls are handled by the site name, whatever its test pattern.
+// The other sites are removed from the configuration, use it on a Clone.
+func (s *Grab) RunForSite(name string, urls []string) error {
+	s.Reset()
+	s.URLs = urls
+
+	s.Config.Sites = utils.Filter(s.Config.Sites, func(site config.SiteConfig) bool {
+		return site.Name == name
+	})
+
+	if len(s.Config.Sites) == 0 {
+		return fmt.Errorf("unknown site %q", name)
+	}
+
+	s.Config.Sites[0].URLs = urls
+
+	if diags := s.BuildAssetCache(); diags.HasErrors() {
+		return diags
+	}
+
+	return s.Download()
+}
+
+// Poll reads the list of the watch block and returns the urls that were not seen before.
+// The urls that are not in the list anymore are forgotten, so that the state does not grow forever.
+func (s *Grab) Poll(watch config.WatchConfig, source *WatchSource) ([]string, error) {
+	reader := &utils.URLReader{
+		Fetch: func(url string) (string, error) {
+			return net.Fetch(url, s.listFetchOptions())
+		},
+	}
+
+	if watch.Column != nil {
+		reader.Column = *watch.Column
+	}
+
+	source.Polled = time.Now().UTC()
+
+	urls, diags := reader.FromList(watch.List)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	urls = utils.Unique(urls)
+
+	listed := make(map[string]bool, len(urls))
+	unseen := make([]string, 0)
+
+	for _, url := range urls {
+		listed[url] = true
+
+		if _, ok := source.Seen[url]; !ok {
+			unseen = append(unseen, url)
+		}
+	}
+
+	for url := range source.Seen {
+		if !listed[url] {
+			delete(source.Seen, url)
+		}
+	}
+
+	return unseen, nil
+}
+
+// Watch polls the lists of the watch blocks at their interval and runs the urls that were not seen before,
+// until ctx is done. Runs are not interrupted, the context is only checked between them.
+// If once is true, every list is polled a single time, regardless of its interval.
+// afterRun is called after every run with the name of the watch block and the summary of the run.
+func (s *Grab) Watch(ctx context.Context, state *WatchState, once bool, afterRun func(name string, report *Report)) error {
+	if len(s.Config.Watches) == 0 {
+		return fmt.Errorf("no watch blocks in the configuration")
+	}
+
+	// the handlers cannot be removed, they dispatch to the report and to the failures of the current run
+	var mu sync.Mutex
+	var report *Report
+	failed := make(map[string]bool)
+
+	s.OnEvent(func(e *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if e.Type == EventPageFailed {
+			failed[e.Page] = true
+		}
+
+		if report != nil {
+			report.Handle(e)
+		}
+	})
+
+	save := func() {
+		if s.Flags.DryRun {
+			return
+		}
+
+		if err := state.Save(); err != nil {
+			log.Err(err).Str("path", WatchStatePath(state.location)).Msg("could not write the watch state")
+		}
+	}
+
+	intervals := make([]time.Duration, len(s.Config.Watches))
+	for i, watch := range s.Config.Watches {
+		// already validated by config.ValidateConfig
+		intervals[i], _ = time.ParseDuration(watch.Interval)
+	}
+
+	due := func(i int) time.Time {
+		if once {
+			return time.Time{}
+		}
+
+		return state.Source(s.Config.Watches[i].Name).Polled.Add(intervals[i])
+	}
+
+	polled := make([]bool, len(s.Config.Watches))
+
+	for {
+		// find the next list to poll
+		next := -1
+		for i := range s.Config.Watches {
+			if once && polled[i] {
+				continue
+			}
+
+			if next == -1 || due(i).Before(due(next)) {
+				next = i
+			}
+		}
+
+		if next == -1 {
+			return nil
+		}
+
+		if wait := time.Until(due(next)); wait > 0 {
+			log.Debug().Str("watch", s.Config.Watches[next].Name).Msgf("next poll in %s", wait.Round(time.Second))
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			return nil
+		}
+
+		watch := s.Config.Watches[next]
+		source := state.Source(watch.Name)
+		polled[next] = true
+
+		log.Info().Str("watch", watch.Name).Str("list", watch.List).Msg("polling")
+
+		urls, err := s.Poll(watch, source)
+		if err != nil {
+			log.Warn().Err(err).Str("watch", watch.Name).Str("list", watch.List).Msg("could not read the list, skipping")
+		} else if len(urls) == 0 {
+			log.Info().Str("watch", watch.Name).Msg("nothing new")
+		} else {
+			log.Info().Str("watch", watch.Name).Msgf("%d new %s", len(urls), utils.Plural(len(urls), "page", "pages"))
+
+			mu.Lock()
+			report = NewReport()
+			failed = make(map[string]bool)
+			mu.Unlock()
+
+			runErr := s.Run(urls)
+
+			mu.Lock()
+			report.Finish()
+
+			// the pages that could not be fetched are polled again
+			for _, url := range urls {
+				if !failed[url] {
+					source.Seen[url] = time.Now().UTC()
+				}
+			}
+			mu.Unlock()
+
+			if afterRun != nil {
+				afterRun(watch.Name, report)
+			}
+
+			s.Notify("watch "+watch.Name, report, runErr)
+
+			if runErr != nil {
+				if s.Flags.Strict {
+					save()
+					return runErr
+				}
+
+				log.Warn().Err(runErr).Str("watch", watch.Name).Msg("the run stopped early")
+			}
+		}
+
+		save()
+	}
+}
@@ -0,0 +1,148 @@
+package instance
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestPoll(t *testing.T) {
+	root := tu.GetOSRoot()
+	list := filepath.Join(root, "list.txt")
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+	utils.Io.WriteFile(utils.Fs, list, []byte("https://a.com/1\nhttps://a.com/2\n"), os.ModePerm)
+
+	g := &Grab{Config: &config.Config{}, Flags: &FlagsState{}}
+	watch := config.WatchConfig{Name: "list", List: list, Interval: "1h"}
+	source := &WatchSource{Seen: map[string]time.Time{
+		"https://a.com/1":   {},
+		"https://a.com/old": {},
+	}}
+
+	got, err := g.Poll(watch, source)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if want := []string{"https://a.com/2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+
+	// the urls that left the list are forgotten
+	if _, ok := source.Seen["https://a.com/old"]; ok {
+		t.Errorf("got: %v, want the old url to be removed", source.Seen)
+	}
+
+	if source.Polled.IsZero() {
+		t.Errorf("got: zero, want the time of the poll")
+	}
+
+	if _, err := g.Poll(config.WatchConfig{Name: "missing", List: filepath.Join(root, "missing.txt")}, source); err == nil {
+		t.Errorf("got: nil, want an error")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	root := tu.GetOSRoot()
+	location := filepath.Join(root, "global")
+	list := filepath.Join(root, "list.txt")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	defer ts.Close()
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+	utils.Io.WriteFile(utils.Fs, list, []byte(ts.URL+"/gallery/123/test\n"+ts.URL+"/givesNotFound\n"), os.ModePerm)
+
+	cfg, _, regexCache, diags := config.Parse([]byte(`
+global {
+	location = "`+tu.EscapeHCLString(location)+`"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/a[^\"]+)"
+		capture = 1
+	}
+}
+
+watch "list" {
+	list = "`+tu.EscapeHCLString(list)+`"
+	interval = "1h"
+}
+`), "grab.hcl")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	g := &Grab{Config: cfg, RegexCache: regexCache, Flags: &FlagsState{}}
+
+	state, err := LoadWatchState(location)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	runs := make([]Counts, 0)
+	afterRun := func(name string, report *Report) {
+		runs = append(runs, report.Totals)
+	}
+
+	if err := g.Watch(context.Background(), state, true, afterRun); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if want := []Counts{{PagesFetched: 1, PagesFailed: 1, AssetsDownloaded: 1, Bytes: 6}}; !reflect.DeepEqual(runs, want) {
+		t.Fatalf("got: %+v, want: %+v", runs, want)
+	}
+
+	if got, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(location, "example", "a.jpg")); string(got) != "imagea" {
+		t.Errorf("got: %q, want: %q", got, "imagea")
+	}
+
+	// the failed page is not seen, it is polled again
+	loaded, err := LoadWatchState(location)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	seen := loaded.Source("list").Seen
+	if _, ok := seen[ts.URL+"/gallery/123/test"]; !ok || len(seen) != 1 {
+		t.Errorf("got: %v, want only the fetched page", seen)
+	}
+
+	if err := g.Watch(context.Background(), loaded, true, afterRun); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if len(runs) != 2 || runs[1].PagesFetched != 0 || runs[1].PagesFailed != 1 {
+		t.Errorf("got: %+v, want only the failed page to be run again", runs)
+	}
+
+	// the lists were just polled, a canceled context stops the wait for the next poll
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := g.Watch(ctx, loaded, false, afterRun); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if len(runs) != 2 {
+		t.Errorf("got: %d runs, want: 2", len(runs))
+	}
+}
@@ -0,0 +1,86 @@
+package instance
+
+import (
+	"path/filepath"
+
+	"github.com/everdrone/grab/internal/net"
+)
+
+type SiteMatch struct {
+	// the name of the site block
+	Name string
+	// the test pattern that matched
+	Test string
+}
+
+type AssetExplanation struct {
+	// the name of the asset block
+	Name string
+	// the effective network options of the asset, with secrets masked
+	Network *net.FetchOptions
+}
+
+type Explanation struct {
+	// the url being explained
+	URL string
+	// all the sites whose test pattern matches the url, in configuration order
+	Matches []SiteMatch
+	// the site that handles the url (the first match), nil if no site matches
+	Site *SiteMatch
+	// the directory where the assets of the page will be downloaded
+	Location string
+	// true if the location depends on the page body, in which case Location
+	// only contains the site directory
+	LocationFromBody bool
+	// the reason why the subdirectory could not be computed from the url, if any.
+	// get fails on such a page, Location is empty.
+	LocationError error
+	// the effective network options used to fetch the page, with secrets masked
+	Network *net.FetchOptions
+	// the assets of the selected site
+	Assets []AssetExplanation
+}
+
+// Explain describes how the url would be handled, without accessing the network
+func (s *Grab) Explain(url string) *Explanation {
+	explanation := &Explanation{
+		URL:     url,
+		Matches: make([]SiteMatch, 0),
+		Assets:  make([]AssetExplanation, 0),
+	}
+
+	matches := s.MatchSites(url)
+	for _, i := range matches {
+		explanation.Matches = append(explanation.Matches, SiteMatch{
+			Name: s.Config.Sites[i].Name,
+			Test: s.Config.Sites[i].Test,
+		})
+	}
+
+	if len(matches) == 0 {
+		return explanation
+	}
+
+	// the first match wins
+	siteIndex := matches[0]
+	site := s.Config.Sites[siteIndex]
+	explanation.Site = &explanation.Matches[0]
+
+	if site.Subdirectory != nil && site.Subdirectory.From != "url" {
+		explanation.Location = filepath.Join(s.Config.Global.Location, site.Name)
+		explanation.LocationFromBody = true
+	} else {
+		explanation.Location, explanation.LocationError = s.Subdirectory(siteIndex, url, "")
+	}
+
+	explanation.Network = net.MergeFetchOptionsChain(s.Config.Global.Network, site.Network).Masked()
+
+	for _, asset := range site.Assets {
+		explanation.Assets = append(explanation.Assets, AssetExplanation{
+			Name:    asset.Name,
+			Network: net.MergeFetchOptionsChain(s.Config.Global.Network, site.Network, asset.Network).Masked(),
+		})
+	}
+
+	return explanation
+}
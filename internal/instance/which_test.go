@@ -0,0 +1,186 @@
+package instance
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/net"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestExplain(t *testing.T) {
+	root := tu.GetOSRoot()
+	globalLocation := filepath.Join(root, "global")
+
+	cfg := `
+global {
+	location = "` + tu.EscapeHCLString(globalLocation) + `"
+
+	network {
+		headers = {
+			"Authorization" = "Bearer secret"
+		}
+	}
+}
+
+site "gallery" {
+	test = "example\\.com\\/gallery"
+
+	network {
+		retries = 3
+	}
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+)"
+		capture = 1
+
+		network {
+			inherit = false
+			timeout = 10000
+		}
+	}
+
+	subdirectory {
+		pattern = "gallery\\/(\\d+)"
+		capture = 1
+		from    = url
+	}
+}
+
+site "user" {
+	test = "example\\.com\\/(gallery|user)"
+
+	info "name" {
+		pattern = "<h1>([^<]+)"
+		capture = 1
+	}
+
+	subdirectory {
+		pattern = "<h2>([^<]+)"
+		capture = 1
+		from    = body
+	}
+}
+`
+
+	tests := []struct {
+		Name string
+		URL  string
+		Want *Explanation
+		// the subdirectory could not be captured from the url
+		WantLocationError bool
+	}{
+		{
+			Name: "no match",
+			URL:  "https://other.com",
+			Want: &Explanation{
+				URL:     "https://other.com",
+				Matches: []SiteMatch{},
+				Assets:  []AssetExplanation{},
+			},
+		},
+		{
+			Name: "first match wins",
+			URL:  "https://example.com/gallery/1337",
+			Want: &Explanation{
+				URL: "https://example.com/gallery/1337",
+				Matches: []SiteMatch{
+					{Name: "gallery", Test: "example\\.com\\/gallery"},
+					{Name: "user", Test: "example\\.com\\/(gallery|user)"},
+				},
+				Site:     &SiteMatch{Name: "gallery", Test: "example\\.com\\/gallery"},
+				Location: filepath.Join(globalLocation, "gallery", "1337"),
+				Network: &net.FetchOptions{
+					Timeout: 3000,
+					Retries: 3,
+					Headers: map[string]string{"Authorization": "********"},
+				},
+				Assets: []AssetExplanation{
+					{
+						Name: "image",
+						Network: &net.FetchOptions{
+							Timeout: 10000,
+							Retries: 1,
+							Headers: map[string]string{},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "subdirectory not captured",
+			URL:  "https://example.com/gallery/new",
+			Want: &Explanation{
+				URL: "https://example.com/gallery/new",
+				Matches: []SiteMatch{
+					{Name: "gallery", Test: "example\\.com\\/gallery"},
+					{Name: "user", Test: "example\\.com\\/(gallery|user)"},
+				},
+				Site: &SiteMatch{Name: "gallery", Test: "example\\.com\\/gallery"},
+				// get fails on the page, there is no location
+				Network: &net.FetchOptions{
+					Timeout: 3000,
+					Retries: 3,
+					Headers: map[string]string{"Authorization": "********"},
+				},
+				Assets: []AssetExplanation{
+					{
+						Name: "image",
+						Network: &net.FetchOptions{
+							Timeout: 10000,
+							Retries: 1,
+							Headers: map[string]string{},
+						},
+					},
+				},
+			},
+			WantLocationError: true,
+		},
+		{
+			Name: "location from body",
+			URL:  "https://example.com/user/everdrone",
+			Want: &Explanation{
+				URL: "https://example.com/user/everdrone",
+				Matches: []SiteMatch{
+					{Name: "user", Test: "example\\.com\\/(gallery|user)"},
+				},
+				Site:             &SiteMatch{Name: "user", Test: "example\\.com\\/(gallery|user)"},
+				Location:         filepath.Join(globalLocation, "user"),
+				LocationFromBody: true,
+				Network: &net.FetchOptions{
+					Timeout: 3000,
+					Retries: 1,
+					Headers: map[string]string{"Authorization": "********"},
+				},
+				Assets: []AssetExplanation{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			g := New(nil)
+			g.Flags = &FlagsState{}
+
+			config, _, regexCache, diags := config.Parse([]byte(cfg), "test.hcl")
+			if diags.HasErrors() {
+				tc.Fatalf("got errors: %+v", diags)
+			}
+			g.Config = config
+			g.RegexCache = regexCache
+
+			got := g.Explain(tt.URL)
+
+			if (got.LocationError != nil) != tt.WantLocationError {
+				tc.Errorf("got: %v, want error: %v", got.LocationError, tt.WantLocationError)
+			}
+			got.LocationError = nil
+
+			if !reflect.DeepEqual(got, tt.Want) {
+				tc.Errorf("got: %+v, want: %+v", got, tt.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,451 @@
+package lsp
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/everdrone/grab/internal/config"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Document is an open configuration file
+type Document struct {
+	URI  string
+	Text string
+}
+
+// returns the path of the document, used as the filename of the diagnostics and to choose the syntax
+func (d *Document) filename() string {
+	u, err := url.Parse(d.URI)
+	if err != nil || u.Scheme != "file" {
+		return d.URI
+	}
+
+	return filepath.FromSlash(u.Path)
+}
+
+// completion, hover and go to definition are only available for the HCL native syntax
+func (d *Document) isNative() bool {
+	ext := strings.ToLower(filepath.Ext(d.filename()))
+	return ext != ".json" && ext != ".yaml" && ext != ".yml"
+}
+
+// Diagnostics returns the errors of the syntax, of the spec validation, of the static validation
+// and of the regular expressions compilation
+func (d *Document) Diagnostics() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+
+	_, _, _, diags := config.Parse([]byte(d.Text), d.filename())
+	for _, diag := range diags {
+		severity := SeverityError
+		if diag.Severity == hcl.DiagWarning {
+			severity = SeverityWarning
+		}
+
+		message := diag.Summary
+		if diag.Detail != "" {
+			message += ": " + diag.Detail
+		}
+
+		r := Range{}
+		if diag.Subject != nil {
+			r = d.hclRange(*diag.Subject)
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    r,
+			Severity: severity,
+			Source:   config.Name,
+			Message:  message,
+		})
+	}
+
+	return diagnostics
+}
+
+// Completion returns the attributes and blocks allowed in the block that contains the position
+func (d *Document) Completion(pos Position) []CompletionItem {
+	items := make([]CompletionItem, 0)
+	if !d.isNative() {
+		return items
+	}
+
+	offset := d.offset(pos)
+
+	// only complete names at the beginning of a line
+	if !isNamePrefix(d.Text[lineStart(d.Text, offset):offset]) {
+		return items
+	}
+
+	path, ok := blockPath(d.Text[:offset])
+	if !ok {
+		return items
+	}
+
+	spec := specAt(path)
+	if spec == nil {
+		return items
+	}
+
+	for name, child := range specChildren(spec) {
+		item := CompletionItem{
+			Label:         name,
+			Documentation: config.Doc(append(path, name)...),
+		}
+
+		switch c := child.(type) {
+		case *hcldec.AttrSpec:
+			item.Kind = KindProperty
+			item.Detail = typeName(c.Type)
+			item.InsertText = name + " = "
+		default:
+			item.Kind = KindModule
+			item.Detail = "block"
+		}
+
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+
+	return items
+}
+
+// Hover returns the documentation of the attribute or block name under the position
+func (d *Document) Hover(pos Position) *Hover {
+	if !d.isNative() {
+		return nil
+	}
+
+	offset := d.offset(pos)
+	start, end := wordAt(d.Text, offset)
+	if start == end {
+		return nil
+	}
+
+	// the name must be the first word of the line
+	if strings.TrimSpace(d.Text[lineStart(d.Text, start):start]) != "" {
+		return nil
+	}
+
+	path, ok := blockPath(d.Text[:start])
+	if !ok {
+		return nil
+	}
+
+	spec := specAt(path)
+	if spec == nil {
+		return nil
+	}
+
+	name := d.Text[start:end]
+	child, ok := specChildren(spec)[name]
+	if !ok {
+		return nil
+	}
+
+	kind := "block"
+	if attr, ok := child.(*hcldec.AttrSpec); ok {
+		kind = typeName(attr.Type)
+	}
+
+	value := fmt.Sprintf("**%s** (%s)", strings.Join(append(path, name), "."), kind)
+	if doc := config.Doc(append(path, name)...); doc != "" {
+		value += "\n\n" + doc
+	}
+
+	r := Range{Start: d.position(start), End: d.position(end)}
+
+	return &Hover{
+		Contents: markupContent{Kind: "markdown", Value: value},
+		Range:    &r,
+	}
+}
+
+// Definition returns the location of the named capture group referenced by the capture attribute
+// under the position, inside the pattern attribute of the same block
+func (d *Document) Definition(pos Position) *Location {
+	if !d.isNative() {
+		return nil
+	}
+
+	// the parser recovers from most errors, the body is usable even if the document is incomplete
+	file, _ := hclsyntax.ParseConfig([]byte(d.Text), d.filename(), hcl.InitialPos)
+	if file == nil {
+		return nil
+	}
+
+	offset := d.offset(pos)
+
+	body := innermostBody(file.Body.(*hclsyntax.Body), offset)
+	if body == nil {
+		return nil
+	}
+
+	capture, ok := body.Attributes["capture"]
+	if !ok || !contains(capture.Expr.Range(), offset) {
+		return nil
+	}
+
+	pattern, ok := body.Attributes["pattern"]
+	if !ok {
+		return nil
+	}
+
+	val, diags := capture.Expr.Value(nil)
+	if diags.HasErrors() || val.IsNull() || !val.Type().Equals(cty.String) {
+		return nil
+	}
+	name := val.AsString()
+
+	// named groups can be written as (?P<name>) or (?<name>)
+	r := pattern.Expr.Range()
+	src := d.Text[r.Start.Byte:r.End.Byte]
+	loc := regexp.MustCompile(`\(\?P?<(` + regexp.QuoteMeta(name) + `)>`).FindStringSubmatchIndex(src)
+	if loc == nil {
+		return nil
+	}
+
+	start, end := r.Start.Byte+loc[2], r.Start.Byte+loc[3]
+
+	return &Location{
+		URI:   d.URI,
+		Range: Range{Start: d.position(start), End: d.position(end)},
+	}
+}
+
+// returns the body of the innermost block that contains offset
+func innermostBody(body *hclsyntax.Body, offset int) *hclsyntax.Body {
+	for _, block := range body.Blocks {
+		if contains(block.Range(), offset) {
+			if inner := innermostBody(block.Body, offset); inner != nil {
+				return inner
+			}
+			return block.Body
+		}
+	}
+
+	return nil
+}
+
+func contains(r hcl.Range, offset int) bool {
+	return r.Start.Byte <= offset && offset <= r.End.Byte
+}
+
+// returns the types of the blocks that are open at the end of src.
+// The second value is false if the end of src is inside an expression (e.g. a map) instead of a block.
+func blockPath(src string) ([]string, bool) {
+	tokens, _ := hclsyntax.LexConfig([]byte(src), "", hcl.InitialPos)
+
+	// an empty string marks braces that do not open a block
+	stack := make([]string, 0)
+	line := make(hclsyntax.Tokens, 0)
+
+	for _, token := range tokens {
+		switch token.Type {
+		case hclsyntax.TokenNewline:
+			line = line[:0]
+			continue
+		case hclsyntax.TokenComment:
+			// line comments include the new line
+			if strings.HasSuffix(string(token.Bytes), "\n") {
+				line = line[:0]
+			}
+			continue
+		case hclsyntax.TokenOBrace:
+			blockType := ""
+			if len(line) > 0 && line[0].Type == hclsyntax.TokenIdent && !hasEqual(line) {
+				blockType = string(line[0].Bytes)
+			}
+			stack = append(stack, blockType)
+		case hclsyntax.TokenCBrace:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+
+		line = append(line, token)
+	}
+
+	for _, blockType := range stack {
+		if blockType == "" {
+			return nil, false
+		}
+	}
+
+	return stack, true
+}
+
+func hasEqual(tokens hclsyntax.Tokens) bool {
+	for _, token := range tokens {
+		if token.Type == hclsyntax.TokenEqual {
+			return true
+		}
+	}
+
+	return false
+}
+
+// returns the spec of the block at path, nil if path does not describe valid nested blocks
+func specAt(path []string) *hcldec.ObjectSpec {
+	spec := config.ConfigSpec
+
+	for _, blockType := range path {
+		child, ok := specChildren(spec)[blockType]
+		if !ok {
+			return nil
+		}
+
+		var nested hcldec.Spec
+		switch c := child.(type) {
+		case *hcldec.BlockSpec:
+			nested = c.Nested
+		case *hcldec.BlockTupleSpec:
+			nested = c.Nested
+		case *hcldec.BlockListSpec:
+			nested = c.Nested
+		default:
+			return nil
+		}
+
+		if spec, ok = nested.(*hcldec.ObjectSpec); !ok {
+			return nil
+		}
+	}
+
+	return spec
+}
+
+// returns the attributes and blocks of spec, by name
+func specChildren(spec *hcldec.ObjectSpec) map[string]hcldec.Spec {
+	children := make(map[string]hcldec.Spec)
+
+	for _, child := range *spec {
+		switch c := child.(type) {
+		case *hcldec.AttrSpec:
+			children[c.Name] = c
+		case *hcldec.BlockSpec:
+			children[c.TypeName] = c
+		case *hcldec.BlockTupleSpec:
+			children[c.TypeName] = c
+		case *hcldec.BlockListSpec:
+			children[c.TypeName] = c
+		}
+	}
+
+	return children
+}
+
+func typeName(t cty.Type) string {
+	return t.FriendlyNameForConstraint()
+}
+
+func isNamePrefix(str string) bool {
+	str = strings.TrimLeft(str, " \t")
+	for _, r := range str {
+		if !isNameRune(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+// returns the byte offsets of the identifier around offset
+func wordAt(text string, offset int) (int, int) {
+	start, end := offset, offset
+
+	for start > 0 && isNameRune(rune(text[start-1])) {
+		start--
+	}
+	for end < len(text) && isNameRune(rune(text[end])) {
+		end++
+	}
+
+	return start, end
+}
+
+func lineStart(text string, offset int) int {
+	return strings.LastIndexByte(text[:offset], '\n') + 1
+}
+
+// converts a LSP position to a byte offset, clamped to the document
+func (d *Document) offset(pos Position) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		next := strings.IndexByte(d.Text[offset:], '\n')
+		if next < 0 {
+			return len(d.Text)
+		}
+		offset += next + 1
+	}
+
+	for units := 0; units < pos.Character && offset < len(d.Text); {
+		r, size := utf8.DecodeRuneInString(d.Text[offset:])
+		if r == '\n' {
+			break
+		}
+
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+
+	return offset
+}
+
+// converts a byte offset to a LSP position
+func (d *Document) position(offset int) Position {
+	if offset > len(d.Text) {
+		offset = len(d.Text)
+	}
+
+	start := lineStart(d.Text, offset)
+
+	return Position{
+		Line:      strings.Count(d.Text[:start], "\n"),
+		Character: len(utf16.Encode([]rune(d.Text[start:offset]))),
+	}
+}
+
+// converts a HCL range to a LSP range. Lines and columns are used instead of byte offsets,
+// because the offsets of the JSON and YAML syntaxes do not refer to the original text
+func (d *Document) hclRange(r hcl.Range) Range {
+	return Range{Start: d.hclPosition(r.Start), End: d.hclPosition(r.End)}
+}
+
+func (d *Document) hclPosition(pos hcl.Pos) Position {
+	if pos.Line < 1 {
+		return Position{}
+	}
+
+	lines := strings.SplitAfter(d.Text, "\n")
+	if pos.Line > len(lines) {
+		return Position{Line: pos.Line - 1}
+	}
+
+	// columns count characters, LSP characters count UTF-16 code units
+	runes := []rune(strings.TrimRight(lines[pos.Line-1], "\r\n"))
+	column := pos.Column - 1
+	if column > len(runes) {
+		column = len(runes)
+	}
+	if column < 0 {
+		column = 0
+	}
+
+	return Position{Line: pos.Line - 1, Character: len(utf16.Encode(runes[:column]))}
+}
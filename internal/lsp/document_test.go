@@ -0,0 +1,274 @@
+package lsp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testConfig = `global {
+  location = "downloads"
+}
+
+# a comment
+site "example" {
+  test = "example\\.com"
+
+  network {
+    headers = {
+      "User-Agent" = "grab"
+    }
+  }
+
+  info "title" {
+    pattern = "<title>(?P<title>[^<]+)</title>"
+    capture = "title"
+  }
+
+  
+}
+`
+
+// returns the position of the first occurrence of marker, plus delta characters
+func positionOf(tc *testing.T, doc *Document, marker string, delta int) Position {
+	offset := strings.Index(doc.Text, marker)
+	if offset < 0 {
+		tc.Fatalf("marker %q not found", marker)
+	}
+
+	return doc.position(offset + delta)
+}
+
+func labels(items []CompletionItem) []string {
+	got := make([]string, 0, len(items))
+	for _, item := range items {
+		got = append(got, item.Label)
+	}
+
+	return got
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		Name      string
+		URI       string
+		Text      string
+		WantRange Range
+		WantMsg   string
+	}{
+		{
+			Name: "valid",
+			URI:  "file:///grab.hcl",
+			Text: testConfig,
+		},
+		{
+			Name: "invalid regex",
+			URI:  "file:///grab.hcl",
+			Text: "global {\n  location = \"x\"\n}\n\nsite \"a\" {\n  test = \"a(\"\n  info \"b\" {\n    pattern = \"b\"\n    capture = 0\n  }\n}\n",
+			WantRange: Range{
+				Start: Position{Line: 5, Character: 9},
+				End:   Position{Line: 5, Character: 13},
+			},
+			WantMsg: "Invalid regex pattern: error parsing regexp: missing closing ): `a(`",
+		},
+		{
+			Name: "static validation",
+			URI:  "file:///grab.hcl",
+			Text: "global {\n  location = \"x\"\n}\n\nsite \"a\" {\n  test = \"a\"\n}\n",
+			WantRange: Range{
+				Start: Position{Line: 4, Character: 9},
+				End:   Position{Line: 6, Character: 1},
+			},
+			WantMsg: "Insufficient \"site\" and \"info\" blocks: At least one asset or one info block must be defined inside a \"site\" block.",
+		},
+		{
+			Name: "yaml",
+			URI:  "file:///grab.yaml",
+			Text: "global:\n  location: x\nsite:\n  a:\n    test: a(\n    info:\n      b:\n        pattern: b\n        capture: 0\n",
+			WantRange: Range{
+				// the closing quote of the converted string is clamped to the end of the line
+				Start: Position{Line: 4, Character: 10},
+				End:   Position{Line: 4, Character: 12},
+			},
+			WantMsg: "Invalid regex pattern: error parsing regexp: missing closing ): `a(`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			doc := &Document{URI: tt.URI, Text: tt.Text}
+			got := doc.Diagnostics()
+
+			if tt.WantMsg == "" {
+				if len(got) != 0 {
+					tc.Errorf("got: %v, want no diagnostics", got)
+				}
+				return
+			}
+
+			if len(got) != 1 {
+				tc.Fatalf("got: %v, want one diagnostic", got)
+			}
+
+			if got[0].Message != tt.WantMsg {
+				tc.Errorf("got: %q, want: %q", got[0].Message, tt.WantMsg)
+			}
+
+			if got[0].Range != tt.WantRange {
+				tc.Errorf("got: %v, want: %v", got[0].Range, tt.WantRange)
+			}
+		})
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	doc := &Document{URI: "file:///grab.hcl", Text: testConfig}
+
+	tests := []struct {
+		Name   string
+		Marker string
+		Delta  int
+		Want   []string
+	}{
+		{
+			Name:   "root",
+			Marker: "# a comment",
+			Want:   []string{"global", "schedule", "site", "watch"},
+		},
+		{
+			Name:   "site",
+			Marker: "\n  \n}",
+			Delta:  3,
+			Want:   []string{"asset", "fixture", "hook", "info", "max_age", "network", "on_exists", "sidecar", "subdirectory", "test"},
+		},
+		{
+			Name:   "site network",
+			Marker: "    headers",
+			Delta:  4,
+			Want:   []string{"headers", "inherit", "retries", "timeout"},
+		},
+		{
+			Name:   "partial name",
+			Marker: "    capture",
+			Delta:  6,
+			Want:   []string{"capture", "pattern"},
+		},
+		{
+			Name:   "inside a map",
+			Marker: "      \"User-Agent\"",
+			Delta:  6,
+			Want:   []string{},
+		},
+		{
+			Name:   "after an equal sign",
+			Marker: "\"downloads\"",
+			Want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			got := labels(doc.Completion(positionOf(tc, doc, tt.Marker, tt.Delta)))
+
+			if !reflect.DeepEqual(got, tt.Want) {
+				tc.Errorf("got: %v, want: %v", got, tt.Want)
+			}
+		})
+	}
+}
+
+func TestHover(t *testing.T) {
+	doc := &Document{URI: "file:///grab.hcl", Text: testConfig}
+
+	tests := []struct {
+		Name         string
+		Marker       string
+		Delta        int
+		WantContains string
+	}{
+		{
+			Name:         "attribute",
+			Marker:       "test =",
+			Delta:        2,
+			WantContains: "**site.test** (string)",
+		},
+		{
+			Name:         "block",
+			Marker:       "info \"title\"",
+			WantContains: "**site.info** (block)",
+		},
+		{
+			Name:         "inherited documentation",
+			Marker:       "headers =",
+			WantContains: "The headers sent with every request",
+		},
+		{
+			Name:   "value",
+			Marker: "\"downloads\"",
+			Delta:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			got := doc.Hover(positionOf(tc, doc, tt.Marker, tt.Delta))
+
+			if tt.WantContains == "" {
+				if got != nil {
+					tc.Errorf("got: %v, want: nil", got)
+				}
+				return
+			}
+
+			if got == nil {
+				tc.Fatalf("got: nil, want: %q", tt.WantContains)
+			}
+
+			if !strings.Contains(got.Contents.Value, tt.WantContains) {
+				tc.Errorf("got: %q, does not contain: %q", got.Contents.Value, tt.WantContains)
+			}
+		})
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	doc := &Document{URI: "file:///grab.hcl", Text: testConfig}
+
+	got := doc.Definition(positionOf(t, doc, "capture = \"title\"", 12))
+	want := &Location{
+		URI: "file:///grab.hcl",
+		Range: Range{
+			Start: positionOf(t, doc, "(?P<title>", 4),
+			End:   positionOf(t, doc, "(?P<title>", 9),
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+
+	// the cursor is not on the capture attribute
+	if got := doc.Definition(positionOf(t, doc, "pattern = ", 0)); got != nil {
+		t.Errorf("got: %v, want: nil", got)
+	}
+}
+
+func TestPositionOffset(t *testing.T) {
+	doc := &Document{Text: "a\n😀b\nc"}
+
+	for offset, want := range map[int]Position{
+		0: {Line: 0, Character: 0},
+		2: {Line: 1, Character: 0},
+		6: {Line: 1, Character: 2},
+		8: {Line: 2, Character: 0},
+	} {
+		got := doc.position(offset)
+		if got != want {
+			t.Errorf("position(%d) got: %v, want: %v", offset, got, want)
+		}
+
+		if back := doc.offset(got); back != offset {
+			t.Errorf("offset(%v) got: %d, want: %d", got, back, offset)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Server is a language server for the configuration files, speaking JSON-RPC over a pair of streams
+// (usually stdin and stdout). Documents are synchronized in full on every change.
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// open documents, by uri
+	documents map[string]*Document
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: make(map[string]*Document),
+	}
+}
+
+// Run handles the messages until the client sends the exit notification or closes the input stream
+func (s *Server) Run() error {
+	for {
+		body, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := s.replyError(nil, codeParseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		if err := s.handle(&msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg *message) error {
+	log.Debug().Str("method", msg.Method).Msg("received message")
+
+	switch msg.Method {
+	case "initialize":
+		return s.reply(msg.ID, initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:   1,
+				CompletionProvider: completionOptions{},
+				HoverProvider:      true,
+				DefinitionProvider: true,
+			},
+			ServerInfo: serverInfo{Name: config.Name, Version: config.Version},
+		})
+
+	case "shutdown":
+		return s.reply(msg.ID, nil)
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+
+		return s.update(params.TextDocument.URI, params.TextDocument.Text)
+
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+
+		// full synchronization, the last change contains the whole document
+		return s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+
+		delete(s.documents, params.TextDocument.URI)
+
+		// clear the diagnostics of the closed document
+		return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+
+	case "textDocument/completion", "textDocument/hover", "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return s.replyError(msg.ID, codeInvalidParams, err.Error())
+		}
+
+		doc, ok := s.documents[params.TextDocument.URI]
+		if !ok {
+			return s.replyError(msg.ID, codeInvalidParams, fmt.Sprintf("document not open: %s", params.TextDocument.URI))
+		}
+
+		switch msg.Method {
+		case "textDocument/completion":
+			return s.reply(msg.ID, doc.Completion(params.Position))
+		case "textDocument/hover":
+			return s.reply(msg.ID, doc.Hover(params.Position))
+		default:
+			return s.reply(msg.ID, doc.Definition(params.Position))
+		}
+
+	default:
+		// notifications that are not supported are ignored, requests must get a response
+		if msg.ID != nil {
+			return s.replyError(msg.ID, codeMethodNotFound, fmt.Sprintf("method not supported: %s", msg.Method))
+		}
+		return nil
+	}
+}
+
+// stores the new content of the document and publishes its diagnostics
+func (s *Server) update(uri, text string) error {
+	doc := &Document{URI: uri, Text: text}
+	s.documents[uri] = doc
+
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: doc.Diagnostics(),
+	})
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}) error {
+	return s.write(response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) replyError(id *json.RawMessage, code int, message string) error {
+	return s.write(errorResponse{JSONRPC: "2.0", ID: id, Error: &responseError{Code: code, Message: message}})
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return s.write(notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// reads the body of the next message, framed by the base protocol headers
+func (s *Server) read() ([]byte, error) {
+	headers, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || strings.Contains(err.Error(), "EOF") {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %w", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+func (s *Server) write(v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
@@ -0,0 +1,118 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"testing"
+)
+
+func frame(tc *testing.T, messages ...interface{}) io.Reader {
+	buf := &bytes.Buffer{}
+
+	for _, msg := range messages {
+		body, err := json.Marshal(msg)
+		if err != nil {
+			tc.Fatal(err)
+		}
+
+		fmt.Fprintf(buf, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	return buf
+}
+
+func unframe(tc *testing.T, r io.Reader) []map[string]interface{} {
+	messages := make([]map[string]interface{}, 0)
+	reader := bufio.NewReader(r)
+
+	for {
+		headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+		if err != nil {
+			return messages
+		}
+
+		length, _ := strconv.Atoi(headers.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			tc.Fatal(err)
+		}
+
+		var msg map[string]interface{}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			tc.Fatal(err)
+		}
+
+		messages = append(messages, msg)
+	}
+}
+
+func TestServer(t *testing.T) {
+	uri := "file:///project/grab.hcl"
+
+	in := frame(t,
+		map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]interface{}{}},
+		map[string]interface{}{"jsonrpc": "2.0", "method": "initialized", "params": map[string]interface{}{}},
+		map[string]interface{}{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri, "languageId": "hcl", "version": 1, "text": "global {\n"},
+		}},
+		map[string]interface{}{"jsonrpc": "2.0", "method": "textDocument/didChange", "params": map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+			"contentChanges": []interface{}{map[string]interface{}{"text": testConfig}},
+		}},
+		map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "textDocument/hover", "params": map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri},
+			"position":     map[string]interface{}{"line": 1, "character": 3},
+		}},
+		map[string]interface{}{"jsonrpc": "2.0", "id": 3, "method": "textDocument/unknown", "params": map[string]interface{}{}},
+		map[string]interface{}{"jsonrpc": "2.0", "id": 4, "method": "shutdown"},
+		map[string]interface{}{"jsonrpc": "2.0", "method": "exit"},
+	)
+
+	out := &bytes.Buffer{}
+	if err := NewServer(in, out).Run(); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	messages := unframe(t, out)
+	if len(messages) != 6 {
+		t.Fatalf("got: %d messages, want: 6 (%v)", len(messages), messages)
+	}
+
+	// initialize
+	capabilities := messages[0]["result"].(map[string]interface{})["capabilities"].(map[string]interface{})
+	if capabilities["hoverProvider"] != true || capabilities["definitionProvider"] != true {
+		t.Errorf("got: %v, want hover and definition capabilities", capabilities)
+	}
+
+	// diagnostics of the incomplete document
+	diagnostics := messages[1]["params"].(map[string]interface{})["diagnostics"].([]interface{})
+	if messages[1]["method"] != "textDocument/publishDiagnostics" || len(diagnostics) == 0 {
+		t.Errorf("got: %v, want diagnostics", messages[1])
+	}
+
+	// diagnostics of the complete document
+	diagnostics = messages[2]["params"].(map[string]interface{})["diagnostics"].([]interface{})
+	if len(diagnostics) != 0 {
+		t.Errorf("got: %v, want no diagnostics", diagnostics)
+	}
+
+	// hover
+	if messages[3]["id"] != 2.0 || messages[3]["result"] == nil {
+		t.Errorf("got: %v, want a hover result", messages[3])
+	}
+
+	// unknown method
+	if messages[4]["id"] != 3.0 || messages[4]["error"].(map[string]interface{})["code"] != float64(codeMethodNotFound) {
+		t.Errorf("got: %v, want method not found", messages[4])
+	}
+
+	// shutdown
+	if result, ok := messages[5]["result"]; messages[5]["id"] != 4.0 || !ok || result != nil {
+		t.Errorf("got: %v, want a null result", messages[5])
+	}
+}
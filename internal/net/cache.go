@@ -0,0 +1,293 @@
+package net
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+)
+
+// CacheStatus tells where the body returned by Cache.Fetch comes from
+type CacheStatus string
+
+const (
+	// the page was fetched
+	CacheMiss CacheStatus = "miss"
+	// the page was fresh in the cache, no request was sent
+	CacheHit CacheStatus = "hit"
+	// the page was stale in the cache, the server answered 304 Not Modified
+	CacheRevalidated CacheStatus = "revalidated"
+)
+
+// Cache stores the responses of the pages in a directory, following the rules of a private cache (RFC 7234)
+type Cache struct {
+	Dir string
+	// one of config.CacheModes, CacheOff fetches every page without reading or writing the cache
+	Mode string
+}
+
+// cacheEntry is the file of a cached response
+type cacheEntry struct {
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	// the values of the request headers listed in the Vary header of the response
+	Vary         map[string]string `json:"vary,omitempty"`
+	Body         string            `json:"body"`
+	RequestTime  time.Time         `json:"request_time"`
+	ResponseTime time.Time         `json:"response_time"`
+}
+
+// Fetch returns the body of the page at url, from the cache if it is still fresh.
+// maxAge, if not zero, replaces the freshness lifetime given by the headers of the response
+// and makes every response storable.
+func (c *Cache) Fetch(url string, options *FetchOptions, maxAge time.Duration) (string, CacheStatus, error) {
+	if c == nil || c.Mode == "" || c.Mode == config.CacheOff {
+		body, err := Fetch(url, options)
+		return body, CacheMiss, err
+	}
+
+	path := c.path(url)
+
+	var entry *cacheEntry
+	var extra map[string]string
+
+	if c.Mode == config.CacheUse && !directive(headerValue(options.Headers, "Cache-Control"), "no-store") {
+		entry = c.read(path, url, options.Headers)
+	}
+
+	if entry != nil {
+		if entry.fresh(maxAge, time.Now()) && !directive(headerValue(options.Headers, "Cache-Control"), "no-cache") {
+			return entry.Body, CacheHit, nil
+		}
+
+		extra = entry.validators()
+		if len(extra) == 0 {
+			// the entry cannot be revalidated
+			entry = nil
+		}
+	}
+
+	res, err := fetch(url, options, extra)
+	if err != nil {
+		return "", CacheMiss, err
+	}
+
+	if res.Status == http.StatusNotModified {
+		// the headers of the 304 response update the stored ones
+		for k, v := range res.Header {
+			entry.Header[k] = v
+		}
+
+		entry.RequestTime = res.RequestTime
+		entry.ResponseTime = res.ResponseTime
+
+		if err := c.write(path, entry); err != nil {
+			return "", CacheMiss, err
+		}
+
+		return entry.Body, CacheRevalidated, nil
+	}
+
+	if res.Status == http.StatusOK && storable(options.Headers, res.Header, maxAge) {
+		err = c.write(path, &cacheEntry{
+			URL:          url,
+			Header:       res.Header,
+			Vary:         varied(options.Headers, res.Header),
+			Body:         string(res.Body),
+			RequestTime:  res.RequestTime,
+			ResponseTime: res.ResponseTime,
+		})
+	} else {
+		// the stored response is outdated
+		utils.Fs.Remove(path)
+	}
+
+	return string(res.Body), CacheMiss, err
+}
+
+// the file of the cached response of url
+func (c *Cache) path(url string) string {
+	digest := sha256.Sum256([]byte(url))
+	return filepath.Join(c.Dir, hex.EncodeToString(digest[:])+".json")
+}
+
+// reads the cached response of url, nil if there is none or if it was sent for other values of the varying headers
+func (c *Cache) read(path, url string, headers map[string]string) *cacheEntry {
+	fc, err := utils.Io.ReadFile(utils.Fs, path)
+	if err != nil {
+		return nil
+	}
+
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(fc, entry); err != nil || entry.URL != url || entry.Header == nil {
+		return nil
+	}
+
+	for name, value := range entry.Vary {
+		if headerValue(headers, name) != value {
+			return nil
+		}
+	}
+
+	return entry
+}
+
+func (c *Cache) write(path string, entry *cacheEntry) error {
+	marshaled, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.Fs.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	return utils.Io.WriteFile(utils.Fs, path, marshaled, os.ModePerm)
+}
+
+// fresh reports whether the age of the entry at now is below its freshness lifetime (RFC 7234, section 4.2)
+func (e *cacheEntry) fresh(maxAge time.Duration, now time.Time) bool {
+	if maxAge > 0 {
+		return now.Sub(e.ResponseTime) < maxAge
+	}
+
+	cacheControl := e.Header.Get("Cache-Control")
+	if directive(cacheControl, "no-cache") {
+		return false
+	}
+
+	return e.age(now) < e.lifetime()
+}
+
+// the current age of the entry (RFC 7234, section 4.2.3)
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	apparentAge := time.Duration(0)
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil && e.ResponseTime.After(date) {
+		apparentAge = e.ResponseTime.Sub(date)
+	}
+
+	ageValue := time.Duration(0)
+	if seconds, err := strconv.Atoi(e.Header.Get("Age")); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+
+	correctedAge := ageValue + e.ResponseTime.Sub(e.RequestTime)
+	if apparentAge > correctedAge {
+		correctedAge = apparentAge
+	}
+
+	return correctedAge + now.Sub(e.ResponseTime)
+}
+
+// the freshness lifetime of the entry (RFC 7234, section 4.2.1), the heuristic one is 10% of the time since the last modification
+func (e *cacheEntry) lifetime() time.Duration {
+	if seconds, ok := directiveValue(e.Header.Get("Cache-Control"), "max-age"); ok {
+		if n, err := strconv.Atoi(seconds); err == nil {
+			return time.Duration(n) * time.Second
+		}
+
+		return 0
+	}
+
+	date, err := http.ParseTime(e.Header.Get("Date"))
+	if err != nil {
+		date = e.ResponseTime
+	}
+
+	if expires := e.Header.Get("Expires"); expires != "" {
+		parsed, err := http.ParseTime(expires)
+		if err != nil {
+			// an invalid date means already expired
+			return 0
+		}
+
+		return parsed.Sub(date)
+	}
+
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && date.After(lastModified) {
+		return date.Sub(lastModified) / 10
+	}
+
+	return 0
+}
+
+// the headers that make a request conditional on the version of the entry
+func (e *cacheEntry) validators() map[string]string {
+	extra := make(map[string]string)
+
+	if etag := e.Header.Get("ETag"); etag != "" {
+		extra["If-None-Match"] = etag
+	}
+
+	if lastModified := e.Header.Get("Last-Modified"); lastModified != "" {
+		extra["If-Modified-Since"] = lastModified
+	}
+
+	return extra
+}
+
+// storable reports whether a private cache can store a response (RFC 7234, section 3).
+// With maxAge, the pages are cached whatever the server says, unless the request itself forbids it.
+func storable(request map[string]string, header http.Header, maxAge time.Duration) bool {
+	if directive(headerValue(request, "Cache-Control"), "no-store") {
+		return false
+	}
+
+	if maxAge > 0 {
+		return true
+	}
+
+	return !directive(header.Get("Cache-Control"), "no-store") && strings.TrimSpace(header.Get("Vary")) != "*"
+}
+
+// the values of the request headers listed in the Vary header of the response
+func varied(request map[string]string, header http.Header) map[string]string {
+	values := make(map[string]string)
+
+	for _, names := range header.Values("Vary") {
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimSpace(name); name != "" && name != "*" {
+				values[http.CanonicalHeaderKey(name)] = headerValue(request, name)
+			}
+		}
+	}
+
+	return values
+}
+
+// the value of a header of the network options, whatever the case of its name
+func headerValue(headers map[string]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// reports whether the Cache-Control header value has the directive
+func directive(cacheControl, name string) bool {
+	_, ok := directiveValue(cacheControl, name)
+	return ok
+}
+
+// returns the argument of a directive of the Cache-Control header value, e.g. "60" for max-age=60
+func directiveValue(cacheControl, name string) (string, bool) {
+	for _, part := range strings.Split(cacheControl, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if strings.EqualFold(key, name) {
+			return strings.Trim(value, `"`), true
+		}
+	}
+
+	return "", false
+}
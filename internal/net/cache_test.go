@@ -0,0 +1,179 @@
+package net
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestCacheFetch(t *testing.T) {
+	root := tu.GetOSRoot()
+	dir := filepath.Join(root, "cache")
+
+	// the number of requests received by path
+	requests := make(map[string]int)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+
+		switch r.URL.Path {
+		case "/max-age":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/expired":
+			w.Header().Set("Expires", "Wed, 21 Oct 2015 07:28:00 GMT")
+		case "/etag":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+		}
+
+		w.Write([]byte("page " + r.URL.Path))
+	}))
+	defer ts.Close()
+
+	type step struct {
+		Mode    string
+		Headers map[string]string
+		MaxAge  time.Duration
+		Want    CacheStatus
+	}
+
+	tests := []struct {
+		Name         string
+		Path         string
+		Steps        []step
+		WantRequests int
+	}{
+		{
+			Name:         "fresh",
+			Path:         "/max-age",
+			Steps:        []step{{Mode: config.CacheUse, Want: CacheMiss}, {Mode: config.CacheUse, Want: CacheHit}},
+			WantRequests: 1,
+		},
+		{
+			Name:         "refresh",
+			Path:         "/max-age",
+			Steps:        []step{{Mode: config.CacheUse, Want: CacheMiss}, {Mode: config.CacheRefresh, Want: CacheMiss}, {Mode: config.CacheUse, Want: CacheHit}},
+			WantRequests: 2,
+		},
+		{
+			Name:         "off",
+			Path:         "/max-age",
+			Steps:        []step{{Mode: config.CacheOff, Want: CacheMiss}, {Mode: config.CacheUse, Want: CacheMiss}, {Mode: config.CacheOff, Want: CacheMiss}},
+			WantRequests: 3,
+		},
+		{
+			Name:         "no-store",
+			Path:         "/no-store",
+			Steps:        []step{{Mode: config.CacheUse, Want: CacheMiss}, {Mode: config.CacheUse, Want: CacheMiss}},
+			WantRequests: 2,
+		},
+		{
+			Name:         "no-store with max_age",
+			Path:         "/no-store",
+			Steps:        []step{{Mode: config.CacheUse, MaxAge: time.Hour, Want: CacheMiss}, {Mode: config.CacheUse, MaxAge: time.Hour, Want: CacheHit}},
+			WantRequests: 1,
+		},
+		{
+			Name:         "expired",
+			Path:         "/expired",
+			Steps:        []step{{Mode: config.CacheUse, Want: CacheMiss}, {Mode: config.CacheUse, Want: CacheMiss}},
+			WantRequests: 2,
+		},
+		{
+			Name:         "revalidated",
+			Path:         "/etag",
+			Steps:        []step{{Mode: config.CacheUse, Want: CacheMiss}, {Mode: config.CacheUse, Want: CacheRevalidated}, {Mode: config.CacheUse, Want: CacheRevalidated}},
+			WantRequests: 3,
+		},
+		{
+			Name: "request no-cache",
+			Path: "/max-age",
+			Steps: []step{
+				{Mode: config.CacheUse, Want: CacheMiss},
+				{Mode: config.CacheUse, Headers: map[string]string{"cache-control": "no-cache"}, Want: CacheMiss},
+			},
+			WantRequests: 2,
+		},
+		{
+			Name: "vary",
+			Path: "/vary",
+			Steps: []step{
+				{Mode: config.CacheUse, Headers: map[string]string{"Accept-Language": "en"}, Want: CacheMiss},
+				{Mode: config.CacheUse, Headers: map[string]string{"Accept-Language": "en"}, Want: CacheHit},
+				{Mode: config.CacheUse, Headers: map[string]string{"Accept-Language": "it"}, Want: CacheMiss},
+			},
+			WantRequests: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			requests[tt.Path] = 0
+
+			for i, s := range tt.Steps {
+				cache := &Cache{Dir: dir, Mode: s.Mode}
+
+				body, status, err := cache.Fetch(ts.URL+tt.Path, &FetchOptions{Headers: s.Headers}, s.MaxAge)
+				if err != nil {
+					tc.Fatalf("got: %v, want: nil", err)
+				}
+
+				if body != "page "+tt.Path || status != s.Want {
+					tc.Errorf("step %d got: %q %s, want: %q %s", i, body, status, "page "+tt.Path, s.Want)
+				}
+			}
+
+			if requests[tt.Path] != tt.WantRequests {
+				tc.Errorf("got: %d, want: %d requests", requests[tt.Path], tt.WantRequests)
+			}
+		})
+	}
+}
+
+func TestCacheEntryFresh(t *testing.T) {
+	now := time.Date(2022, 8, 17, 12, 0, 0, 0, time.UTC)
+	received := now.Add(-time.Minute)
+
+	tests := []struct {
+		Name   string
+		Header http.Header
+		MaxAge time.Duration
+		Want   bool
+	}{
+		{Name: "max-age", Header: http.Header{"Cache-Control": {"public, max-age=120"}}, Want: true},
+		{Name: "max-age elapsed", Header: http.Header{"Cache-Control": {"max-age=30"}}, Want: false},
+		{Name: "age header", Header: http.Header{"Cache-Control": {"max-age=120"}, "Age": {"90"}}, Want: false},
+		{Name: "expires", Header: http.Header{"Date": {received.Format(http.TimeFormat)}, "Expires": {now.Add(time.Minute).Format(http.TimeFormat)}}, Want: true},
+		{Name: "invalid expires", Header: http.Header{"Expires": {"0"}}, Want: false},
+		{Name: "heuristic", Header: http.Header{"Last-Modified": {received.Add(-time.Hour).Format(http.TimeFormat)}}, Want: true},
+		{Name: "no-cache", Header: http.Header{"Cache-Control": {"no-cache, max-age=120"}}, Want: false},
+		{Name: "nothing", Header: http.Header{}, Want: false},
+		{Name: "max_age", Header: http.Header{"Cache-Control": {"no-cache"}}, MaxAge: time.Hour, Want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			entry := &cacheEntry{Header: tt.Header, RequestTime: received, ResponseTime: received}
+
+			if got := entry.fresh(tt.MaxAge, now); got != tt.Want {
+				tc.Errorf("got: %v, want: %v", got, tt.Want)
+			}
+		})
+	}
+}
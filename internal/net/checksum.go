@@ -0,0 +1,73 @@
+package net
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// Checksum is the expected digest of a download
+type Checksum struct {
+	Algorithm string
+	// hexadecimal, case insensitive
+	Digest string
+}
+
+// ParseChecksum reads a checksum in the form "algorithm:digest"
+func ParseChecksum(s string) (*Checksum, error) {
+	algorithm, digest, ok := strings.Cut(s, ":")
+	if !ok || digest == "" {
+		return nil, fmt.Errorf("invalid checksum %q, expected algorithm:digest", s)
+	}
+
+	if _, err := NewHash(algorithm); err != nil {
+		return nil, err
+	}
+
+	return &Checksum{Algorithm: algorithm, Digest: digest}, nil
+}
+
+func (c *Checksum) String() string {
+	return c.Algorithm + ":" + strings.ToLower(c.Digest)
+}
+
+// NewHash returns the hash function named algorithm
+func NewHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+	}
+}
+
+// ChecksumError is returned when a download does not match its checksum
+type ChecksumError struct {
+	Expected *Checksum
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%s checksum mismatch: expected %s, got %s", e.Expected.Algorithm, strings.ToLower(e.Expected.Digest), e.Actual)
+}
+
+// returns a ChecksumError if the sum of h does not match c
+func (c *Checksum) verify(h hash.Hash) error {
+	actual := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(actual, c.Digest) {
+		return &ChecksumError{Expected: c, Actual: actual}
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package net
+
+import "testing"
+
+func TestParseChecksum(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Input   string
+		Want    string
+		WantErr bool
+	}{
+		{Name: "valid", Input: "md5:D41D8CD98F00B204E9800998ECF8427E", Want: "md5:d41d8cd98f00b204e9800998ecf8427e"},
+		{Name: "no algorithm", Input: "d41d8cd98f00b204e9800998ecf8427e", WantErr: true},
+		{Name: "no digest", Input: "sha1:", WantErr: true},
+		{Name: "unsupported algorithm", Input: "crc32:00000000", WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			got, err := ParseChecksum(tt.Input)
+			if (err != nil) != tt.WantErr {
+				tc.Fatalf("got: %v, want error: %v", err, tt.WantErr)
+			}
+
+			if err == nil && got.String() != tt.Want {
+				tc.Errorf("got: %s, want: %s", got, tt.Want)
+			}
+		})
+	}
+}
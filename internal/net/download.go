@@ -1,15 +1,51 @@
 package net
 
 import (
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
 	"io"
 	"net/http"
+	"path/filepath"
 	"time"
 
 	"github.com/everdrone/grab/internal/utils"
+	"github.com/spf13/afero"
 )
 
-func Download(url, dest string, options *FetchOptions) error {
+// DownloadResult describes the response of a download
+type DownloadResult struct {
+	// the url of the response, after the redirects
+	URL          string
+	Status       int
+	ContentType  string
+	ETag         string
+	LastModified string
+	// the number of bytes written
+	Size int64
+	// the hexadecimal sha256 digest of the bytes written
+	SHA256 string
+}
+
+// Validators describe the version of a file downloaded before, they make a download conditional
+type Validators struct {
+	// sent as If-None-Match
+	ETag string
+	// sent as If-Modified-Since, in the format of http.TimeFormat
+	LastModified string
+}
+
+// Download writes the response body to dest and describes the response.
+// If checksum is not nil, the body is hashed while it is written: a file that does not match
+// is discarded and downloaded again while there are retries left.
+// If validators is not nil, the request is conditional: dest is left untouched when the server
+// answers 304 Not Modified, the result has the status 304 and no error.
+// If filter is not nil, a response it rejects returns a FilterError and leaves dest untouched:
+// its headers are checked before the body is read, the size again while the body is written.
+// dest is only replaced by a verified body, a failed download keeps the existing file.
+// The result is not nil if a 2xx response was received, even if writing the file failed.
+func Download(url, dest string, options *FetchOptions, checksum *Checksum, validators *Validators, filter *Filter) (*DownloadResult, error) {
 	retriesLeft := options.Retries
 
 	if options.Retries < 1 {
@@ -20,61 +56,150 @@ func Download(url, dest string, options *FetchOptions) error {
 		options.Timeout = 10000
 	}
 
+	if checksum != nil {
+		if _, err := NewHash(checksum.Algorithm); err != nil {
+			return nil, err
+		}
+	}
+
 	client := &http.Client{
-		Timeout: time.Duration(options.Timeout) * time.Millisecond,
+		Timeout:   time.Duration(options.Timeout) * time.Millisecond,
+		Transport: Transport,
 	}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for k, v := range options.Headers {
 		req.Header.Set(k, v)
 	}
 
-	var res *http.Response
+	if validators != nil {
+		if validators.ETag != "" {
+			req.Header.Set("If-None-Match", validators.ETag)
+		}
+
+		if validators.LastModified != "" {
+			req.Header.Set("If-Modified-Since", validators.LastModified)
+		}
+	}
 
-	statusOK := false
 	for retriesLeft > 0 {
-		res, err = client.Do(req)
+		retriesLeft -= 1
+
+		res, doErr := client.Do(req)
 		// we get an error or no response, so retry
-		if err != nil || res == nil {
-			retriesLeft -= 1
+		if doErr != nil || res == nil {
+			err = doErr
 			continue
 		}
 
-		defer res.Body.Close()
+		// the file did not change, keep it
+		if res.StatusCode == http.StatusNotModified && validators != nil {
+			res.Body.Close()
 
-		// we get an "ok" response, so we can stop retrying
-		statusOK = res.StatusCode >= 200 && res.StatusCode < 300
-		if statusOK {
-			break
+			return &DownloadResult{
+				URL:          res.Request.URL.String(),
+				Status:       res.StatusCode,
+				ETag:         res.Header.Get("ETag"),
+				LastModified: res.Header.Get("Last-Modified"),
+			}, nil
 		}
 
-		retriesLeft -= 1
+		// we do not get an "ok" response, so retry
+		if res.StatusCode < 200 || res.StatusCode >= 300 {
+			res.Body.Close()
+			err = errors.New(res.Status)
+			continue
+		}
+
+		result := &DownloadResult{
+			URL:          res.Request.URL.String(),
+			Status:       res.StatusCode,
+			ContentType:  res.Header.Get("Content-Type"),
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+		}
+
+		// the response is not wanted, do not create the file
+		if filterErr := filter.check(res.Header, res.ContentLength); filterErr != nil {
+			res.Body.Close()
+			return result, filterErr
+		}
+
+		writeErr := writeBody(res.Body, dest, checksum, filter, result)
+		res.Body.Close()
+
+		// the file is corrupted, try again
+		var mismatch *ChecksumError
+		if errors.As(writeErr, &mismatch) && retriesLeft > 0 {
+			err = writeErr
+			continue
+		}
+
+		return result, writeErr
+	}
+
+	return nil, err
+}
+
+// writes body to dest, verifying it against checksum and filter if not nil, and stores its size and digest in result.
+// The body is written to a temporary file next to dest, moved onto dest once verified:
+// a download that fails leaves the existing file untouched.
+func writeBody(body io.Reader, dest string, checksum *Checksum, filter *Filter, result *DownloadResult) error {
+	file, err := afero.TempFile(utils.Fs, filepath.Dir(dest), "."+filepath.Base(dest)+".*.part")
+	if err != nil {
+		return err
 	}
 
-	if res == nil {
+	if err := writeVerified(file, body, checksum, filter, result); err != nil {
+		file.Close()
+		utils.Fs.Remove(file.Name())
 		return err
-	} else {
-		if statusOK {
-			// Create a empty file
-			file, err := utils.Fs.Create(dest)
-			if err != nil {
-				return err
-			}
-
-			defer file.Close()
-
-			// Write the bytes to the file
-			_, err = io.Copy(file, res.Body)
-			if err != nil {
-				return err
-			}
-
-			return nil
-		} else {
-			return fmt.Errorf(res.Status)
-		}
 	}
+
+	if err := file.Close(); err != nil {
+		utils.Fs.Remove(file.Name())
+		return err
+	}
+
+	return utils.Fs.Rename(file.Name(), dest)
+}
+
+// copies body to file, and verifies the copy
+func writeVerified(file io.Writer, body io.Reader, checksum *Checksum, filter *Filter, result *DownloadResult) error {
+	digest := sha256.New()
+	w := io.MultiWriter(file, digest)
+
+	var h hash.Hash
+	if checksum != nil {
+		// the algorithm was checked before the request
+		h, _ = NewHash(checksum.Algorithm)
+		w = io.MultiWriter(file, digest, h)
+	}
+
+	// stop as soon as the body is too large, the length of the response may be unknown
+	if filter != nil && filter.MaxSize > 0 {
+		body = &limitedReader{r: body, max: filter.MaxSize}
+	}
+
+	// Write the bytes to the file
+	size, err := io.Copy(w, body)
+	result.Size = size
+	if err != nil {
+		return err
+	}
+
+	if err := filter.checkSize(size); err != nil {
+		return err
+	}
+
+	result.SHA256 = hex.EncodeToString(digest.Sum(nil))
+
+	if h != nil {
+		return checksum.verify(h)
+	}
+
+	return nil
 }
@@ -2,6 +2,7 @@ package net
 
 import (
 	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -206,7 +207,7 @@ func TestDownload(t *testing.T) {
 				fileURL = resolved.String()
 			}
 
-			err := Download(fileURL, tt.Dest, tt.Options)
+			result, err := Download(fileURL, tt.Dest, tt.Options, nil, nil, nil)
 			if (err != nil) != tt.HasError {
 				tc.Errorf("got: %v, want: %v", err, tt.HasError)
 			}
@@ -231,6 +232,19 @@ func TestDownload(t *testing.T) {
 				if !slices.Equal(h1, h2) {
 					tc.Errorf("got %v, want %v", string(h1), string(h2))
 				}
+
+				info, err := utils.Fs.Stat(tt.Dest)
+				if err != nil {
+					tc.Fatalf("unexpected error: %v", err)
+				}
+
+				if info.Size() != result.Size {
+					tc.Errorf("got %d bytes written, want %d", result.Size, info.Size())
+				}
+
+				if result.SHA256 != hex.EncodeToString(h2) {
+					tc.Errorf("got sha256 %s, want %x", result.SHA256, h2)
+				}
 			}
 		})
 	}
@@ -250,3 +264,213 @@ func getHash(filename string) ([]byte, error) {
 
 	return h.Sum(nil), nil
 }
+
+func TestDownloadChecksum(t *testing.T) {
+	root := tu.GetOSRoot()
+	dest := filepath.Join(root, "file.txt")
+
+	// sha256 of "binary"
+	valid := &Checksum{Algorithm: "sha256", Digest: "9A3A45D01531A20E89AC6AE10B0B0BEB0492ACD7216A368AA062D1A5FECAF9CD"}
+
+	// the first response of every test is corrupted
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Write([]byte("corrupted"))
+			return
+		}
+
+		w.Write([]byte("binary"))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		Name     string
+		Checksum *Checksum
+		Retries  int
+		WantErr  string
+		// the file is kept only if it matches
+		WantFile bool
+	}{
+		{Name: "retried until it matches", Checksum: valid, Retries: 2, WantFile: true},
+		{Name: "no retries left", Checksum: valid, Retries: 1, WantErr: "sha256 checksum mismatch: expected 9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd, got"},
+		{Name: "unsupported algorithm", Checksum: &Checksum{Algorithm: "crc32", Digest: "00"}, Retries: 1, WantErr: `unsupported checksum algorithm "crc32"`},
+		{Name: "not verified", Retries: 1, WantFile: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+			requests = 0
+
+			_, err := Download(ts.URL, dest, &FetchOptions{Retries: tt.Retries, Timeout: 3000}, tt.Checksum, nil, nil)
+			if tt.WantErr == "" && err != nil {
+				tc.Errorf("got: %v, want: nil", err)
+			}
+
+			if tt.WantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.WantErr)) {
+				tc.Errorf("got: %v, want: %s", err, tt.WantErr)
+			}
+
+			if exists, _ := utils.Io.Exists(utils.Fs, dest); exists != tt.WantFile {
+				tc.Errorf("got: %v, want the file to exist: %v", exists, tt.WantFile)
+			}
+		})
+	}
+}
+
+func TestDownloadResult(t *testing.T) {
+	root := tu.GetOSRoot()
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", `"abc"`)
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		w.Write([]byte("binary"))
+	}))
+	defer ts.Close()
+
+	result, err := Download(ts.URL+"/old", filepath.Join(root, "file.txt"), &FetchOptions{}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	want := DownloadResult{
+		URL:          ts.URL + "/new",
+		Status:       http.StatusOK,
+		ContentType:  "text/plain",
+		ETag:         `"abc"`,
+		LastModified: "Wed, 21 Oct 2015 07:28:00 GMT",
+		Size:         6,
+		SHA256:       "9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd",
+	}
+
+	if *result != want {
+		t.Errorf("got: %+v, want: %+v", *result, want)
+	}
+}
+
+func TestDownloadConditional(t *testing.T) {
+	root := tu.GetOSRoot()
+	modified := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "file.txt", modified, strings.NewReader("version 2"))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		Name       string
+		Validators *Validators
+		WantStatus int
+		WantBody   string
+	}{
+		{
+			Name:       "unconditional",
+			WantStatus: http.StatusOK,
+			WantBody:   "version 2",
+		},
+		{
+			Name:       "same etag",
+			Validators: &Validators{ETag: `"v2"`},
+			WantStatus: http.StatusNotModified,
+			WantBody:   "version 1",
+		},
+		{
+			Name:       "different etag",
+			Validators: &Validators{ETag: `"v1"`},
+			WantStatus: http.StatusOK,
+			WantBody:   "version 2",
+		},
+		{
+			Name:       "not modified since",
+			Validators: &Validators{LastModified: modified.Format(http.TimeFormat)},
+			WantStatus: http.StatusNotModified,
+			WantBody:   "version 1",
+		},
+		{
+			Name:       "modified since",
+			Validators: &Validators{LastModified: modified.Add(-time.Hour).Format(http.TimeFormat)},
+			WantStatus: http.StatusOK,
+			WantBody:   "version 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+			dest := filepath.Join(root, "file.txt")
+			utils.Io.WriteFile(utils.Fs, dest, []byte("version 1"), os.ModePerm)
+
+			result, err := Download(ts.URL, dest, &FetchOptions{}, nil, tt.Validators, nil)
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			if result.Status != tt.WantStatus || result.ETag != `"v2"` {
+				tc.Errorf("got: %+v, want the status %d and the etag of the server", result, tt.WantStatus)
+			}
+
+			if got, _ := utils.Io.ReadFile(utils.Fs, dest); string(got) != tt.WantBody {
+				tc.Errorf("got: %q, want: %q", got, tt.WantBody)
+			}
+		})
+	}
+}
+
+func TestDownloadKeepsExistingFile(t *testing.T) {
+	root := tu.GetOSRoot()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("corrupted"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(" body"))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		Name     string
+		Checksum *Checksum
+		Filter   *Filter
+	}{
+		{
+			Name:     "checksum mismatch",
+			Checksum: &Checksum{Algorithm: "sha256", Digest: strings.Repeat("0", 64)},
+		},
+		{
+			Name:   "above max_size while writing",
+			Filter: &Filter{MaxSize: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+			dest := filepath.Join(root, "dir", "file.txt")
+			utils.Io.WriteFile(utils.Fs, dest, []byte("good"), os.ModePerm)
+
+			if _, err := Download(ts.URL, dest, &FetchOptions{}, tt.Checksum, nil, tt.Filter); err == nil {
+				tc.Fatalf("got: nil, want an error")
+			}
+
+			if got, _ := utils.Io.ReadFile(utils.Fs, dest); string(got) != "good" {
+				tc.Errorf("got: %q, want the existing file to be kept", got)
+			}
+
+			// the temporary file is removed
+			if files, _ := afero.ReadDir(utils.Fs, filepath.Dir(dest)); len(files) != 1 {
+				tc.Errorf("got: %d files, want: 1", len(files))
+			}
+		})
+	}
+}
@@ -8,6 +8,27 @@ import (
 )
 
 func Fetch(url string, options *FetchOptions) (string, error) {
+	res, err := fetch(url, options, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(res.Body), nil
+}
+
+// response is a response whose body was read
+type response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// when the request that got the response was sent, and when the response was received
+	RequestTime  time.Time
+	ResponseTime time.Time
+}
+
+// fetch sends a GET request with the headers of the options and the extra ones, retrying while the response is not 2xx.
+// A 304 Not Modified response is returned too when there are extra headers, they make the request conditional.
+func fetch(url string, options *FetchOptions, extra map[string]string) (*response, error) {
 	retriesLeft := options.Retries
 
 	if options.Retries < 1 {
@@ -19,22 +40,29 @@ func Fetch(url string, options *FetchOptions) (string, error) {
 	}
 
 	client := &http.Client{
-		Timeout: time.Duration(options.Timeout) * time.Millisecond,
+		Timeout:   time.Duration(options.Timeout) * time.Millisecond,
+		Transport: Transport,
 	}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	for k, v := range options.Headers {
 		req.Header.Set(k, v)
 	}
 
-	var body []byte
+	for k, v := range extra {
+		req.Header.Set(k, v)
+	}
+
 	var res *http.Response
+	var requestTime time.Time
 
 	statusOK := false
 	for retriesLeft > 0 {
+		requestTime = time.Now()
+
 		res, err = client.Do(req)
 		// we get an error or no response, so retry
 		if err != nil || res == nil {
@@ -45,7 +73,7 @@ func Fetch(url string, options *FetchOptions) (string, error) {
 		defer res.Body.Close()
 
 		// we get an "ok" response, so we can stop retrying
-		statusOK = res.StatusCode >= 200 && res.StatusCode < 300
+		statusOK = res.StatusCode >= 200 && res.StatusCode < 300 || res.StatusCode == http.StatusNotModified && len(extra) > 0
 		if statusOK {
 			break
 		}
@@ -54,13 +82,23 @@ func Fetch(url string, options *FetchOptions) (string, error) {
 	}
 
 	if res == nil {
-		return "", err
-	} else {
-		if statusOK {
-			body, err = io.ReadAll(res.Body)
-			return string(body), err
-		} else {
-			return "", fmt.Errorf(res.Status)
-		}
+		return nil, err
 	}
+
+	if !statusOK {
+		return nil, fmt.Errorf(res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response{
+		Status:       res.StatusCode,
+		Header:       res.Header,
+		Body:         body,
+		RequestTime:  requestTime,
+		ResponseTime: time.Now(),
+	}, nil
 }
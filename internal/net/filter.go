@@ -0,0 +1,102 @@
+package net
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Filter restricts the responses written by Download, with the headers of the response, before its body is read
+type Filter struct {
+	// the accepted content types, e.g. "image/*", all of them if empty
+	Accept []string `json:"accept,omitempty"`
+	// the limits of the size of the body in bytes, 0 for no limit
+	MinSize int64 `json:"min_size,omitempty"`
+	MaxSize int64 `json:"max_size,omitempty"`
+}
+
+// FilterError is returned when a response is rejected by the filter of the download, nothing was written
+type FilterError struct {
+	Reason string
+}
+
+func (e *FilterError) Error() string {
+	return e.Reason
+}
+
+// check rejects a response by its Content-Type and Content-Length headers.
+// A response without a content type is treated as "application/octet-stream".
+func (f *Filter) check(header http.Header, contentLength int64) error {
+	if f == nil {
+		return nil
+	}
+
+	if len(f.Accept) > 0 {
+		mediaType := "application/octet-stream"
+		if parsed, _, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil {
+			mediaType = parsed
+		}
+
+		if !MatchMediaType(f.Accept, mediaType) {
+			return &FilterError{Reason: fmt.Sprintf("content type %s is not accepted", mediaType)}
+		}
+	}
+
+	// -1 when the length is not known, the size is checked while writing
+	if contentLength >= 0 {
+		return f.checkSize(contentLength)
+	}
+
+	return nil
+}
+
+func (f *Filter) checkSize(size int64) error {
+	if f == nil {
+		return nil
+	}
+
+	if f.MinSize > 0 && size < f.MinSize {
+		return &FilterError{Reason: fmt.Sprintf("size of %d bytes is below min_size", size)}
+	}
+
+	if f.MaxSize > 0 && size > f.MaxSize {
+		return &FilterError{Reason: fmt.Sprintf("size of %d bytes is above max_size", size)}
+	}
+
+	return nil
+}
+
+// MatchMediaType reports whether the media type matches one of the patterns, like "image/png", "image/*" or "*/*"
+func MatchMediaType(patterns []string, mediaType string) bool {
+	typ, subtype, _ := strings.Cut(strings.ToLower(mediaType), "/")
+
+	for _, pattern := range patterns {
+		patternType, patternSubtype, _ := strings.Cut(strings.ToLower(strings.TrimSpace(pattern)), "/")
+
+		if (patternType == "*" || patternType == typ) && (patternSubtype == "*" || patternSubtype == subtype) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// limitedReader fails with a FilterError once more than max bytes were read
+type limitedReader struct {
+	r    io.Reader
+	max  int64
+	read int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+
+	if l.read > l.max {
+		return n, &FilterError{Reason: fmt.Sprintf("size is above max_size of %d bytes", l.max)}
+	}
+
+	return n, err
+}
@@ -0,0 +1,133 @@
+package net
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func TestMatchMediaType(t *testing.T) {
+	tests := []struct {
+		Patterns  []string
+		MediaType string
+		Want      bool
+	}{
+		{[]string{"image/png"}, "image/png", true},
+		{[]string{"image/*"}, "image/jpeg", true},
+		{[]string{"*/*"}, "video/mp4", true},
+		{[]string{"Image/PNG"}, "image/png", true},
+		{[]string{"video/*", "image/gif"}, "image/gif", true},
+		{[]string{"image/*"}, "text/html", false},
+		{[]string{"image/png"}, "image/pngx", false},
+		{[]string{}, "image/png", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.Patterns, ",")+" "+tt.MediaType, func(tc *testing.T) {
+			if got := MatchMediaType(tt.Patterns, tt.MediaType); got != tt.Want {
+				tc.Errorf("got: %v, want: %v", got, tt.Want)
+			}
+		})
+	}
+}
+
+func TestDownloadFilter(t *testing.T) {
+	root := tu.GetOSRoot()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(strings.Repeat("a", 100)))
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html></html>"))
+		case "/stream":
+			// flushing before the end sends the body without a Content-Length
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(strings.Repeat("a", 50)))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(strings.Repeat("a", 50)))
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		Name   string
+		Path   string
+		Filter *Filter
+		// the reason of the FilterError, empty if the file is written
+		WantReason string
+	}{
+		{
+			Name:   "no filter",
+			Path:   "/image.png",
+			Filter: nil,
+		},
+		{
+			Name:   "accepted",
+			Path:   "/image.png",
+			Filter: &Filter{Accept: []string{"image/*"}, MinSize: 10, MaxSize: 100},
+		},
+		{
+			Name:       "content type not accepted",
+			Path:       "/page.html",
+			Filter:     &Filter{Accept: []string{"image/*"}},
+			WantReason: "content type text/html is not accepted",
+		},
+		{
+			Name:       "below min_size",
+			Path:       "/image.png",
+			Filter:     &Filter{MinSize: 101},
+			WantReason: "size of 100 bytes is below min_size",
+		},
+		{
+			Name:       "above max_size",
+			Path:       "/image.png",
+			Filter:     &Filter{MaxSize: 99},
+			WantReason: "size of 100 bytes is above max_size",
+		},
+		{
+			Name:   "unknown length, accepted",
+			Path:   "/stream",
+			Filter: &Filter{MinSize: 100, MaxSize: 100},
+		},
+		{
+			Name:       "unknown length, below min_size",
+			Path:       "/stream",
+			Filter:     &Filter{MinSize: 101},
+			WantReason: "size of 100 bytes is below min_size",
+		},
+		{
+			Name:       "unknown length, above max_size",
+			Path:       "/stream",
+			Filter:     &Filter{MaxSize: 60},
+			WantReason: "size is above max_size of 60 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+			dest := filepath.Join(root, "file")
+
+			_, err := Download(ts.URL+tt.Path, dest, &FetchOptions{Retries: 3}, nil, nil, tt.Filter)
+
+			var rejected *FilterError
+			if tt.WantReason == "" && err != nil || tt.WantReason != "" && (!errors.As(err, &rejected) || rejected.Reason != tt.WantReason) {
+				tc.Fatalf("got: %v, want: %q", err, tt.WantReason)
+			}
+
+			if exists, _ := utils.Io.Exists(utils.Fs, dest); exists != (tt.WantReason == "") {
+				tc.Errorf("got: %v, want the file to exist: %v", exists, tt.WantReason == "")
+			}
+		})
+	}
+}
@@ -1,13 +1,16 @@
 package net
 
 import (
+	"strings"
+
 	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
 )
 
 type FetchOptions struct {
-	Headers map[string]string
-	Timeout int
-	Retries int
+	Headers map[string]string `json:"headers"`
+	Timeout int               `json:"timeout"`
+	Retries int               `json:"retries"`
 }
 
 func ClampDefaults(options *FetchOptions) {
@@ -38,7 +41,11 @@ func MergeFetchOptionsChain(root *config.RootNetworkConfig, configs ...*config.N
 		}
 
 		if root.Headers != nil {
-			options.Headers = *root.Headers
+			// copied, the headers of the other blocks must not leak into the global ones
+			options.Headers = make(map[string]string, len(*root.Headers))
+			for k, v := range *root.Headers {
+				options.Headers[k] = v
+			}
 		}
 	}
 
@@ -72,3 +79,37 @@ func MergeFetchOptionsChain(root *config.RootNetworkConfig, configs ...*config.N
 
 	return options
 }
+
+// header names whose values must never be printed
+var sensitiveHeaders = []string{"authorization", "proxy-authorization", "cookie", "set-cookie"}
+
+// substrings that mark a header name as sensitive
+var sensitiveFragments = []string{"token", "secret", "key", "auth", "session", "password"}
+
+func IsSensitiveHeader(name string) bool {
+	lower := strings.ToLower(name)
+
+	if utils.Contains(sensitiveHeaders, lower) {
+		return true
+	}
+
+	return utils.Any(sensitiveFragments, func(fragment string) bool {
+		return strings.Contains(lower, fragment)
+	})
+}
+
+// Masked returns a copy of the options where the values of sensitive headers are hidden
+func (o *FetchOptions) Masked() *FetchOptions {
+	masked := *o
+	masked.Headers = make(map[string]string, len(o.Headers))
+
+	for k, v := range o.Headers {
+		if IsSensitiveHeader(k) {
+			v = "********"
+		}
+
+		masked.Headers[k] = v
+	}
+
+	return &masked
+}
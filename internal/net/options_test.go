@@ -227,3 +227,46 @@ func TestMergeFetchOptionsChain(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeFetchOptionsChainCopiesHeaders(t *testing.T) {
+	root := &config.RootNetworkConfig{Headers: &map[string]string{"foo": "bar"}}
+
+	MergeFetchOptionsChain(root, &config.NetworkConfig{Headers: &map[string]string{"baz": "qux"}})
+
+	if want := map[string]string{"foo": "bar"}; !reflect.DeepEqual(*root.Headers, want) {
+		t.Errorf("got: %v, want: %v", *root.Headers, want)
+	}
+}
+
+func TestMasked(t *testing.T) {
+	options := &FetchOptions{
+		Timeout: 1000,
+		Retries: 2,
+		Headers: map[string]string{
+			"User-Agent":    "grab",
+			"Authorization": "Bearer abc",
+			"Cookie":        "session=abc",
+			"X-Api-Key":     "abc",
+		},
+	}
+
+	want := &FetchOptions{
+		Timeout: 1000,
+		Retries: 2,
+		Headers: map[string]string{
+			"User-Agent":    "grab",
+			"Authorization": "********",
+			"Cookie":        "********",
+			"X-Api-Key":     "********",
+		},
+	}
+
+	if got := options.Masked(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+
+	// the original options must not change
+	if options.Headers["Authorization"] != "Bearer abc" {
+		t.Errorf("got: %s, want: %s", options.Headers["Authorization"], "Bearer abc")
+	}
+}
@@ -0,0 +1,63 @@
+package net
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Post sends body to url with the headers of the options, retrying until the response is 2xx
+func Post(url, contentType string, body []byte, options *FetchOptions) error {
+	retriesLeft := options.Retries
+
+	if options.Retries < 1 {
+		retriesLeft = 1
+	}
+
+	if options.Timeout < 1 {
+		options.Timeout = 10000
+	}
+
+	client := &http.Client{
+		Timeout:   time.Duration(options.Timeout) * time.Millisecond,
+		Transport: Transport,
+	}
+
+	var err error
+	for retriesLeft > 0 {
+		retriesLeft -= 1
+
+		var req *http.Request
+		req, err = http.NewRequest("POST", url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		for k, v := range options.Headers {
+			req.Header.Set(k, v)
+		}
+
+		req.Header.Set("Content-Type", contentType)
+
+		var res *http.Response
+		res, err = client.Do(req)
+		// we get an error or no response, so retry
+		if err != nil || res == nil {
+			continue
+		}
+
+		// drain the body so that the connection can be reused
+		_, _ = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+
+		if res.StatusCode >= 200 && res.StatusCode < 300 {
+			return nil
+		}
+
+		err = fmt.Errorf(res.Status)
+	}
+
+	return err
+}
@@ -0,0 +1,52 @@
+package net
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPost(t *testing.T) {
+	attempts := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || string(body) != `{"ok":true}` || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Foo") != "bar" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// the first attempt fails
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	options := &FetchOptions{Retries: 2, Timeout: 1000, Headers: map[string]string{"X-Foo": "bar"}}
+
+	if err := Post(ts.URL, "application/json", []byte(`{"ok":true}`), options); err != nil {
+		t.Errorf("got: %v, want: nil", err)
+	}
+
+	if attempts != 2 {
+		t.Errorf("got: %d attempts, want: 2", attempts)
+	}
+
+	attempts = 0
+	options.Retries = 1
+
+	if err := Post(ts.URL, "application/json", []byte(`{"ok":true}`), options); err == nil || err.Error() != "503 Service Unavailable" {
+		t.Errorf("got: %v, want: 503 Service Unavailable", err)
+	}
+
+	if err := Post("http://127.0.0.1:0", "application/json", nil, options); err == nil {
+		t.Errorf("got: nil, want an error")
+	}
+}
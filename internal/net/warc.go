@@ -0,0 +1,634 @@
+package net
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/spf13/afero"
+)
+
+// Transport sends the requests of Fetch, Download and Post, nil for http.DefaultTransport.
+// It is replaced to record the requests in a WARC file, or to replay them from one.
+var Transport http.RoundTripper
+
+// ErrNotRecorded is returned when replaying a request that is not in the WARC file
+var ErrNotRecorded = errors.New("not recorded in the WARC file")
+
+// WARCRecord is a record of a WARC file, Block is its content
+type WARCRecord struct {
+	Header textproto.MIMEHeader
+	Block  []byte
+}
+
+// WARCWriter writes records to a WARC file, compressing every record on its own if the path ends with ".gz"
+type WARCWriter struct {
+	mu   sync.Mutex
+	file io.WriteCloser
+	gzip bool
+}
+
+// NewWARCWriter creates the WARC file at path, starting with a warcinfo record
+func NewWARCWriter(path string) (*WARCWriter, error) {
+	file, err := utils.Fs.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WARCWriter{file: file, gzip: strings.HasSuffix(path, ".gz")}
+
+	info := fmt.Sprintf("software: %s/%s\r\nformat: WARC File Format 1.0\r\n", config.Name, config.Version)
+	header := textproto.MIMEHeader{}
+	header.Set("WARC-Type", "warcinfo")
+	header.Set("Content-Type", "application/warc-fields")
+
+	if err := w.Write(&WARCRecord{Header: header, Block: []byte(info)}); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write appends a record, setting its WARC-Block-Digest and Content-Length fields, and its WARC-Record-ID and WARC-Date if they are missing
+func (w *WARCWriter) Write(record *WARCRecord) error {
+	return w.writeStream(record.Header, record.Block, bytes.NewReader(nil))
+}
+
+// writeStream appends a record whose block is prefix followed by the content of rest, like Write.
+// rest is read twice, for the digest and then to write it, so that the block is never held in memory.
+func (w *WARCWriter) writeStream(header textproto.MIMEHeader, prefix []byte, rest io.ReadSeeker) error {
+	if header.Get("WARC-Record-ID") == "" {
+		header.Set("WARC-Record-ID", newRecordID())
+	}
+
+	if header.Get("WARC-Date") == "" {
+		header.Set("WARC-Date", time.Now().UTC().Format(time.RFC3339))
+	}
+
+	digest := sha1.New()
+	digest.Write(prefix)
+
+	size, err := io.Copy(digest, rest)
+	if err != nil {
+		return err
+	}
+
+	if _, err := rest.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	header.Set("WARC-Block-Digest", "sha1:"+base32.StdEncoding.EncodeToString(digest.Sum(nil)))
+	header.Set("Content-Length", strconv.FormatInt(int64(len(prefix))+size, 10))
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("WARC/1.0\r\n")
+
+	// the type first, then the other fields in a stable order
+	buf.WriteString("WARC-Type: " + header.Get("WARC-Type") + "\r\n")
+	for _, key := range sortedHeaderKeys(header) {
+		if key == "Warc-Type" {
+			continue
+		}
+
+		for _, value := range header[key] {
+			buf.WriteString(warcFieldName(key) + ": " + value + "\r\n")
+		}
+	}
+
+	buf.WriteString("\r\n")
+	buf.Write(prefix)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// a gzip member per record, so that the records can be read on their own
+	var out io.Writer = w.file
+	var gz *gzip.Writer
+	if w.gzip {
+		gz = gzip.NewWriter(w.file)
+		out = gz
+	}
+
+	if _, err := out.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, rest); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(out, "\r\n\r\n"); err != nil {
+		return err
+	}
+
+	if gz != nil {
+		return gz.Close()
+	}
+
+	return nil
+}
+
+func (w *WARCWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+}
+
+// ReadWARC reads all the records of a WARC file, compressed or not, with their blocks in memory
+func ReadWARC(path string) ([]*WARCRecord, error) {
+	uncompressed, temporary, err := uncompressWARC(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if temporary {
+		defer utils.Fs.Remove(uncompressed)
+	}
+
+	file, err := utils.Fs.Open(uncompressed)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	entries, err := indexWARC(file)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*WARCRecord, 0, len(entries))
+	for _, entry := range entries {
+		block := make([]byte, entry.block.length)
+		if _, err := io.ReadFull(io.NewSectionReader(file, entry.block.offset, entry.block.length), block); err != nil {
+			return nil, err
+		}
+
+		records = append(records, &WARCRecord{Header: entry.header, Block: block})
+	}
+
+	return records, nil
+}
+
+// WARCRecorder sends the requests with Next and writes every request and its response to a WARC file.
+// The body of a response is copied to a temporary file while it is read, and the records are written
+// once it is read to the end or closed, so that large downloads are not held in memory. A body closed
+// before its end, like a download rejected by its filter, is recorded as far as it was read.
+type WARCRecorder struct {
+	Writer *WARCWriter
+	// nil for http.DefaultTransport
+	Next http.RoundTripper
+}
+
+func (r *WARCRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := r.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	request, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	spool, err := afero.TempFile(utils.Fs, "", "grab-warc-*")
+	if err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+
+	res.Body = &recordedBody{writer: r.Writer, uri: req.URL.String(), res: res, body: res.Body, request: request, spool: spool}
+
+	return res, nil
+}
+
+// recordedBody copies the body of a response to its spool file while it is read,
+// and writes the records of the exchange when the end of the body is reached
+type recordedBody struct {
+	writer *WARCWriter
+	uri    string
+	res    *http.Response
+	body   io.ReadCloser
+	// the dump of the request
+	request []byte
+	spool   afero.File
+	size    int64
+	// the end of the body was reached
+	eof  bool
+	done bool
+}
+
+func (b *recordedBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+
+	if n > 0 && !b.done {
+		if _, err := b.spool.Write(p[:n]); err != nil {
+			return n, err
+		}
+		b.size += int64(n)
+	}
+
+	if err == io.EOF && !b.done {
+		b.eof = true
+		if err := b.record(); err != nil {
+			return n, err
+		}
+	}
+
+	return n, err
+}
+
+// Close records the part of the body that was read, the rest is not downloaded
+func (b *recordedBody) Close() error {
+	var err error
+	if !b.done {
+		err = b.record()
+	}
+
+	if closeErr := b.body.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// writes the response and the request records, then removes the spool file
+func (b *recordedBody) record() error {
+	b.done = true
+	defer b.discard()
+
+	// the body is recorded as it was read, with its length instead of the transfer encoding
+	head := *b.res
+	head.Body = nil
+	head.TransferEncoding = nil
+
+	// a truncated body keeps the length sent by the server, if any, so that the replay fails
+	// or is filtered like the recorded run. Without a length, the body ends with the record.
+	truncated := !b.eof && b.size != b.res.ContentLength
+	if !truncated {
+		head.ContentLength = b.size
+	}
+
+	response, err := httputil.DumpResponse(&head, false)
+	if err != nil {
+		return err
+	}
+
+	if _, err := b.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	date := time.Now().UTC().Format(time.RFC3339)
+	responseID := newRecordID()
+
+	responseHeader := textproto.MIMEHeader{}
+	responseHeader.Set("WARC-Type", "response")
+	responseHeader.Set("WARC-Record-ID", responseID)
+	responseHeader.Set("WARC-Target-URI", b.uri)
+	responseHeader.Set("WARC-Date", date)
+	responseHeader.Set("Content-Type", "application/http;msgtype=response")
+	if truncated {
+		responseHeader.Set("WARC-Truncated", "unspecified")
+	}
+
+	requestHeader := textproto.MIMEHeader{}
+	requestHeader.Set("WARC-Type", "request")
+	requestHeader.Set("WARC-Target-URI", b.uri)
+	requestHeader.Set("WARC-Date", date)
+	requestHeader.Set("WARC-Concurrent-To", responseID)
+	requestHeader.Set("Content-Type", "application/http;msgtype=request")
+
+	if err := b.writer.writeStream(responseHeader, response, b.spool); err != nil {
+		return err
+	}
+
+	return b.writer.Write(&WARCRecord{Header: requestHeader, Block: b.request})
+}
+
+func (b *recordedBody) discard() {
+	b.spool.Close()
+	utils.Fs.Remove(b.spool.Name())
+}
+
+// WARCReplayer answers the requests with the responses of a WARC file, without sending them.
+// A request sent more times than it was recorded gets its last response again. The responses are
+// read from the file when they are replayed, a compressed file is decompressed to a temporary file first.
+type WARCReplayer struct {
+	mu sync.Mutex
+	// the uncompressed file
+	path string
+	// path is a temporary file, removed by Close
+	temporary bool
+	responses map[string][]warcBlock
+}
+
+// warcBlock is the position of the block of a record in an uncompressed WARC file
+type warcBlock struct {
+	offset int64
+	length int64
+}
+
+// NewWARCReplayer indexes the responses of the WARC file at path
+func NewWARCReplayer(path string) (*WARCReplayer, error) {
+	uncompressed, temporary, err := uncompressWARC(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &WARCReplayer{path: uncompressed, temporary: temporary, responses: make(map[string][]warcBlock)}
+
+	file, err := utils.Fs.Open(uncompressed)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	defer file.Close()
+
+	entries, err := indexWARC(file)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	blocks := make(map[string]warcBlock)
+	for _, entry := range entries {
+		if entry.header.Get("WARC-Type") == "response" {
+			blocks[entry.header.Get("WARC-Record-ID")] = entry.block
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.header.Get("WARC-Type") != "request" {
+			continue
+		}
+
+		block, ok := blocks[entry.header.Get("WARC-Concurrent-To")]
+		if !ok {
+			continue
+		}
+
+		method := "GET"
+		if line, _, found := strings.Cut(string(entry.start), " "); found {
+			method = line
+		}
+
+		key := replayKey(method, entry.header.Get("WARC-Target-URI"))
+		r.responses[key] = append(r.responses[key], block)
+	}
+
+	return r, nil
+}
+
+func (r *WARCReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := replayKey(req.Method, req.URL.String())
+
+	r.mu.Lock()
+	blocks := r.responses[key]
+	if len(blocks) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, ErrNotRecorded)
+	}
+
+	block := blocks[0]
+	if len(blocks) > 1 {
+		r.responses[key] = blocks[1:]
+	}
+	r.mu.Unlock()
+
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	file, err := utils.Fs.Open(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(io.NewSectionReader(file, block.offset, block.length)), req)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	res.Body = &replayedBody{ReadCloser: res.Body, file: file}
+
+	return res, nil
+}
+
+// Close removes the temporary file of a compressed WARC file
+func (r *WARCReplayer) Close() error {
+	if !r.temporary {
+		return nil
+	}
+
+	return utils.Fs.Remove(r.path)
+}
+
+// replayedBody closes the WARC file with the body
+type replayedBody struct {
+	io.ReadCloser
+	file afero.File
+}
+
+func (b *replayedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if closeErr := b.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// uncompressWARC returns path if the WARC file is not compressed,
+// or the temporary file where it was decompressed, which must be removed
+func uncompressWARC(path string) (string, bool, error) {
+	file, err := utils.Fs.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	defer file.Close()
+
+	buffered := bufio.NewReader(file)
+	if magic, _ := buffered.Peek(2); len(magic) != 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return path, false, nil
+	}
+
+	// the members are read one after the other
+	gz, err := gzip.NewReader(buffered)
+	if err != nil {
+		return "", false, err
+	}
+
+	defer gz.Close()
+
+	tmp, err := afero.TempFile(utils.Fs, "", "grab-warc-*")
+	if err != nil {
+		return "", false, err
+	}
+
+	if _, err := io.Copy(tmp, gz); err != nil {
+		tmp.Close()
+		utils.Fs.Remove(tmp.Name())
+		return "", false, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		utils.Fs.Remove(tmp.Name())
+		return "", false, err
+	}
+
+	return tmp.Name(), true, nil
+}
+
+// warcEntry is a record of an uncompressed WARC file, without its block
+type warcEntry struct {
+	header textproto.MIMEHeader
+	block  warcBlock
+	// the first bytes of the block
+	start []byte
+}
+
+// indexWARC reads the fields of the records of an uncompressed WARC file and the positions of their blocks,
+// the blocks are skipped
+func indexWARC(file io.Reader) ([]*warcEntry, error) {
+	buffered := bufio.NewReader(file)
+	entries := make([]*warcEntry, 0)
+	offset := int64(0)
+
+	readLine := func() (string, error) {
+		line, err := buffered.ReadString('\n')
+		offset += int64(len(line))
+		return line, err
+	}
+
+	for {
+		line, err := readLine()
+		if err == io.EOF && line == "" {
+			return entries, nil
+		}
+
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		version := strings.TrimRight(line, "\r\n")
+		if version == "" {
+			// the separators of the previous record
+			continue
+		}
+
+		if !strings.HasPrefix(version, "WARC/") {
+			return nil, fmt.Errorf("invalid WARC record: %q", version)
+		}
+
+		// the fields end with an empty line
+		fields := &strings.Builder{}
+		for {
+			line, err := readLine()
+			if err != nil {
+				return nil, fmt.Errorf("invalid WARC record: %w", io.ErrUnexpectedEOF)
+			}
+
+			fields.WriteString(line)
+			if strings.TrimRight(line, "\r\n") == "" {
+				break
+			}
+		}
+
+		header, err := textproto.NewReader(bufio.NewReader(strings.NewReader(fields.String()))).ReadMIMEHeader()
+		if err != nil {
+			return nil, err
+		}
+
+		length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid WARC record length: %q", header.Get("Content-Length"))
+		}
+
+		peek := 16
+		if length < int64(peek) {
+			peek = int(length)
+		}
+
+		start, _ := buffered.Peek(peek)
+
+		entries = append(entries, &warcEntry{
+			header: header,
+			block:  warcBlock{offset: offset, length: length},
+			start:  append([]byte(nil), start...),
+		})
+
+		discarded, err := buffered.Discard(int(length))
+		offset += int64(discarded)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func replayKey(method, uri string) string {
+	return method + " " + uri
+}
+
+// returns a new "<urn:uuid:...>" identifier (a version 4 UUID)
+func newRecordID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// the WARC field names are spelled like in the specification, e.g. WARC-Record-ID
+func warcFieldName(key string) string {
+	switch key {
+	case "Warc-Record-Id":
+		return "WARC-Record-ID"
+	case "Warc-Target-Uri":
+		return "WARC-Target-URI"
+	}
+
+	if strings.HasPrefix(key, "Warc-") {
+		return "WARC-" + strings.TrimPrefix(key, "Warc-")
+	}
+
+	return key
+}
+
+func sortedHeaderKeys(header textproto.MIMEHeader) []string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
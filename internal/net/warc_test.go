@@ -0,0 +1,219 @@
+package net
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+	"github.com/spf13/afero"
+)
+
+func TestWARC(t *testing.T) {
+	for _, name := range []string{"out.warc", "out.warc.gz"} {
+		t.Run(name, func(tc *testing.T) {
+			root := tu.GetOSRoot()
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+			tc.Cleanup(func() { Transport = nil })
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/old":
+					http.Redirect(w, r, "/file.txt", http.StatusFound)
+				case "/file.txt":
+					w.Header().Set("ETag", `"abc"`)
+					w.Write([]byte("file"))
+				default:
+					w.Write([]byte("<title>Page</title>"))
+				}
+			}))
+
+			path := filepath.Join(root, name)
+
+			writer, err := NewWARCWriter(path)
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			Transport = &WARCRecorder{Writer: writer}
+
+			if body, err := Fetch(ts.URL+"/page", &FetchOptions{}); err != nil || body != "<title>Page</title>" {
+				tc.Fatalf("got: %q %v, want the page", body, err)
+			}
+
+			if _, err := Download(ts.URL+"/old", filepath.Join(root, "file.txt"), &FetchOptions{}, nil, nil, nil); err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			if err := Post(ts.URL+"/hook", "application/json", []byte(`{}`), &FetchOptions{}); err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			writer.Close()
+			ts.Close()
+
+			records, err := ReadWARC(path)
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			types := make([]string, 0, len(records))
+			for _, record := range records {
+				types = append(types, record.Header.Get("WARC-Type"))
+			}
+
+			// the warcinfo record, then a response and a request for the page, the redirect, the file and the notification
+			if len(records) != 9 || types[0] != "warcinfo" || types[1] != "response" || types[2] != "request" {
+				tc.Fatalf("got: %q, want the warcinfo record and 4 exchanges", types)
+			}
+
+			if got := records[5].Header.Get("WARC-Target-URI"); got != ts.URL+"/file.txt" {
+				tc.Errorf("got: %s, want the target of the redirect", got)
+			}
+
+			replayer, err := NewWARCReplayer(path)
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			// the server is closed, everything comes from the file
+			Transport = replayer
+
+			if body, err := Fetch(ts.URL+"/page", &FetchOptions{}); err != nil || body != "<title>Page</title>" {
+				tc.Errorf("got: %q %v, want the page", body, err)
+			}
+
+			result, err := Download(ts.URL+"/old", filepath.Join(root, "replayed.txt"), &FetchOptions{}, nil, nil, nil)
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			if got, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(root, "replayed.txt")); string(got) != "file" || result.URL != ts.URL+"/file.txt" || result.ETag != `"abc"` {
+				tc.Errorf("got: %q %+v, want the recorded file", got, result)
+			}
+
+			if _, err := Fetch(ts.URL+"/other", &FetchOptions{}); !errors.Is(err, ErrNotRecorded) {
+				tc.Errorf("got: %v, want: %v", err, ErrNotRecorded)
+			}
+
+			// the notification is answered by the file, not sent
+			if err := Post(ts.URL+"/hook", "application/json", []byte(`{}`), &FetchOptions{}); err != nil {
+				tc.Errorf("got: %v, want: nil", err)
+			}
+
+			if err := Post(ts.URL+"/other", "application/json", []byte(`{}`), &FetchOptions{}); !errors.Is(err, ErrNotRecorded) {
+				tc.Errorf("got: %v, want: %v", err, ErrNotRecorded)
+			}
+
+			if err := replayer.Close(); err != nil {
+				tc.Errorf("got: %v, want: nil", err)
+			}
+
+			// only the decompressed copy is removed
+			if exists, _ := utils.Io.Exists(utils.Fs, replayer.path); exists == replayer.temporary {
+				tc.Errorf("got: %v, want the decompressed file to be removed", exists)
+			}
+		})
+	}
+}
+
+func TestWARCRecorderStream(t *testing.T) {
+	body := strings.Repeat("0123456789", 100000)
+
+	// flushed, the body is sent with the chunked transfer encoding
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body[:10])
+		w.(http.Flusher).Flush()
+		io.WriteString(w, body[10:])
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		Name string
+		// the number of bytes read before closing the body, -1 to read all of it
+		Read          int
+		WantBody      string
+		WantLength    int64
+		WantTruncated bool
+	}{
+		{Name: "read to the end", Read: -1, WantBody: body, WantLength: int64(len(body))},
+		// like a download rejected while streaming, the rest is not downloaded
+		{Name: "closed before the end", Read: 10, WantBody: body[:10], WantLength: -1, WantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			root := tu.GetOSRoot()
+			utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+			path := filepath.Join(root, "out.warc")
+
+			writer, err := NewWARCWriter(path)
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			client := &http.Client{Transport: &WARCRecorder{Writer: writer}}
+
+			res, err := client.Get(ts.URL)
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			if tt.Read < 0 {
+				_, err = io.ReadAll(res.Body)
+			} else {
+				_, err = io.ReadFull(res.Body, make([]byte, tt.Read))
+			}
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			if err := res.Body.Close(); err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			writer.Close()
+
+			records, err := ReadWARC(path)
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			if len(records) != 3 || records[1].Header.Get("WARC-Type") != "response" {
+				tc.Fatalf("got: %d records, want the warcinfo record and an exchange", len(records))
+			}
+
+			if got := records[1].Header.Get("WARC-Truncated") != ""; got != tt.WantTruncated {
+				tc.Errorf("got: truncated %v, want: %v", got, tt.WantTruncated)
+			}
+
+			recorded, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(records[1].Block)), nil)
+			if err != nil {
+				tc.Fatalf("got: %v, want: nil", err)
+			}
+
+			got, _ := io.ReadAll(recorded.Body)
+			if string(got) != tt.WantBody || recorded.ContentLength != tt.WantLength || len(recorded.TransferEncoding) != 0 {
+				tc.Errorf("got: %d bytes, length %d, %q, want: %d bytes, length %d", len(got), recorded.ContentLength, recorded.TransferEncoding, len(tt.WantBody), tt.WantLength)
+			}
+
+			// the temporary files are removed
+			infos, _ := afero.ReadDir(utils.Fs, os.TempDir())
+			for _, info := range infos {
+				if strings.HasPrefix(info.Name(), "grab-warc-") {
+					tc.Errorf("got: %s, want the temporary file to be removed", info.Name())
+				}
+			}
+		})
+	}
+}
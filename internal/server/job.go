@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/utils"
+)
+
+type JobState string
+
+const (
+	JobQueued  JobState = "queued"
+	JobRunning JobState = "running"
+	// the run completed, the failed pages and downloads are in the totals
+	JobDone JobState = "done"
+	// the run stopped early, see Error
+	JobFailed   JobState = "failed"
+	JobCanceled JobState = "canceled"
+)
+
+const (
+	AssetPending     = "pending"
+	AssetDownloading = "downloading"
+	AssetDone        = "done"
+	AssetSkipped     = "skipped"
+	AssetFailed      = "failed"
+)
+
+// the number of finished jobs that are kept, the oldest ones are forgotten first
+const maxFinishedJobs = 100
+
+// AssetProgress is the state of a download of a job
+type AssetProgress struct {
+	Site        string `json:"site"`
+	Asset       string `json:"asset"`
+	Page        string `json:"page,omitempty"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	// one of AssetPending, AssetDownloading, AssetDone, AssetSkipped or AssetFailed
+	State string `json:"state"`
+	Bytes int64  `json:"bytes,omitempty"`
+	// why the download was skipped or failed
+	Reason string `json:"reason,omitempty"`
+}
+
+// Job is a set of urls submitted to the server, run like the arguments of the get command
+type Job struct {
+	ID   string   `json:"id"`
+	URLs []string `json:"urls"`
+	// the site that handles all the urls, whatever its test pattern. Empty to match the sites as usual.
+	Site     string          `json:"site,omitempty"`
+	State    JobState        `json:"state"`
+	Created  time.Time       `json:"created"`
+	Started  *time.Time      `json:"started,omitempty"`
+	Finished *time.Time      `json:"finished,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Totals   instance.Counts `json:"totals"`
+	// in the order they were found
+	Assets []*AssetProgress `json:"assets,omitempty"`
+
+	cancel context.CancelFunc
+	// canceled by a client, not by the shutdown of the server
+	canceled bool
+	// the progress of the assets, by source and destination
+	progress map[string]*AssetProgress
+}
+
+func (j *Job) finished() bool {
+	return j.State == JobDone || j.State == JobFailed || j.State == JobCanceled
+}
+
+// returns a copy of the job without the assets
+func (j *Job) summary() *Job {
+	return &Job{
+		ID:       j.ID,
+		URLs:     j.URLs,
+		Site:     j.Site,
+		State:    j.State,
+		Created:  j.Created,
+		Started:  j.Started,
+		Finished: j.Finished,
+		Error:    j.Error,
+		Totals:   j.Totals,
+	}
+}
+
+// returns a copy of the job with its assets
+func (j *Job) detail() *Job {
+	detail := j.summary()
+	detail.Assets = make([]*AssetProgress, 0, len(j.Assets))
+
+	for _, asset := range j.Assets {
+		copied := *asset
+		detail.Assets = append(detail.Assets, &copied)
+	}
+
+	return detail
+}
+
+// handle updates the progress of the job with an event of its run, the server must be locked
+func (j *Job) handle(e *instance.Event) {
+	switch e.Type {
+	case instance.EventPageFetched:
+		j.Totals.PagesFetched++
+		return
+	case instance.EventPageFailed:
+		j.Totals.PagesFailed++
+		return
+	}
+
+	if e.Source == "" {
+		return
+	}
+
+	key := e.Source + "\x00" + e.Destination
+
+	asset, ok := j.progress[key]
+	if !ok {
+		asset = &AssetProgress{Site: e.Site, Asset: e.Asset, Page: e.Page, Source: e.Source, Destination: e.Destination, State: AssetPending}
+		j.progress[key] = asset
+		j.Assets = append(j.Assets, asset)
+	}
+
+	switch e.Type {
+	case instance.EventDownloadStarted:
+		asset.State = AssetDownloading
+	case instance.EventDownloadFinished:
+		asset.State = AssetDone
+		asset.Bytes = e.Bytes
+		j.Totals.AssetsDownloaded++
+		j.Totals.Bytes += e.Bytes
+	case instance.EventDownloadSkipped:
+		asset.State = AssetSkipped
+		asset.Reason = e.Reason
+		j.Totals.AssetsSkipped++
+	case instance.EventDownloadFailed:
+		asset.State = AssetFailed
+		asset.Reason = e.Error
+		j.Totals.AssetsFailed++
+	}
+}
+
+// QueuePath returns the path of the jobs of the server downloading in location
+func QueuePath(location string) string {
+	return filepath.Join(location, ".grab", "jobs.json")
+}
+
+type queueFile struct {
+	NextID int    `json:"next_id"`
+	Jobs   []*Job `json:"jobs"`
+}
+
+// reads the jobs saved in location. The jobs that were running are queued again.
+func loadQueue(location string) (*queueFile, error) {
+	queue := &queueFile{NextID: 1, Jobs: make([]*Job, 0)}
+
+	path := QueuePath(location)
+
+	if exists, err := utils.Io.Exists(utils.Fs, path); err != nil || !exists {
+		return queue, err
+	}
+
+	fc, err := utils.Io.ReadFile(utils.Fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(fc, queue); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, job := range queue.Jobs {
+		job.progress = make(map[string]*AssetProgress)
+		for _, asset := range job.Assets {
+			job.progress[asset.Source+"\x00"+asset.Destination] = asset
+		}
+
+		if job.State == JobRunning {
+			job.State = JobQueued
+		}
+	}
+
+	return queue, nil
+}
+
+func (q *queueFile) save(location string) error {
+	marshaled, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := QueuePath(location)
+
+	if err := utils.Fs.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return utils.Io.WriteFile(utils.Fs, path, marshaled, os.ModePerm)
+}
@@ -0,0 +1,501 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// Server runs the jobs submitted through its HTTP API with a pool of workers. Every job runs on a clone
+// of the instance, and the queue is saved in the global location so that it survives restarts.
+type Server struct {
+	// the number of jobs that run at the same time
+	Workers int
+	// the number of files downloaded at the same time by all the jobs, Workers if less than 1
+	Downloads int
+	// if not empty, requests must send it as a bearer token
+	Token string
+	// the origin allowed to call the API from a browser, e.g. "*". Cross origin requests are refused if empty.
+	AllowOrigin string
+	// called after every job with the summary of its run
+	AfterRun func(job *Job)
+
+	grab     *instance.Grab
+	location string
+	// shared by the instances of every job
+	pool instance.Pool
+
+	jobs   []*Job
+	byID   map[string]*Job
+	nextID int
+	closed bool
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	wg   sync.WaitGroup
+}
+
+// New returns a server running the jobs with the configuration of g, and the jobs that were queued
+// when the previous server stopped
+func New(g *instance.Grab) (*Server, error) {
+	queue, err := loadQueue(g.Config.Global.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Workers:  2,
+		grab:     g,
+		location: g.Config.Global.Location,
+		jobs:     queue.Jobs,
+		byID:     make(map[string]*Job),
+		nextID:   queue.NextID,
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	for _, job := range s.jobs {
+		s.byID[job.ID] = job
+	}
+
+	return s, nil
+}
+
+// Start starts the workers
+func (s *Server) Start() {
+	workers := s.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	downloads := s.Downloads
+	if downloads < 1 {
+		downloads = workers
+	}
+	s.pool = instance.NewPool(downloads)
+
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+}
+
+// Stop cancels the running jobs, which are queued again to resume after a restart, and waits for the workers
+func (s *Server) Stop() {
+	s.mu.Lock()
+	s.closed = true
+	for _, job := range s.jobs {
+		if job.State == JobRunning && job.cancel != nil {
+			job.cancel()
+		}
+	}
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.save()
+}
+
+func (s *Server) work() {
+	defer s.wg.Done()
+
+	for {
+		s.mu.Lock()
+
+		var job *Job
+		for !s.closed {
+			if job = s.nextQueued(); job != nil {
+				break
+			}
+			s.cond.Wait()
+		}
+
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// every timestamp has its own variable, the details of the job keep pointers to them
+		started := time.Now().UTC()
+		job.State = JobRunning
+		job.Started = &started
+		job.Finished = nil
+		job.Error = ""
+		job.Totals = instance.Counts{}
+		job.Assets = nil
+		job.progress = make(map[string]*AssetProgress)
+		job.cancel = cancel
+
+		report := instance.NewReport()
+
+		g := s.grab.Clone()
+		g.Context = ctx
+		g.Pool = s.pool
+		g.OnEvent(report.Handle)
+		g.OnEvent(func(e *instance.Event) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			job.handle(e)
+		})
+
+		s.save()
+		s.mu.Unlock()
+
+		log.Info().Str("job", job.ID).Msgf("running, %d %s", len(job.URLs), utils.Plural(len(job.URLs), "url", "urls"))
+
+		var err error
+		if job.Site != "" {
+			err = g.RunForSite(job.Site, job.URLs)
+		} else {
+			err = g.Run(job.URLs)
+		}
+
+		stopped := ctx.Err() != nil
+		cancel()
+
+		s.mu.Lock()
+
+		finished := time.Now().UTC()
+		job.cancel = nil
+
+		switch {
+		case stopped && job.canceled:
+			job.State = JobCanceled
+			job.Finished = &finished
+		case stopped:
+			// the server is stopping, run the job again after the restart
+			job.State = JobQueued
+			job.Started = nil
+		case err != nil:
+			job.State = JobFailed
+			job.Error = err.Error()
+			job.Finished = &finished
+		default:
+			job.State = JobDone
+			job.Finished = &finished
+		}
+
+		s.prune()
+		s.save()
+
+		detail := job.detail()
+		s.mu.Unlock()
+
+		if detail.finished() {
+			log.Info().Str("job", detail.ID).Str("state", string(detail.State)).Msg("job finished")
+
+			if !stopped {
+				report.Finish()
+				g.Notify("job "+detail.ID, report, err)
+			}
+
+			if s.AfterRun != nil {
+				s.AfterRun(detail)
+			}
+		}
+	}
+}
+
+// returns the oldest queued job, the server must be locked
+func (s *Server) nextQueued() *Job {
+	for _, job := range s.jobs {
+		if job.State == JobQueued {
+			return job
+		}
+	}
+
+	return nil
+}
+
+// forgets the oldest finished jobs beyond maxFinishedJobs, the server must be locked
+func (s *Server) prune() {
+	finished := 0
+	for _, job := range s.jobs {
+		if job.finished() {
+			finished++
+		}
+	}
+
+	s.jobs = utils.Filter(s.jobs, func(job *Job) bool {
+		if finished > maxFinishedJobs && job.finished() {
+			finished--
+			delete(s.byID, job.ID)
+			return false
+		}
+		return true
+	})
+}
+
+// saves the queue, the server must be locked
+func (s *Server) save() {
+	queue := &queueFile{NextID: s.nextID, Jobs: s.jobs}
+	if err := queue.save(s.location); err != nil {
+		log.Err(err).Str("path", QueuePath(s.location)).Msg("could not write the jobs")
+	}
+}
+
+// Submit queues a job for the urls, handled by site if not empty
+func (s *Server) Submit(urls []string, site string) (*Job, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no urls")
+	}
+
+	cleaned := make([]string, 0, len(urls))
+	for _, url := range urls {
+		parsed, ok := utils.IsValidURL(url)
+		if !ok {
+			return nil, fmt.Errorf("invalid url %q", url)
+		}
+
+		parsed.Fragment = ""
+		parsed.RawFragment = ""
+		cleaned = append(cleaned, parsed.String())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if site != "" && !utils.Any(s.grab.Config.Sites, func(c config.SiteConfig) bool { return c.Name == site }) {
+		return nil, fmt.Errorf("unknown site %q", site)
+	}
+
+	job := &Job{
+		ID:       strconv.Itoa(s.nextID),
+		URLs:     utils.Unique(cleaned),
+		Site:     site,
+		State:    JobQueued,
+		Created:  time.Now().UTC(),
+		progress: make(map[string]*AssetProgress),
+	}
+	s.nextID++
+
+	s.jobs = append(s.jobs, job)
+	s.byID[job.ID] = job
+
+	s.save()
+	s.cond.Signal()
+
+	return job.detail(), nil
+}
+
+var (
+	errNotFound    = errors.New("job not found")
+	errNotCanceled = errors.New("the job is already finished")
+)
+
+// Cancel cancels a queued or running job
+func (s *Server) Cancel(id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.byID[id]
+	if !ok {
+		return nil, errNotFound
+	}
+
+	switch job.State {
+	case JobQueued:
+		now := time.Now().UTC()
+		job.State = JobCanceled
+		job.Finished = &now
+		s.save()
+	case JobRunning:
+		// the worker updates the state when the run stops
+		job.canceled = true
+		job.cancel()
+	default:
+		return nil, errNotCanceled
+	}
+
+	return job.detail(), nil
+}
+
+// Jobs returns the jobs without their assets, in the order they were submitted
+func (s *Server) Jobs() []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job.summary())
+	}
+
+	return jobs
+}
+
+// Job returns the job with the progress of its assets, nil if there is none
+func (s *Server) Job(id string) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.byID[id]
+	if !ok {
+		return nil
+	}
+
+	return job.detail()
+}
+
+// Reload parses the configuration file again, the running jobs keep the previous configuration
+func (s *Server) Reload() error {
+	s.mu.Lock()
+	g := s.grab.Clone()
+	s.mu.Unlock()
+
+	if diags := g.ParseConfig(); diags.HasErrors() {
+		return diags
+	}
+
+	s.mu.Lock()
+	s.grab = g
+	s.mu.Unlock()
+
+	log.Info().Str("path", g.Flags.ConfigPath).Msg("configuration reloaded")
+
+	return nil
+}
+
+// Handler returns the HTTP API:
+//
+//	GET  /jobs             the jobs, without their assets
+//	POST /jobs             submit {"urls": [...], "site": "optional"}
+//	GET  /jobs/:id         a job with the progress of its assets
+//	POST /jobs/:id/cancel  cancel a queued or running job
+//	POST /config/reload    parse the configuration file again
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": s.Jobs()})
+		case http.MethodPost:
+			var body struct {
+				URLs []string `json:"urls"`
+				Site string   `json:"site"`
+			}
+
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+				return
+			}
+
+			job, err := s.Submit(body.URLs, body.Site)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+
+			writeJSON(w, http.StatusCreated, job)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		}
+	})
+
+	mux.HandleFunc("/jobs/", func(w http.ResponseWriter, r *http.Request) {
+		id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+
+		switch {
+		case action == "" && r.Method == http.MethodGet:
+			job := s.Job(id)
+			if job == nil {
+				writeError(w, http.StatusNotFound, errNotFound)
+				return
+			}
+
+			writeJSON(w, http.StatusOK, job)
+		case action == "cancel" && r.Method == http.MethodPost:
+			job, err := s.Cancel(id)
+			switch {
+			case errors.Is(err, errNotFound):
+				writeError(w, http.StatusNotFound, err)
+			case err != nil:
+				writeError(w, http.StatusConflict, err)
+			default:
+				writeJSON(w, http.StatusOK, job)
+			}
+		case action != "" && action != "cancel":
+			writeError(w, http.StatusNotFound, errors.New("not found"))
+		default:
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		}
+	})
+
+	mux.HandleFunc("/config/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+
+		if err := s.Reload(); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
+	})
+
+	return s.middleware(mux)
+}
+
+// checks the token and the content type, and answers the CORS preflight requests
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.AllowOrigin != "" && r.Header.Get("Origin") != "" {
+			w.Header().Set("Access-Control-Allow-Origin", s.AllowOrigin)
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if s.Token != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+				return
+			}
+		}
+
+		// browsers cannot send JSON to another origin without a preflight request. Every POST must
+		// declare it, even without a body, so that a plain form cannot cancel jobs or reload the configuration.
+		if r.Method == http.MethodPost && !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			writeError(w, http.StatusUnsupportedMediaType, errors.New("the content type must be application/json"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
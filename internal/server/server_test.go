@@ -0,0 +1,395 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func setup(t *testing.T) (*instance.Grab, string, *httptest.Server) {
+	t.Helper()
+
+	root := tu.GetOSRoot()
+	location := filepath.Join(root, "global")
+	configPath := filepath.Join(root, "grab.hcl")
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	t.Cleanup(ts.Close)
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+	utils.Io.WriteFile(utils.Fs, configPath, []byte(`
+global {
+	location = "`+tu.EscapeHCLString(location)+`"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/a[^\"]+)"
+		capture = 1
+	}
+}
+
+site "forced" {
+	test = "never matches"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/b[^\"]+)"
+		capture = 1
+	}
+}
+`), os.ModePerm)
+
+	g := &instance.Grab{Flags: &instance.FlagsState{ConfigPath: configPath}}
+	if diags := g.ParseConfig(); diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	return g, location, ts
+}
+
+func request(t *testing.T, handler http.Handler, method, path string, body interface{}, v interface{}) int {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		marshaled, _ := json.Marshal(body)
+		reader = bytes.NewReader(marshaled)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil || method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("got invalid json %q: %v", rec.Body.String(), err)
+		}
+	}
+
+	return rec.Code
+}
+
+// waits until the job is finished
+func wait(t *testing.T, handler http.Handler, id string) *Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job := &Job{}
+		request(t, handler, http.MethodGet, "/jobs/"+id, nil, job)
+
+		if job.finished() {
+			return job
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func TestServer(t *testing.T) {
+	g, location, ts := setup(t)
+
+	srv, err := New(g)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	finished := make(chan *Job, 2)
+	srv.AfterRun = func(job *Job) { finished <- job }
+
+	srv.Start()
+	defer srv.Stop()
+
+	handler := srv.Handler()
+
+	job := &Job{}
+	if code := request(t, handler, http.MethodPost, "/jobs", map[string]interface{}{"urls": []string{ts.URL + "/gallery/123/test#top"}}, job); code != http.StatusCreated {
+		t.Fatalf("got: %d, want: %d", code, http.StatusCreated)
+	}
+
+	if job.ID != "1" || job.URLs[0] != ts.URL+"/gallery/123/test" {
+		t.Errorf("got: %+v, want the first job without the fragment", job)
+	}
+
+	done := wait(t, handler, job.ID)
+	if done.State != JobDone || done.Totals.AssetsDownloaded != 1 {
+		t.Fatalf("got: %+v, want one download", done)
+	}
+
+	if len(done.Assets) != 1 || done.Assets[0].State != AssetDone || done.Assets[0].Bytes != 6 || done.Assets[0].Source != ts.URL+"/img/a.jpg" {
+		t.Errorf("got: %+v, want the progress of a.jpg", done.Assets)
+	}
+
+	if got := <-finished; got.ID != job.ID {
+		t.Errorf("got: %s, want: %s", got.ID, job.ID)
+	}
+
+	// the site is forced, even if its test pattern does not match
+	forced := &Job{}
+	request(t, handler, http.MethodPost, "/jobs", map[string]interface{}{"urls": []string{ts.URL + "/gallery/123/test"}, "site": "forced"}, forced)
+
+	if done := wait(t, handler, forced.ID); done.State != JobDone || len(done.Assets) != 1 || done.Assets[0].Site != "forced" {
+		t.Errorf("got: %+v, want b.jpg downloaded by forced", done)
+	}
+
+	if got, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(location, "forced", "b.jpg")); string(got) != "imageb" {
+		t.Errorf("got: %q, want: %q", got, "imageb")
+	}
+
+	var list struct{ Jobs []*Job }
+	request(t, handler, http.MethodGet, "/jobs", nil, &list)
+
+	if len(list.Jobs) != 2 || list.Jobs[0].Assets != nil {
+		t.Errorf("got: %+v, want two jobs without assets", list.Jobs)
+	}
+
+	// errors
+	for _, tt := range []struct {
+		Name   string
+		Method string
+		Path   string
+		Body   interface{}
+		Want   int
+	}{
+		{Name: "no urls", Method: http.MethodPost, Path: "/jobs", Body: map[string]interface{}{"urls": []string{}}, Want: http.StatusBadRequest},
+		{Name: "invalid url", Method: http.MethodPost, Path: "/jobs", Body: map[string]interface{}{"urls": []string{"not a url"}}, Want: http.StatusBadRequest},
+		{Name: "unknown site", Method: http.MethodPost, Path: "/jobs", Body: map[string]interface{}{"urls": []string{ts.URL}, "site": "missing"}, Want: http.StatusBadRequest},
+		{Name: "unknown job", Method: http.MethodGet, Path: "/jobs/42", Want: http.StatusNotFound},
+		{Name: "cancel finished job", Method: http.MethodPost, Path: "/jobs/1/cancel", Want: http.StatusConflict},
+		{Name: "method not allowed", Method: http.MethodDelete, Path: "/jobs", Want: http.StatusMethodNotAllowed},
+	} {
+		t.Run(tt.Name, func(tc *testing.T) {
+			var body map[string]string
+			if code := request(tc, handler, tt.Method, tt.Path, tt.Body, &body); code != tt.Want || body["error"] == "" {
+				tc.Errorf("got: %d %v, want: %d and an error", code, body, tt.Want)
+			}
+		})
+	}
+}
+
+func TestServerQueue(t *testing.T) {
+	g, location, ts := setup(t)
+
+	// not started, the jobs stay queued
+	srv, err := New(g)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	handler := srv.Handler()
+
+	for i := 0; i < 2; i++ {
+		request(t, handler, http.MethodPost, "/jobs", map[string]interface{}{"urls": []string{ts.URL + "/gallery/123/test"}}, nil)
+	}
+
+	canceled := &Job{}
+	if code := request(t, handler, http.MethodPost, "/jobs/1/cancel", nil, canceled); code != http.StatusOK || canceled.State != JobCanceled {
+		t.Fatalf("got: %d %+v, want the job to be canceled", code, canceled)
+	}
+
+	// the queue is read again after a restart
+	restarted, err := New(g)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	jobs := restarted.Jobs()
+	if len(jobs) != 2 || jobs[0].State != JobCanceled || jobs[1].State != JobQueued {
+		t.Fatalf("got: %+v, want a canceled and a queued job", jobs)
+	}
+
+	restarted.Start()
+	defer restarted.Stop()
+
+	if done := wait(t, restarted.Handler(), "2"); done.State != JobDone {
+		t.Errorf("got: %+v, want the queued job to run", done)
+	}
+
+	job := &Job{}
+	request(t, restarted.Handler(), http.MethodPost, "/jobs", map[string]interface{}{"urls": []string{ts.URL + "/gallery/123/test"}}, job)
+	if job.ID != "3" {
+		t.Errorf("got: %s, want: 3", job.ID)
+	}
+
+	if exists, _ := utils.Io.Exists(utils.Fs, QueuePath(location)); !exists {
+		t.Errorf("got: no queue, want: %s", QueuePath(location))
+	}
+}
+
+func TestServerPool(t *testing.T) {
+	g, location, ts := setup(t)
+
+	srv, err := New(g)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	srv.Workers = 2
+	srv.Downloads = 1
+
+	srv.Start()
+	defer srv.Stop()
+
+	if cap(srv.pool) != 1 {
+		t.Fatalf("got: %d, want: %d", cap(srv.pool), 1)
+	}
+
+	// take the only slot, the jobs cannot download anything
+	srv.pool <- struct{}{}
+
+	handler := srv.Handler()
+
+	jobs := make([]*Job, 0, 2)
+	for _, site := range []string{"example", "forced"} {
+		job := &Job{}
+		request(t, handler, http.MethodPost, "/jobs", map[string]interface{}{"urls": []string{ts.URL + "/gallery/123/test"}, "site": site}, job)
+		jobs = append(jobs, job)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	for _, name := range []string{filepath.Join("example", "a.jpg"), filepath.Join("forced", "b.jpg")} {
+		if exists, _ := utils.Io.Exists(utils.Fs, filepath.Join(location, name)); exists {
+			t.Errorf("got: %s, want no download without a free slot", name)
+		}
+	}
+
+	<-srv.pool
+
+	for _, job := range jobs {
+		done := wait(t, handler, job.ID)
+		if done.State != JobDone || done.Totals.AssetsDownloaded != 1 {
+			t.Errorf("got: %+v, want one download", done)
+		}
+
+		// the jobs waited for the slot between their start and their end
+		if done.Started == nil || done.Finished == nil || done.Finished.Sub(*done.Started) < 100*time.Millisecond {
+			t.Errorf("got: started %v, finished %v, want distinct timestamps", done.Started, done.Finished)
+		}
+	}
+}
+
+func TestServerReload(t *testing.T) {
+	g, _, _ := setup(t)
+
+	srv, err := New(g)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	handler := srv.Handler()
+
+	utils.Io.WriteFile(utils.Fs, g.Flags.ConfigPath, []byte(`site "x" {}`), os.ModePerm)
+
+	var body map[string]string
+	if code := request(t, handler, http.MethodPost, "/config/reload", nil, &body); code != http.StatusBadRequest || body["error"] == "" {
+		t.Errorf("got: %d %v, want an error", code, body)
+	}
+
+	utils.Io.WriteFile(utils.Fs, g.Flags.ConfigPath, []byte(`
+global {
+	location = "somewhere"
+}
+
+site "reloaded" {
+	test = "example"
+
+	info "title" {
+		pattern = "<title>([^<]+)"
+		capture = 1
+	}
+}
+`), os.ModePerm)
+
+	if code := request(t, handler, http.MethodPost, "/config/reload", nil, nil); code != http.StatusOK {
+		t.Fatalf("got: %d, want: %d", code, http.StatusOK)
+	}
+
+	if code := request(t, handler, http.MethodPost, "/jobs", map[string]interface{}{"urls": []string{"https://example.com"}, "site": "reloaded"}, nil); code != http.StatusCreated {
+		t.Errorf("got: %d, want the new site to be known", code)
+	}
+}
+
+func TestServerMiddleware(t *testing.T) {
+	g, _, _ := setup(t)
+
+	srv, err := New(g)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	srv.Token = "secret"
+	srv.AllowOrigin = "*"
+
+	handler := srv.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("got: %d, want: %d without the token", rec.Code, http.StatusUnauthorized)
+	}
+
+	// preflight requests do not carry the token
+	req := httptest.NewRequest(http.MethodOptions, "/jobs", nil)
+	req.Header.Set("Origin", "https://example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("got: %d %v, want the CORS headers", rec.Code, rec.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewReader([]byte(`{"urls": ["https://example.com"]}`)))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "text/plain")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("got: %d, want: %d", rec.Code, http.StatusUnsupportedMediaType)
+	}
+
+	// the requests without a body must declare the content type too
+	for _, path := range []string{"/jobs/1/cancel", "/config/reload"} {
+		req = httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("got: %d, want: %d for %s", rec.Code, http.StatusUnsupportedMediaType, path)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewReader([]byte(`{"urls": ["https://example.com"]}`)))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("got: %d, want: %d", rec.Code, http.StatusCreated)
+	}
+}
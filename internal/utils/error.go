@@ -13,6 +13,48 @@ import (
 
 var ErrSilent = errors.New("ErrSilent")
 
+// exit codes of the program
+const (
+	// everything went well
+	ExitOK = 0
+	// an unexpected error, e.g. the disk could not be written
+	ExitError = 1
+	// the configuration file, the arguments or the flags are invalid, nothing was done
+	ExitConfig = 2
+	// some pages or downloads failed, the others succeeded
+	ExitPartial = 3
+	// pages or downloads failed and nothing succeeded
+	ExitFailure = 4
+)
+
+// ExitCodeError makes the program exit with Code. Wrap ErrSilent to exit without printing the error.
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code for the error returned by a command
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitErr *ExitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	return ExitError
+}
+
 const (
 	DiagInvalid hcl.DiagnosticSeverity = iota
 	DiagError
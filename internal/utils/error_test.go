@@ -2,6 +2,8 @@ package utils
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/hashicorp/hcl/v2"
@@ -127,3 +129,29 @@ func TestPrintDiag(t *testing.T) {
 		})
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		Name string
+		Err  error
+		Want int
+	}{
+		{Name: "nil", Err: nil, Want: ExitOK},
+		{Name: "silent", Err: ErrSilent, Want: ExitError},
+		{Name: "other", Err: errors.New("foo"), Want: ExitError},
+		{Name: "exit code", Err: &ExitCodeError{Code: ExitPartial, Err: ErrSilent}, Want: ExitPartial},
+		{Name: "wrapped", Err: fmt.Errorf("foo: %w", &ExitCodeError{Code: ExitConfig, Err: ErrSilent}), Want: ExitConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			if got := ExitCode(tt.Err); got != tt.Want {
+				tc.Errorf("got: %d, want: %d", got, tt.Want)
+			}
+		})
+	}
+
+	if err := (&ExitCodeError{Code: ExitConfig, Err: ErrSilent}); !errors.Is(err, ErrSilent) {
+		t.Errorf("got: %v, want: to wrap ErrSilent", err)
+	}
+}
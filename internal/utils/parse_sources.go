@@ -0,0 +1,447 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+type URLFormat string
+
+const (
+	// one url per line, with comments
+	FormatList URLFormat = "list"
+	// an array of urls or of objects with a url key
+	FormatJSON URLFormat = "json"
+	FormatCSV  URLFormat = "csv"
+	// sitemaps, sitemap indexes, RSS and Atom feeds
+	FormatXML URLFormat = "xml"
+)
+
+// DetectURLFormat returns the format of a list of urls from the extension of its name,
+// or from its first character if the extension is unknown
+func DetectURLFormat(name, contents string) URLFormat {
+	if u, err := url.Parse(name); err == nil && u.Scheme != "" && u.Host != "" {
+		name = u.Path
+	}
+
+	switch strings.ToLower(path.Ext(strings.ReplaceAll(name, "\\", "/"))) {
+	case ".json":
+		return FormatJSON
+	case ".csv":
+		return FormatCSV
+	case ".xml", ".rss", ".atom":
+		return FormatXML
+	}
+
+	trimmed := strings.TrimSpace(strings.TrimPrefix(contents, "\ufeff"))
+	switch {
+	case strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{"):
+		return FormatJSON
+	case strings.HasPrefix(trimmed, "<"):
+		return FormatXML
+	}
+
+	return FormatList
+}
+
+// ParseURLJSON parses an array of urls, or an array of objects with a "url" key
+func ParseURLJSON(contents, filename string) ([]string, hcl.Diagnostics) {
+	urls := make([]string, 0)
+
+	dec := json.NewDecoder(strings.NewReader(contents))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, jsonErrorDiags(contents, filename, dec, err)
+	}
+
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		start := skipJSONSpace(contents, 0)
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid URL list",
+			Detail:   "The document must be an array of urls, or an array of objects with a \"url\" key.",
+			Subject:  rangeOf(contents, filename, start, int(dec.InputOffset())),
+		}}
+	}
+
+	for dec.More() {
+		start := skipJSONSpace(contents, int(dec.InputOffset()))
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, jsonErrorDiags(contents, filename, dec, err)
+		}
+
+		subject := rangeOf(contents, filename, start, int(dec.InputOffset()))
+
+		var str string
+		var entry map[string]json.RawMessage
+
+		switch {
+		case json.Unmarshal(raw, &str) == nil:
+		case json.Unmarshal(raw, &entry) == nil:
+			value, ok := entry["url"]
+			if !ok || json.Unmarshal(value, &str) != nil {
+				return nil, hcl.Diagnostics{{
+					Severity: hcl.DiagError,
+					Summary:  "Missing url",
+					Detail:   "The object must have a \"url\" key with a string value.",
+					Subject:  subject,
+				}}
+			}
+		default:
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid URL entry",
+				Detail:   fmt.Sprintf("Expected a string or an object, got '%s'.", string(raw)),
+				Subject:  subject,
+			}}
+		}
+
+		cleaned, ok := cleanURL(str)
+		if !ok {
+			return nil, invalidURLDiags(str, subject)
+		}
+
+		urls = append(urls, cleaned)
+	}
+
+	return urls, nil
+}
+
+// the decoder reads one value at a time, the offset is right after the previous value
+func skipJSONSpace(contents string, offset int) int {
+	for offset < len(contents) && strings.ContainsRune(" \t\r\n,", rune(contents[offset])) {
+		offset++
+	}
+
+	return offset
+}
+
+func jsonErrorDiags(contents, filename string, dec *json.Decoder, err error) hcl.Diagnostics {
+	offset := int(dec.InputOffset())
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// the offset is right after the last valid token, point at the invalid character instead
+		offset = int(syntaxErr.Offset)
+		for offset < len(contents) && strings.ContainsRune(" \t\r\n", rune(contents[offset])) {
+			offset++
+		}
+	}
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		offset = len(contents)
+	}
+
+	return hcl.Diagnostics{{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid JSON",
+		Detail:   err.Error(),
+		Subject:  rangeOf(contents, filename, offset, offset),
+	}}
+}
+
+// ParseURLCSV returns the urls of a column of a csv file. The column is the name of a header or
+// a 1 based index. If empty, the column with the "url" header is used, or the first one.
+// The first row is considered a header if its value in the column is not a url.
+func ParseURLCSV(contents, filename, column string) ([]string, hcl.Diagnostics) {
+	urls := make([]string, 0)
+
+	r := csv.NewReader(strings.NewReader(contents))
+	r.FieldsPerRecord = -1
+
+	index := -1
+	if column != "" {
+		if i, err := strconv.Atoi(column); err == nil {
+			if i < 1 {
+				return nil, hcl.Diagnostics{{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid column",
+					Detail:   fmt.Sprintf("The column index must be greater than zero, got %d.", i),
+				}}
+			}
+			index = i - 1
+		}
+	}
+
+	for row := 0; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, hcl.Diagnostics{{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid CSV",
+					Detail:   parseErr.Err.Error(),
+					Subject: &hcl.Range{
+						Filename: filename,
+						Start:    hcl.Pos{Line: parseErr.Line, Column: parseErr.Column},
+						End:      hcl.Pos{Line: parseErr.Line, Column: parseErr.Column},
+					},
+				}}
+			}
+
+			return nil, hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "Invalid CSV", Detail: err.Error()}}
+		}
+
+		if row == 0 {
+			header := -1
+			for i, name := range record {
+				name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+				if (column == "" && strings.EqualFold(name, "url")) || (index < 0 && strings.EqualFold(name, column)) {
+					header = i
+					break
+				}
+			}
+
+			switch {
+			case header >= 0:
+				index = header
+				continue
+			case column != "" && index < 0:
+				line, _ := r.FieldPos(0)
+				return nil, hcl.Diagnostics{{
+					Severity: hcl.DiagError,
+					Summary:  "Unknown column",
+					Detail:   fmt.Sprintf("The header has no column named '%s'.", column),
+					Subject: &hcl.Range{
+						Filename: filename,
+						Start:    hcl.Pos{Line: line, Column: 1},
+						End:      hcl.Pos{Line: line, Column: utf8.RuneCountInString(strings.Join(record, ",")) + 1},
+					},
+				}}
+			case index < 0:
+				index = 0
+			}
+
+			// the first row is a header without a "url" column
+			if index < len(record) {
+				if _, ok := cleanURL(strings.TrimSpace(record[index])); !ok {
+					continue
+				}
+			}
+		}
+
+		if index >= len(record) {
+			continue
+		}
+
+		value := strings.TrimSpace(record[index])
+		if value == "" {
+			continue
+		}
+
+		cleaned, ok := cleanURL(value)
+		if !ok {
+			line, col := r.FieldPos(index)
+			return nil, invalidURLDiags(value, &hcl.Range{
+				Filename: filename,
+				Start:    hcl.Pos{Line: line, Column: col},
+				End:      hcl.Pos{Line: line, Column: col + utf8.RuneCountInString(record[index])},
+			})
+		}
+
+		urls = append(urls, cleaned)
+	}
+
+	return urls, nil
+}
+
+// ParseURLXML returns the urls of a sitemap, a RSS feed or an Atom feed.
+// The locations of the sitemaps of a sitemap index are returned separately.
+func ParseURLXML(contents, filename string) ([]string, []string, hcl.Diagnostics) {
+	urls := make([]string, 0)
+	sitemaps := make([]string, 0)
+
+	dec := xml.NewDecoder(strings.NewReader(contents))
+	// feeds are not always valid XML, e.g. they use HTML entities
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+
+	stack := make([]string, 0)
+	var text strings.Builder
+	var textStart, textEnd hcl.Pos
+
+	for {
+		line, col := dec.InputPos()
+		tokenStart := hcl.Pos{Line: line, Column: col}
+
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line, col := dec.InputPos()
+
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				line, col = syntaxErr.Line, 1
+			}
+
+			return nil, nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid XML",
+				Detail:   err.Error(),
+				Subject: &hcl.Range{
+					Filename: filename,
+					Start:    hcl.Pos{Line: line, Column: col},
+					End:      hcl.Pos{Line: line, Column: col},
+				},
+			}}
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			text.Reset()
+
+			line, col := dec.InputPos()
+			textStart = hcl.Pos{Line: line, Column: col}
+			textEnd = textStart
+
+			if len(stack) == 1 {
+				switch t.Name.Local {
+				case "urlset", "sitemapindex", "rss", "feed", "RDF":
+				default:
+					return nil, nil, hcl.Diagnostics{{
+						Severity: hcl.DiagError,
+						Summary:  "Unsupported XML document",
+						Detail:   fmt.Sprintf("Expected a sitemap, a sitemap index, a RSS or an Atom feed, got a '%s' element.", t.Name.Local),
+						Subject:  &hcl.Range{Filename: filename, Start: tokenStart, End: textStart},
+					}}
+				}
+			}
+
+			// atom links are attributes: <link rel="alternate" href="..." />
+			if matchesPath(stack, "feed", "entry", "link") {
+				href, rel := "", ""
+				for _, attr := range t.Attr {
+					switch attr.Name.Local {
+					case "href":
+						href = strings.TrimSpace(attr.Value)
+					case "rel":
+						rel = attr.Value
+					}
+				}
+
+				if href != "" && (rel == "" || rel == "alternate") {
+					cleaned, ok := cleanURL(href)
+					if !ok {
+						return nil, nil, invalidURLDiags(href, &hcl.Range{Filename: filename, Start: tokenStart, End: textStart})
+					}
+					urls = append(urls, cleaned)
+				}
+			}
+
+		case xml.CharData:
+			text.Write(t)
+
+			line, col := dec.InputPos()
+			textEnd = hcl.Pos{Line: line, Column: col}
+
+		case xml.EndElement:
+			value := strings.TrimSpace(text.String())
+			subject := &hcl.Range{Filename: filename, Start: textStart, End: textEnd}
+
+			isPage := matchesPath(stack, "urlset", "url", "loc") ||
+				matchesPath(stack, "rss", "channel", "item", "link") ||
+				matchesPath(stack, "RDF", "item", "link")
+			isSitemap := matchesPath(stack, "sitemapindex", "sitemap", "loc")
+
+			if (isPage || isSitemap) && value != "" {
+				cleaned, ok := cleanURL(value)
+				if !ok {
+					return nil, nil, invalidURLDiags(value, subject)
+				}
+
+				if isPage {
+					urls = append(urls, cleaned)
+				} else {
+					sitemaps = append(sitemaps, cleaned)
+				}
+			}
+
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			text.Reset()
+		}
+	}
+
+	return urls, sitemaps, nil
+}
+
+func matchesPath(stack []string, names ...string) bool {
+	if len(stack) != len(names) {
+		return false
+	}
+
+	for i := range names {
+		if stack[i] != names[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// returns the absolute url without the fragment
+func cleanURL(str string) (string, bool) {
+	u, err := url.Parse(str)
+	if err != nil || !u.IsAbs() {
+		return "", false
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	return u.String(), true
+}
+
+func invalidURLDiags(str string, subject *hcl.Range) hcl.Diagnostics {
+	return hcl.Diagnostics{{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid URL",
+		Detail:   fmt.Sprintf("The string '%s' is not a valid url.", str),
+		Subject:  subject,
+	}}
+}
+
+// returns the range between two byte offsets of contents
+func rangeOf(contents, filename string, start, end int) *hcl.Range {
+	return &hcl.Range{
+		Filename: filename,
+		Start:    posOf(contents, start),
+		End:      posOf(contents, end),
+	}
+}
+
+func posOf(contents string, offset int) hcl.Pos {
+	if offset > len(contents) {
+		offset = len(contents)
+	}
+
+	lineStart := strings.LastIndexByte(contents[:offset], '\n') + 1
+
+	return hcl.Pos{
+		Line:   strings.Count(contents[:offset], "\n") + 1,
+		Column: utf8.RuneCountInString(contents[lineStart:offset]) + 1,
+		Byte:   offset,
+	}
+}
@@ -0,0 +1,353 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tu "github.com/everdrone/grab/testutils"
+	"github.com/hashicorp/hcl/v2"
+)
+
+func TestDetectURLFormat(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Filename string
+		Contents string
+		Want     URLFormat
+	}{
+		{Name: "json extension", Filename: "list.json", Want: FormatJSON},
+		{Name: "csv extension", Filename: "/tmp/LIST.CSV", Want: FormatCSV},
+		{Name: "sitemap", Filename: "sitemap.xml", Want: FormatXML},
+		{Name: "remote feed", Filename: "https://example.com/feed.rss?page=2", Want: FormatXML},
+		{Name: "json content", Filename: "<stdin>", Contents: "  [\"https://example.com\"]", Want: FormatJSON},
+		{Name: "xml content", Filename: "https://example.com/feed", Contents: "<?xml version=\"1.0\"?><rss/>", Want: FormatXML},
+		{Name: "list", Filename: "list.ini", Contents: "https://example.com", Want: FormatList},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			if got := DetectURLFormat(tt.Filename, tt.Contents); got != tt.Want {
+				tc.Errorf("got: %s, want: %s", got, tt.Want)
+			}
+		})
+	}
+}
+
+func TestParseURLJSON(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Input     string
+		Want      []string
+		WantDiag  string
+		WantRange *hcl.Range
+	}{
+		{
+			Name:  "strings",
+			Input: `["https://a.com/1#top", "https://a.com/2"]`,
+			Want:  []string{"https://a.com/1", "https://a.com/2"},
+		},
+		{
+			Name: "objects",
+			Input: `[
+	{"url": "https://a.com/1", "title": "one"},
+	"https://a.com/2"
+]`,
+			Want: []string{"https://a.com/1", "https://a.com/2"},
+		},
+		{
+			Name: "missing url",
+			Input: `[
+	{"url": "https://a.com/1"},
+	{"link": "https://a.com/2"}
+]`,
+			WantDiag: "Missing url",
+			WantRange: &hcl.Range{
+				Filename: "list.json",
+				Start:    hcl.Pos{Line: 3, Column: 2, Byte: 32},
+				End:      hcl.Pos{Line: 3, Column: 29, Byte: 59},
+			},
+		},
+		{
+			Name:     "invalid url",
+			Input:    `["https://a.com/1", "not a url"]`,
+			WantDiag: "Invalid URL",
+			WantRange: &hcl.Range{
+				Filename: "list.json",
+				Start:    hcl.Pos{Line: 1, Column: 21, Byte: 20},
+				End:      hcl.Pos{Line: 1, Column: 32, Byte: 31},
+			},
+		},
+		{
+			Name:     "not an array",
+			Input:    `{"url": "https://a.com/1"}`,
+			WantDiag: "Invalid URL list",
+		},
+		{
+			Name:     "syntax error",
+			Input:    "[\n\t\"https://a.com/1\",\n\t}\n]",
+			WantDiag: "Invalid JSON",
+			WantRange: &hcl.Range{
+				Filename: "list.json",
+				Start:    hcl.Pos{Line: 3, Column: 2, Byte: 23},
+				End:      hcl.Pos{Line: 3, Column: 2, Byte: 23},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			got, diags := ParseURLJSON(tt.Input, "list.json")
+			checkURLs(tc, got, diags, tt.Want, tt.WantDiag, tt.WantRange)
+		})
+	}
+}
+
+func TestParseURLCSV(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Input     string
+		Column    string
+		Want      []string
+		WantDiag  string
+		WantRange *hcl.Range
+	}{
+		{
+			Name:  "url header",
+			Input: "title,url\none,https://a.com/1\ntwo,https://a.com/2\nthree,\n",
+			Want:  []string{"https://a.com/1", "https://a.com/2"},
+		},
+		{
+			Name:  "no header",
+			Input: "https://a.com/1,one\nhttps://a.com/2,two\n",
+			Want:  []string{"https://a.com/1", "https://a.com/2"},
+		},
+		{
+			Name:  "header without url column",
+			Input: "link,title\nhttps://a.com/1,one\n",
+			Want:  []string{"https://a.com/1"},
+		},
+		{
+			Name:   "column name",
+			Input:  "url,Link\nhttps://a.com/0,https://a.com/1\n",
+			Column: "link",
+			Want:   []string{"https://a.com/1"},
+		},
+		{
+			Name:   "column index",
+			Input:  "one,https://a.com/1\ntwo,https://a.com/2\n",
+			Column: "2",
+			Want:   []string{"https://a.com/1", "https://a.com/2"},
+		},
+		{
+			Name:     "unknown column",
+			Input:    "title,url\none,https://a.com/1\n",
+			Column:   "link",
+			WantDiag: "Unknown column",
+			WantRange: &hcl.Range{
+				Filename: "list.csv",
+				Start:    hcl.Pos{Line: 1, Column: 1},
+				End:      hcl.Pos{Line: 1, Column: 10},
+			},
+		},
+		{
+			Name:     "invalid index",
+			Input:    "https://a.com/1\n",
+			Column:   "0",
+			WantDiag: "Invalid column",
+		},
+		{
+			Name:     "invalid url",
+			Input:    "title,url\none,https://a.com/1\ntwo,nope\n",
+			WantDiag: "Invalid URL",
+			WantRange: &hcl.Range{
+				Filename: "list.csv",
+				Start:    hcl.Pos{Line: 3, Column: 5},
+				End:      hcl.Pos{Line: 3, Column: 9},
+			},
+		},
+		{
+			Name:     "syntax error",
+			Input:    "title,url\none,\"https://a.com/1\n",
+			WantDiag: "Invalid CSV",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			got, diags := ParseURLCSV(tt.Input, "list.csv", tt.Column)
+			checkURLs(tc, got, diags, tt.Want, tt.WantDiag, tt.WantRange)
+		})
+	}
+}
+
+func TestParseURLXML(t *testing.T) {
+	tests := []struct {
+		Name         string
+		Input        string
+		Want         []string
+		WantSitemaps []string
+		WantDiag     string
+		WantRange    *hcl.Range
+	}{
+		{
+			Name: "sitemap",
+			Input: `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://a.com/1</loc><lastmod>2022-01-01</lastmod></url>
+  <url>
+    <loc>
+      https://a.com/2?a=1&amp;b=2
+    </loc>
+  </url>
+</urlset>`,
+			Want:         []string{"https://a.com/1", "https://a.com/2?a=1&b=2"},
+			WantSitemaps: []string{},
+		},
+		{
+			Name: "sitemap index",
+			Input: `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://a.com/sitemap-1.xml</loc></sitemap>
+  <sitemap><loc>https://a.com/sitemap-2.xml</loc></sitemap>
+</sitemapindex>`,
+			Want:         []string{},
+			WantSitemaps: []string{"https://a.com/sitemap-1.xml", "https://a.com/sitemap-2.xml"},
+		},
+		{
+			Name: "rss",
+			Input: `<rss version="2.0"><channel>
+  <title>Feed</title>
+  <link>https://a.com/</link>
+  <item><title>One &mdash; first</title><link>https://a.com/1</link></item>
+  <item><title>Two</title><link>https://a.com/2</link></item>
+</channel></rss>`,
+			Want:         []string{"https://a.com/1", "https://a.com/2"},
+			WantSitemaps: []string{},
+		},
+		{
+			Name: "atom",
+			Input: `<feed xmlns="http://www.w3.org/2005/Atom">
+  <link href="https://a.com/" rel="self" />
+  <entry>
+    <link href="https://a.com/1" />
+    <link rel="enclosure" href="https://a.com/1.mp3" />
+  </entry>
+  <entry><link rel="alternate" href="https://a.com/2"/></entry>
+</feed>`,
+			Want:         []string{"https://a.com/1", "https://a.com/2"},
+			WantSitemaps: []string{},
+		},
+		{
+			Name:     "unsupported document",
+			Input:    "<html>\n<body></body></html>",
+			WantDiag: "Unsupported XML document",
+			WantRange: &hcl.Range{
+				Filename: "sitemap.xml",
+				Start:    hcl.Pos{Line: 1, Column: 1},
+				End:      hcl.Pos{Line: 1, Column: 7},
+			},
+		},
+		{
+			Name: "invalid url",
+			Input: `<urlset>
+  <url><loc>https://a.com/1</loc></url>
+  <url><loc>nope</loc></url>
+</urlset>`,
+			WantDiag: "Invalid URL",
+			WantRange: &hcl.Range{
+				Filename: "sitemap.xml",
+				Start:    hcl.Pos{Line: 3, Column: 13},
+				End:      hcl.Pos{Line: 3, Column: 17},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(tc *testing.T) {
+			got, sitemaps, diags := ParseURLXML(tt.Input, "sitemap.xml")
+			checkURLs(tc, got, diags, tt.Want, tt.WantDiag, tt.WantRange)
+
+			if tt.WantDiag == "" && !reflect.DeepEqual(sitemaps, tt.WantSitemaps) {
+				tc.Errorf("got: %v, want: %v", sitemaps, tt.WantSitemaps)
+			}
+		})
+	}
+}
+
+func TestURLReader(t *testing.T) {
+	root := tu.GetOSRoot()
+	Fs, Io, Wd = tu.SetupMemMapFs(root)
+
+	Io.WriteFile(Fs, filepath.Join(root, "urls.json"), []byte(`["https://a.com/1"]`), os.ModePerm)
+	Io.WriteFile(Fs, filepath.Join(root, "sitemap.xml"), []byte(`<sitemapindex>
+  <sitemap><loc>https://a.com/sitemap-pages.xml</loc></sitemap>
+</sitemapindex>`), os.ModePerm)
+
+	remote := map[string]string{
+		"https://a.com/sitemap-pages.xml": `<urlset><url><loc>https://a.com/3</loc></url></urlset>`,
+		"https://a.com/list.csv":          "url\nhttps://a.com/4\n",
+		"https://a.com/loop.xml":          `<sitemapindex><sitemap><loc>https://a.com/loop.xml</loc></sitemap></sitemapindex>`,
+	}
+
+	reader := &URLReader{
+		Stdin: strings.NewReader("# from stdin\nhttps://a.com/2\n"),
+		Fetch: func(url string) (string, error) {
+			if body, ok := remote[url]; ok {
+				return body, nil
+			}
+			return "", errors.New("not found")
+		},
+	}
+
+	got, diags := reader.FromArgs([]string{"urls.json", "-", "sitemap.xml", "https://a.com/5"})
+	if diags.HasErrors() {
+		t.Fatalf("got: %v, want: no errors", diags)
+	}
+
+	want := []string{"https://a.com/1", "https://a.com/2", "https://a.com/3", "https://a.com/5"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+
+	got, diags = reader.FromList("https://a.com/list.csv")
+	if diags.HasErrors() || !reflect.DeepEqual(got, []string{"https://a.com/4"}) {
+		t.Errorf("got: %v %v, want: the remote csv", got, diags)
+	}
+
+	for _, source := range []string{"https://a.com/missing.xml", "https://a.com/loop.xml"} {
+		if _, diags := reader.FromList(source); !diags.HasErrors() {
+			t.Errorf("got: no errors for %s, want errors", source)
+		}
+	}
+
+	// remote lists need a fetch function
+	if _, diags := (&URLReader{}).FromList("https://a.com/list.csv"); !diags.HasErrors() {
+		t.Errorf("got: no errors, want: remote lists are not supported")
+	}
+}
+
+func checkURLs(t *testing.T, got []string, diags hcl.Diagnostics, want []string, wantDiag string, wantRange *hcl.Range) {
+	t.Helper()
+
+	if wantDiag == "" {
+		if diags.HasErrors() {
+			t.Fatalf("got: %v, want: no errors", diags)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got: %v, want: %v", got, want)
+		}
+		return
+	}
+
+	if len(diags) != 1 || diags[0].Summary != wantDiag {
+		t.Fatalf("got: %v, want: %s", diags, wantDiag)
+	}
+
+	if wantRange != nil && !reflect.DeepEqual(diags[0].Subject, wantRange) {
+		t.Errorf("got: %#v, want: %#v", diags[0].Subject, wantRange)
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"net/url"
 	"strings"
 
@@ -51,7 +52,26 @@ func ParseURLList(contents, filename string) ([]string, hcl.Diagnostics) {
 	return urls, nil
 }
 
+// the maximum depth of nested sitemap indexes
+const maxSitemapDepth = 3
+
+// URLReader collects the urls to scrape from the arguments of the get command
+type URLReader struct {
+	// read by the "-" argument
+	Stdin io.Reader
+	// fetches the remote lists and the sitemaps of the sitemap indexes, remote lists are not supported if nil
+	Fetch func(url string) (string, error)
+	// the column of the csv files, see ParseURLCSV
+	Column string
+}
+
 func GetURLsFromArgs(args []string) ([]string, hcl.Diagnostics) {
+	return (&URLReader{}).FromArgs(args)
+}
+
+// FromArgs returns the urls of the arguments. An argument can be a url, "-" to read a list from the
+// standard input, or the path of a list of urls (one per line, JSON, CSV, sitemap, RSS or Atom feed).
+func (r *URLReader) FromArgs(args []string) ([]string, hcl.Diagnostics) {
 	urls := make([]string, 0)
 
 	for _, arg := range args {
@@ -61,10 +81,12 @@ func GetURLsFromArgs(args []string) ([]string, hcl.Diagnostics) {
 			parsed.RawFragment = ""
 
 			urls = append(urls, parsed.String())
-		} else {
+			continue
+		}
+
+		if arg != "-" {
 			// not valid, check if it's a file
-			absolute := Abs(arg)
-			exists, err := Io.Exists(Fs, absolute)
+			exists, err := Io.Exists(Fs, Abs(arg))
 			if err != nil || !exists {
 				return nil, hcl.Diagnostics{&hcl.Diagnostic{
 					Severity: hcl.DiagError,
@@ -72,27 +94,120 @@ func GetURLsFromArgs(args []string) ([]string, hcl.Diagnostics) {
 					Detail:   fmt.Sprintf("The argument '%s' is not a valid url, nor a file.", arg),
 				}}
 			}
+		}
 
-			// we got a file, parse it
-			fc, err := Io.ReadFile(Fs, absolute)
-			if err != nil {
-				return nil, hcl.Diagnostics{&hcl.Diagnostic{
-					Severity: hcl.DiagError,
-					Summary:  "Could not read file",
-					Detail:   fmt.Sprintf("Could not read file '%s'.", absolute),
-				}}
-			}
+		parsed, diags := r.FromList(arg)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		urls = append(urls, parsed...)
+	}
+
+	return urls, nil
+}
+
+// FromList returns the urls of a list, which can be a path, "-" for the standard input or a url
+func (r *URLReader) FromList(source string) ([]string, hcl.Diagnostics) {
+	return r.fromList(source, 0)
+}
+
+func (r *URLReader) fromList(source string, depth int) ([]string, hcl.Diagnostics) {
+	contents, filename, diags := r.read(source)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	switch DetectURLFormat(filename, contents) {
+	case FormatJSON:
+		return ParseURLJSON(contents, filename)
+	case FormatCSV:
+		return ParseURLCSV(contents, filename, r.Column)
+	case FormatXML:
+		urls, sitemaps, diags := ParseURLXML(contents, filename)
+		if diags.HasErrors() {
+			return nil, diags
+		}
 
-			parsed, diags := ParseURLList(string(fc), absolute)
+		if len(sitemaps) > 0 && depth >= maxSitemapDepth {
+			return nil, hcl.Diagnostics{&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Too many nested sitemap indexes",
+				Detail:   fmt.Sprintf("The sitemap index '%s' is nested more than %d levels deep.", filename, maxSitemapDepth),
+			}}
+		}
+
+		for _, sitemap := range sitemaps {
+			nested, diags := r.fromList(sitemap, depth+1)
 			if diags.HasErrors() {
 				return nil, diags
 			}
 
-			urls = append(urls, parsed...)
+			urls = append(urls, nested...)
 		}
+
+		return urls, nil
+	default:
+		return ParseURLList(contents, filename)
 	}
+}
 
-	return urls, nil
+// returns the contents of the list and the filename used in the diagnostics
+func (r *URLReader) read(source string) (string, string, hcl.Diagnostics) {
+	if source == "-" {
+		if r.Stdin == nil {
+			return "", "", hcl.Diagnostics{&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Could not read the standard input",
+				Detail:   "The standard input is not available.",
+			}}
+		}
+
+		b, err := io.ReadAll(r.Stdin)
+		if err != nil {
+			return "", "", hcl.Diagnostics{&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Could not read the standard input",
+				Detail:   err.Error(),
+			}}
+		}
+
+		return string(b), "<stdin>", nil
+	}
+
+	if _, ok := IsValidURL(source); ok {
+		if r.Fetch == nil {
+			return "", "", hcl.Diagnostics{&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Could not fetch list",
+				Detail:   fmt.Sprintf("Remote lists are not supported here, could not fetch '%s'.", source),
+			}}
+		}
+
+		body, err := r.Fetch(source)
+		if err != nil {
+			return "", "", hcl.Diagnostics{&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Could not fetch list",
+				Detail:   fmt.Sprintf("%s: %s", source, err.Error()),
+			}}
+		}
+
+		return body, source, nil
+	}
+
+	absolute := Abs(source)
+
+	fc, err := Io.ReadFile(Fs, absolute)
+	if err != nil {
+		return "", "", hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Could not read file",
+			Detail:   fmt.Sprintf("Could not read file '%s'.", absolute),
+		}}
+	}
+
+	return string(fc), absolute, nil
 }
 
 func IsValidURL(str string) (*url.URL, bool) {
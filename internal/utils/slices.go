@@ -62,3 +62,15 @@ func ZipMap(keys []string, values []string) map[string]string {
 	}
 	return m
 }
+
+// Inherit returns the last value that is not nil, fallback if they are all nil.
+// The values are ordered from the outermost to the innermost block, e.g. global, site, asset.
+func Inherit[T any](fallback T, values ...*T) T {
+	for i := len(values) - 1; i >= 0; i-- {
+		if values[i] != nil {
+			return *values[i]
+		}
+	}
+
+	return fallback
+}
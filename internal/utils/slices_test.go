@@ -267,3 +267,19 @@ func TestZipMap(t *testing.T) {
 		})
 	}
 }
+
+func TestInherit(t *testing.T) {
+	yes, no := true, false
+
+	if got := Inherit(false); got != false {
+		t.Errorf("got: %v, want the fallback", got)
+	}
+
+	if got := Inherit(false, &yes, nil); got != true {
+		t.Errorf("got: %v, want the outer value", got)
+	}
+
+	if got := Inherit(false, &yes, nil, &no); got != false {
+		t.Errorf("got: %v, want the innermost value", got)
+	}
+}
@@ -7,6 +7,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -18,9 +19,9 @@ func main() {
 	// from: https://github.com/spf13/cobra/issues/914#issuecomment-548411337
 	if err := cmd.RootCmd.Execute(); err != nil {
 		// if we have ErrSilent, we don't want to print the error
-		if err != utils.ErrSilent {
+		if !errors.Is(err, utils.ErrSilent) {
 			fmt.Fprintln(os.Stderr, err)
 		}
-		os.Exit(1)
+		os.Exit(utils.ExitCode(err))
 	}
 }
@@ -0,0 +1,123 @@
+package grab
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// ErrInvalidURL is wrapped by the error of Scrape when one of the urls is not valid
+var ErrInvalidURL = errors.New("invalid url")
+
+// Problem is an error found in the configuration or during a run
+type Problem struct {
+	Summary string
+	Detail  string
+	// the position in the configuration, empty if the problem is not about a line of it
+	Filename string
+	Line     int
+	Column   int
+}
+
+func (p Problem) String() string {
+	str := p.Summary
+	if p.Detail != "" {
+		str += ": " + p.Detail
+	}
+
+	if p.Filename != "" {
+		str = fmt.Sprintf("%s:%d,%d: %s", p.Filename, p.Line, p.Column, str)
+	}
+
+	return str
+}
+
+func problems(diags hcl.Diagnostics) []Problem {
+	list := make([]Problem, 0, len(diags))
+
+	for _, diag := range diags {
+		if diag.Severity != hcl.DiagError {
+			continue
+		}
+
+		problem := Problem{Summary: diag.Summary, Detail: diag.Detail}
+		if diag.Subject != nil {
+			problem.Filename = diag.Subject.Filename
+			problem.Line = diag.Subject.Start.Line
+			problem.Column = diag.Subject.Start.Column
+		}
+
+		list = append(list, problem)
+	}
+
+	return list
+}
+
+func join(list []Problem) string {
+	strs := make([]string, 0, len(list))
+	for _, problem := range list {
+		strs = append(strs, problem.String())
+	}
+
+	return strings.Join(strs, "; ")
+}
+
+// ConfigError is returned when the configuration cannot be parsed or is not valid
+type ConfigError struct {
+	Problems []Problem
+}
+
+func newConfigError(diags hcl.Diagnostics) *ConfigError {
+	return &ConfigError{Problems: problems(diags)}
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration: " + join(e.Problems)
+}
+
+// RunError is returned when a run stops for a reason other than a page or a download, e.g. a directory that cannot be created
+type RunError struct {
+	Problems []Problem
+}
+
+func newRunError(diags hcl.Diagnostics) *RunError {
+	return &RunError{Problems: problems(diags)}
+}
+
+func (e *RunError) Error() string {
+	return join(e.Problems)
+}
+
+// PageError is returned by Scrape in strict mode, when a page cannot be fetched
+type PageError struct {
+	Site string
+	URL  string
+	Err  error
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("failed to fetch page %s: %s", e.URL, e.Err)
+}
+
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// DownloadError is returned by Download in strict mode, when an asset cannot be downloaded
+type DownloadError struct {
+	Site        string
+	Asset       string
+	Source      string
+	Destination string
+	Err         error
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("failed to download %s: %s", e.Source, e.Err)
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,27 @@
+package grab
+
+import "github.com/everdrone/grab/internal/instance"
+
+// Event describes a step of the scraping and downloading process
+type Event = instance.Event
+
+type EventType = instance.EventType
+
+const (
+	// a page was fetched, Bytes is the size of the body
+	EventPageFetched = instance.EventPageFetched
+	// a page could not be fetched
+	EventPageFailed = instance.EventPageFailed
+	// an asset url was found in a page
+	EventAssetMatched    = instance.EventAssetMatched
+	EventDownloadStarted = instance.EventDownloadStarted
+	// an asset was written to the disk, Bytes is the size of the file
+	EventDownloadFinished = instance.EventDownloadFinished
+	// an asset was not downloaded, the reason is in Reason
+	EventDownloadSkipped = instance.EventDownloadSkipped
+	EventDownloadFailed  = instance.EventDownloadFailed
+	// an info file was written
+	EventInfoWritten = instance.EventInfoWritten
+	// the command of a hook failed or timed out
+	EventHookFailed = instance.EventHookFailed
+)
@@ -0,0 +1,266 @@
+// Package grab embeds the scraper in other programs.
+//
+// A Client is created from a configuration file, from the contents of one or from a Config value.
+// Scrape fetches the pages and returns a Plan of the downloads, Download writes the files of a plan:
+//
+//	client, err := grab.NewFromFile("grab.hcl", grab.Options{})
+//	if err != nil {
+//		return err
+//	}
+//
+//	client.OnEvent(func(e *grab.Event) {
+//		if e.Type == grab.EventDownloadFinished {
+//			fmt.Println(e.Destination)
+//		}
+//	})
+//
+//	plan, err := client.Scrape(ctx, []string{"https://example.com/gallery/1"})
+//	if err != nil {
+//		return err
+//	}
+//
+//	report, err := client.Download(ctx, plan)
+//
+// The progress is reported with events, the failures with the error types of this package.
+// The internal packages still write their messages with the global zerolog logger,
+// use zerolog.SetGlobalLevel(zerolog.Disabled) to silence them.
+package grab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/everdrone/grab/internal/config"
+	"github.com/everdrone/grab/internal/instance"
+	"github.com/everdrone/grab/internal/utils"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// the configuration blocks, see the configuration guide for the meaning of every field
+type (
+	Config             = config.Config
+	GlobalConfig       = config.GlobalConfig
+	RootNetworkConfig  = config.RootNetworkConfig
+	SiteConfig         = config.SiteConfig
+	SubdirectoryConfig = config.SubdirectoryConfig
+	AssetConfig        = config.AssetConfig
+	InfoConfig         = config.InfoConfig
+	NetworkConfig      = config.NetworkConfig
+	TransformConfig    = config.TransformConfig
+	ChecksumConfig     = config.ChecksumConfig
+	HookConfig         = config.HookConfig
+	NotifyConfig       = config.NotifyConfig
+	IndexConfig        = config.IndexConfig
+)
+
+// the result of Scrape, it can be saved with its JSON method and read again with LoadPlan
+type (
+	Plan         = instance.Plan
+	PlanSite     = instance.PlanSite
+	PlanAsset    = instance.PlanAsset
+	PlanDownload = instance.PlanDownload
+	PlanInfo     = instance.PlanInfo
+)
+
+// Report is the summary of a run, returned by Download
+type Report = instance.Report
+
+// Counts are the totals of a Report
+type Counts = instance.Counts
+
+// LoadPlan decodes a plan written by Plan.JSON
+func LoadPlan(b []byte) (*Plan, error) {
+	return instance.LoadPlan(b)
+}
+
+// Options change the behavior of a client, like the flags of the get command
+type Options struct {
+	// replaces global.location if not empty, relative to the working directory
+	Location string
+	// overwrite existing files, the same as OnExists "overwrite"
+	Force bool
+	// what happens to the existing files, replaces the on_exists attributes if not empty
+	OnExists string
+	// how the pages are cached ("off", "use" or "refresh"), like the --cache flag. Empty for off.
+	Cache string
+	// stop at the first page or download that fails
+	Strict bool
+	// scrape the pages, but do not write anything
+	DryRun bool
+}
+
+// Client scrapes and downloads with a configuration. Its methods can run at the same time,
+// but the event handlers must be registered before.
+type Client struct {
+	grab *instance.Grab
+}
+
+// NewFromFile reads the configuration file at path, in any of the formats of the command line
+func NewFromFile(path string, options Options) (*Client, error) {
+	fc, err := utils.Io.ReadFile(utils.Fs, utils.Abs(path))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFromBytes(fc, path, options)
+}
+
+// NewFromBytes parses the configuration src. The extension of filename selects the format,
+// HCL native syntax by default, HCL JSON syntax for ".json" and YAML for ".yaml" or ".yml".
+func NewFromBytes(src []byte, filename string, options Options) (*Client, error) {
+	if options.OnExists != "" && !utils.Contains(config.OnExistsPolicies, options.OnExists) {
+		return nil, fmt.Errorf("invalid on_exists policy %q", options.OnExists)
+	}
+
+	if options.Cache != "" && !utils.Contains(config.CacheModes, options.Cache) {
+		return nil, fmt.Errorf("invalid cache mode %q", options.Cache)
+	}
+
+	g := &instance.Grab{
+		Flags: &instance.FlagsState{
+			ConfigPath: utils.Abs(filename),
+			Force:      options.Force,
+			OnExists:   options.OnExists,
+			Cache:      options.Cache,
+			Strict:     options.Strict,
+			DryRun:     options.DryRun,
+		},
+	}
+
+	if diags := g.LoadConfig(src, g.Flags.ConfigPath); diags.HasErrors() {
+		return nil, newConfigError(*diags)
+	}
+
+	if options.Location != "" {
+		g.Config.Global.Location = utils.Abs(options.Location)
+	}
+
+	return &Client{grab: g}, nil
+}
+
+// NewFromConfig uses a configuration built in code, validated like a configuration file.
+// The computed fields of the sites and the assets are ignored.
+func NewFromConfig(cfg *Config, options Options) (*Client, error) {
+	// the location is required, even when it is replaced by the options
+	copied := *cfg
+	if options.Location != "" {
+		copied.Global.Location = options.Location
+	}
+
+	file := hclwrite.NewEmptyFile()
+	gohcl.EncodeIntoBody(&copied, file.Body())
+
+	return NewFromBytes(file.Bytes(), filepath.Join(utils.Wd, "grab.hcl"), options)
+}
+
+// OnEvent registers a handler that receives the events of all the runs of the client, in the order they happen.
+// Runs started at the same time call the handlers at the same time.
+func (c *Client) OnEvent(handler func(e *Event)) {
+	c.grab.OnEvent(handler)
+}
+
+// Config returns the parsed configuration, with the location resolved
+func (c *Client) Config() *Config {
+	return c.grab.Config
+}
+
+// Scrape fetches the urls with the sites matching them and returns the downloads and the info found in the pages.
+// It stops before the next page when ctx is done, returning the error of the context.
+func (c *Client) Scrape(ctx context.Context, urls []string) (*Plan, error) {
+	g := c.grab.Clone()
+	g.Context = ctx
+
+	cleaned := make([]string, 0, len(urls))
+	for _, u := range urls {
+		parsed, ok := utils.IsValidURL(u)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidURL, u)
+		}
+
+		parsed.Fragment = ""
+		parsed.RawFragment = ""
+
+		cleaned = append(cleaned, parsed.String())
+	}
+
+	g.URLs = utils.Unique(cleaned)
+
+	failures := track(g)
+
+	g.BuildSiteCache()
+	if diags := g.BuildAssetCache(); diags.HasErrors() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if failures.page != nil {
+			return nil, &PageError{Site: failures.page.Site, URL: failures.page.Page, Err: errors.New(failures.page.Error)}
+		}
+
+		return nil, newRunError(*diags)
+	}
+
+	return g.Plan(), nil
+}
+
+// Download writes the files and the info of a plan, in the location of the plan.
+// It stops before the next download when ctx is done, returning the report so far and the error of the context.
+func (c *Client) Download(ctx context.Context, plan *Plan) (*Report, error) {
+	g := c.grab.Clone()
+	g.Context = ctx
+	g.ApplyPlan(plan, "")
+
+	report := instance.NewReport()
+	g.OnEvent(report.Handle)
+
+	failures := track(g)
+
+	err := g.Download()
+	report.Finish()
+
+	if err == nil {
+		return report, nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return report, ctxErr
+	}
+
+	var diags *hcl.Diagnostics
+	if errors.As(err, &diags) {
+		return report, newRunError(*diags)
+	}
+
+	if failures.download != nil {
+		d := failures.download
+		return report, &DownloadError{Site: d.Site, Asset: d.Asset, Source: d.Source, Destination: d.Destination, Err: err}
+	}
+
+	return report, err
+}
+
+// the last failures of a run
+type failures struct {
+	page     *Event
+	download *Event
+}
+
+// track records the failures of the run of g, they are returned as errors in strict mode
+func track(g *instance.Grab) *failures {
+	f := &failures{}
+
+	g.OnEvent(func(e *Event) {
+		switch e.Type {
+		case EventPageFailed:
+			f.page = e
+		case EventDownloadFailed:
+			f.download = e
+		}
+	})
+
+	return f
+}
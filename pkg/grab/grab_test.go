@@ -0,0 +1,203 @@
+package grab
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/everdrone/grab/internal/utils"
+	tu "github.com/everdrone/grab/testutils"
+)
+
+func setup(t *testing.T) (string, *httptest.Server) {
+	t.Helper()
+
+	root := tu.GetOSRoot()
+
+	e := tu.CreateMockServer()
+	ts := httptest.NewUnstartedServer(e)
+
+	ts.Listener.Close()
+	ts.Listener = e.Listener
+	ts.Start()
+
+	t.Cleanup(ts.Close)
+
+	utils.Fs, utils.Io, utils.Wd = tu.SetupMemMapFs(root)
+
+	return root, ts
+}
+
+const source = `
+global {
+	location = "downloads"
+}
+
+site "example" {
+	test = "http:\\/\\/127\\.0\\.0\\.1:\\d+"
+
+	asset "image" {
+		pattern = "<img src=\"([^\"]+/img/[ab][^\"]+)"
+		capture = 1
+		find_all = true
+	}
+}
+`
+
+func TestClient(t *testing.T) {
+	root, ts := setup(t)
+
+	client, err := NewFromBytes([]byte(source), "grab.hcl", Options{})
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if want := filepath.Join(root, "downloads"); client.Config().Global.Location != want {
+		t.Errorf("got: %s, want: %s", client.Config().Global.Location, want)
+	}
+
+	var mu sync.Mutex
+	events := make(map[EventType]int)
+	client.OnEvent(func(e *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		events[e.Type]++
+	})
+
+	plan, err := client.Scrape(context.Background(), []string{ts.URL + "/gallery/123/test#top"})
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if plan.Count() != 2 {
+		t.Fatalf("got: %d, want: 2 downloads", plan.Count())
+	}
+
+	// nothing is written before Download
+	if exists, _ := utils.Io.Exists(utils.Fs, filepath.Join(root, "downloads", "example")); exists {
+		t.Errorf("got: a directory, want nothing on disk")
+	}
+
+	report, err := client.Download(context.Background(), plan)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if report.Totals.AssetsDownloaded != 2 || report.Totals.Bytes != 12 {
+		t.Errorf("got: %+v, want two downloads of 6 bytes", report.Totals)
+	}
+
+	if got, _ := utils.Io.ReadFile(utils.Fs, filepath.Join(root, "downloads", "example", "b.jpg")); string(got) != "imageb" {
+		t.Errorf("got: %q, want: %q", got, "imageb")
+	}
+
+	if events[EventPageFetched] != 1 || events[EventDownloadFinished] != 2 {
+		t.Errorf("got: %v, want one page and two downloads", events)
+	}
+
+	// the plan can be saved and downloaded later
+	marshaled, _ := plan.JSON()
+	loaded, err := LoadPlan(marshaled)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if report, _ := client.Download(context.Background(), loaded); report.Totals.AssetsSkipped != 2 {
+		t.Errorf("got: %+v, want the existing files to be skipped", report.Totals)
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	root, ts := setup(t)
+
+	client, err := NewFromConfig(&Config{
+		Sites: []SiteConfig{{
+			Name: "example",
+			Test: `http:\/\/127\.0\.0\.1:\d+`,
+			Assets: []AssetConfig{{
+				Name:    "image",
+				Pattern: `<img src="([^"]+/img/c[^"]+)`,
+				Capture: "1",
+			}},
+		}},
+	}, Options{Location: "elsewhere", DryRun: true})
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	plan, err := client.Scrape(context.Background(), []string{ts.URL + "/gallery/123/test"})
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if plan.Location != filepath.Join(root, "elsewhere") || plan.Count() != 1 || plan.Sites[0].Assets[0].Downloads[0].Destination != "example/c.jpg" {
+		t.Errorf("got: %+v, want c.jpg in elsewhere", plan)
+	}
+
+	report, err := client.Download(context.Background(), plan)
+	if err != nil || report.Totals.AssetsSkipped != 1 {
+		t.Errorf("got: %v %+v, want the dry run to skip the download", err, report)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	root, ts := setup(t)
+
+	var configErr *ConfigError
+	if _, err := NewFromBytes([]byte(`site "x" {}`), "grab.hcl", Options{}); !errors.As(err, &configErr) || len(configErr.Problems) == 0 || configErr.Problems[0].Line == 0 {
+		t.Errorf("got: %v, want a configuration error with its position", err)
+	}
+
+	if _, err := NewFromFile(filepath.Join(root, "missing.hcl"), Options{}); err == nil {
+		t.Errorf("got: nil, want an error")
+	}
+
+	utils.Io.WriteFile(utils.Fs, filepath.Join(root, "grab.hcl"), []byte(source), os.ModePerm)
+
+	client, err := NewFromFile(filepath.Join(root, "grab.hcl"), Options{Strict: true})
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	if _, err := client.Scrape(context.Background(), []string{"not a url"}); !errors.Is(err, ErrInvalidURL) {
+		t.Errorf("got: %v, want: %v", err, ErrInvalidURL)
+	}
+
+	var pageErr *PageError
+	if _, err := client.Scrape(context.Background(), []string{ts.URL + "/givesNotFound"}); !errors.As(err, &pageErr) || pageErr.URL != ts.URL+"/givesNotFound" || pageErr.Site != "example" {
+		t.Errorf("got: %v, want a page error", err)
+	}
+
+	plan := &Plan{
+		Version:  1,
+		Location: filepath.Join(root, "downloads"),
+		Sites: []PlanSite{{
+			Name: "example",
+			Assets: []PlanAsset{{
+				Name:      "image",
+				Downloads: []PlanDownload{{Source: ts.URL + "/givesNotFound", Destination: "missing.jpg"}},
+			}},
+		}},
+	}
+
+	var downloadErr *DownloadError
+	if _, err := client.Download(context.Background(), plan); !errors.As(err, &downloadErr) || downloadErr.Destination != filepath.Join(root, "downloads", "missing.jpg") {
+		t.Errorf("got: %v, want a download error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.Scrape(ctx, []string{ts.URL + "/gallery/123/test"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got: %v, want: %v", err, context.Canceled)
+	}
+
+	if report, err := client.Download(ctx, plan); !errors.Is(err, context.Canceled) || report == nil {
+		t.Errorf("got: %v, want: %v and a report", err, context.Canceled)
+	}
+}
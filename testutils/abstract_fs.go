@@ -36,6 +36,23 @@ func (m *MockFs) Create(name string) (afero.File, error) {
 	return m.MemMapFs.Create(name)
 }
 
+// OpenFile gives an error when writing, like Create
+func (m *MockFs) OpenFile(name string, flag int, perm fs.FileMode) (afero.File, error) {
+	if strings.Contains(name, "restricted__w") && flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE) != 0 {
+		return nil, fs.ErrPermission
+	}
+
+	return m.MemMapFs.OpenFile(name, flag, perm)
+}
+
+func (m *MockFs) Rename(oldname, newname string) error {
+	if strings.Contains(newname, "restricted__w") {
+		return fs.ErrPermission
+	}
+
+	return m.MemMapFs.Rename(oldname, newname)
+}
+
 func (m *MockFs) MkdirAll(path string, perm fs.FileMode) error {
 	if strings.Contains(path, "restricted__m") {
 		return fs.ErrPermission
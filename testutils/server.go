@@ -88,6 +88,25 @@ func CreateMockServer() *echo.Echo {
 		return c.NoContent(http.StatusNotFound)
 	})
 
+	// a sitemap index pointing to a sitemap that lists the gallery
+	e.GET("/sitemap.xml", func(c echo.Context) error {
+		addr := "http://" + strings.Replace(e.ListenerAddr().String(), "[::]", "127.0.0.1", -1)
+
+		return c.Blob(http.StatusOK, "application/xml", []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>`+addr+`/sitemap-pages.xml</loc></sitemap>
+</sitemapindex>`))
+	})
+
+	e.GET("/sitemap-pages.xml", func(c echo.Context) error {
+		addr := "http://" + strings.Replace(e.ListenerAddr().String(), "[::]", "127.0.0.1", -1)
+
+		return c.Blob(http.StatusOK, "application/xml", []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>`+addr+`/gallery/123/test</loc></url>
+</urlset>`))
+	})
+
 	e.GET("/broken/:id", func(c echo.Context) error {
 		// will cause a reading error
 		c.Response().Header().Set("Content-Length", "999")